@@ -0,0 +1,253 @@
+// ---- File: actions.go ----
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Action Registry ---
+
+// menuAction is an entry of the action registry: a menu label, the items it
+// applies to and the function that runs it.
+type menuAction struct {
+	Label     string
+	AppliesTo func(item FileInfo, state *AppState) bool // nil means the action applies to every item
+	ActionFn  func(g *gocui.Gui, item FileInfo, state *AppState) error
+	Custom    bool // User-defined; reports its own outcome
+}
+
+// Applicability predicates shared by registry entries.
+func isRegularFile(item FileInfo, _ *AppState) bool { return !item.IsDir }
+func isDirectory(item FileInfo, _ *AppState) bool   { return item.IsDir }
+
+// isReadableFile reports whether item is a file whose content can be read;
+// actions on unreadable entries are limited to their paths and metadata.
+func isReadableFile(item FileInfo, _ *AppState) bool { return !item.IsDir && !item.Unreadable }
+
+// isReadable reports whether item's content can be read.
+func isReadable(item FileInfo, _ *AppState) bool { return !item.Unreadable }
+
+// isSymlink reports whether item is a symbolic link (listings don't follow them).
+func isSymlink(item FileInfo, _ *AppState) bool { return item.Mode&os.ModeSymlink != 0 }
+
+// isNotSymlink reports whether item is a file or folder rather than a link.
+func isNotSymlink(item FileInfo, state *AppState) bool { return !isSymlink(item, state) }
+
+// canCompareWithMark reports whether item is a file other than the one marked for compare.
+func canCompareWithMark(item FileInfo, state *AppState) bool {
+	marked, ok := state.CompareMark()
+	return ok && !item.IsDir && !item.Unreadable && item.Path != marked.Path
+}
+
+// sortsAscending reports whether names are listed A-Z, so the menu offers Z-A.
+func sortsAscending(_ FileInfo, state *AppState) bool { return !state.IsSortDescending() }
+
+// sortsDescending reports whether names are listed Z-A, so the menu offers A-Z.
+func sortsDescending(_ FileInfo, state *AppState) bool { return state.IsSortDescending() }
+
+// builtinActions returns the registry of built-in actions in menu order.
+func builtinActions() []menuAction {
+	return []menuAction{
+		{Label: "Copy Full Path", ActionFn: copyFullPath},
+		{Label: "Copy Relative Path", ActionFn: copyRelativePath},
+		{Label: "Copy file:// URL", ActionFn: copyFileURL},
+		{Label: "Copy scp Path", ActionFn: copyScpPath},
+		{Label: "View Content", AppliesTo: isReadableFile, ActionFn: viewFileContentAction},
+		{Label: "Open in Pager", AppliesTo: isReadableFile, ActionFn: openInPagerAction},
+		{Label: "Copy Content (UTF-8)", AppliesTo: isReadableFile, ActionFn: copyContent},
+		{Label: "Copy Head (First N Lines)", AppliesTo: isReadableFile, ActionFn: copyHeadAction},
+		{Label: "Copy Tail (Last N Lines)", AppliesTo: isReadableFile, ActionFn: copyTailAction},
+		{Label: "Duplicate", AppliesTo: isReadable, ActionFn: duplicateAction},
+		{Label: "Compare with Selected", AppliesTo: canCompareWithMark, ActionFn: compareWithMarkAction},
+		{Label: "History", AppliesTo: isTrackedFile, ActionFn: fileHistoryAction},
+		{Label: "Reveal in File Manager", ActionFn: revealInFileManagerAction},
+		{Label: "Rename", ActionFn: renameAction},
+		{Label: "Retarget Link", AppliesTo: isSymlink, ActionFn: retargetLinkAction},
+		{Label: "Delete Link", AppliesTo: isSymlink, ActionFn: deleteLinkAction},
+		{Label: "Delete", AppliesTo: isNotSymlink, ActionFn: deleteAction},
+		{Label: "Change Permissions", ActionFn: changePermissions},
+		{Label: "Properties", ActionFn: showPropertiesAction},
+		{Label: "Sort Z-A (Descending)", AppliesTo: sortsAscending, ActionFn: toggleSortOrderAction},
+		{Label: "Sort A-Z (Ascending)", AppliesTo: sortsDescending, ActionFn: toggleSortOrderAction},
+	}
+}
+
+// actionsFor returns the menu options that apply to item: the built-in
+// actions, then the custom ones, then Cancel.
+func actionsFor(item FileInfo, state *AppState) []ActionMenuItem {
+	var options []ActionMenuItem
+	for _, action := range append(builtinActions(), customActions...) {
+		if action.AppliesTo != nil && !action.AppliesTo(item, state) {
+			continue
+		}
+		options = append(options, ActionMenuItem{Label: action.Label, ActionFn: action.ActionFn, Custom: action.Custom})
+	}
+	options = append(options, ActionMenuItem{Label: "Cancel", ActionFn: func(*gocui.Gui, FileInfo, *AppState) error { return nil }}) // No-op cancel
+	assignMnemonics(options)
+	return options
+}
+
+// menuReservedKeys are action menu keys that can't be mnemonics.
+var menuReservedKeys = map[rune]bool{'j': true, 'k': true, 'q': true}
+
+// assignMnemonics gives each option a shortcut letter from its label, in menu
+// order: the first free word-initial letter if there is one, otherwise the
+// first free letter anywhere. Options without a free letter get none.
+func assignMnemonics(options []ActionMenuItem) {
+	taken := make(map[rune]bool)
+	for key := range menuReservedKeys {
+		taken[key] = true
+	}
+	for i := range options {
+		options[i].MnemonicIdx = -1
+		label := options[i].Label
+		for _, wordStartsOnly := range []bool{true, false} {
+			atWordStart := true
+			for idx, r := range label {
+				lower := unicode.ToLower(r)
+				if unicode.IsLetter(r) && (atWordStart || !wordStartsOnly) && !taken[lower] {
+					options[i].MnemonicIdx = idx
+					taken[lower] = true
+					break
+				}
+				if unicode.IsLetter(r) {
+					atWordStart = false
+				} else if unicode.IsSpace(r) {
+					atWordStart = true
+				}
+			}
+			if options[i].MnemonicIdx >= 0 {
+				break
+			}
+		}
+	}
+}
+
+// mnemonic returns the option's lowercase shortcut letter, or 0 if it has none.
+func (item ActionMenuItem) mnemonic() rune {
+	if item.MnemonicIdx < 0 || item.MnemonicIdx >= len(item.Label) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(item.Label[item.MnemonicIdx:])
+	return unicode.ToLower(r)
+}
+
+// --- Custom Actions ---
+
+// customActionOutputLines caps how much of a command's stdout the viewer shows.
+const customActionOutputLines = 500
+
+// customActions are the user-defined actions from config.json.
+var customActions []menuAction
+
+// configureActions builds customActions from the "custom_actions" setting.
+// Entries without a label or command are skipped.
+func configureActions(cfg config) {
+	customActions = nil
+	for _, ca := range cfg.CustomActions {
+		if ca.Label == "" || ca.Cmd == "" {
+			log.Printf("Warning: Skipping custom action without label or cmd: %+v", ca)
+			continue
+		}
+		action := menuAction{Label: ca.Label, ActionFn: customActionFn(ca), Custom: true}
+		switch strings.ToLower(ca.For) {
+		case "", "any":
+		case "file":
+			action.AppliesTo = isRegularFile
+		case "dir":
+			action.AppliesTo = isDirectory
+		default:
+			log.Printf("Warning: Custom action '%s' has unknown \"for\" value %q, offering it for any item", ca.Label, ca.For)
+		}
+		customActions = append(customActions, action)
+	}
+}
+
+// expandPlaceholders substitutes {path}, {dir} and {name} in cmdline with the
+// item's shell-quoted path, parent directory and base name.
+func expandPlaceholders(cmdline string, item FileInfo) string {
+	return strings.NewReplacer(
+		"{path}", shellQuote(item.Path),
+		"{dir}", shellQuote(filepath.Dir(item.Path)),
+		"{name}", shellQuote(item.Name),
+	).Replace(cmdline)
+}
+
+// customActionFn returns the menu function running ca for an item. Suspending
+// commands run in the foreground with the terminal; others run in the
+// background and report back through the message bar.
+func customActionFn(ca customActionConfig) func(g *gocui.Gui, item FileInfo, state *AppState) error {
+	return func(g *gocui.Gui, item FileInfo, state *AppState) error {
+		cmdline := expandPlaceholders(ca.Cmd, item)
+		log.Printf("Running custom action '%s': %s", ca.Label, cmdline)
+		if ca.Suspend {
+			cmd := shellCommand(cmdline)
+			cmd.Dir = state.Cwd()
+			state.SetMessage(customActionResult(ca.Label, runSuspended(g, cmd), ""))
+			return nil
+		}
+		state.SetMessage(fmt.Sprintf("Running '%s'...", ca.Label))
+		go runCustomAction(g, ca, cmdline, state.GetPreviousFocusView(), state)
+		return nil
+	}
+}
+
+// runCustomAction runs cmdline with its output captured, then reports the exit
+// status and, if requested, shows the first lines of stdout in the viewer.
+func runCustomAction(g *gocui.Gui, ca customActionConfig, cmdline, prevFocus string, state *AppState) {
+	cmd := shellCommand(cmdline)
+	cmd.Dir = state.Cwd()
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+
+	g.Update(func(gui *gocui.Gui) error {
+		state.SetMessage(customActionResult(ca.Label, err, stderr.String()))
+		if ca.ShowOutput && !state.IsOverlayVisible() {
+			output := firstLines(stdout.String(), customActionOutputLines)
+			if output == "" {
+				output = "[No output]"
+			}
+			state.SetFileContentView(ca.Label, strings.ReplaceAll(output, "\t", "    "), prevFocus)
+		}
+		return nil
+	})
+}
+
+// customActionResult describes how a custom action's command ended.
+func customActionResult(label string, err error, stderr string) string {
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return fmt.Sprintf("'%s' finished", label)
+	case errors.As(err, &exitErr):
+		msg := fmt.Sprintf("'%s' failed (exit status %d)", label, exitErr.ExitCode())
+		if line := firstLines(strings.TrimSpace(stderr), 1); line != "" {
+			msg += ": " + line
+		}
+		return msg
+	default:
+		return fmt.Sprintf("'%s' failed: %s", label, trimError(err))
+	}
+}
+
+// firstLines returns at most n lines of s.
+func firstLines(s string, n int) string {
+	lines := strings.SplitAfterN(s, "\n", n+1)
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	return strings.TrimRight(strings.Join(lines, ""), "\n")
+}
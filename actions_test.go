@@ -0,0 +1,127 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/gocui"
+)
+
+func TestActionsFor(t *testing.T) {
+	pathActions := []string{"Copy Full Path", "Copy Relative Path", "Copy file:// URL", "Copy scp Path"}
+	tests := []struct {
+		name string
+		item FileInfo
+		want []string // Labels after the path actions, before Cancel
+	}{
+		{
+			name: "file",
+			item: FileInfo{Name: "a.txt", Path: "/work/a.txt"},
+			want: []string{"View Content", "Open in Pager", "Copy Content (UTF-8)", "Copy Head (First N Lines)", "Copy Tail (Last N Lines)",
+				"Duplicate", "Reveal in File Manager", "Rename", "Delete", "Change Permissions", "Properties"},
+		},
+		{
+			name: "folder",
+			item: FileInfo{Name: "src", Path: "/work/src", IsDir: true},
+			want: []string{"Duplicate", "Reveal in File Manager", "Rename", "Delete", "Change Permissions", "Properties"},
+		},
+		{
+			name: "unreadable file",
+			item: FileInfo{Name: "secret", Path: "/work/secret", Unreadable: true},
+			want: []string{"Reveal in File Manager", "Rename", "Delete", "Change Permissions", "Properties"},
+		},
+		{
+			name: "symlink",
+			item: FileInfo{Name: "link", Path: "/work/link", Mode: os.ModeSymlink},
+			want: []string{"View Content", "Open in Pager", "Copy Content (UTF-8)", "Copy Head (First N Lines)", "Copy Tail (Last N Lines)",
+				"Duplicate", "Reveal in File Manager", "Rename", "Retarget Link", "Delete Link", "Change Permissions", "Properties"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := NewAppState(t.TempDir()) // Outside a repository: no History
+			var got []string
+			for _, option := range actionsFor(tt.item, state) {
+				got = append(got, option.Label)
+			}
+			want := slices.Concat(pathActions, tt.want, []string{"Sort Z-A (Descending)", "Cancel"})
+			if !slices.Equal(got, want) {
+				t.Errorf("got\n%q\nwant\n%q", got, want)
+			}
+		})
+	}
+}
+
+func TestActionsForCustomAndCompare(t *testing.T) {
+	defer func(saved []menuAction) { customActions = saved }(customActions)
+	noop := func(*gocui.Gui, FileInfo, *AppState) error { return nil }
+	customActions = []menuAction{
+		{Label: "Lint", AppliesTo: isRegularFile, ActionFn: noop, Custom: true},
+		{Label: "Open Terminal Here", AppliesTo: isDirectory, ActionFn: noop, Custom: true},
+	}
+
+	state := NewAppState(t.TempDir())
+	marked := FileInfo{Name: "a.txt", Path: "/work/a.txt"}
+	state.SetCompareMark(marked)
+	labels := func(item FileInfo) []string {
+		var out []string
+		for _, option := range actionsFor(item, state) {
+			out = append(out, option.Label)
+		}
+		return out
+	}
+
+	other := labels(FileInfo{Name: "b.txt", Path: "/work/b.txt"})
+	if !slices.Contains(other, "Compare with Selected") {
+		t.Errorf("no compare entry for a second file: %q", other)
+	}
+	if !slices.Contains(other, "Lint") || slices.Contains(other, "Open Terminal Here") {
+		t.Errorf("file got the wrong custom actions: %q", other)
+	}
+	if slices.Index(other, "Lint") != len(other)-2 {
+		t.Errorf("custom actions should come last, before Cancel: %q", other)
+	}
+	if self := labels(marked); slices.Contains(self, "Compare with Selected") {
+		t.Errorf("the marked file can be compared with itself: %q", self)
+	}
+	if dir := labels(FileInfo{Name: "src", Path: "/work/src", IsDir: true}); !slices.Contains(dir, "Open Terminal Here") {
+		t.Errorf("folder is missing its custom action: %q", dir)
+	}
+}
+
+func TestAssignMnemonics(t *testing.T) {
+	options := []ActionMenuItem{
+		{Label: "Copy Full Path"},
+		{Label: "Copy Relative Path"},
+		{Label: "Copy Content"},
+		{Label: "Quit"},
+		{Label: "cccc"},
+		{Label: "Cancel"},
+	}
+	assignMnemonics(options)
+	want := []rune{'c', 'r', 'o', 'u', 0, 'a'} // q is reserved; "cccc" has no free letter
+	for i, option := range options {
+		if got := option.mnemonic(); got != want[i] {
+			t.Errorf("%q: mnemonic %q, want %q", option.Label, got, want[i])
+		}
+	}
+}
+
+func TestActionsForSortDirection(t *testing.T) {
+	state := NewAppState(t.TempDir())
+	item := FileInfo{Name: "a.txt", Path: "/work/a.txt"}
+	for _, want := range []string{"Sort Z-A (Descending)", "Sort A-Z (Ascending)"} {
+		var sortLabels []string
+		for _, option := range actionsFor(item, state) {
+			if strings.HasPrefix(option.Label, "Sort ") {
+				sortLabels = append(sortLabels, option.Label)
+			}
+		}
+		if !slices.Equal(sortLabels, []string{want}) {
+			t.Errorf("descending %v: got %q, want %q", state.IsSortDescending(), sortLabels, want)
+		}
+		state.ToggleSortDescending()
+	}
+}
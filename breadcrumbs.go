@@ -0,0 +1,184 @@
+// ---- File: breadcrumbs.go ----
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Breadcrumbs ---
+// The Root Folder pane shows the CWD as one segment per folder ("~ › projects
+// › lazyls"). With the pane focused, left and right select a segment and
+// Enter goes to that folder, selecting the one it came from. Segments that
+// don't fit are elided from the middle, never the selected one.
+
+// breadcrumb is one segment of the CWD.
+type breadcrumb struct {
+	label string // As shown: a folder name, "~" for the home folder, or the root
+	path  string // The folder it leads to
+}
+
+// breadcrumbs splits path into its segments, from the root (or the home
+// folder, as "~") down to path itself.
+func breadcrumbs(path, home string) []breadcrumb {
+	if home != "" {
+		home = filepath.Clean(home)
+	}
+	var crumbs []breadcrumb
+	for dir := filepath.Clean(path); ; {
+		if dir == home {
+			crumbs = append(crumbs, breadcrumb{label: "~", path: dir})
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			crumbs = append(crumbs, breadcrumb{label: dir, path: dir}) // "/" or `C:\`
+			break
+		}
+		crumbs = append(crumbs, breadcrumb{label: filepath.Base(dir), path: dir})
+		dir = parent
+	}
+	slices.Reverse(crumbs)
+	return crumbs
+}
+
+// selectedCrumb returns the index of the breadcrumb selected in the Root
+// Folder pane, the last one (the CWD) unless idx, the state's
+// BreadcrumbIndex, is another.
+func selectedCrumb(idx int, crumbs []breadcrumb) int {
+	if idx >= 0 && idx < len(crumbs) {
+		return idx
+	}
+	return len(crumbs) - 1
+}
+
+// breadcrumbSeparator goes between breadcrumbs.
+func breadcrumbSeparator() string {
+	return " " + glyph("›") + " "
+}
+
+// visibleCrumbs picks the breadcrumbs that fit in width: the selected one,
+// the last and the first come first, then the selected one's neighbors, then
+// the rest from the end. Each gap left in between shows as an ellipsis.
+func visibleCrumbs(crumbs []breadcrumb, selected, width int) []bool {
+	shown := make([]bool, len(crumbs))
+	lineWidth := func() int {
+		total, gap := 0, false
+		for i, crumb := range crumbs {
+			switch {
+			case shown[i]:
+				if total > 0 {
+					total += displayWidth(breadcrumbSeparator())
+				}
+				total += displayWidth(crumb.label)
+				gap = false
+			case !gap:
+				if total > 0 {
+					total += displayWidth(breadcrumbSeparator())
+				}
+				total += displayWidth(ellipsis)
+				gap = true
+			}
+		}
+		return total
+	}
+	order := []int{selected, len(crumbs) - 1, 0}
+	for d := 1; d < len(crumbs); d++ {
+		order = append(order, selected+d, selected-d)
+	}
+	for i := len(crumbs) - 1; i >= 0; i-- {
+		order = append(order, i)
+	}
+	for n, i := range order {
+		if i < 0 || i >= len(crumbs) || shown[i] {
+			continue
+		}
+		shown[i] = true
+		if n >= 3 && lineWidth() > width { // The first three are shown whatever the width
+			shown[i] = false
+		}
+	}
+	return shown
+}
+
+// breadcrumbLine renders crumbs in width cells, the selected one in reverse
+// video while the pane has focus. The crumbs visibleCrumbs always shows may
+// not fit on their own; a label running past width is cut short and the
+// crumbs after it are left out.
+func breadcrumbLine(crumbs []breadcrumb, selected, width int, focused bool) string {
+	shown := visibleCrumbs(crumbs, selected, width)
+	var b strings.Builder
+	used, gap := 0, false
+	for i, crumb := range crumbs {
+		if !shown[i] && gap {
+			continue
+		}
+		sepWidth := 0
+		if used > 0 {
+			sepWidth = displayWidth(breadcrumbSeparator())
+		}
+		if !shown[i] {
+			if used+sepWidth+displayWidth(ellipsis) > width {
+				break
+			}
+		} else if used+sepWidth >= width {
+			break
+		}
+		if sepWidth > 0 {
+			b.WriteString(ansiDim + breadcrumbSeparator() + ansiReset)
+			used += sepWidth
+		}
+		if !shown[i] {
+			b.WriteString(ansiDim + ellipsis + ansiReset)
+			used += displayWidth(ellipsis)
+			gap = true
+			continue
+		}
+		gap = false
+		label := truncateWidth(crumb.label, width-used)
+		used += displayWidth(label)
+		label = toCells(label)
+		if focused && i == selected {
+			b.WriteString(ansiGreen + ansiReverse + label + ansiReset) // A color code after the reverse one would clear it
+		} else {
+			b.WriteString(ansiGreen + label + ansiReset)
+		}
+	}
+	return b.String()
+}
+
+// handleMoveBreadcrumb selects the breadcrumb delta places from the selected one.
+func handleMoveBreadcrumb(g *gocui.Gui, state *AppState, delta int) error {
+	home, _ := os.UserHomeDir()
+	crumbs := breadcrumbs(state.Cwd(), home)
+	idx := min(max(selectedCrumb(state.BreadcrumbIndex(), crumbs)+delta, 0), len(crumbs)-1)
+	if idx == len(crumbs)-1 {
+		idx = -1 // Stays on the CWD across reloads
+	}
+	state.SetBreadcrumbIndex(idx)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleOpenBreadcrumb goes to the folder of the selected breadcrumb,
+// selecting the folder it came from. On the CWD's own breadcrumb it copies
+// the CWD's full path instead.
+func handleOpenBreadcrumb(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	home, _ := os.UserHomeDir()
+	crumbs := breadcrumbs(state.Cwd(), home)
+	idx := selectedCrumb(state.BreadcrumbIndex(), crumbs)
+	if idx == len(crumbs)-1 {
+		return handleCopyCwd(g, v, state)
+	}
+	target, from := crumbs[idx].path, crumbs[idx+1].path
+	changeDirectory(g, state, target, func(gui *gocui.Gui) {
+		selectPath(gui, state, from)
+		state.SetMessage(fmt.Sprintf("Moved to %s", target))
+	})
+	return nil
+}
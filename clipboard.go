@@ -0,0 +1,149 @@
+// ---- File: clipboard.go ----
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Clipboard ---
+// The clipboard is reached through helper programs (xclip, xsel, wl-copy,
+// pbcopy) that can hang, e.g. without a display to talk to, so every read and
+// write runs in the background and is given up on after clipboardTimeout.
+// Ctrl+V goes to the path in the clipboard: a folder is opened, a file is
+// selected in its folder.
+
+// clipboardTimeout is how long a clipboard read or write may take.
+var clipboardTimeout = 2 * time.Second
+
+// errClipboardTimeout is returned when the clipboard didn't answer in time.
+var errClipboardTimeout = errors.New("clipboard not responding")
+
+// withClipboard runs fn, a clipboard access, in the background and waits at
+// most clipboardTimeout for it, returning the text fn read (reads) or ""
+// (writes). A timed-out fn is left to finish on its own; its result goes
+// into the buffered channel and is dropped.
+func withClipboard(fn func() (string, error)) (string, error) {
+	type result struct {
+		text string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		text, err := fn()
+		done <- result{text, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			log.Printf("Error: clipboard: %v", r.err)
+			return "", errors.New("clipboard unavailable")
+		}
+		return r.text, nil
+	case <-time.After(clipboardTimeout):
+		log.Printf("Error: clipboard: no answer within %v", clipboardTimeout)
+		return "", errClipboardTimeout
+	}
+}
+
+// copyToClipboard writes the given text to the clipboard.
+func copyToClipboard(cb Clipboard, text string) error {
+	_, err := withClipboard(func() (string, error) { return "", cb.WriteAll(text) })
+	return err
+}
+
+// readClipboard returns the text in the clipboard.
+func readClipboard(cb Clipboard) (string, error) {
+	return withClipboard(cb.ReadAll)
+}
+
+// pastedPath turns clipboard text into the path it names, relative paths
+// and "~" resolved against cwd: surrounding whitespace and quotes are
+// dropped and a file:// URL becomes its local path. ok is false for text
+// that can't be a path.
+func pastedPath(text, cwd string) (path string, ok bool) {
+	text = strings.TrimSpace(text)
+	if len(text) >= 2 && (text[0] == '"' || text[0] == '\'') && text[len(text)-1] == text[0] {
+		text = strings.TrimSpace(text[1 : len(text)-1])
+	}
+	if strings.ContainsAny(text, "\r\n") {
+		return "", false
+	}
+	if len(text) >= len("file://") && strings.EqualFold(text[:len("file://")], "file://") {
+		u, err := url.Parse(text)
+		if err != nil || u.Path == "" {
+			return "", false
+		}
+		text = u.Path
+		if u.Host != "" && u.Host != "localhost" {
+			text = "//" + u.Host + text // A UNC share
+		} else if filepath.Separator == '\\' && len(text) >= 3 && text[0] == '/' && text[2] == ':' {
+			text = text[1:] // file:///C:/x
+		}
+		text = filepath.FromSlash(text)
+	}
+	path, err := expandInputPath(text, cwd)
+	if err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// handlePasteClipboardPath goes to the path in the clipboard: into a folder,
+// or to a file's folder with the file selected.
+func handlePasteClipboardPath(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	cwd := state.Cwd()
+	go func() {
+		text, readErr := readClipboard(state.Clipboard())
+		path, ok := pastedPath(text, cwd)
+		var info os.FileInfo
+		if readErr == nil && ok {
+			var err error
+			if info, err = os.Stat(path); err != nil {
+				ok = false
+			}
+		}
+		g.Update(func(gui *gocui.Gui) error {
+			switch {
+			case readErr != nil:
+				state.SetMessage(fmt.Sprintf("Could not read the clipboard: %s", trimError(readErr)))
+			case !ok:
+				state.SetMessage("The clipboard does not contain a valid path")
+			case info.IsDir():
+				if path == state.Cwd() {
+					state.SetMessage(fmt.Sprintf("Already in %s", path))
+					return nil
+				}
+				changeDirectory(gui, state, path, func(gui *gocui.Gui) {
+					state.SetMessage(fmt.Sprintf("Moved to %s", path))
+				})
+			case filepath.Dir(path) == state.Cwd():
+				if !selectPath(gui, state, path) {
+					state.SetMessage(fmt.Sprintf("'%s' is not listed (hidden by a filter?)", filepath.Base(path)))
+				}
+			default:
+				dir := filepath.Dir(path)
+				changeDirectory(gui, state, dir, func(gui *gocui.Gui) {
+					if selectPath(gui, state, path) {
+						state.SetMessage(fmt.Sprintf("Moved to %s", dir))
+					} else {
+						state.SetMessage(fmt.Sprintf("Moved to %s; '%s' is not listed (hidden by a filter?)", dir, filepath.Base(path)))
+					}
+				})
+			}
+			return nil
+		})
+	}()
+	return nil
+}
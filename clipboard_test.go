@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClipboard is an in-memory Clipboard. With delay set, every access
+// takes that long; with err set, every access fails.
+type fakeClipboard struct {
+	mu    sync.Mutex
+	text  string
+	err   error
+	delay time.Duration
+}
+
+func (c *fakeClipboard) ReadAll() (string, error) {
+	time.Sleep(c.delay)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.text, c.err
+}
+
+func (c *fakeClipboard) WriteAll(text string) error {
+	time.Sleep(c.delay)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.text = text
+	}
+	return c.err
+}
+
+func (c *fakeClipboard) Text() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.text
+}
+
+func TestReadClipboard(t *testing.T) {
+	defer func(saved time.Duration) { clipboardTimeout = saved }(clipboardTimeout)
+	clipboardTimeout = 50 * time.Millisecond
+
+	tests := []struct {
+		name    string
+		cb      *fakeClipboard
+		want    string
+		wantErr error
+	}{
+		{"text", &fakeClipboard{text: "/tmp/x"}, "/tmp/x", nil},
+		{"failing tool", &fakeClipboard{err: errors.New("xclip: exit status 1")}, "", errors.New("clipboard unavailable")},
+		{"hanging tool", &fakeClipboard{text: "late", delay: 200 * time.Millisecond}, "", errClipboardTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readClipboard(tt.cb)
+			if got != tt.want {
+				t.Errorf("text = %q, want %q", got, tt.want)
+			}
+			if (err == nil) != (tt.wantErr == nil) || (err != nil && err.Error() != tt.wantErr.Error()) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	time.Sleep(250 * time.Millisecond) // Let the abandoned read finish while the race detector watches
+}
+
+func TestCopyToClipboard(t *testing.T) {
+	defer func(saved time.Duration) { clipboardTimeout = saved }(clipboardTimeout)
+	clipboardTimeout = 50 * time.Millisecond
+
+	cb := &fakeClipboard{}
+	if err := copyToClipboard(cb, "hello"); err != nil || cb.Text() != "hello" {
+		t.Errorf("copy = %v, clipboard %q", err, cb.Text())
+	}
+	slow := &fakeClipboard{delay: 200 * time.Millisecond}
+	if err := copyToClipboard(slow, "hello"); !errors.Is(err, errClipboardTimeout) {
+		t.Errorf("slow copy err = %v, want a timeout", err)
+	}
+}
+
+func TestPastedPath(t *testing.T) {
+	cwd := filepath.FromSlash("/work/project")
+	home, _ := os.UserHomeDir()
+	tests := []struct {
+		text   string
+		want   string
+		wantOK bool
+	}{
+		{"/tmp/a", "/tmp/a", true},
+		{"  /tmp/a\n", "/tmp/a", true},
+		{`"/tmp/a b"`, "/tmp/a b", true},
+		{"'/tmp/a b'", "/tmp/a b", true},
+		{"file:///tmp/a%20b.txt", "/tmp/a b.txt", true},
+		{"FILE://localhost/tmp/x", "/tmp/x", true},
+		{"src/main.go", "/work/project/src/main.go", true},
+		{"~/notes", filepath.Join(home, "notes"), true},
+		{"", "", false},
+		{"   ", "", false},
+		{"/tmp/a\n/tmp/b", "", false},
+		{"file://", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := pastedPath(tt.text, cwd)
+		want := tt.want
+		if tt.wantOK && want != "" && !filepath.IsAbs(filepath.FromSlash(want)) {
+			want = filepath.Join(cwd, want)
+		}
+		if ok != tt.wantOK || (ok && got != filepath.FromSlash(want)) {
+			t.Errorf("pastedPath(%q) = %q, %v; want %q, %v", tt.text, got, ok, filepath.FromSlash(want), tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// ---- File: colors.go ----
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- File-Type Coloring ---
+
+// colorScheme maps entry types to SGR parameters (e.g. "1;34").
+type colorScheme struct {
+	dir  string
+	link string
+	exec string
+	ext  map[string]string // Lowercase extension with dot -> SGR parameters
+}
+
+// archiveExts and imageExts get the built-in archive (red) and image (magenta) colors.
+var (
+	archiveExts = []string{".zip", ".tar", ".gz", ".tgz", ".bz2", ".xz", ".zst", ".rar", ".7z", ".deb", ".rpm", ".jar"}
+	imageExts   = []string{".png", ".jpg", ".jpeg", ".gif", ".bmp", ".svg", ".webp", ".ico", ".tif", ".tiff"}
+)
+
+// builtinColors returns the default palette: directories blue/bold,
+// executables green, symlinks cyan, archives red, images magenta.
+func builtinColors() *colorScheme {
+	scheme := &colorScheme{dir: "1;34", link: "36", exec: "32", ext: map[string]string{}}
+	for _, ext := range archiveExts {
+		scheme.ext[ext] = "31"
+	}
+	for _, ext := range imageExts {
+		scheme.ext[ext] = "35"
+	}
+	return scheme
+}
+
+// activeColors is the scheme used by entryColor; nil disables file-type coloring.
+var activeColors = builtinColors()
+
+// configureColors picks the color scheme from the "file_colors" setting:
+// "auto" (default) uses $LS_COLORS when set and the built-in palette otherwise,
+// "builtin" ignores $LS_COLORS, and "off" disables coloring.
+func configureColors(cfg config) {
+	switch strings.ToLower(cfg.FileColors) {
+	case "off":
+		activeColors = nil
+	case "builtin":
+		activeColors = builtinColors()
+	default:
+		activeColors = builtinColors()
+		if lsColors := os.Getenv("LS_COLORS"); lsColors != "" {
+			applyLSColors(activeColors, lsColors)
+		}
+	}
+}
+
+// applyLSColors overrides scheme with the entries of an LS_COLORS value
+// ("di=01;34:ln=01;36:*.tar=01;31:..."). Unknown keys are ignored.
+func applyLSColors(scheme *colorScheme, lsColors string) {
+	for _, entry := range strings.Split(lsColors, ":") {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		sgr := sanitizeSGR(value)
+		switch {
+		case key == "di":
+			scheme.dir = sgr
+		case key == "ln":
+			scheme.link = sgr
+		case key == "ex":
+			scheme.exec = sgr
+		case strings.HasPrefix(key, "*."):
+			scheme.ext[strings.ToLower(key[1:])] = sgr
+		}
+	}
+}
+
+// sanitizeSGR reduces SGR parameters to what gocui's escape parser renders:
+// bold, underline, reverse and the 8 basic colors. Bright (90-97) and 256-color
+// values in the basic range are mapped down; anything else is dropped.
+func sanitizeSGR(value string) string {
+	params := strings.Split(value, ";")
+	var kept []string
+	for i := 0; i < len(params); i++ {
+		p, err := strconv.Atoi(params[i])
+		if err != nil {
+			continue
+		}
+		switch {
+		case p == 0 || p == 1 || p == 4 || p == 7:
+			kept = append(kept, strconv.Itoa(p))
+		case p >= 30 && p <= 37, p >= 40 && p <= 47:
+			kept = append(kept, strconv.Itoa(p))
+		case p >= 90 && p <= 97:
+			kept = append(kept, strconv.Itoa(p-60))
+		case p >= 100 && p <= 107:
+			kept = append(kept, strconv.Itoa(p-60))
+		case (p == 38 || p == 48) && i+1 < len(params):
+			base := 30
+			if p == 48 {
+				base = 40
+			}
+			if params[i+1] == "5" && i+2 < len(params) {
+				if n, err := strconv.Atoi(params[i+2]); err == nil && n < 16 {
+					kept = append(kept, strconv.Itoa(base+n%8))
+				}
+				i += 2
+			} else if params[i+1] == "2" {
+				i += 4 // Truecolor: r;g;b can't be shown, skip it
+			}
+		}
+	}
+	return strings.Join(kept, ";")
+}
+
+// entryColor returns the ANSI sequence to color item's name with, or "" for none.
+func entryColor(item FileInfo) string {
+	if activeColors == nil {
+		return ""
+	}
+	var sgr string
+	switch {
+	case item.Mode&os.ModeSymlink != 0:
+		sgr = activeColors.link
+	case item.IsDir:
+		sgr = activeColors.dir
+	case isExecutable(item):
+		sgr = activeColors.exec // Like ls, the executable bit wins over the extension
+	default:
+		sgr = activeColors.ext[strings.ToLower(filepath.Ext(item.Name))]
+	}
+	if sgr == "" {
+		return ""
+	}
+	return "\x1b[" + sgr + "m"
+}
+
+// --- Recently Modified Files ---
+
+// defaultRecentWindow is how recently a file must have been modified to be
+// highlighted when recent_minutes is not set.
+const defaultRecentWindow = 60 * time.Minute
+
+// recentRedrawInterval is how often the lists are redrawn while a highlighted
+// file is on screen, so files lose the highlight as they age.
+const recentRedrawInterval = 30 * time.Second
+
+// recentWindow is how recently modified files are named in yellow; 0 turns
+// the highlight off.
+var recentWindow = defaultRecentWindow
+
+// configureRecent reads the "recent_minutes" setting: unset (or 0) keeps the
+// default hour, a negative value turns the highlight off.
+func configureRecent(cfg config) {
+	switch {
+	case cfg.RecentMinutes < 0:
+		recentWindow = 0
+	case cfg.RecentMinutes > 0:
+		recentWindow = time.Duration(cfg.RecentMinutes) * time.Minute
+	default:
+		recentWindow = defaultRecentWindow
+	}
+}
+
+// isRecent reports whether item is a file modified within recentWindow of
+// now. Files whose metadata isn't loaded yet don't count. A future ModTime
+// (clock skew, extracted archives) counts as modified when it was loaded, so
+// the file fades like any other instead of staying highlighted.
+func isRecent(item FileInfo, now time.Time) bool {
+	if recentWindow <= 0 || item.IsDir || !item.HasInfo {
+		return false
+	}
+	modTime := item.ModTime
+	if !item.Seen.IsZero() && modTime.After(item.Seen) {
+		modTime = item.Seen
+	}
+	return now.Sub(modTime) < recentWindow
+}
+
+// nameColor returns the ANSI sequence to color item's name with in the
+// lists: gray for hidden entries listed among the visible ones (gocui drops
+// the dim attribute), yellow for recently modified files, its file-type color
+// otherwise.
+func nameColor(item FileInfo) string {
+	if item.Hidden {
+		return ansiGray
+	}
+	if isRecent(item, time.Now()) {
+		scheduleRecentRedraw()
+		return ansiYellow
+	}
+	return entryColor(item)
+}
+
+// recentRedraw holds the pending redraw that fades the highlight. Drawing a
+// highlighted name arms it, so redraws stop once none is on screen.
+var recentRedraw struct {
+	sync.Mutex
+	g       *gocui.Gui
+	pending bool
+}
+
+// startRecentRedraw lets highlighted names schedule redraws of g, so they
+// fade without waiting for the listing to reload.
+func startRecentRedraw(g *gocui.Gui) {
+	recentRedraw.Lock()
+	defer recentRedraw.Unlock()
+	recentRedraw.g = g
+}
+
+// scheduleRecentRedraw redraws the screen in recentRedrawInterval unless a
+// redraw is already pending.
+func scheduleRecentRedraw() {
+	recentRedraw.Lock()
+	defer recentRedraw.Unlock()
+	if recentRedraw.g == nil || recentRedraw.pending {
+		return
+	}
+	recentRedraw.pending = true
+	g := recentRedraw.g
+	time.AfterFunc(recentRedrawInterval, func() {
+		recentRedraw.Lock()
+		recentRedraw.pending = false
+		recentRedraw.Unlock()
+		g.Update(func(gui *gocui.Gui) error { return nil })
+	})
+}
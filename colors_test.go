@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsRecent(t *testing.T) {
+	now := time.Now()
+	file := func(modTime, seen time.Time) FileInfo {
+		return FileInfo{Name: "a.txt", ModTime: modTime, Seen: seen, HasInfo: true}
+	}
+	tests := []struct {
+		name string
+		item FileInfo
+		want bool
+	}{
+		{"just modified", file(now.Add(-time.Minute), now), true},
+		{"old", file(now.Add(-2*recentWindow), now), false},
+		{"not loaded", FileInfo{Name: "a.txt", ModTime: now}, false},
+		{"folder", FileInfo{Name: "d", IsDir: true, ModTime: now, Seen: now, HasInfo: true}, false},
+		// A future mtime counts from when it was loaded, and fades from there
+		{"future, just loaded", file(now.Add(24*time.Hour), now.Add(-time.Minute)), true},
+		{"future, loaded long ago", file(now.Add(24*time.Hour), now.Add(-2*recentWindow)), false},
+	}
+	for _, tt := range tests {
+		if got := isRecent(tt.item, now); got != tt.want {
+			t.Errorf("%s: recent %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNameColorHidden(t *testing.T) {
+	hidden := FileInfo{Name: ".env", Hidden: true}
+	if got := nameColor(hidden); got != "\x1b[38;5;244m" {
+		t.Errorf("hidden entry color %q, want gray (gocui ignores dim)", got)
+	}
+	// A hidden file that was just modified still reads as hidden
+	now := time.Now()
+	hidden.HasInfo, hidden.ModTime, hidden.Seen = true, now, now
+	if got := nameColor(hidden); got != "\x1b[38;5;244m" {
+		t.Errorf("recent hidden entry color %q, want gray", got)
+	}
+}
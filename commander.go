@@ -0,0 +1,173 @@
+// ---- File: commander.go ----
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Commander Mode ---
+// Commander mode replaces the Folders and Files panes with two directory
+// browsers side by side, like Midnight Commander. Each browser is a state of
+// its own (CWD, listing, cursors, stats), shown as a combined list. The gui
+// runs on the active one, as for tabs, and Tab swaps which one that is; the
+// other is only drawn. Copies and moves go from the active browser's
+// selection to the other browser's folder.
+
+// commanderSide is where the active browser is in commander mode.
+type commanderSide int
+
+const (
+	commanderOff commanderSide = iota
+	commanderLeft
+	commanderRight
+)
+
+// Commander returns the inactive browser of the active tab and the side the
+// active one is on, or nil and commanderOff outside commander mode.
+func (t *tabSet) Commander() (*AppState, commanderSide) {
+	t.Lock()
+	defer t.Unlock()
+	if len(t.tabs) == 0 || t.tabs[t.active].other == nil {
+		return nil, commanderOff
+	}
+	return t.tabs[t.active].other, t.tabs[t.active].side
+}
+
+// enterCommander turns on commander mode in the active tab, with other as the
+// inactive browser on the right.
+func (t *tabSet) enterCommander(other *AppState) {
+	t.Lock()
+	defer t.Unlock()
+	t.tabs[t.active].other = other
+	t.tabs[t.active].side = commanderLeft
+}
+
+// leaveCommander turns off commander mode in the active tab and returns the
+// inactive browser it drops.
+func (t *tabSet) leaveCommander() *AppState {
+	t.Lock()
+	defer t.Unlock()
+	other := t.tabs[t.active].other
+	t.tabs[t.active].other = nil
+	t.tabs[t.active].side = commanderOff
+	return other
+}
+
+// swapCommander makes the inactive browser the active one and returns it.
+func (t *tabSet) swapCommander() *AppState {
+	t.Lock()
+	defer t.Unlock()
+	tab := &t.tabs[t.active]
+	tab.state, tab.other = tab.other, tab.state
+	if tab.side == commanderLeft {
+		tab.side = commanderRight
+	} else {
+		tab.side = commanderLeft
+	}
+	t.pendingFocus = viewCombined
+	return tab.state
+}
+
+// handleToggleCommander turns commander mode on, with the second browser at
+// the selected folder (or the CWD), or off, keeping the active browser.
+func handleToggleCommander(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	if other, _ := tabs.Commander(); other != nil {
+		tabs.leaveCommander()
+		other.StopBackgroundJobs()
+		state.SetCommanderPane(false)
+		state.SetMessage("Commander mode off")
+		g.Update(func(gui *gocui.Gui) error { return nil })
+		return nil
+	}
+	dir := state.Cwd()
+	if item, ok := state.SelectedItem(v.Name()); ok && item.IsDir {
+		dir = item.Path
+	}
+	other := newTabState(dir, state)
+	other.SetCommanderPane(true)
+	state.SetCommanderPane(true)
+	tabs.enterCommander(other)
+	reloadDirectory(g, other, nil)
+	key, _ := primaryKey(keyTable, "Lists", "commander.copy")
+	state.SetMessage(fmt.Sprintf("Commander mode: Tab switches browsers, %s copies to the other one", key))
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// switchCommanderPane makes the other commander mode browser the active one.
+func switchCommanderPane(g *gocui.Gui) error {
+	return activateTab(g, tabs.swapCommander())
+}
+
+// handleCommanderTransfer copies or moves the selection of the active browser
+// into the other browser's folder in the background, then reloads both.
+func handleCommanderTransfer(g *gocui.Gui, v *gocui.View, state *AppState, move bool) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	other, _ := tabs.Commander()
+	if other == nil {
+		key, _ := primaryKey(keyTable, "Lists", "commander.toggle")
+		state.SetMessage(fmt.Sprintf("Copying to another folder needs commander mode (%s)", key))
+		g.Update(func(gui *gocui.Gui) error { return nil })
+		return nil
+	}
+	item, ok := state.SelectedItem(v.Name())
+	if !ok {
+		return nil
+	}
+	verb, done := "Copy", "Copied"
+	if move {
+		verb, done = "Move", "Moved"
+	}
+	dir := other.Cwd()
+	dst, err := transferTarget(item, dir)
+	if err != nil {
+		state.SetMessage(fmt.Sprintf("%s failed: %s", verb, trimError(err)))
+		g.Update(func(gui *gocui.Gui) error { return nil })
+		return nil
+	}
+
+	var result string
+	run := func(ctx context.Context, report func(done, total int64)) (string, error) {
+		var err error
+		if move {
+			err = moveEntry(ctx, item.Path, dst, item.IsDir, report)
+		} else {
+			err = copyEntry(ctx, item.Path, dst, item.IsDir, report)
+		}
+		if err != nil {
+			log.Printf("Error: %s %s to %s failed: %v", verb, item.Path, dst, err)
+			return "", err
+		}
+		result = fmt.Sprintf("%s '%s' to %s", done, item.Name, dir)
+		return result, nil
+	}
+	startTask(g, state, fmt.Sprintf("%s '%s' to %s", verb, item.Name, filepath.Base(dir)), run, func(gui *gocui.Gui, err error) {
+		// Even a failed move may have changed both folders
+		reloadDirectory(gui, state, func(gui *gocui.Gui) {
+			if err == nil {
+				state.SetMessage(result) // The reload cleared it
+			}
+		})
+		reloadDirectory(gui, other, nil)
+	})
+	return nil
+}
+
+// updateCommanderView draws the inactive commander mode browser, from a
+// snapshot of its own state.
+func updateCommanderView(g *gocui.Gui, geo layoutGeometry) {
+	if other, _ := tabs.Commander(); other != nil {
+		frames := map[string]listFrame{viewCombined: prepareListFrame(other, viewCombined, geo.views[viewCommander])}
+		updateListViewAs(g, other, other.Snapshot(frames), viewCombined, viewCommander)
+	}
+}
@@ -0,0 +1,109 @@
+// ---- File: config.go ----
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// configFileName is the user-edited configuration file, inside the config dir.
+const configFileName = "config.json"
+
+// config holds the user's settings from config.json. Unlike preferences, it is
+// never written by lazyls.
+type config struct {
+	IconSet         string               `json:"icon_set"` // "nerd" (default) or "plain" for ASCII markers
+	Icons           iconsConfig          `json:"icons"`
+	FileColors      string               `json:"file_colors"`    // "auto" (default, uses $LS_COLORS), "builtin" or "off"
+	DefaultViewer   string               `json:"default_viewer"` // "builtin" (default) or "pager" to view files in $PAGER
+	CustomActions   []customActionConfig `json:"custom_actions"`
+	JSONFormatLimit byteSize             `json:"json_format_limit"` // Largest JSON file to pretty-print (default 5 MiB)
+	MaxViewSize     byteSize             `json:"max_view_size"`     // Largest file the viewer opens (default 20 MiB)
+	MaxCopySize     byteSize             `json:"max_copy_size"`     // Largest file Copy Content copies whole (default 5 MiB)
+	HideKeyHints    bool                 `json:"hide_key_hints"`    // Hide the key-hint line above the message bar
+	GitTUI          string               `json:"git_tui"`           // Command Ctrl+G runs in a repository (default "lazygit")
+	SizeUnits       string               `json:"size_units"`        // "binary" (default, KiB/MiB) or "decimal" (kB/MB)
+	NameOrder       string               `json:"name_order"`        // "natural" (default, file2 before file10) or "lexical"
+	EnterAction     string               `json:"enter_action"`      // Enter on files: "menu" (default), "view" or "open"
+	RecentMinutes   int                  `json:"recent_minutes"`    // Files modified this recently are named in yellow (default 60, -1 turns it off)
+	HideEntryCounts bool                 `json:"hide_entry_counts"` // Don't count the entries of folders on screen (for slow network filesystems)
+	ASCII           bool                 `json:"ascii"`             // Draw with ASCII only, like --ascii
+	HideStats       bool                 `json:"hide_stats"`        // Start with the stats column collapsed into a status line
+	ScpHost         string               `json:"scp_host"`          // Host (or user@host) for Copy scp Path (default: you at $HOSTNAME)
+}
+
+// byteSize is a size setting, given either as a number of bytes or as a
+// string with a unit, e.g. "50MiB" (see parseByteSize).
+type byteSize int64
+
+func (b *byteSize) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		var n int64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("size must be a number of bytes or a string like \"50MiB\"")
+		}
+		*b = byteSize(n)
+		return nil
+	}
+	n, err := parseByteSize(text)
+	if err != nil {
+		return err
+	}
+	*b = byteSize(n)
+	return nil
+}
+
+// sizeLimits returns the view and copy limits, the defaults where unset.
+func (c config) sizeLimits() (viewLimit, copyLimit int64) {
+	viewLimit, copyLimit = defaultMaxViewSize, defaultMaxCopySize
+	if c.MaxViewSize > 0 {
+		viewLimit = int64(c.MaxViewSize)
+	}
+	if c.MaxCopySize > 0 {
+		copyLimit = int64(c.MaxCopySize)
+	}
+	return viewLimit, copyLimit
+}
+
+// customActionConfig is a user-defined shell command shown in the action menu.
+// {path}, {dir} and {name} in Cmd are replaced by the selected item's path,
+// parent directory and base name, quoted for the shell.
+type customActionConfig struct {
+	Label      string `json:"label"`
+	Cmd        string `json:"cmd"`
+	For        string `json:"for"`         // "any" (default), "file" or "dir"
+	Suspend    bool   `json:"suspend"`     // Hand the terminal to the command (editors, pagers, ...)
+	ShowOutput bool   `json:"show_output"` // Show the first lines of stdout in the content viewer
+}
+
+// iconsConfig adds or overrides icons. A "*" key in files or dirs replaces
+// the default file or folder icon.
+type iconsConfig struct {
+	Extensions map[string]string `json:"extensions"` // ".rs" (or "rs") -> icon
+	Files      map[string]string `json:"files"`      // Exact file name, case-insensitive
+	Dirs       map[string]string `json:"dirs"`       // Exact folder name, case-insensitive
+}
+
+// loadConfig reads config.json from the config dir. A missing file yields the
+// zero config.
+func loadConfig() (config, error) {
+	var cfg config
+	dir, err := appConfigDir()
+	if err != nil {
+		return cfg, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, configFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return config{}, fmt.Errorf("parsing %s: %w", configFileName, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,96 @@
+// ---- File: confirm.go ----
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Confirmation Dialog ---
+
+// typedConfirmThreshold is the entry count above which removing a directory
+// asks for its name to be typed instead of a y/n answer.
+const typedConfirmThreshold = 100
+
+// confirmSpec describes a confirmation dialog. Features that need a yes/no
+// decision fill one in and pass it to AppState.OpenConfirm; the dialog calls
+// back with the outcome.
+type confirmSpec struct {
+	Title         string
+	Message       string
+	Details       []string // Extra lines, e.g. the item count and total size
+	TypeToConfirm string   // When set, this text must be typed to confirm instead of pressing y
+	OnConfirm     func(g *gocui.Gui, state *AppState) error
+	OnCancel      func(g *gocui.Gui, state *AppState) // Optional
+}
+
+// confirmDecision is the outcome of an answer given to a confirmation dialog.
+type confirmDecision int
+
+const (
+	confirmPending  confirmDecision = iota // Not an answer; the dialog stays open
+	confirmAccepted                        // Run OnConfirm
+	confirmRejected                        // Run OnCancel
+)
+
+// decideKey returns the decision for a key pressed in the dialog. Typed
+// dialogs can't be accepted by a key, only rejected.
+func (c confirmSpec) decideKey(ch rune) confirmDecision {
+	switch ch {
+	case 'y', 'Y':
+		if c.TypeToConfirm == "" {
+			return confirmAccepted
+		}
+	case 'n', 'N':
+		return confirmRejected
+	}
+	return confirmPending
+}
+
+// decideTyped returns the decision for text submitted in a typed dialog: it
+// must match TypeToConfirm exactly, ignoring surrounding spaces.
+func (c confirmSpec) decideTyped(input string) confirmDecision {
+	if c.TypeToConfirm != "" && strings.TrimSpace(input) == c.TypeToConfirm {
+		return confirmAccepted
+	}
+	return confirmPending
+}
+
+// removalConfirm builds the dialog for deleting item, which holds entries
+// entries totalling size bytes. Directories with more than
+// typedConfirmThreshold entries must have their name typed.
+func removalConfirm(item FileInfo, entries int, size int64, onConfirm func(g *gocui.Gui, state *AppState) error) confirmSpec {
+	spec := confirmSpec{
+		Title:     " Delete ",
+		Message:   fmt.Sprintf("Delete '%s'?", item.Name),
+		OnConfirm: onConfirm,
+	}
+	if item.IsDir {
+		spec.Message = fmt.Sprintf("Delete '%s' and everything in it?", item.Name)
+		spec.Details = []string{fmt.Sprintf("%s entries, %s", formatCount(entries), formatSize(size))}
+		if entries > typedConfirmThreshold {
+			spec.TypeToConfirm = item.Name
+		}
+	}
+	return spec
+}
+
+// confirmLines is the dialog's text: the message, the details and how to answer.
+func confirmLines(c confirmSpec) []string {
+	lines := []string{c.Message}
+	if len(c.Details) > 0 {
+		lines = append(lines, "")
+		for _, detail := range c.Details {
+			lines = append(lines, ansiDim+detail+ansiReset)
+		}
+	}
+	lines = append(lines, "")
+	if c.TypeToConfirm != "" {
+		lines = append(lines, fmt.Sprintf("Type %s%s%s below to confirm, Esc to cancel", ansiBold, c.TypeToConfirm, ansiReset))
+	} else {
+		lines = append(lines, fmt.Sprintf("%sy%s yes   %sn%s no", ansiCyan, ansiReset, ansiCyan, ansiReset))
+	}
+	return lines
+}
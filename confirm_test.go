@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestConfirmDecisions(t *testing.T) {
+	plain := confirmSpec{Message: "Delete 'a.txt'?"}
+	typed := confirmSpec{Message: "Delete 'src'?", TypeToConfirm: "src"}
+
+	keys := []struct {
+		spec confirmSpec
+		ch   rune
+		want confirmDecision
+	}{
+		{plain, 'y', confirmAccepted},
+		{plain, 'Y', confirmAccepted},
+		{plain, 'n', confirmRejected},
+		{plain, 'N', confirmRejected},
+		{plain, 'x', confirmPending},
+		{typed, 'y', confirmPending}, // Only typing the name accepts
+		{typed, 'n', confirmRejected},
+	}
+	for _, tt := range keys {
+		if got := tt.spec.decideKey(tt.ch); got != tt.want {
+			t.Errorf("decideKey(%q) with TypeToConfirm %q = %v, want %v", tt.ch, tt.spec.TypeToConfirm, got, tt.want)
+		}
+	}
+
+	inputs := []struct {
+		spec  confirmSpec
+		input string
+		want  confirmDecision
+	}{
+		{typed, "src", confirmAccepted},
+		{typed, "  src\n", confirmAccepted},
+		{typed, "Src", confirmPending},
+		{typed, "sr", confirmPending},
+		{typed, "src2", confirmPending},
+		{typed, "", confirmPending},
+		{plain, "", confirmPending}, // Nothing to match
+	}
+	for _, tt := range inputs {
+		if got := tt.spec.decideTyped(tt.input); got != tt.want {
+			t.Errorf("decideTyped(%q) with TypeToConfirm %q = %v, want %v", tt.input, tt.spec.TypeToConfirm, got, tt.want)
+		}
+	}
+}
+
+func TestRemovalConfirm(t *testing.T) {
+	tests := []struct {
+		item      FileInfo
+		entries   int
+		wantTyped string
+	}{
+		{FileInfo{Name: "a.txt"}, 0, ""},
+		{FileInfo{Name: "small", IsDir: true}, typedConfirmThreshold, ""},
+		{FileInfo{Name: "big", IsDir: true}, typedConfirmThreshold + 1, "big"},
+	}
+	for _, tt := range tests {
+		spec := removalConfirm(tt.item, tt.entries, 0, nil)
+		if spec.TypeToConfirm != tt.wantTyped {
+			t.Errorf("%s with %d entries: TypeToConfirm %q, want %q", tt.item.Name, tt.entries, spec.TypeToConfirm, tt.wantTyped)
+		}
+		if wantDetails := tt.item.IsDir; (len(spec.Details) > 0) != wantDetails {
+			t.Errorf("%s: details %q", tt.item.Name, spec.Details)
+		}
+	}
+}
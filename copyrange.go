@@ -0,0 +1,174 @@
+// ---- File: copyrange.go ----
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// --- Partial Content Reads ---
+// Files over the copy limit can still be copied in part: their first bytes, or
+// their first or last lines. Only the bytes needed are read, never the whole file.
+// The reads run as background tasks, as does the whole-file copy: they report
+// the bytes read so far and stop with ctx's error once ctx is cancelled.
+
+// tailChunkSize is how much readTailLines reads per step back from the end.
+const tailChunkSize = 64 * 1024
+
+// readProgress wraps r to report the bytes read toward total (0 while unknown)
+// and to fail once ctx is cancelled.
+func readProgress(ctx context.Context, r io.Reader, total int64, report func(done, total int64)) io.Reader {
+	var done int64
+	return progressReader{ctx: ctx, r: r, read: func(n int64) {
+		done += n
+		report(done, total)
+	}}
+}
+
+// readWithinLimit returns the whole content of the file at path, refusing it
+// with errFileTooLarge if it is over limit bytes.
+func readWithinLimit(ctx context.Context, files FileReader, path string, limit int64, report func(done, total int64)) ([]byte, error) {
+	f, err := files.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("path is a directory")
+	}
+	tooLarge := fmt.Errorf("%w (> %s, configurable)", errFileTooLarge, formatSize(limit))
+	if info.Size() > limit {
+		return nil, tooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(readProgress(ctx, f, info.Size(), report), limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, tooLarge // Grew since the stat
+	}
+	return data, nil
+}
+
+// readHeadBytes returns the first limit bytes of the file at path, cut back to
+// the last complete UTF-8 character.
+func readHeadBytes(ctx context.Context, files FileReader, path string, limit int64, report func(done, total int64)) ([]byte, error) {
+	f, err := files.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(readProgress(ctx, f, limit, report), limit))
+	if err != nil {
+		return nil, err
+	}
+	return trimPartialRune(data), nil
+}
+
+// readHeadLines returns the first n lines of the file at path, newlines
+// included. capped is true if limit bytes were read before the n-th line ended.
+func readHeadLines(ctx context.Context, files FileReader, path string, n int, limit int64, report func(done, total int64)) (data []byte, capped bool, err error) {
+	f, err := files.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(io.LimitReader(readProgress(ctx, f, 0, report), limit+1)) // One more byte tells a capped read from an exact fit
+	var buf bytes.Buffer
+	for lines := 0; lines < n; lines++ {
+		line, err := r.ReadBytes('\n')
+		buf.Write(line)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if int64(buf.Len()) > limit {
+		return trimPartialRune(buf.Bytes()[:limit]), true, nil
+	}
+	return buf.Bytes(), false, nil
+}
+
+// readTailLines returns the last n lines of the file at path, reading it
+// backwards from the end in chunks. A newline ending the file doesn't start
+// an extra empty line. capped is true if the lines are longer than limit
+// bytes; the data is then the last limit bytes, starting at a complete character.
+func readTailLines(ctx context.Context, files FileReader, path string, n int, limit int64, report func(done, total int64)) (data []byte, capped bool, err error) {
+	f, err := files.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+
+	end := info.Size()
+	var tail []byte // The bytes read so far, from pos to end
+	pos := end
+	newlines := 0
+	for pos > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+		size := min(int64(tailChunkSize), pos)
+		pos -= size
+		chunk := make([]byte, size)
+		if _, err := f.ReadAt(chunk, pos); err != nil && !errors.Is(err, io.EOF) {
+			return nil, false, err
+		}
+		tail = append(chunk, tail...)
+		report(end-pos, 0)
+		// Count the newlines in the new chunk from its end; the file's last byte doesn't count
+		for i := len(chunk) - 1; i >= 0; i-- {
+			if chunk[i] != '\n' || pos+int64(i) == end-1 {
+				continue
+			}
+			newlines++
+			if newlines == n {
+				return capTail(tail[i+1:], limit)
+			}
+		}
+		if int64(len(tail)) > limit {
+			break // The lines won't fit anyway
+		}
+	}
+	return capTail(tail, limit)
+}
+
+// capTail keeps the last limit bytes of data, reporting whether it cut any.
+func capTail(data []byte, limit int64) ([]byte, bool, error) {
+	if int64(len(data)) <= limit {
+		return data, false, nil
+	}
+	data = data[int64(len(data))-limit:]
+	// Skip the continuation bytes of a character cut at the start
+	for i := 0; i < utf8.UTFMax-1 && len(data) > 0 && !utf8.RuneStart(data[0]); i++ {
+		data = data[1:]
+	}
+	return data, true, nil
+}
+
+// trimPartialRune drops an incomplete UTF-8 character from the end of data,
+// as left by cutting it at a byte count.
+func trimPartialRune(data []byte) []byte {
+	for i := 0; i < utf8.UTFMax-1 && len(data) > 0; i++ {
+		if r, size := utf8.DecodeLastRune(data); r != utf8.RuneError || size != 1 {
+			break
+		}
+		data = data[:len(data)-1]
+	}
+	return data
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPartialReads(t *testing.T) {
+	longLine := strings.Repeat("x", tailChunkSize) + "\n" // Pushes the tail read past one chunk
+	files := fakeFiles{files: map[string]string{
+		"/work/lines.txt": "one\ntwo\nthree\n",
+		"/work/no-eol":    "one\ntwo",
+		"/work/utf8.txt":  "aé\nbé\n",
+		"/work/long.log":  "first\n" + longLine + "last\n",
+	}}
+	ctx := context.Background()
+	noReport := func(done, total int64) {}
+
+	lines := []struct {
+		name       string
+		path       string
+		n          int
+		limit      int64
+		fromEnd    bool
+		want       string
+		wantCapped bool
+	}{
+		{"first lines", "/work/lines.txt", 2, 100, false, "one\ntwo\n", false},
+		{"more lines than the file", "/work/lines.txt", 9, 100, false, "one\ntwo\nthree\n", false},
+		{"first lines capped", "/work/lines.txt", 2, 5, false, "one\nt", true},
+		{"exact fit", "/work/lines.txt", 2, 8, false, "one\ntwo\n", false},
+		{"capped inside a character", "/work/utf8.txt", 1, 2, false, "a", true},
+		{"last lines", "/work/lines.txt", 2, 100, true, "two\nthree\n", false},
+		{"last line without a newline", "/work/no-eol", 1, 100, true, "two", false},
+		{"last lines capped", "/work/lines.txt", 2, 5, true, "hree\n", true},
+		{"last lines capped inside a character", "/work/utf8.txt", 1, 2, true, "\n", true},
+		{"last lines across chunks", "/work/long.log", 2, 1 << 20, true, longLine + "last\n", false},
+	}
+	for _, tt := range lines {
+		t.Run(tt.name, func(t *testing.T) {
+			read := readHeadLines
+			if tt.fromEnd {
+				read = readTailLines
+			}
+			got, capped, err := read(ctx, files, tt.path, tt.n, tt.limit, noReport)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want || capped != tt.wantCapped {
+				t.Errorf("got %q (capped %v), want %q (capped %v)", got, capped, tt.want, tt.wantCapped)
+			}
+		})
+	}
+
+	t.Run("whole file", func(t *testing.T) {
+		var done, total int64
+		got, err := readWithinLimit(ctx, files, "/work/long.log", 1<<20, func(d, n int64) { done, total = d, n })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size := int64(len("first\n" + longLine + "last\n")); string(got) != "first\n"+longLine+"last\n" || done != size || total != size {
+			t.Errorf("read %d bytes, reported %d of %d", len(got), done, total)
+		}
+		if _, err := readWithinLimit(ctx, files, "/work/long.log", 10, noReport); !errors.Is(err, errFileTooLarge) {
+			t.Errorf("over the limit: got error %v", err)
+		}
+	})
+
+	t.Run("first bytes", func(t *testing.T) {
+		got, err := readHeadBytes(ctx, files, "/work/utf8.txt", 2, noReport)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "a" {
+			t.Errorf("got %q, want the é cut at byte 2 dropped", got)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := readHeadBytes(cancelled, files, "/work/long.log", 1<<20, noReport); !errors.Is(err, context.Canceled) {
+			t.Errorf("head bytes: got error %v", err)
+		}
+		if _, err := readWithinLimit(cancelled, files, "/work/long.log", 1<<20, noReport); !errors.Is(err, context.Canceled) {
+			t.Errorf("whole file: got error %v", err)
+		}
+		for _, read := range []func(context.Context, FileReader, string, int, int64, func(int64, int64)) ([]byte, bool, error){readHeadLines, readTailLines} {
+			if _, _, err := read(cancelled, files, "/work/long.log", 2, 1<<20, noReport); !errors.Is(err, context.Canceled) {
+				t.Errorf("got error %v", err)
+			}
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, _, err := readTailLines(ctx, files, "/work/gone", 1, 100, noReport); err == nil {
+			t.Error("no error for a missing file")
+		}
+	})
+}
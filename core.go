@@ -2,109 +2,126 @@
 package main
 
 import (
+	"container/heap"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log"
 	"os"
 	"os/exec"
+	"os/user"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/jroimartin/gocui"
 )
 
-// --- Icon Mapping (Requires Nerd Fonts) ---
-var iconMap = map[string]string{
-	// Folders
-	"dir":          "", // Default folder icon
-	"node_modules": "", // npm icon
-	".git":         "", // Git icon
-
-	// Files by extension
-	".go":   "",
-	".py":   "",
-	".js":   "",
-	".ts":   "", // Added TypeScript
-	".jsx":  "", // Added JSX
-	".tsx":  "", // Added TSX
-	".json": "",
-	".html": "",
-	".css":  "",
-	".scss": "", // Added SCSS
-	".md":   "",
-	".sh":   "",
-	".bash": "",
-	".zsh":  "",
-	".yml":  "",
-	".yaml": "",
-	".toml": "", // Added TOML
-	".zip":  "",
-	".tar":  "",
-	".gz":   "",
-	".bz2":  "",
-	".xz":   "",
-	".rar":  "", // Added RAR
-	".7z":   "", // Added 7z
-	".log":  "",
-	".env":  "", // Env files similar to config
-	".sql":  "", // Added SQL
-	".pdf":  "", // Added PDF
-	".png":  "", // Added Image icons
-	".jpg":  "",
-	".jpeg": "",
-	".gif":  "",
-	".svg":  "", // Added SVG
-
-	// Files by name
-	"makefile":   "",
-	"dockerfile": "",
-	"license":    "",
-	"readme.md":  "", // Prioritize Readme icon
-}
-
-var defaultFileIcon = "" // Default file icon
-var defaultDirIcon = ""  // Default directory icon
-
-func getIcon(name string, isDir bool) string {
-	lowerName := strings.ToLower(name)
-
-	// Check full name first (e.g., "README.md")
-	if icon, ok := iconMap[lowerName]; ok {
-		return icon
-	}
-
-	if isDir {
-		if icon, ok := iconMap[lowerName]; ok { // Check dir name (e.g., ".git")
-			return icon
-		}
-		if strings.HasPrefix(name, ".") { // Check hidden dir name without dot? Less common.
-			if icon, ok := iconMap[name]; ok {
-				return icon
-			}
-		}
-		// Check if name is in the map specifically for dirs (if we add any like "vendor": "...")
-		// if icon, ok := iconMap["dir:"+lowerName]; ok { return icon }
-		return defaultDirIcon // Default dir icon
-	}
-
-	// Check extension for files
-	ext := strings.ToLower(filepath.Ext(name))
-	if ext != "" {
-		if icon, ok := iconMap[ext]; ok {
-			return icon
-		}
-	}
-
-	// Fallback to default file icon
-	return defaultFileIcon
+// dirListing is the result of reading a directory: its entries split by
+// visibility and kind, sorted, plus what the ignore rules suppressed.
+type dirListing struct {
+	visibleDirs, visibleFiles []FileInfo
+	hiddenDirs, hiddenFiles   []FileInfo
+	ignoredDirs, ignoredFiles int
+	gitIgnoreActive           bool
+	warning                   string   // Non-fatal problem to show in the message bar
+	names                     []string // Every entry read, listed or filtered out
 }
 
-// loadDirectoryContents reads CWD, filters, sorts, and updates appState.
-func loadDirectoryContents(state *AppState) error {
+// dirLoadPublishInterval throttles the "Loading..." entry count in the pane titles.
+const dirLoadPublishInterval = 100 * time.Millisecond
+
+// dirLoadProgressStep is how many entries are processed between progress
+// reports (and checks for a superseding load).
+const dirLoadProgressStep = 1000
+
+// startDirectoryLoad reads the CWD in a goroutine and publishes the listing via
+// g.Update, showing a running entry count in the pane titles meanwhile. A newer
+// load supersedes an older one, whose results are then dropped. then, if not
+// nil, runs on the UI goroutine once the listing is in place.
+func startDirectoryLoad(g *gocui.Gui, state *AppState, then func(gui *gocui.Gui)) {
 	state.ClearMessage() // Clear any previous messages on reload
+	gen := state.BeginDirLoad()
 	cwd := state.Cwd()
+	g.Update(func(gui *gocui.Gui) error { return nil }) // Show "Loading..."
+
+	go func() {
+		lastPublish := time.Now()
+		progress := func(n int) {
+			state.SetDirLoadProgress(gen, n)
+			if time.Since(lastPublish) >= dirLoadPublishInterval {
+				lastPublish = time.Now()
+				g.Update(func(gui *gocui.Gui) error { return nil })
+			}
+		}
+		cancelled := func() bool { return !state.IsDirLoadGen(gen) }
+
+		listing, err := readDirectory(state, cwd, progress, cancelled)
+		g.Update(func(gui *gocui.Gui) error {
+			if !state.FinishDirLoad(gen) {
+				return nil // Superseded by a newer load
+			}
+			if err != nil {
+				// A deleted or unmounted CWD would leave a stale listing behind
+				if isVanishedError(err) {
+					if parent, ok := nearestExistingDir(cwd); ok {
+						moveToAncestor(gui, state, parent)
+						return nil
+					}
+				}
+				state.SetMessage(fmt.Sprintf("Error reading dir: %s", trimError(err)))
+			} else {
+				state.SetGitIgnoreActive(listing.gitIgnoreActive)
+				state.SetIgnoredCounts(listing.ignoredDirs, listing.ignoredFiles)
+				// Update state using the method (this also resets cursors/origins)
+				state.SetDirectoryContents(listing.visibleDirs, listing.visibleFiles, listing.hiddenDirs, listing.hiddenFiles)
+				state.TrackNewEntries(cwd, listing.names)
+				if listing.warning != "" {
+					state.SetMessage(listing.warning)
+				}
+			}
+			if then != nil {
+				then(gui)
+			}
+			return nil
+		})
+	}()
+}
+
+// isVanishedError reports whether err means a path no longer exists, including
+// stale handles on network filesystems.
+func isVanishedError(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ESTALE)
+}
+
+// nearestExistingDir returns the closest ancestor of dir that still exists.
+func nearestExistingDir(dir string) (string, bool) {
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false // Reached the root (or a drive root) without finding one
+		}
+		dir = parent
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir, true
+		}
+	}
+}
 
+// isDotName reports whether name follows the Unix convention for hidden entries.
+func isDotName(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+// readDirectory reads, filters and sorts the entries of cwd. progress is called
+// with the number of entries processed so far; once cancelled reports true the
+// read stops early and its (partial) result must be discarded.
+func readDirectory(state *AppState, cwd string, progress func(n int), cancelled func() bool) (dirListing, error) {
+	var listing dirListing
 	visibleFiles := []FileInfo{}
 	visibleDirs := []FileInfo{}
 	hiddenFiles := []FileInfo{}
@@ -112,37 +129,85 @@ func loadDirectoryContents(state *AppState) error {
 
 	entries, err := os.ReadDir(cwd)
 	if err != nil {
-		state.SetMessage(fmt.Sprintf("Error reading dir: %s", trimError(err)))
-		// Return nil to allow UI to update with the error message, but don't stop the app
-		return nil // Was: fmt.Errorf("reading directory %s: %w", cwd, err)
+		return listing, err
 	}
 
-	for _, entry := range entries {
-		name := entry.Name()
-		// Simple check for hidden (can be platform specific)
-		isHidden := strings.HasPrefix(name, ".") && name != "." && name != ".."
-
-		// Use os.Stat to check if it's a directory or symlink etc.
-		// Using Lstat to not follow symlinks for the IsDir check itself.
-		info, err := os.Lstat(filepath.Join(cwd, name))
+	// In ignore mode, drop anything git ignores (one subprocess for the whole directory)
+	var ignored map[string]bool
+	if state.IsGitIgnoreMode() {
+		var inRepo bool
+		ignored, inRepo, err = GitIgnoredNames(state.Git(), cwd, entries)
 		if err != nil {
-			log.Printf("Warning: Could not stat entry %s: %v", name, err)
-			// Skip this entry or mark it as an error? Let's skip for now.
-			continue
+			log.Printf("Warning: Could not check git-ignored entries in %s: %v", cwd, err)
+			listing.warning = fmt.Sprintf("Git ignore check failed: %s", trimError(err))
 		}
+		listing.gitIgnoreActive = inRepo && err == nil
+	}
+
+	ignoreStack := baseIgnoreStack(state, cwd)
+	glob := state.FileGlob()
+	unreadable := 0
 
-		isDir := info.IsDir()
-		// TODO: Handle Symlinks visually? (Maybe add a different icon or suffix)
-		// isSymlink := info.Mode()&os.ModeSymlink != 0
+	for i, entry := range entries {
+		if i%dirLoadProgressStep == 0 {
+			if cancelled() {
+				return listing, nil
+			}
+			progress(i)
+		}
+		name := entry.Name()
+		if ignored[name] {
+			continue
+		}
+		if len(ignoreStack) > 0 && isIgnoredBy(ignoreStack, filepath.Join(cwd, name), entry.IsDir()) {
+			if entry.IsDir() {
+				listing.ignoredDirs++
+			} else {
+				listing.ignoredFiles++
+			}
+			continue
+		}
+		if !entry.IsDir() && !glob.Match(name) {
+			continue // Left out by the file filter
+		}
+		isHidden := isHiddenEntry(entry)
 
+		// The entry's type bits come with ReadDir, so IsDir and symlinks need no
+		// extra syscall (symlinks aren't followed). Files load their size, mtime and
+		// permissions lazily when drawn; see AppState.LoadEntryInfo.
+		isDir := entry.IsDir()
 		fullPath := filepath.Join(cwd, name) // Needed for actions
 
 		fi := FileInfo{
 			Name:  name,
 			Path:  fullPath,
 			IsDir: isDir,
-			Icon:  getIcon(name, isDir), // Pass isDir here
-			// Size is populated by calculateStats for largestFile
+			Icon:  getEntryIcon(name, isDir, entry.Type()),
+			Mode:  entry.Type(),
+		}
+		if isDir {
+			// Folders need their mtime now: it keys the folder size cache
+			info, err := entry.Info()
+			if err != nil && isVanishedError(err) {
+				continue // Deleted since ReadDir
+			}
+			if err != nil {
+				// Listed but marked, rather than vanishing from the pane; there is no size to
+				// compute. Folders that stat but can't be opened are marked by the size and
+				// entry count jobs, sparing the listing a syscall per folder
+				log.Printf("Warning: Could not stat entry %s: %v", name, err)
+				fi.Unreadable = true
+				fi.Size = -2
+				unreadable++
+			} else {
+				setEntryInfo(&fi, info)
+				fi.Repo = detectRepoKind(state, fullPath, fi.ModTime)
+				// Recursive size is filled in by the directory size job; reuse a cached value if unchanged
+				fi.Size = -1
+				if size, ok := state.CachedDirSize(fullPath, fi.ModTime); ok {
+					fi.Size = size
+				}
+			}
 		}
 
 		if isHidden {
@@ -160,41 +225,406 @@ func loadDirectoryContents(state *AppState) error {
 		}
 	}
 
-	// Sort alphabetically (case-insensitive)
-	sortFunc := func(a, b FileInfo) bool {
-		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	progress(len(entries))
+	if unreadable > 0 {
+		note := fmt.Sprintf("%s unreadable", pluralize(unreadable, "entry", "entries"))
+		if listing.warning != "" {
+			note = listing.warning + "; " + note
+		}
+		listing.warning = note
 	}
-	sort.Slice(visibleDirs, func(i, j int) bool { return sortFunc(visibleDirs[i], visibleDirs[j]) })
-	sort.Slice(visibleFiles, func(i, j int) bool { return sortFunc(visibleFiles[i], visibleFiles[j]) })
-	sort.Slice(hiddenDirs, func(i, j int) bool { return sortFunc(hiddenDirs[i], hiddenDirs[j]) })
-	sort.Slice(hiddenFiles, func(i, j int) bool { return sortFunc(hiddenFiles[i], hiddenFiles[j]) })
 
-	// Update state using the method (this also resets cursors/origins)
-	state.SetDirectoryContents(visibleDirs, visibleFiles, hiddenDirs, hiddenFiles)
+	natural, desc := state.IsNaturalSort(), state.IsSortDescending()
+	for _, list := range [][]FileInfo{visibleDirs, visibleFiles, hiddenDirs, hiddenFiles} {
+		sortEntries(list, natural, desc)
+	}
 
-	return nil
+	listing.visibleDirs, listing.visibleFiles = visibleDirs, visibleFiles
+	listing.hiddenDirs, listing.hiddenFiles = hiddenDirs, hiddenFiles
+	listing.names = make([]string, len(entries))
+	for i, entry := range entries {
+		listing.names[i] = entry.Name()
+	}
+	return listing, nil
+}
+
+// naturalSortDefault is the name order new states start with: natural, or
+// plain case-insensitive when "name_order" is "lexical".
+var naturalSortDefault = true
+
+// configureNameOrder applies the "name_order" setting.
+func configureNameOrder(cfg config) {
+	switch cfg.NameOrder {
+	case "", "natural":
+		naturalSortDefault = true
+	case "lexical":
+		naturalSortDefault = false
+	default:
+		log.Printf("Warning: Unknown name_order %q, using natural order", cfg.NameOrder)
+		naturalSortDefault = true
+	}
+}
+
+// sortEntries sorts items by name, case-insensitive; natural order compares
+// digit runs numerically.
+func sortEntries(items []FileInfo, natural, descending bool) {
+	less := func(a, b string) bool { return strings.ToLower(a) < strings.ToLower(b) }
+	if natural {
+		less = naturalLess
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if descending {
+			return less(items[j].Name, items[i].Name)
+		}
+		return less(items[i].Name, items[j].Name)
+	})
+}
+
+// mergeEntries returns the visible and hidden entries of one kind in a single
+// sorted list, the hidden ones marked so they can be drawn dimmed.
+func mergeEntries(visible, hidden []FileInfo, natural, descending bool) []FileInfo {
+	all := make([]FileInfo, 0, len(visible)+len(hidden))
+	all = append(all, visible...)
+	for _, item := range hidden {
+		item.Hidden = true
+		all = append(all, item)
+	}
+	sortEntries(all, natural, descending)
+	return all
+}
+
+// setEntryInfo fills item's metadata from info.
+func setEntryInfo(item *FileInfo, info os.FileInfo) {
+	item.Size = info.Size()
+	item.ModTime = info.ModTime()
+	item.Mode = info.Mode()
+	item.HasInfo = true
+	item.Seen = time.Now()
+}
+
+// withEntryInfo returns item with its metadata loaded, stat'ing it (without
+// following symlinks) if the listing deferred that. On error item is returned
+// as is, marked unreadable unless it has vanished.
+func withEntryInfo(item FileInfo) FileInfo {
+	if item.HasInfo {
+		return item
+	}
+	info, err := os.Lstat(item.Path)
+	if err != nil {
+		log.Printf("Warning: Could not stat entry %s: %v", item.Path, err)
+		item.Unreadable = !isVanishedError(err)
+		return item
+	}
+	setEntryInfo(&item, info)
+	return item
+}
+
+// topFilesCount is how many of the largest files calculateStats keeps track of.
+const topFilesCount = 10
+
+// fileSizeHeap is a min-heap of files ordered by size, implementing heap.Interface.
+type fileSizeHeap []FileInfo
+
+func (h fileSizeHeap) Len() int           { return len(h) }
+func (h fileSizeHeap) Less(i, j int) bool { return h[i].Size < h[j].Size }
+func (h fileSizeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *fileSizeHeap) Push(x any)        { *h = append(*h, x.(FileInfo)) }
+func (h *fileSizeHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// extStat aggregates the files of one extension bucket found by the stats walk.
+type extStat struct {
+	Ext   string // e.g. ".go", or one of the special buckets below
+	Count int
+	Bytes int64
+}
+
+const (
+	extBucketNone   = "(no extension)"
+	extBucketHidden = "(hidden)"
+)
+
+// extensionBucket returns the breakdown bucket a file name belongs to.
+func extensionBucket(name string) string {
+	if strings.HasPrefix(name, ".") {
+		return extBucketHidden
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == "" {
+		return extBucketNone
+	}
+	return ext
+}
+
+// sortedExtStats flattens the aggregation map, sorted by total bytes descending.
+func sortedExtStats(byExt map[string]*extStat) []extStat {
+	stats := make([]extStat, 0, len(byExt))
+	for _, st := range byExt {
+		stats = append(stats, *st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Bytes != stats[j].Bytes {
+			return stats[i].Bytes > stats[j].Bytes
+		}
+		return stats[i].Ext < stats[j].Ext
+	})
+	return stats
+}
+
+// extStatsPublishInterval throttles live updates of the extension breakdown during the walk.
+const extStatsPublishInterval = 250 * time.Millisecond
+
+// statsCacheTTL is how long a cached stats result is trusted without a new
+// walk; older ones are shown while a revalidation walk runs. The root mtime
+// only catches changes to direct children, hence the expiry.
+const statsCacheTTL = 2 * time.Minute
+
+// maxStatsErrors bounds how many walk errors a stats run keeps for the errors overlay.
+const maxStatsErrors = 100
+
+// maxCleanupEntries bounds how many empty folders, zero-byte files and broken
+// links a stats run keeps for the cleanup reports; the counts include the rest.
+const maxCleanupEntries = 200
+
+// brokenLink is a symlink whose target doesn't resolve.
+type brokenLink struct {
+	Path   string
+	Target string // As stored in the link, possibly relative
+	Reason string // Why it doesn't resolve, e.g. "no such file or directory"
+}
+
+// checkSymlink reports whether the symlink at path is broken: Lstat saw a
+// link, so a failing Stat means its target (or a link in its chain) is
+// missing. Stat gives up on loops with ELOOP instead of following them.
+func checkSymlink(path string) (brokenLink, bool) {
+	_, err := os.Stat(path)
+	if err == nil {
+		return brokenLink{}, false
+	}
+	target, readErr := os.Readlink(path)
+	if readErr != nil {
+		target = "?"
+	}
+	reason := err.Error()
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		reason = pathErr.Err.Error()
+	}
+	return brokenLink{Path: path, Target: target, Reason: reason}, true
+}
+
+// statsWalkError is a path the stats walk could not read.
+type statsWalkError struct {
+	Path   string
+	Reason string
+}
+
+// fileID identifies a file across its hard links.
+type fileID struct {
+	dev, ino uint64
+}
+
+// linkTracker recognizes files a walk has already counted under another hard
+// link, so they are counted once, as du does.
+type linkTracker map[fileID]bool
+
+// seen reports whether info is another link to a file counted before, and
+// remembers it otherwise. Without Unix metadata (Windows) nothing is deduplicated.
+func (t linkTracker) seen(info os.FileInfo) bool {
+	sys, ok := platformStat(info)
+	if !ok || sys.Nlink < 2 {
+		return false
+	}
+	id := fileID{dev: sys.Dev, ino: sys.Ino}
+	if t[id] {
+		return true
+	}
+	t[id] = true
+	return false
+}
+
+// allocatedSize returns the space info takes on disk, which is less than its
+// size for sparse files and more for small ones. ok is false where the
+// platform doesn't report allocated blocks.
+func allocatedSize(info os.FileInfo) (size int64, ok bool) {
+	sys, ok := platformStat(info)
+	if !ok {
+		return 0, false
+	}
+	return sys.Blocks * 512, true
+}
+
+// statsSnapshot is the outcome of a stats walk, as published to the state and cached.
+type statsSnapshot struct {
+	totalSize   int64 // Apparent size; -2 if the walk had errors
+	diskSize    int64 // Allocated size; -1 where the platform doesn't report it
+	largestFile FileInfo
+	largestDir  FileInfo   // The immediate subdirectory holding the most bytes; zero if none holds any
+	topFiles    []FileInfo // Biggest first
+	extStats    []extStat
+	fileCount   int
+	dirCount    int
+	err         error
+	errs        []statsWalkError // The first maxStatsErrors errors
+	errCount    int              // All errors, including those beyond maxStatsErrors
+
+	// Cleanup candidates, in walk order: the first maxCleanupEntries of each and their totals
+	emptyDirs      []FileInfo
+	emptyDirCount  int
+	emptyFiles     []FileInfo
+	emptyFileCount int
+
+	brokenLinks     []brokenLink // The first maxCleanupEntries
+	brokenLinkCount int
 }
 
 // calculateStats runs in a goroutine to get size, largest file, and git status.
+// A cached result for the CWD is used as is while fresh, or shown while a new
+// walk revalidates it.
 func calculateStats(g *gocui.Gui, state *AppState) {
-	state.SetStatsLoading() // Mark as loading
+	gen := state.SetStatsLoading() // Mark as loading
 
-	// Trigger UI update immediately to show "Calculating..."
+	cwd := state.Cwd()
+	cacheKey := statsCacheKey{path: cwd, gitIgnore: state.IsGitIgnoreMode()}
+	var rootModTime time.Time
+	if info, err := os.Stat(cwd); err == nil {
+		rootModTime = info.ModTime()
+	}
+	revalidating := false
+	if cached, storedAt, ok := state.CachedStats(cacheKey, rootModTime); ok {
+		if time.Since(storedAt) < statsCacheTTL {
+			publishStats(g, state, gen, cwd, cached)
+			return
+		}
+		state.ShowCachedStats(cached)
+		revalidating = true
+	}
+
+	// Trigger UI update immediately to show "Calculating..." (or the cached result)
 	g.Update(func(gui *gocui.Gui) error { return nil })
 
-	cwd := state.Cwd()
+	opts := statsWalkOptions{
+		loadIgnoreFile: func(file string) *ignoreRules { return loadIgnoreFile(state, file) },
+		progress: func(extStats []extStat) bool {
+			if !state.IsStatsGen(gen) {
+				return false // A newer stats run has started
+			}
+			// The extension breakdown is published periodically so the overlay updates live
+			if !revalidating {
+				state.SetExtStats(extStats)
+				if state.IsExtStatsVisible() {
+					g.Update(func(gui *gocui.Gui) error { return nil })
+				}
+			}
+			return true
+		},
+	}
+	// In ignore mode, skip what git ignores so the size reflects tracked-ish content
+	if state.IsGitIgnoreMode() {
+		var ignoreErr error
+		opts.gitIgnored, ignoreErr = GitIgnoredUnder(state.Git(), cwd)
+		if ignoreErr != nil {
+			log.Printf("Warning: Could not list git-ignored paths in %s: %v", cwd, ignoreErr)
+		}
+	}
+	stats := walkStats(cwd, opts)
+
+	// Only complete walks are worth reusing; errors may be transient
+	if stats.err == nil && !rootModTime.IsZero() && state.IsStatsGen(gen) {
+		state.StoreStats(cacheKey, rootModTime, stats)
+	}
+	publishStats(g, state, gen, cwd, stats)
+}
+
+// statsWalkOptions configures walkStats.
+type statsWalkOptions struct {
+	gitIgnored     map[string]bool                // Paths from GitIgnoredUnder to skip, nil to skip none
+	loadIgnoreFile func(file string) *ignoreRules // Reads .lazylsignore files (readIgnoreFile if nil)
+	// progress, if set, is called with the extension breakdown so far every
+	// extStatsPublishInterval; returning false stops the walk.
+	progress func(extStats []extStat) bool
+}
+
+// walkStats walks root and returns its total size, entry counts, largest
+// files and extension breakdown. It needs neither the UI nor the app state,
+// so --stats runs the same walk as the Size pane.
+func walkStats(root string, opts statsWalkOptions) statsSnapshot {
+	loadRules := opts.loadIgnoreFile
+	if loadRules == nil {
+		loadRules = readIgnoreFile
+	}
 
 	var totalSize int64 = 0                       // Start at 0, handle errors explicitly
 	var largestFile FileInfo = FileInfo{Size: -1} // Size -1 indicates none found yet
-	var gitStatus string
+	var topFiles fileSizeHeap
+	var fileCount, dirCount int
+	var diskSize int64
+	diskSizeKnown := true
+	links := linkTracker{}
+
+	byExt := make(map[string]*extStat)
+	bySubdir := make(map[string]int64) // Bytes per immediate subdirectory of root
+	lastExtPublish := time.Now()
 	var firstWalkErr error // Store the first significant error encountered
+	var walkErrs []statsWalkError
+	errCount := 0
+	recordErr := func(path string, err error) {
+		errCount++
+		if len(walkErrs) >= maxStatsErrors {
+			return
+		}
+		// The path is listed separately, so keep just the reason
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		walkErrs = append(walkErrs, statsWalkError{Path: path, Reason: err.Error()})
+	}
+
+	var brokenLinks []brokenLink
+	brokenLinkCount := 0
+	var emptyDirs, emptyFiles []FileInfo
+	var emptyDirCount, emptyFileCount int
+	recordEmpty := func(path string, isDir bool) {
+		if isDir {
+			emptyDirCount++
+			if len(emptyDirs) < maxCleanupEntries {
+				emptyDirs = append(emptyDirs, FileInfo{Name: filepath.Base(path), Path: path, IsDir: true, Icon: getIcon(filepath.Base(path), true)})
+			}
+			return
+		}
+		emptyFileCount++
+		if len(emptyFiles) < maxCleanupEntries {
+			emptyFiles = append(emptyFiles, FileInfo{Name: filepath.Base(path), Path: path, Icon: getIcon(filepath.Base(path), false)})
+		}
+	}
+	// WalkDir visits a directory's children right after it, so the last
+	// directory entered is empty unless the next callback is for a child.
+	// Ignored children still count: the folder isn't empty on disk.
+	var pendingDir string
+
+	ignoreStacks := map[string][]scopedIgnoreRules{root: ignoreStack(root, loadRules)}
 
 	// Use WalkDir for potentially better performance and error handling per entry
-	err := filepath.WalkDir(cwd, func(path string, d fs.DirEntry, walkError error) error {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, walkError error) error {
+		if pendingDir != "" {
+			// A second callback for the directory itself means it couldn't be read
+			if path != pendingDir && filepath.Dir(path) != pendingDir {
+				recordEmpty(pendingDir, true)
+			}
+			pendingDir = ""
+		}
+
 		// --- Handle Walk Errors ---
 		if walkError != nil {
 			// Log the error but try to continue if possible
 			log.Printf("Warning: Walk error accessing %s: %v", path, walkError)
+			recordErr(path, walkError)
 			if firstWalkErr == nil { // Store the first error
 				// Try to get a more user-friendly name if possible
 				entryName := "entry"
@@ -212,22 +642,92 @@ func calculateStats(g *gocui.Gui, state *AppState) {
 		}
 
 		// Skip the root directory itself for size calculation
-		if path == cwd {
+		if path == root {
 			return nil
 		}
 
+		// .lazylsignore rules: a directory inherits its parent's stack plus its own file
+		stack := ignoreStacks[filepath.Dir(path)]
+		if len(stack) > 0 && isIgnoredBy(stack, path, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if rules := loadRules(filepath.Join(path, ignoreFileName)); rules != nil {
+				stack = append(stack[:len(stack):len(stack)], scopedIgnoreRules{base: path, rules: rules})
+			}
+			ignoreStacks[path] = stack
+		}
+
+		if opts.gitIgnored != nil {
+			if rel, relErr := filepath.Rel(root, path); relErr == nil {
+				rel = filepath.ToSlash(rel)
+				if d.IsDir() && opts.gitIgnored[rel+"/"] {
+					return filepath.SkipDir
+				}
+				if !d.IsDir() && opts.gitIgnored[rel] {
+					return nil
+				}
+			}
+		}
+
 		// --- Process Entry ---
-		if !d.IsDir() {
+		if d.IsDir() {
+			dirCount++
+			pendingDir = path
+		} else {
 			info, infoErr := d.Info()
 			if infoErr != nil {
 				log.Printf("Warning: Could not get info for %s: %v", path, infoErr)
+				recordErr(path, infoErr)
 				if firstWalkErr == nil {
 					firstWalkErr = fmt.Errorf("info for %s: %w", d.Name(), infoErr)
 				}
 				return nil // Skip this entry
 			}
+			if info.Mode()&os.ModeSymlink != 0 {
+				if link, broken := checkSymlink(path); broken {
+					brokenLinkCount++
+					if len(brokenLinks) < maxCleanupEntries {
+						brokenLinks = append(brokenLinks, link)
+					}
+				}
+			}
+			if links.seen(info) {
+				return nil // Counted with its first link
+			}
+			fileCount++
 			fileSize := info.Size()
+			if fileSize == 0 && info.Mode().IsRegular() {
+				recordEmpty(path, false)
+			}
 			totalSize += fileSize
+			if top := topLevelDir(root, path); top != "" {
+				bySubdir[top] += fileSize
+			}
+			if allocated, ok := allocatedSize(info); ok {
+				diskSize += allocated
+			} else {
+				diskSizeKnown = false
+			}
+
+			// Extension breakdown
+			bucket := extensionBucket(d.Name())
+			st, ok := byExt[bucket]
+			if !ok {
+				st = &extStat{Ext: bucket}
+				byExt[bucket] = st
+			}
+			st.Count++
+			st.Bytes += fileSize
+			if opts.progress != nil && time.Since(lastExtPublish) >= extStatsPublishInterval {
+				lastExtPublish = time.Now()
+				if !opts.progress(sortedExtStats(byExt)) {
+					return fs.SkipAll
+				}
+			}
 
 			// Update largest file found so far
 			if fileSize > largestFile.Size {
@@ -239,16 +739,31 @@ func calculateStats(g *gocui.Gui, state *AppState) {
 					Icon:  getIcon(d.Name(), false), // Get icon for the largest file
 				}
 			}
+
+			// Keep the N largest files in a min-heap (smallest of the top N at the root)
+			if len(topFiles) < topFilesCount || fileSize > topFiles[0].Size {
+				fi := FileInfo{Name: d.Name(), Path: path, Size: fileSize, Icon: getIcon(d.Name(), false)}
+				if len(topFiles) < topFilesCount {
+					heap.Push(&topFiles, fi)
+				} else {
+					topFiles[0] = fi
+					heap.Fix(&topFiles, 0)
+				}
+			}
 		}
 		return nil // Continue walking
 	})
 
+	if pendingDir != "" {
+		recordEmpty(pendingDir, true) // The last directory of the walk
+	}
+
 	// Handle error returned directly by WalkDir (e.g., initial access error)
 	if err != nil && firstWalkErr == nil {
-		firstWalkErr = fmt.Errorf("walking %s: %w", filepath.Base(cwd), err)
+		firstWalkErr = fmt.Errorf("walking %s: %w", filepath.Base(root), err)
 	}
 
-	// --- Update State Based on Walk Results ---
+	// --- Assemble the Result ---
 	finalTotalSize := totalSize
 	finalLargestFile := largestFile
 	if firstWalkErr != nil {
@@ -256,44 +771,118 @@ func calculateStats(g *gocui.Gui, state *AppState) {
 		finalTotalSize = -2         // Indicate error state for size
 		if largestFile.Size == -1 { // If no file was ever successfully processed
 			finalLargestFile = FileInfo{Name: "Error during scan", Size: -2}
-		} else {
-			// Keep the largest file found, but maybe indicate the total size is partial?
-			// For now, just marking total size as error is sufficient.
-			// No name change needed here, error is indicated by totalSize = -2
 		}
-
 	} else if largestFile.Size == -1 { // Walk completed without error, but no files found
 		finalLargestFile = FileInfo{} // Represents "no files" correctly
 	}
 
-	// 2. Check Git Status (runs regardless of walk errors)
-	// Use IsGitRepo and GetGitBranch functions for clarity
-	isRepo, repoCheckErr := IsGitRepo(cwd)
-	if repoCheckErr != nil {
-		log.Printf("Warning: Git check failed for %s: %v", cwd, repoCheckErr)
-		gitStatus = "Status Unknown (Error)" // More specific error
-	} else if !isRepo {
-		gitStatus = "Inactive"
-	} else {
-		branchName, branchErr := GetGitBranch(cwd)
-		if branchErr != nil {
-			log.Printf("Warning: Could not get git branch for %s: %v", cwd, branchErr)
-			gitStatus = "Active: (Branch Error)" // Specific error for branch issue
-		} else if branchName == "" {
-			// This might happen in detached HEAD state
-			gitStatus = "Active: (Detached HEAD?)"
-		} else {
-			gitStatus = fmt.Sprintf("Active: (%s)", branchName)
+	// Pop the heap smallest-first, filling the slice from the back so it ends up biggest-first
+	sortedTop := make([]FileInfo, topFiles.Len())
+	for i := len(sortedTop) - 1; i >= 0; i-- {
+		sortedTop[i] = heap.Pop(&topFiles).(FileInfo)
+	}
+	if !diskSizeKnown {
+		diskSize = -1
+	}
+	var largestDir FileInfo
+	for name, size := range bySubdir {
+		// Ties go to the first name, so the pane doesn't flip between walks
+		if size > largestDir.Size || (size == largestDir.Size && size > 0 && name < largestDir.Name) {
+			largestDir = FileInfo{Name: name, Path: filepath.Join(root, name), IsDir: true, Size: size, Icon: getIcon(name, true)}
 		}
-		// Optional: Check for modifications (adds overhead)
-		// modified, modCheckErr := HasGitModifications(cwd)
-		// if modCheckErr == nil && modified {
-		// 	gitStatus += " *" // Add indicator if modified
-		// }
 	}
+	return statsSnapshot{
+		totalSize:   finalTotalSize,
+		diskSize:    diskSize,
+		largestFile: finalLargestFile,
+		largestDir:  largestDir,
+		topFiles:    sortedTop,
+		extStats:    sortedExtStats(byExt),
+		fileCount:   fileCount,
+		dirCount:    dirCount,
+		err:         firstWalkErr,
+		errs:        walkErrs,
+		errCount:    errCount,
+
+		emptyDirs:      emptyDirs,
+		emptyDirCount:  emptyDirCount,
+		emptyFiles:     emptyFiles,
+		emptyFileCount: emptyFileCount,
+
+		brokenLinks:     brokenLinks,
+		brokenLinkCount: brokenLinkCount,
+	}
+}
+
+// topLevelDir returns the name of the immediate subdirectory of root that path
+// is in, or "" for entries directly in root.
+func topLevelDir(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return ""
+	}
+	top, _, found := strings.Cut(rel, string(filepath.Separator))
+	if !found {
+		return ""
+	}
+	return top
+}
+
+// unresolvedRepoRoot returns root, as git reports it (symlinks resolved), in
+// terms of cwd's own path: under a symlinked CWD, /link/sub in the repository
+// /real/repo gives /link. Where cwd's path can't be mapped that way (a symlink
+// inside the repository), root is returned as is.
+func unresolvedRepoRoot(root, cwd string) string {
+	resolved, err := filepath.EvalSymlinks(cwd)
+	if err != nil {
+		return root
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return root
+	}
+	candidate := cwd
+	if rel != "." {
+		for range strings.Split(rel, string(filepath.Separator)) {
+			candidate = filepath.Dir(candidate)
+		}
+	}
+	if same, err := filepath.EvalSymlinks(candidate); err != nil || same != root {
+		return root
+	}
+	return candidate
+}
+
+// publishStats adds free disk space and git status (never cached: both change
+// independently of the tree) to stats and stores the result unless gen was superseded.
+func publishStats(g *gocui.Gui, state *AppState, gen int, cwd string, stats statsSnapshot) {
+	// Free space is informational; on failure the Size pane just omits the disk line
+	diskFree, diskTotal, diskErr := filesystemSpace(cwd)
+	if diskErr != nil {
+		log.Printf("Warning: Could not get filesystem space for %s: %v", cwd, diskErr)
+	}
+
+	// Check Git Status (runs regardless of walk errors)
+	gitStatus, repoRoot, lastCommit, lastCommitErr := gitSummary(state.Git(), state.Files(), cwd)
 
 	// --- Update state safely ---
-	state.SetStatsResults(finalTotalSize, finalLargestFile, gitStatus, firstWalkErr)
+	if !state.IsStatsGen(gen) {
+		return // Superseded by a newer stats run
+	}
+	state.SetTopFiles(stats.topFiles)
+	state.SetLargestDir(stats.largestDir)
+	state.SetExtStats(stats.extStats)
+	state.SetEntryCounts(stats.fileCount, stats.dirCount)
+	state.SetDiskUsage(stats.diskSize)
+	if diskErr == nil {
+		state.SetDiskSpace(diskFree, diskTotal)
+	}
+	state.SetStatsResults(stats.totalSize, stats.largestFile, gitStatus, stats.err)
+	state.SetLastCommit(lastCommit, lastCommitErr)
+	state.SetRepoRoot(repoRoot)
+	state.SetStatsErrors(stats.errs, stats.errCount)
+	state.SetCleanup(stats.emptyDirs, stats.emptyDirCount, stats.emptyFiles, stats.emptyFileCount)
+	state.SetBrokenLinks(stats.brokenLinks, stats.brokenLinkCount)
 
 	// Trigger UI update from the goroutine
 	g.Update(func(gui *gocui.Gui) error {
@@ -301,33 +890,316 @@ func calculateStats(g *gocui.Gui, state *AppState) {
 	})
 }
 
+// gitSummary describes dir for the Git Status pane: "Inactive" outside a
+// repository, otherwise "Active: (branch)" plus any rebase or merge in
+// progress, the root of the work tree and the last commit.
+func gitSummary(git GitRunner, files FileReader, dir string) (status, root string, lastCommit gitCommit, lastCommitErr error) {
+	isRepo, root, shallow, repoCheckErr := IsGitRepo(git, dir)
+	if repoCheckErr != nil {
+		log.Printf("Warning: Git check failed for %s: %v", dir, repoCheckErr)
+		return "Status Unknown (Error)", "", gitCommit{}, nil // More specific error
+	}
+	if !isRepo {
+		return "Inactive", "", gitCommit{}, nil
+	}
+	branchName, branchErr := GetGitBranch(git, dir)
+	if branchErr != nil {
+		log.Printf("Warning: Could not get git branch for %s: %v", dir, branchErr)
+		status = "Active: (Branch Error)" // Specific error for branch issue
+	} else if branchName == "" {
+		// Detached HEAD (also during a rebase): name the commit instead
+		if hash, hashErr := GetGitHeadHash(git, dir); hashErr == nil {
+			status = fmt.Sprintf("Active: (detached @ %s)", hash)
+		} else {
+			log.Printf("Warning: Could not resolve HEAD for %s: %v", dir, hashErr)
+			status = "Active: (Detached HEAD?)"
+		}
+	} else {
+		status = fmt.Sprintf("Active: (%s)", branchName)
+	}
+	if operation, opErr := GitOperationInProgress(git, files, dir); opErr != nil {
+		log.Printf("Warning: Could not check for a rebase or merge in %s: %v", dir, opErr)
+	} else if operation != "" {
+		status += fmt.Sprintf(" (%s)", operation)
+	}
+	lastCommit, lastCommitErr = GetLastCommit(git, dir)
+	if lastCommitErr != nil {
+		log.Printf("Warning: Could not get last commit for %s: %v", dir, lastCommitErr)
+	}
+	lastCommit.Shallow = shallow
+	// Optional: Check for modifications (adds overhead)
+	// modified, modCheckErr := HasGitModifications(git, dir)
+	// if modCheckErr == nil && modified {
+	// 	status += " *" // Add indicator if modified
+	// }
+	return status, root, lastCommit, lastCommitErr
+}
+
+// dirSizeWorkers bounds how many directories are walked concurrently by the size job.
+const dirSizeWorkers = 4
+
+// startDirSizeJob computes the recursive size of every listed directory in the
+// background, updating the Folders pane as each result arrives. Any previous job
+// is cancelled, and directories with a cached size are skipped.
+func startDirSizeJob(g *gocui.Gui, state *AppState) {
+	ctx := state.BeginDirSizeJob()
+
+	var pending []FileInfo
+	for _, dir := range append(state.VisibleDirs(), state.HiddenDirs()...) {
+		if dir.Size < 0 && !dir.Unreadable {
+			pending = append(pending, dir)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	go func() {
+		sem := make(chan struct{}, dirSizeWorkers)
+		var wg sync.WaitGroup
+		for _, dir := range pending {
+			select {
+			case <-ctx.Done():
+				return
+			case sem <- struct{}{}:
+			}
+			wg.Add(1)
+			go func(dir FileInfo) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				size, _, err := dirUsage(dir.Path, func() bool { return ctx.Err() != nil })
+				if ctx.Err() != nil {
+					return // Partial result from a cancelled walk; don't cache it
+				}
+				if err != nil {
+					log.Printf("Warning: Could not size directory %s: %v", dir.Path, err)
+					size = -2
+				}
+				state.SetDirSize(dir.Path, dir.ModTime, size)
+				if errors.Is(err, fs.ErrPermission) {
+					state.SetDirUnreadable(dir.Path)
+				}
+				g.Update(func(gui *gocui.Gui) error { return nil })
+			}(dir)
+		}
+		wg.Wait()
+	}()
+}
+
+// --- File Properties ---
+
+// sysStat holds platform-specific file metadata not exposed by os.FileInfo.
+type sysStat struct {
+	Blocks int64 // Allocated 512-byte blocks
+	Nlink  uint64
+	Dev    uint64 // Device and inode identify the file across its hard links
+	Ino    uint64
+	Uid    uint32
+	Gid    uint32
+	Atime  time.Time
+	Ctime  time.Time
+}
+
+// fileProperties is the snapshot shown in the properties popup.
+type fileProperties struct {
+	Path      string
+	Kind      string // "File", "Directory", "Symlink", ...
+	LinkDest  string // Symlink target, if any
+	Size      int64
+	Mode      os.FileMode
+	ModTime   time.Time
+	HasSys    bool // Whether the fields below are available on this platform
+	Sys       sysStat
+	Owner     string
+	Group     string
+	MIMEType  string // Sniffed from the content of regular files
+	StatError error
+}
+
+// gatherProperties collects metadata for the properties popup. It uses Lstat so
+// symlinks are described rather than followed.
+func gatherProperties(item FileInfo) fileProperties {
+	props := fileProperties{Path: item.Path, Size: -1}
+
+	info, err := os.Lstat(item.Path)
+	if err != nil {
+		props.StatError = err
+		return props
+	}
+	props.Size = info.Size()
+	props.Mode = info.Mode()
+	props.ModTime = info.ModTime()
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		props.Kind = "Symlink"
+		if dest, err := os.Readlink(item.Path); err == nil {
+			props.LinkDest = dest
+		}
+	case info.IsDir():
+		props.Kind = "Directory"
+	case info.Mode().IsRegular():
+		props.Kind = "File"
+		props.MIMEType = fileMIME(item.Path)
+	default:
+		props.Kind = "Special"
+	}
+
+	props.Sys, props.HasSys = platformStat(info)
+	if props.HasSys {
+		uid := fmt.Sprint(props.Sys.Uid)
+		gid := fmt.Sprint(props.Sys.Gid)
+		props.Owner, props.Group = uid, gid
+		if u, err := user.LookupId(uid); err == nil {
+			props.Owner = u.Username
+		}
+		if grp, err := user.LookupGroupId(gid); err == nil {
+			props.Group = grp.Name
+		}
+	}
+	return props
+}
+
+// dirUsage walks dir and returns the total size of regular files (hard links
+// counted once) and the number of entries below it. The walk stops early (returning the partial result) when
+// cancelled reports true.
+func dirUsage(dir string, cancelled func() bool) (size int64, items int, err error) {
+	links := linkTracker{}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, walkErr error) error {
+		if cancelled != nil && cancelled() {
+			return fs.SkipAll
+		}
+		if walkErr != nil {
+			if path == dir {
+				return walkErr // The folder itself can't be read
+			}
+			log.Printf("Warning: Walk error accessing %s: %v", path, walkErr)
+			if d != nil && d.IsDir() && path != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if path == dir {
+			return nil
+		}
+		items++
+		if d.Type().IsRegular() {
+			if info, infoErr := d.Info(); infoErr == nil && !links.seen(info) {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size, items, err
+}
+
 // --- Git Helper Functions ---
 
-// IsGitRepo checks if a directory is part of a git repository.
-func IsGitRepo(dir string) (bool, error) {
-	// `git rev-parse --is-inside-work-tree` is reliable
-	cmd := exec.Command("git", "-C", dir, "rev-parse", "--is-inside-work-tree")
-	output, err := cmd.Output()
+// IsGitRepo checks if a directory is part of a git repository's work tree,
+// and returns the root of that work tree and whether the repository is a
+// shallow clone.
+func IsGitRepo(git GitRunner, dir string) (isRepo bool, root string, shallow bool, err error) {
+	// `git rev-parse --is-inside-work-tree` is reliable; the root and the shallow flag
+	// come with it in the same call. Older git doesn't know --is-shallow-repository
+	// and echoes it, so the marker is just left off there
+	output, err := git.Run(dir, nil, "rev-parse", "--is-inside-work-tree", "--show-toplevel", "--is-shallow-repository")
 	if err != nil {
 		// This often means 'git' command not found or it's not a repo.
 		// Check if it's the specific "not a git repository" error.
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			// stderr output often contains "fatal: not a git repository"
-			if strings.Contains(string(exitErr.Stderr), "not a git repository") {
-				return false, nil // Not an error, just not a repo
+			// stderr output often contains "fatal: not a git repository";
+			// inside the .git directory there's no work tree to show the root of
+			stderr := string(exitErr.Stderr)
+			if strings.Contains(stderr, "not a git repository") || strings.Contains(stderr, "must be run in a work tree") {
+				return false, "", false, nil // Not an error, just not a repo
 			}
 		}
 		// Otherwise, it's a different error (e.g., git not installed)
-		return false, fmt.Errorf("git check failed: %w", err)
+		return false, "", false, fmt.Errorf("git check failed: %w", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if lines[0] != "true" {
+		return false, "", false, nil
+	}
+	if len(lines) > 1 {
+		root = filepath.FromSlash(strings.TrimSpace(lines[1])) // Git for Windows prints C:/...
+	}
+	if len(lines) > 2 {
+		shallow = strings.TrimSpace(lines[2]) == "true"
+	}
+	return true, root, shallow, nil
+}
+
+// repoKind tells folders that are git repositories of their own apart from
+// ordinary ones.
+type repoKind uint8
+
+const (
+	repoNone      repoKind = iota
+	repoNested             // Has its own .git directory, or a .git file from --separate-git-dir
+	repoSubmodule          // .git file pointing into the parent's .git/modules
+	repoWorktree           // .git file pointing into another repository's .git/worktrees
+)
+
+// label is the marker shown after the folder's name, "" for ordinary folders.
+func (k repoKind) label() string {
+	switch k {
+	case repoNested:
+		return "repo"
+	case repoSubmodule:
+		return "submodule"
+	case repoWorktree:
+		return "worktree"
+	}
+	return ""
+}
+
+// detectRepoKind looks for a .git entry in dir. Submodules, linked worktrees
+// and repositories made with --separate-git-dir all have a .git file
+// ("gitdir: <path>") instead of a directory. Worktrees are told by the path;
+// only git knows whether a parent repository tracks dir as a submodule. Its
+// answer is cached while dir's mtime (modTime) is unchanged, so reloading a
+// superproject doesn't start a git process per submodule.
+func detectRepoKind(state *AppState, dir string, modTime time.Time) repoKind {
+	gitPath := filepath.Join(dir, ".git")
+	info, err := os.Lstat(gitPath)
+	if err != nil {
+		return repoNone
+	}
+	if info.IsDir() {
+		return repoNested
+	}
+	data, err := os.ReadFile(gitPath)
+	if err != nil || !strings.HasPrefix(string(data), "gitdir:") {
+		return repoNone
+	}
+	gitDir := filepath.ToSlash(strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:")))
+	if strings.Contains(gitDir, "/worktrees/") {
+		return repoWorktree
+	}
+	if kind, ok := state.CachedRepoKind(dir, modTime); ok {
+		return kind
+	}
+	kind := repoNested
+	output, err := state.Git().Run(dir, nil, "rev-parse", "--show-superproject-working-tree")
+	switch {
+	case err != nil:
+		// Without git, fall back to where submodules keep their repositories
+		if strings.Contains(gitDir, "/modules/") {
+			kind = repoSubmodule
+		}
+	case strings.TrimSpace(string(output)) != "":
+		kind = repoSubmodule
 	}
-	return strings.TrimSpace(string(output)) == "true", nil
+	state.SetRepoKind(dir, modTime, kind)
+	return kind
 }
 
 // GetGitBranch returns the current branch name.
-func GetGitBranch(dir string) (string, error) {
+func GetGitBranch(git GitRunner, dir string) (string, error) {
 	// Use `git branch --show-current` as it's simpler
-	cmd := exec.Command("git", "-C", dir, "branch", "--show-current")
-	output, err := cmd.Output()
+	output, err := git.Run(dir, nil, "branch", "--show-current")
 	if err != nil {
 		// Check if it's detached HEAD state (often returns exit code 1, but no output on stdout)
 		// If it's an ExitError and output is empty, likely detached HEAD. We don't need exitErr itself.
@@ -341,13 +1213,176 @@ func GetGitBranch(dir string) (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
+// GitBranches returns the names of the repository's local branches and the
+// one checked out, "" on a detached HEAD.
+func GitBranches(git GitRunner, dir string) (branches []string, current string, err error) {
+	// %(HEAD) is "*" for the checked-out branch and " " for the others
+	output, err := git.Run(dir, nil, "branch", "--format=%(HEAD)%(refname:short)")
+	if err != nil {
+		return nil, "", fmt.Errorf("git branch failed: %w", err)
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		if len(line) < 2 || (line[0] == '*' && strings.HasPrefix(line[1:], "(")) {
+			continue // Empty output, or the "(HEAD detached at ...)" pseudo-branch
+		}
+		name := line[1:]
+		if line[0] == '*' {
+			current = name
+		}
+		branches = append(branches, name)
+	}
+	return branches, current, nil
+}
+
+// SwitchGitBranch checks out branch with `git switch`, which refuses rather
+// than overwriting uncommitted changes. A refusal is reported by git's own
+// first line of explanation.
+func SwitchGitBranch(git GitRunner, dir, branch string) error {
+	_, err := git.Run(dir, nil, "switch", "--no-guess", branch)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if line := firstLines(strings.TrimSpace(string(exitErr.Stderr)), 1); line != "" {
+				return errors.New(strings.TrimPrefix(strings.TrimPrefix(line, "error: "), "fatal: "))
+			}
+		}
+		return fmt.Errorf("git switch failed: %w", err)
+	}
+	return nil
+}
+
+// GetGitHeadHash returns the abbreviated hash of the commit HEAD points at.
+func GetGitHeadHash(git GitRunner, dir string) (string, error) {
+	output, err := git.Run(dir, nil, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GitOperationInProgress returns "rebasing" or "merging" while a rebase or
+// merge waits to be finished, and "" otherwise. It looks for the state files
+// git keeps in the repository's git dir.
+func GitOperationInProgress(git GitRunner, files FileReader, dir string) (string, error) {
+	// --git-path resolves the files for worktrees and relocated git dirs too
+	output, err := git.Run(dir, nil, "rev-parse",
+		"--git-path", "rebase-merge", "--git-path", "rebase-apply", "--git-path", "MERGE_HEAD")
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	paths := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(paths) != 3 {
+		return "", fmt.Errorf("unexpected git rev-parse output %q", output)
+	}
+	for i, operation := range []string{"rebasing", "rebasing", "merging"} {
+		path := paths[i]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if _, err := files.Stat(path); err == nil {
+			return operation, nil
+		}
+	}
+	return "", nil
+}
+
+// gitCommit is the summary of a commit shown in the Git Status pane.
+type gitCommit struct {
+	Hash    string // Abbreviated; empty when the branch has no commits yet
+	Time    time.Time
+	Subject string
+	Shallow bool // The repository is a shallow clone, so history stops early; set by gitSummary
+}
+
+// GetLastCommit returns the commit HEAD points at. A repository without
+// commits yields a zero gitCommit and no error.
+func GetLastCommit(git GitRunner, dir string) (gitCommit, error) {
+	output, err := git.Run(dir, nil, "log", "-1", "--format=%h%x00%ct%x00%s")
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			stderr := string(exitErr.Stderr)
+			if strings.Contains(stderr, "does not have any commits yet") || strings.Contains(stderr, "bad default revision") {
+				return gitCommit{}, nil
+			}
+		}
+		return gitCommit{}, fmt.Errorf("git log failed: %w", err)
+	}
+	fields := strings.SplitN(strings.TrimRight(string(output), "\n"), "\x00", 3)
+	if len(fields) != 3 {
+		return gitCommit{}, fmt.Errorf("unexpected git log output %q", output)
+	}
+	commit := gitCommit{Hash: fields[0], Subject: fields[2]}
+	if seconds, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+		commit.Time = time.Unix(seconds, 0)
+	}
+	return commit, nil
+}
+
 // HasGitModifications checks for uncommitted changes or untracked files.
-func HasGitModifications(dir string) (bool, error) {
+func HasGitModifications(git GitRunner, dir string) (bool, error) {
 	// `git status --porcelain` is fast and output is empty if clean
-	cmd := exec.Command("git", "-C", dir, "status", "--porcelain")
-	output, err := cmd.Output()
+	output, err := git.Run(dir, nil, "status", "--porcelain")
 	if err != nil {
 		return false, fmt.Errorf("git status check failed: %w", err)
 	}
 	return len(output) > 0, nil
 }
+
+// GitIgnoredNames reports which entries of dir are ignored by git, using a single
+// batched `git check-ignore --stdin` call. inRepo is false when dir is not inside
+// a work tree, in which case nothing is ignored.
+func GitIgnoredNames(git GitRunner, dir string, entries []os.DirEntry) (ignored map[string]bool, inRepo bool, err error) {
+	var input strings.Builder
+	for _, entry := range entries {
+		input.WriteString(entry.Name())
+		if entry.IsDir() {
+			input.WriteByte('/') // Lets directory-only patterns like "build/" match
+		}
+		input.WriteByte(0)
+	}
+
+	output, err := git.Run(dir, strings.NewReader(input.String()), "check-ignore", "--stdin", "-z")
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if strings.Contains(string(exitErr.Stderr), "not a git repository") {
+				return nil, false, nil
+			}
+			if exitErr.ExitCode() == 1 { // None of the paths are ignored
+				return map[string]bool{}, true, nil
+			}
+		}
+		return nil, false, fmt.Errorf("git check-ignore failed: %w", err)
+	}
+
+	ignored = make(map[string]bool)
+	for _, path := range strings.Split(string(output), "\x00") {
+		if path != "" {
+			ignored[strings.TrimSuffix(path, "/")] = true
+		}
+	}
+	return ignored, true, nil
+}
+
+// GitIgnoredUnder lists the ignored files and directories below dir, keyed by
+// slash-separated path relative to dir (directories end in "/"). It returns nil
+// when dir is not inside a work tree.
+func GitIgnoredUnder(git GitRunner, dir string) (map[string]bool, error) {
+	output, err := git.Run(dir, nil, "ls-files", "--others", "--ignored", "--exclude-standard", "--directory", "-z")
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if strings.Contains(string(exitErr.Stderr), "not a git repository") {
+				return nil, nil
+			}
+		}
+		return nil, fmt.Errorf("git ls-files failed: %w", err)
+	}
+
+	ignored := make(map[string]bool)
+	for _, path := range strings.Split(string(output), "\x00") {
+		if path != "" {
+			ignored[path] = true
+		}
+	}
+	return ignored, nil
+}
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnresolvedRepoRoot(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(base, "real", "repo")
+	for _, dir := range []string{"sub/deep", "other/x"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(base, "link")
+	if err := os.Symlink(root, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	inner := filepath.Join(root, "inner")
+	if err := os.Symlink(filepath.Join(root, "other", "x"), inner); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, cwd, want string
+	}{
+		{"plain root", root, root},
+		{"plain subfolder", filepath.Join(root, "sub", "deep"), root},
+		{"symlinked root", link, link},
+		{"under a symlinked root", filepath.Join(link, "sub", "deep"), link},
+		{"symlink inside the repository", inner, root},
+		{"outside the repository", base, root},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unresolvedRepoRoot(root, tt.cwd); got != tt.want {
+				t.Errorf("unresolvedRepoRoot(%s) = %s, want %s", tt.cwd, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHeadStates(t *testing.T) {
+	dir, git := testRepo(t)
+	commitFile(t, dir, git, "a.txt", "base\n", "base")
+	git("checkout", "-q", "-b", "topic")
+	commitFile(t, dir, git, "a.txt", "topic\n", "topic")
+	git("checkout", "-q", "-")
+	commitFile(t, dir, git, "a.txt", "main\n", "main")
+	commitFile(t, dir, git, "b.txt", "b\n", "b")
+
+	runner, files := execGit{}, osFileReader{}
+	check := func(wantBranch bool, wantOperation string) {
+		t.Helper()
+		branch, err := GetGitBranch(runner, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (branch != "") != wantBranch {
+			t.Errorf("branch %q, want one: %v", branch, wantBranch)
+		}
+		if !wantBranch {
+			hash, err := GetGitHeadHash(runner, dir)
+			if err != nil || len(hash) < 7 {
+				t.Errorf("detached HEAD hash %q, %v", hash, err)
+			}
+		}
+		operation, err := GitOperationInProgress(runner, files, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if operation != wantOperation {
+			t.Errorf("operation %q, want %q", operation, wantOperation)
+		}
+	}
+
+	check(true, "")
+
+	git("merge", "-q", "--no-ff", "--no-commit", "-s", "ours", "topic")
+	check(true, "merging")
+	git("merge", "--abort")
+
+	git("checkout", "-q", "--detach", "HEAD~1")
+	check(false, "")
+	git("checkout", "-q", "-")
+
+	git("checkout", "-q", "topic")
+	rebase := exec.Command("git", "-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com", "rebase", "@{-1}")
+	if output, err := rebase.CombinedOutput(); err == nil {
+		t.Fatalf("conflicting rebase succeeded:\n%s", output)
+	}
+	check(false, "rebasing")
+}
+
+// writeTree creates files (slash-separated path to content) under dir; a path
+// ending in "/" is an empty folder.
+func writeTree(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGitSummaryShallow(t *testing.T) {
+	dir, git := testRepo(t)
+	commitFile(t, dir, git, "a.txt", "a\n", "first")
+	commitFile(t, dir, git, "a.txt", "b\n", "second")
+	clone := filepath.Join(t.TempDir(), "clone")
+	git("clone", "-q", "--depth", "1", "file://"+filepath.ToSlash(dir), clone)
+
+	for _, tt := range []struct {
+		dir  string
+		want bool
+	}{{dir, false}, {clone, true}} {
+		_, _, commit, err := gitSummary(execGit{}, osFileReader{}, tt.dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if commit.Shallow != tt.want || commit.Subject != "second" {
+			t.Errorf("%s: last commit %q, shallow %v, want %v", tt.dir, commit.Subject, commit.Shallow, tt.want)
+		}
+	}
+}
+
+func TestWalkStats(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"a.go":           strings.Repeat("a", 100),
+		"b.go":           strings.Repeat("b", 50),
+		"docs/readme.md": strings.Repeat("r", 300),
+		"docs/empty.txt": "",
+		"emptydir/":      "",
+		ignoreFileName:   "skip/\n",
+		"skip/big.bin":   strings.Repeat("x", 1000), // Left out by .lazylsignore
+		"gen/out.txt":    strings.Repeat("g", 500),  // Left out as git-ignored
+	})
+
+	stats := walkStats(root, statsWalkOptions{gitIgnored: map[string]bool{"gen/": true}})
+	if stats.err != nil {
+		t.Fatal(stats.err)
+	}
+	if want := int64(100 + 50 + 300 + len("skip/\n")); stats.totalSize != want {
+		t.Errorf("total size %d, want %d", stats.totalSize, want)
+	}
+	if stats.fileCount != 5 || stats.dirCount != 2 {
+		t.Errorf("%d files and %d folders, want 5 and 2", stats.fileCount, stats.dirCount)
+	}
+	if stats.largestFile.Name != "readme.md" || stats.largestDir.Name != "docs" {
+		t.Errorf("largest file %q in %q, want readme.md in docs", stats.largestFile.Name, stats.largestDir.Name)
+	}
+	var top []string
+	for _, file := range stats.topFiles {
+		top = append(top, file.Name)
+	}
+	if want := []string{"readme.md", "a.go", "b.go", ignoreFileName, "empty.txt"}; !slices.Equal(top, want) {
+		t.Errorf("top files %q, want %q", top, want)
+	}
+	if len(stats.extStats) == 0 || stats.extStats[0].Ext != ".md" {
+		t.Errorf("extension breakdown %+v should start with .md", stats.extStats)
+	}
+	if stats.emptyDirCount != 1 || stats.emptyDirs[0].Name != "emptydir" {
+		t.Errorf("empty folders %+v, want emptydir", stats.emptyDirs)
+	}
+	if stats.emptyFileCount != 1 || stats.emptyFiles[0].Name != "empty.txt" {
+		t.Errorf("empty files %+v, want empty.txt", stats.emptyFiles)
+	}
+}
+
+func TestWalkStatsBrokenLinks(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"target.txt": "x"})
+	if err := os.Symlink("target.txt", filepath.Join(root, "good")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if err := os.Symlink("missing.txt", filepath.Join(root, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	stats := walkStats(root, statsWalkOptions{})
+	if stats.brokenLinkCount != 1 || stats.brokenLinks[0].Target != "missing.txt" {
+		t.Errorf("broken links %+v, want dangling -> missing.txt", stats.brokenLinks)
+	}
+}
+
+func TestWalkStatsMissingRoot(t *testing.T) {
+	stats := walkStats(filepath.Join(t.TempDir(), "gone"), statsWalkOptions{})
+	if stats.err == nil || stats.totalSize != -2 {
+		t.Errorf("got error %v and size %d, want an error and -2", stats.err, stats.totalSize)
+	}
+}
+
+func TestReadDirectoryKeepsUnreadableFolders(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"locked/secret.txt": "x", "open/": ""})
+	locked := filepath.Join(dir, "locked")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0o755) }) // Let TempDir remove it
+	if f, err := os.Open(locked); err == nil {
+		f.Close()
+		t.Skip("permissions are not enforced (running as root?)")
+	}
+
+	state := NewAppState(dir)
+	listing, err := readDirectory(state, dir, func(int) {}, func() bool { return false })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listing.visibleDirs) != 2 {
+		t.Fatalf("listed %d folders, want both", len(listing.visibleDirs))
+	}
+	if listing.warning != "" {
+		t.Errorf("warning %q; the listing doesn't open folders", listing.warning)
+	}
+
+	// The background jobs find out when they read the folder
+	if _, err := countEntries(locked); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("counting entries: %v, want a permission error", err)
+	}
+	if _, _, err := dirUsage(locked, nil); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("sizing: %v, want a permission error", err)
+	}
+	state.SetDirectoryContents(listing.visibleDirs, listing.visibleFiles, listing.hiddenDirs, listing.hiddenFiles)
+	state.SetDirUnreadable(locked)
+	for _, item := range state.VisibleDirs() {
+		if want := item.Name == "locked"; item.Unreadable != want {
+			t.Errorf("%s: unreadable %v, want %v", item.Name, item.Unreadable, want)
+		}
+	}
+
+	var labels []string
+	for _, option := range actionsFor(state.VisibleDirs()[0], NewAppState(dir)) {
+		labels = append(labels, option.Label)
+	}
+	if !slices.Contains(labels, "Copy Full Path") || slices.Contains(labels, "Duplicate") {
+		t.Errorf("actions on an unreadable folder: %q", labels)
+	}
+}
+
+func TestReadDirectoryLoadsFileInfoLazily(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	state := NewAppState(dir)
+	listing, err := readDirectory(state, dir, func(int) {}, func() bool { return false })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range listing.visibleFiles {
+		if item.HasInfo {
+			t.Errorf("%s was stat'ed while listing", item.Name)
+		}
+	}
+	for _, item := range listing.visibleDirs {
+		if !item.HasInfo {
+			t.Errorf("folder %s has no mtime to key its size", item.Name)
+		}
+	}
+
+	state.SetDirectoryContents(listing.visibleDirs, listing.visibleFiles, listing.hiddenDirs, listing.hiddenFiles)
+	state.LoadEntryInfo(viewFiles, 0, 2)
+	for i, item := range state.VisibleFiles() {
+		if want := i < 2; item.HasInfo != want {
+			t.Errorf("%s: HasInfo %v, want %v", item.Name, item.HasInfo, want)
+		}
+		if item.HasInfo && item.Size != int64(len(item.Name)) {
+			t.Errorf("%s: size %d, want %d", item.Name, item.Size, len(item.Name))
+		}
+	}
+}
+
+func TestReadDirectorySortsNaturally(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"d10", "d2", ".d10", ".d2"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"f10", "f2", ".f10", ".f2"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names := func(items []FileInfo) []string {
+		var out []string
+		for _, item := range items {
+			out = append(out, item.Name)
+		}
+		return out
+	}
+
+	state := NewAppState(dir)
+	for _, natural := range []bool{true, false} {
+		state.SetNaturalSort(natural)
+		listing, err := readDirectory(state, dir, func(int) {}, func() bool { return false })
+		if err != nil {
+			t.Fatal(err)
+		}
+		lists := []struct {
+			got           []FileInfo
+			small, larger string
+		}{
+			{listing.visibleDirs, "d2", "d10"},
+			{listing.visibleFiles, "f2", "f10"},
+			{listing.hiddenDirs, ".d2", ".d10"},
+			{listing.hiddenFiles, ".f2", ".f10"},
+		}
+		for _, list := range lists {
+			want := []string{list.small, list.larger}
+			if !natural {
+				want = []string{list.larger, list.small}
+			}
+			if got := names(list.got); !slices.Equal(got, want) {
+				t.Errorf("natural %v: got %q, want %q", natural, got, want)
+			}
+		}
+	}
+}
+
+func TestConfigureNameOrder(t *testing.T) {
+	defer configureNameOrder(config{})
+	tests := []struct {
+		order string
+		want  bool
+	}{
+		{"", true},
+		{"natural", true},
+		{"lexical", false},
+		{"random", true}, // Unknown values keep the default
+	}
+	for _, tt := range tests {
+		configureNameOrder(config{NameOrder: tt.order})
+		if got := NewAppState(t.TempDir()).IsNaturalSort(); got != tt.want {
+			t.Errorf("name_order %q: natural sort %v, want %v", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestDetectRepoKind(t *testing.T) {
+	dir, git := testRepo(t)
+	commitFile(t, dir, git, "a.txt", "a\n", "a")
+	lib, libGit := testRepo(t)
+	commitFile(t, lib, libGit, "lib.txt", "lib\n", "lib")
+
+	git("-c", "protocol.file.allow=always", "submodule", "add", "-q", lib, "sub")
+	git("worktree", "add", "-q", "-b", "tree", "tree")
+	git("init", "-q", "--separate-git-dir", filepath.Join(t.TempDir(), "sep.git"), "separate")
+	git("init", "-q", "nested")
+	if err := os.Mkdir(filepath.Join(dir, "plain"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want repoKind
+	}{
+		{"sub", repoSubmodule},
+		{"tree", repoWorktree},
+		{"separate", repoNested},
+		{"nested", repoNested},
+		{"plain", repoNone},
+	}
+	runner := &countingGit{}
+	state := NewAppState(dir)
+	state.git = runner
+	modTime := time.Now()
+	for range 2 {
+		for _, tt := range tests {
+			if got := detectRepoKind(state, filepath.Join(dir, tt.name), modTime); got != tt.want {
+				t.Errorf("%s: kind %q, want %q", tt.name, got.label(), tt.want.label())
+			}
+		}
+	}
+	// Only the submodule and the separate-git-dir repository need git, once each
+	if runner.calls != 2 {
+		t.Errorf("ran git %d times over two listings, want 2", runner.calls)
+	}
+}
+
+// BenchmarkReadDirectory lists a generated 50k-entry folder, comparing the
+// lazy listing with one that lstats every entry as the listing used to.
+func BenchmarkReadDirectory(b *testing.B) {
+	const entries = 50000
+	dir := b.TempDir()
+	for i := range entries {
+		f, err := os.Create(filepath.Join(dir, fmt.Sprintf("file%05d.txt", i)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		f.Close()
+	}
+	state := NewAppState(dir)
+	read := func(b *testing.B) dirListing {
+		listing, err := readDirectory(state, dir, func(int) {}, func() bool { return false })
+		if err != nil {
+			b.Fatal(err)
+		}
+		return listing
+	}
+
+	b.Run("lazy", func(b *testing.B) {
+		for b.Loop() {
+			read(b)
+		}
+	})
+	b.Run("lstat every entry", func(b *testing.B) {
+		for b.Loop() {
+			for _, item := range read(b).visibleFiles {
+				withEntryInfo(item)
+			}
+		}
+	})
+}
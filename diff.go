@@ -0,0 +1,272 @@
+// ---- File: diff.go ----
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// --- Line Diff ---
+
+// diffContextLines is how many unchanged lines surround each hunk.
+const diffContextLines = 3
+
+// maxDiffEdits bounds the search for a shortest edit script; files that
+// differ in more lines than this are reported instead of diffed.
+const maxDiffEdits = 4000
+
+// errFilesIdentical is returned by diffFiles when there is nothing to show.
+var errFilesIdentical = errors.New("files are identical")
+
+// diffOp is one line of an edit script: kind is ' ' (kept), '-' (only in a)
+// or '+' (only in b). aPos and bPos are the line positions in a and b at
+// this point of the script.
+type diffOp struct {
+	kind       byte
+	aPos, bPos int
+}
+
+// splitLines splits text into lines without their newlines.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffFiles returns a unified diff of the files at pathA and pathB, labelled
+// with nameA and nameB. Binary files and files over maxDiffSize are refused.
+// It stops with ctx's error once ctx is cancelled.
+func diffFiles(ctx context.Context, files FileReader, pathA, pathB, nameA, nameB string) (string, error) {
+	var contents [2][]byte
+	for i, path := range []string{pathA, pathB} {
+		data, err := ReadFileWithLimit(files, path, maxDiffSize)
+		if err != nil {
+			return "", err
+		}
+		text, mimeType, ok := textContent(data)
+		if !ok {
+			return "", fmt.Errorf("cannot compare binary files (%s)", mimeType)
+		}
+		contents[i] = text
+	}
+	if bytes.Equal(contents[0], contents[1]) {
+		return "", errFilesIdentical
+	}
+	a, b := splitLines(string(contents[0])), splitLines(string(contents[1]))
+	ops, err := diffLines(ctx, a, b)
+	if err != nil {
+		return "", err
+	}
+	return formatUnifiedDiff(nameA, nameB, a, b, ops), nil
+}
+
+// diffLines computes a shortest edit script from a to b with Myers'
+// algorithm, in linear space. It fails with errTooManyEdits if more than
+// maxDiffEdits edits are needed.
+func diffLines(ctx context.Context, a, b []string) ([]diffOp, error) {
+	d := &differ{ctx: ctx, a: a, b: b}
+	if err := d.diff(0, len(a), 0, len(b)); err != nil {
+		return nil, err
+	}
+	return d.ops, nil
+}
+
+// errTooManyEdits is returned by diffLines for files too different to compare.
+var errTooManyEdits = errors.New("files differ in too many lines to compare")
+
+// differ builds the edit script from a to b by divide and conquer: the middle
+// snake of a shortest path splits it in two smaller problems.
+type differ struct {
+	ctx context.Context
+	a   []string
+	b   []string
+	ops []diffOp
+}
+
+// diff appends the edit script from a[a0:a1] to b[b0:b1].
+func (d *differ) diff(a0, a1, b0, b1 int) error {
+	// Common prefix and suffix need no search
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.ops = append(d.ops, diffOp{' ', a0, b0})
+		a0, b0 = a0+1, b0+1
+	}
+	suffix := 0
+	for a0 < a1-suffix && b0 < b1-suffix && d.a[a1-1-suffix] == d.b[b1-1-suffix] {
+		suffix++
+	}
+	a1, b1 = a1-suffix, b1-suffix
+
+	switch {
+	case a0 == a1:
+		for y := b0; y < b1; y++ {
+			d.ops = append(d.ops, diffOp{'+', a0, y})
+		}
+	case b0 == b1:
+		for x := a0; x < a1; x++ {
+			d.ops = append(d.ops, diffOp{'-', x, b0})
+		}
+	default:
+		// Both sides are left and differ at both ends, so at least two edits
+		// are needed and each half of the split has fewer
+		x, y, u, v, err := d.middleSnake(a0, a1, b0, b1)
+		if err != nil {
+			return err
+		}
+		if err := d.diff(a0, x, b0, y); err != nil {
+			return err
+		}
+		for ; x < u; x, y = x+1, y+1 {
+			d.ops = append(d.ops, diffOp{' ', x, y})
+		}
+		if err := d.diff(u, a1, v, b1); err != nil {
+			return err
+		}
+	}
+	for i := range suffix {
+		d.ops = append(d.ops, diffOp{' ', a1 + i, b1 + i})
+	}
+	return nil
+}
+
+// middleSnake searches a shortest path from (a0, b0) to (a1, b1) from both
+// ends at once until they overlap, and returns the stretch of matching lines
+// (x, y) to (u, v) where they met. Only the furthest point on each diagonal
+// is kept, so the search needs space linear in the input.
+func (d *differ) middleSnake(a0, a1, b0, b1 int) (x, y, u, v int, err error) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := min((n+m+1)/2, (maxDiffEdits+1)/2)
+	offset := maxD + 1
+	// forward[k] is how far the forward search got on diagonal k (x-y = k);
+	// backward[c] the same for the search from the end, with c = delta-k
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+
+	for step := 0; step <= maxD; step++ {
+		if err := d.ctx.Err(); err != nil {
+			return 0, 0, 0, 0, err
+		}
+		for k := -step; k <= step; k += 2 {
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1] // Step down: insertion
+			} else {
+				x = forward[offset+k-1] + 1 // Step right: deletion
+			}
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && d.a[a0+u] == d.b[b0+v] {
+				u, v = u+1, v+1
+			}
+			forward[offset+k] = u
+			if c := delta - k; odd && c >= -(step-1) && c <= step-1 && u+backward[offset+c] >= n {
+				return a0 + x, b0 + y, a0 + u, b0 + v, nil
+			}
+		}
+		for c := -step; c <= step; c += 2 {
+			if c == -step || (c != step && backward[offset+c-1] < backward[offset+c+1]) {
+				x = backward[offset+c+1]
+			} else {
+				x = backward[offset+c-1] + 1
+			}
+			y = x - c
+			u, v = x, y
+			for u < n && v < m && d.a[a1-1-u] == d.b[b1-1-v] {
+				u, v = u+1, v+1
+			}
+			backward[offset+c] = u
+			if k := delta - c; !odd && k >= -step && k <= step && u+forward[offset+k] >= n {
+				// Back in forward coordinates, the snake runs from the far end
+				return a1 - u, b1 - v, a1 - x, b1 - y, nil
+			}
+		}
+	}
+	return 0, 0, 0, 0, errTooManyEdits
+}
+
+// formatUnifiedDiff renders ops as a unified diff with diffContextLines of context.
+func formatUnifiedDiff(nameA, nameB string, a, b []string, ops []diffOp) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", nameA, nameB)
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// Extend the hunk while the next change is within twice the context
+		start := i - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j
+			} else if j-end > 2*diffContextLines {
+				break
+			}
+		}
+		end += diffContextLines + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		var aCount, bCount int
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(ops[start].aPos, aCount), hunkRange(ops[start].bPos, bCount))
+		for _, op := range ops[start:end] {
+			switch op.kind {
+			case '-':
+				sb.WriteString("-" + a[op.aPos] + "\n")
+			case '+':
+				sb.WriteString("+" + b[op.bPos] + "\n")
+			default:
+				sb.WriteString(" " + a[op.aPos] + "\n")
+			}
+		}
+		i = end
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// hunkRange formats a hunk header range; an empty range names the line before it.
+func hunkRange(pos, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", pos)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", pos+1)
+	}
+	return fmt.Sprintf("%d,%d", pos+1, count)
+}
+
+// colorizeDiff colors a unified diff for the content viewer: additions green,
+// removals red, hunk headers cyan and file headers bold.
+func colorizeDiff(diff string) string {
+	lines := strings.Split(diff, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "):
+			lines[i] = ansiBold + line + ansiReset
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = ansiCyan + line + ansiReset
+		case strings.HasPrefix(line, "+"):
+			lines[i] = ansiGreen + line + ansiReset
+		case strings.HasPrefix(line, "-"):
+			lines[i] = ansiRed + line + ansiReset
+		}
+	}
+	return strings.Join(lines, "\n")
+}
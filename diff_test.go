@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// editDistance is the number of insertions and deletions turning a into b,
+// by the textbook dynamic program.
+func editDistance(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				cur[j] = prev[j-1]
+			} else {
+				cur[j] = min(prev[j], cur[j-1]) + 1
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func TestDiffLinesIsShortest(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(3)))
+		}
+		return lines
+	}
+	for range 2000 {
+		a, b := randomLines(), randomLines()
+		ops, err := diffLines(context.Background(), a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Replaying the script must walk both inputs in order and rebuild b
+		var x, y, edits int
+		var got []string
+		for _, op := range ops {
+			if op.aPos != x || op.bPos != y {
+				t.Fatalf("%q -> %q: op %c at (%d, %d), expected (%d, %d)", a, b, op.kind, op.aPos, op.bPos, x, y)
+			}
+			switch op.kind {
+			case ' ':
+				if a[x] != b[y] {
+					t.Fatalf("%q -> %q: keeps %q as %q", a, b, a[x], b[y])
+				}
+				got = append(got, a[x])
+				x, y = x+1, y+1
+			case '-':
+				x++
+				edits++
+			case '+':
+				got = append(got, b[y])
+				y++
+				edits++
+			}
+		}
+		if x != len(a) || strings.Join(got, "") != strings.Join(b, "") {
+			t.Fatalf("%q -> %q: script rebuilds %q", a, b, got)
+		}
+		if want := editDistance(a, b); edits != want {
+			t.Fatalf("%q -> %q: %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestDiffLinesLimits(t *testing.T) {
+	var a, b []string
+	for i := range maxDiffEdits {
+		a = append(a, fmt.Sprintf("a%d", i))
+		b = append(b, fmt.Sprintf("b%d", i))
+	}
+	if _, err := diffLines(context.Background(), a, b); !errors.Is(err, errTooManyEdits) {
+		t.Errorf("%d replaced lines: got error %v", len(a), err)
+	}
+	if _, err := diffLines(context.Background(), a[:maxDiffEdits/2], b[:maxDiffEdits/2]); err != nil {
+		t.Errorf("%d edits: %v", maxDiffEdits, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := diffLines(ctx, a, b); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled: got error %v", err)
+	}
+}
+
+func TestDiffFiles(t *testing.T) {
+	files := fakeFiles{files: map[string]string{
+		"/work/a.txt": "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n",
+		"/work/b.txt": "one\ntwo\nthree\nfour\nFIVE\nsix\nseven\neight\nnine\nten\neleven\n",
+		"/work/c.txt": "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n",
+		"/work/d.bin": "\x00\x01",
+	}}
+	ctx := context.Background()
+
+	got, err := diffFiles(ctx, files, "/work/a.txt", "/work/b.txt", "a.txt", "b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `--- a.txt
++++ b.txt
+@@ -2,9 +2,10 @@
+ two
+ three
+ four
+-five
++FIVE
+ six
+ seven
+ eight
+ nine
+ ten
++eleven`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := diffFiles(ctx, files, "/work/a.txt", "/work/c.txt", "a", "c"); !errors.Is(err, errFilesIdentical) {
+		t.Errorf("identical files: got error %v", err)
+	}
+	if _, err := diffFiles(ctx, files, "/work/a.txt", "/work/d.bin", "a", "d"); err == nil {
+		t.Error("compared a binary file")
+	}
+}
@@ -0,0 +1,471 @@
+// ---- File: dircompare.go ----
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Directory Comparison ---
+// 'X' compares the CWD (folder A) with another folder (B): the other browser's
+// in commander mode, otherwise one typed into a prompt. Both trees are walked
+// side by side in the background, like diff -rq: entries only in A, only in B,
+// and files that differ by size or modification time (or, on request, by
+// contents). Folders only on one side are reported once, not descended into.
+// The report overlay fills in as the walk goes; closing it stops the walk.
+
+// maxCompareDiffs is how many findings a comparison keeps; the counts go on.
+const maxCompareDiffs = 5000
+
+// compareChunkSize is how much of each file a contents comparison reads at a time.
+const compareChunkSize = 64 * 1024
+
+// dirDiffKind is how an entry differs between the two folders.
+type dirDiffKind int
+
+const (
+	diffOnlyA dirDiffKind = iota
+	diffOnlyB
+	diffChanged
+)
+
+// label names the kind in the report.
+func (k dirDiffKind) label() string {
+	switch k {
+	case diffOnlyA:
+		return "only in A"
+	case diffOnlyB:
+		return "only in B"
+	}
+	return "differs"
+}
+
+// color is the report's color for the kind.
+func (k dirDiffKind) color() string {
+	switch k {
+	case diffOnlyA:
+		return ansiGreen
+	case diffOnlyB:
+		return ansiCyan
+	}
+	return ansiYellow
+}
+
+// dirDiff is one finding of a comparison.
+type dirDiff struct {
+	Rel    string // Path below both folders
+	Kind   dirDiffKind
+	IsDir  bool   // A folder, in A unless it is only in B
+	Reason string // Why an entry in both differs, e.g. "size 1.2 KiB vs 1.3 KiB"
+}
+
+// dirCompareReport is what a comparison has found so far.
+type dirCompareReport struct {
+	A, B      string
+	ByContent bool // Same-size files compared byte by byte instead of by mtime
+	Running   bool
+	Checked   int       // Entries looked at so far
+	Diffs     []dirDiff // The first maxCompareDiffs findings, in walk order
+	Counts    [3]int    // Findings per kind, beyond maxCompareDiffs too
+	Err       error     // Why the comparison stopped early, if it did
+}
+
+// compareTrees compares folder rel below a with the same folder below b,
+// recursing into folders both have. found gets each finding and checked is
+// called for every entry. A folder below the top that can't be read is a
+// finding; only cancellation and unreadable top folders stop the walk.
+func compareTrees(ctx context.Context, a, b, rel string, byContent bool, found func(dirDiff), checked func()) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	entriesA, errA := readDirEntries(filepath.Join(a, rel))
+	entriesB, errB := readDirEntries(filepath.Join(b, rel))
+	if err := errors.Join(errA, errB); err != nil {
+		if rel == "" {
+			return err
+		}
+		found(dirDiff{Rel: rel, Kind: diffChanged, IsDir: true, Reason: "could not read: " + trimError(err)})
+		return nil
+	}
+
+	names := make([]string, 0, len(entriesA)+len(entriesB))
+	for name := range entriesA {
+		names = append(names, name)
+	}
+	for name := range entriesB {
+		if _, ok := entriesA[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		checked()
+		path := filepath.Join(rel, name)
+		entryA, inA := entriesA[name]
+		entryB, inB := entriesB[name]
+		switch {
+		case !inB:
+			found(dirDiff{Rel: path, Kind: diffOnlyA, IsDir: entryA.IsDir()})
+		case !inA:
+			found(dirDiff{Rel: path, Kind: diffOnlyB, IsDir: entryB.IsDir()})
+		case entryA.IsDir() && entryB.IsDir():
+			if err := compareTrees(ctx, a, b, path, byContent, found, checked); err != nil {
+				return err
+			}
+		default:
+			reason, err := entryDifference(ctx, filepath.Join(a, path), filepath.Join(b, path), entryA, entryB, byContent)
+			if err != nil {
+				return err
+			}
+			if reason != "" {
+				found(dirDiff{Rel: path, Kind: diffChanged, IsDir: entryA.IsDir(), Reason: reason})
+			}
+		}
+	}
+	return nil
+}
+
+// readDirEntries reads dir's entries by name.
+func readDirEntries(dir string) (map[string]fs.DirEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]fs.DirEntry, len(entries))
+	for _, entry := range entries {
+		byName[entry.Name()] = entry
+	}
+	return byName, nil
+}
+
+// entryDifference says how the entries at pathA and pathB differ, or returns
+// "" if they don't. Files of the same size differ by mtime, or by contents if
+// byContent. Only cancellation is an error; a file that can't be read is a
+// difference of its own.
+func entryDifference(ctx context.Context, pathA, pathB string, entryA, entryB fs.DirEntry, byContent bool) (string, error) {
+	typeA, typeB := entryA.Type().Type(), entryB.Type().Type()
+	if typeA != typeB {
+		return fmt.Sprintf("%s in A, %s in B", entryKindName(typeA), entryKindName(typeB)), nil
+	}
+	if typeA&fs.ModeSymlink != 0 {
+		targetA, errA := os.Readlink(pathA)
+		targetB, errB := os.Readlink(pathB)
+		if err := errors.Join(errA, errB); err != nil {
+			return "could not compare: " + trimError(err), nil
+		}
+		if targetA != targetB {
+			return fmt.Sprintf("link to %s vs %s", targetA, targetB), nil
+		}
+		return "", nil
+	}
+	infoA, errA := entryA.Info()
+	infoB, errB := entryB.Info()
+	if err := errors.Join(errA, errB); err != nil {
+		return "could not compare: " + trimError(err), nil
+	}
+	if !infoA.Mode().IsRegular() {
+		return "", nil // Devices, sockets and pipes have nothing to compare
+	}
+	if infoA.Size() != infoB.Size() {
+		return fmt.Sprintf("size %s vs %s", formatSize(infoA.Size()), formatSize(infoB.Size())), nil
+	}
+	if byContent {
+		same, err := sameContent(ctx, pathA, pathB)
+		switch {
+		case ctx.Err() != nil:
+			return "", ctx.Err()
+		case err != nil:
+			return "could not compare: " + trimError(err), nil
+		case !same:
+			return "contents differ", nil
+		}
+		return "", nil
+	}
+	// Copies between filesystems often keep the mtime only to the second
+	modA, modB := infoA.ModTime().Truncate(time.Second), infoB.ModTime().Truncate(time.Second)
+	switch {
+	case modA.After(modB):
+		return "same size, newer in A", nil
+	case modB.After(modA):
+		return "same size, newer in B", nil
+	}
+	return "", nil
+}
+
+// entryKindName names an entry's type for the report.
+func entryKindName(mode fs.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "folder"
+	case mode&fs.ModeSymlink != 0:
+		return "symlink"
+	case mode.IsRegular():
+		return "file"
+	}
+	return "special file"
+}
+
+// sameContent reports whether the files at pathA and pathB (of the same
+// size) have the same bytes, reading both until the first difference.
+func sameContent(ctx context.Context, pathA, pathB string) (bool, error) {
+	fileA, err := os.Open(pathA)
+	if err != nil {
+		return false, err
+	}
+	defer fileA.Close()
+	fileB, err := os.Open(pathB)
+	if err != nil {
+		return false, err
+	}
+	defer fileB.Close()
+
+	bufA := make([]byte, compareChunkSize)
+	bufB := make([]byte, compareChunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		nA, errA := io.ReadFull(fileA, bufA)
+		nB, errB := io.ReadFull(fileB, bufB)
+		if !bytes.Equal(bufA[:nA], bufB[:nB]) {
+			return false, nil
+		}
+		endA := errors.Is(errA, io.EOF) || errors.Is(errA, io.ErrUnexpectedEOF)
+		endB := errors.Is(errB, io.EOF) || errors.Is(errB, io.ErrUnexpectedEOF)
+		switch {
+		case errA != nil && !endA:
+			return false, errA
+		case errB != nil && !endB:
+			return false, errB
+		case endA || endB:
+			return endA && endB, nil
+		}
+	}
+}
+
+// startDirCompare compares folder a with folder b in the background and
+// opens the report, which fills in as the walk goes.
+func startDirCompare(g *gocui.Gui, state *AppState, a, b string, byContent bool, prevFocus string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	gen := state.StartDirCompare(a, b, byContent, cancel, prevFocus)
+	go func() {
+		defer cancel()
+		var lastPublish time.Time
+		publish := func() {
+			if time.Since(lastPublish) >= dirLoadPublishInterval {
+				lastPublish = time.Now()
+				g.Update(func(gui *gocui.Gui) error { return nil })
+			}
+		}
+		found := func(diff dirDiff) {
+			state.AddDirCompareDiff(gen, diff)
+			publish()
+		}
+		checked := func() {
+			state.CountDirCompareEntry(gen)
+			publish()
+		}
+		err := compareTrees(ctx, a, b, "", byContent, found, checked)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("Error: comparing %s with %s: %v", a, b, err)
+		}
+		state.FinishDirCompare(gen, err)
+		g.Update(func(gui *gocui.Gui) error { return nil })
+	}()
+	g.Update(func(gui *gocui.Gui) error { return nil })
+}
+
+// resolveFolderInput turns a folder typed into a prompt into an absolute
+// path, checking that it is a folder.
+func resolveFolderInput(input, cwd string) (string, error) {
+	path, err := expandInputPath(input, cwd)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a folder", path)
+	}
+	return path, nil
+}
+
+// handleCompareDirs compares the CWD with the other browser's folder in
+// commander mode, or with a folder asked for in a prompt.
+func handleCompareDirs(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	a := state.Cwd()
+	if other, _ := tabs.Commander(); other != nil {
+		if b := other.Cwd(); b != a {
+			startDirCompare(g, state, a, b, false, v.Name())
+			return nil
+		}
+	}
+	home, _ := os.UserHomeDir()
+	state.OpenPrompt(promptSpec{
+		Kind:  "compare",
+		Title: fmt.Sprintf(" Compare %s with folder ", shortenPath(a, home, 30)),
+		Validate: func(input string) error {
+			b, err := resolveFolderInput(input, a)
+			if err == nil && b == a {
+				err = errors.New("that is the folder being compared")
+			}
+			return err
+		},
+		OnSubmit: func(gui *gocui.Gui, input string, state *AppState) error {
+			b, _ := resolveFolderInput(input, a) // Checked by Validate
+			startDirCompare(gui, state, a, b, false, v.Name())
+			return nil
+		},
+	}, v.Name())
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleDirCompareNavigate moves the selection in the comparison report.
+func handleDirCompareNavigate(g *gocui.Gui, v *gocui.View, delta int, state *AppState) error {
+	_, viewHeight := contentSize(v)
+	state.NavigateDirCompare(delta, viewHeight)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleDirCompareContents compares the same two folders again, by contents
+// instead of mtime for files of the same size.
+func handleDirCompareContents(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	report := state.DirCompare()
+	if report.ByContent {
+		state.SetMessage("The report already compares file contents")
+		g.Update(func(gui *gocui.Gui) error { return nil })
+		return nil
+	}
+	prevFocus := state.GetDirComparePrevFocus()
+	state.CloseDirCompare() // Stops the running walk, if any
+	startDirCompare(g, state, report.A, report.B, true, prevFocus)
+	return nil
+}
+
+// handleDirCompareSelect closes the report and selects the chosen entry in
+// the folder it is in: in B if it is only there, else in A.
+func handleDirCompareSelect(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	report := state.DirCompare()
+	idx, _ := state.DirComparePosition()
+	if idx < 0 || idx >= len(report.Diffs) {
+		return handleCloseDirCompare(g, v, state)
+	}
+	diff := report.Diffs[idx]
+	root := report.A
+	if diff.Kind == diffOnlyB {
+		root = report.B
+	}
+	path := filepath.Join(root, diff.Rel)
+
+	state.CloseDirCompare()
+	restoreFocus(g, state.GetDirComparePrevFocus(), "comparison report")
+	selectEntry := func(gui *gocui.Gui) {
+		if selectPath(gui, state, path) {
+			state.SetMessage(fmt.Sprintf("%s: %s", diff.Kind.label(), diff.Rel))
+		} else {
+			state.SetMessage(fmt.Sprintf("'%s' is not in the listing", filepath.Base(path)))
+		}
+	}
+	if dir := filepath.Dir(path); dir != state.Cwd() {
+		changeDirectory(g, state, dir, selectEntry)
+		return nil
+	}
+	selectEntry(g)
+	return nil
+}
+
+// handleCloseDirCompare closes the comparison report, stopping the walk if it
+// is still running, and restores focus.
+func handleCloseDirCompare(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if state.DirCompare().Running {
+		state.SetMessage("Comparison cancelled")
+	}
+	state.CloseDirCompare()
+	restoreFocus(g, state.GetDirComparePrevFocus(), "comparison report")
+	return nil
+}
+
+// updateDirCompareView lists the comparison's findings, each with its
+// category in its color, and the counts and folders in the title.
+func updateDirCompareView(g *gocui.Gui, state *AppState) {
+	v, err := g.View(viewDirCompare)
+	if err != nil {
+		return
+	}
+	v.Clear()
+
+	report := state.DirCompare()
+	width, height := contentSize(v)
+	home, _ := os.UserHomeDir()
+	counts := fmt.Sprintf("%d only in A, %d only in B, %d differ", report.Counts[diffOnlyA], report.Counts[diffOnlyB], report.Counts[diffChanged])
+	switch {
+	case report.Running:
+		counts = fmt.Sprintf("Comparing... %s entries, %s", formatCount(report.Checked), counts)
+	case errors.Is(report.Err, context.Canceled):
+		counts += " (cancelled)"
+	case report.Err != nil:
+		counts += " (stopped: " + trimError(report.Err) + ")"
+	}
+	// Commander mode starts by mtime without asking; say how to compare contents
+	if report.ByContent {
+		counts += ", by contents"
+	} else if key, ok := primaryKey(keyTable, "Directory Comparison", "compare.contents"); ok {
+		counts += fmt.Sprintf(", by mtime (%s: by contents)", key)
+	}
+	folders := fmt.Sprintf("A: %s  B: %s", shortenPath(report.A, home, max(width/3, 10)), shortenPath(report.B, home, max(width/3, 10)))
+	v.Title = fmt.Sprintf(" %s | %s ", counts, folders)
+	if total := report.Counts[0] + report.Counts[1] + report.Counts[2]; len(report.Diffs) < total {
+		v.Title += fmt.Sprintf("(showing %d) ", len(report.Diffs))
+	}
+	if len(report.Diffs) == 0 {
+		if report.Running {
+			fmt.Fprint(v, " (No differences so far)")
+		} else if report.Err == nil {
+			fmt.Fprint(v, " (The folders have the same entries)")
+		}
+		return
+	}
+
+	selectedIdx, originY := state.DirComparePosition()
+	if selectedIdx >= originY+height { // The view shrank since the selection moved
+		originY = selectedIdx - height + 1
+	}
+	_ = v.SetOrigin(0, originY)
+	const labelWidth = 9 // "only in A"
+	for i, diff := range report.Diffs {
+		path := diff.Rel
+		if diff.IsDir {
+			path += string(filepath.Separator)
+		}
+		line := path
+		if diff.Reason != "" {
+			line += "  (" + diff.Reason + ")"
+		}
+		line = truncateWidth(line, max(width-labelWidth-3, 10))
+		if i == selectedIdx {
+			fmt.Fprintf(v, "%s %-*s %s %s\n", ansiReverse, labelWidth, diff.Kind.label(), toCells(line), ansiReset)
+		} else {
+			fmt.Fprintf(v, " %s%-*s%s %s\n", diff.Kind.color(), labelWidth, diff.Kind.label(), ansiReset, toCells(line))
+		}
+	}
+	drawScrollbar(g, viewDirCompare, originY, len(report.Diffs), false)
+}
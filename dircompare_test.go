@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareTrees(t *testing.T) {
+	a, b := t.TempDir(), t.TempDir()
+	writeTree(t, a, map[string]string{
+		"only-a.txt":      "a",
+		"only-a-dir/x":    "x",
+		"size.txt":        "abc",
+		"newer.txt":       "abc",
+		"contents.txt":    "abc",
+		"same.txt":        "abc",
+		"sub/deep.txt":    "abc",
+		"sub/same.txt":    "abc",
+		"kind/":           "",
+		"empty-both-dir/": "",
+	})
+	writeTree(t, b, map[string]string{
+		"only-b.txt":      "b",
+		"size.txt":        "abcd",
+		"newer.txt":       "abc",
+		"contents.txt":    "abd",
+		"same.txt":        "abc",
+		"sub/deep.txt":    "abcdef",
+		"sub/same.txt":    "abc",
+		"kind":            "a file in B",
+		"empty-both-dir/": "",
+	})
+	// Same mtime everywhere but newer.txt, which is newer in A
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, root := range []string{a, b} {
+		for _, name := range []string{"size.txt", "newer.txt", "contents.txt", "same.txt", "sub/deep.txt", "sub/same.txt"} {
+			if err := os.Chtimes(filepath.Join(root, filepath.FromSlash(name)), base, base); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := os.Chtimes(filepath.Join(a, "newer.txt"), base, base.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	compare := func(ctx context.Context, byContent bool) ([]string, int, error) {
+		var found []string
+		checked := 0
+		err := compareTrees(ctx, a, b, "", byContent, func(d dirDiff) {
+			found = append(found, filepath.ToSlash(d.Rel)+": "+d.Kind.label()+" "+d.Reason)
+		}, func() { checked++ })
+		return found, checked, err
+	}
+
+	found, checked, err := compare(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"kind: differs folder in A, file in B",
+		"newer.txt: differs same size, newer in A",
+		"only-a-dir: only in A ",
+		"only-a.txt: only in A ",
+		"only-b.txt: only in B ",
+		"size.txt: differs size " + formatSize(3) + " vs " + formatSize(4),
+		"sub/deep.txt: differs size " + formatSize(3) + " vs " + formatSize(6),
+	}
+	if !slices.Equal(found, want) {
+		t.Errorf("by mtime found:\n%s\nwant:\n%s", strings.Join(found, "\n"), strings.Join(want, "\n"))
+	}
+	if checked != 12 { // 10 names at the top, 2 in sub
+		t.Errorf("checked %d entries, want 12", checked)
+	}
+
+	// By contents, same-size files are read instead of compared by mtime
+	found, _, err = compare(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(found, "contents.txt: differs contents differ") || slices.ContainsFunc(found, func(s string) bool { return strings.HasPrefix(s, "newer.txt") }) {
+		t.Errorf("by contents found:\n%s", strings.Join(found, "\n"))
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := compare(cancelled, false); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled comparison: got error %v", err)
+	}
+
+	if err := compareTrees(context.Background(), a, filepath.Join(b, "missing"), "", false, func(dirDiff) {}, func() {}); err == nil {
+		t.Error("no error for a missing top folder")
+	}
+}
+
+func TestSameContent(t *testing.T) {
+	dir := t.TempDir()
+	long := strings.Repeat("x", compareChunkSize+10)
+	writeTree(t, dir, map[string]string{
+		"a":          "hello",
+		"a-copy":     "hello",
+		"a-changed":  "hellp",
+		"long":       long,
+		"long-copy":  long,
+		"long-late":  long[:compareChunkSize+5] + "y" + long[compareChunkSize+6:],
+		"empty":      "",
+		"empty-copy": "",
+	})
+	path := func(name string) string { return filepath.Join(dir, name) }
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"a", "a-copy", true},
+		{"a", "a-changed", false},
+		{"long", "long-copy", true},
+		{"long", "long-late", false}, // Differs in the second chunk
+		{"empty", "empty-copy", true},
+	}
+	for _, tt := range tests {
+		same, err := sameContent(context.Background(), path(tt.a), path(tt.b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if same != tt.want {
+			t.Errorf("%s vs %s: same %v, want %v", tt.a, tt.b, same, tt.want)
+		}
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := sameContent(cancelled, path("long"), path("long-copy")); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled: got error %v", err)
+	}
+	if _, err := sameContent(context.Background(), path("a"), path("missing")); err == nil {
+		t.Error("no error for a missing file")
+	}
+}
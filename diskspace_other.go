@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import "errors"
+
+// filesystemSpace is not supported on this platform; the Size pane omits the disk line.
+func filesystemSpace(path string) (free, total uint64, err error) {
+	return 0, 0, errors.New("filesystem space not supported on this platform")
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin
+
+package main
+
+import "syscall"
+
+// filesystemSpace reports the space available to unprivileged users and the
+// total size of the filesystem containing path.
+func filesystemSpace(path string) (free, total uint64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	blockSize := uint64(st.Bsize)
+	return st.Bavail * blockSize, st.Blocks * blockSize, nil
+}
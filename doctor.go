@@ -0,0 +1,125 @@
+// ---- File: doctor.go ----
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/atotto/clipboard"
+)
+
+// --- Startup Diagnostics (--doctor) ---
+
+// diagnostic is the result of one environment check.
+type diagnostic struct {
+	Name     string
+	OK       bool
+	Detail   string // What was found, e.g. the path of git
+	Degrades string // The features that suffer when the check fails, e.g. "git status"
+	Hint     string // How to fix a failed check
+}
+
+// runDiagnostics checks the external tools and terminal features lazyls
+// relies on. None of them is required; each failed one degrades a feature.
+func runDiagnostics() []diagnostic {
+	return []diagnostic{checkGit(), checkClipboard(), checkColors(), checkLocale()}
+}
+
+func checkGit() diagnostic {
+	d := diagnostic{Name: "git", Degrades: "git status and repository features", Hint: "install git and make sure it is on PATH"}
+	path, err := exec.LookPath("git")
+	if err != nil {
+		d.Detail = "not found on PATH"
+		return d
+	}
+	d.OK, d.Detail = true, path
+	return d
+}
+
+// clipboardHint is only shown where atotto/clipboard needs a helper program,
+// i.e. on Linux and the BSDs.
+const clipboardHint = "install xclip or xsel (X11) or wl-clipboard (Wayland)"
+
+func checkClipboard() diagnostic {
+	d := diagnostic{Name: "clipboard", Degrades: "copying and pasting paths, copying content", Hint: clipboardHint}
+	if clipboard.Unsupported {
+		d.Detail = "no clipboard utility found"
+		return d
+	}
+	d.OK, d.Detail = true, "available"
+	return d
+}
+
+// degradedSummary names the features the failed checks degrade, e.g. "git
+// status and repository features, copying and pasting paths, copying
+// content", or "" if all checks passed.
+func degradedSummary(results []diagnostic) string {
+	var degraded []string
+	for _, d := range results {
+		if !d.OK {
+			degraded = append(degraded, d.Degrades)
+		}
+	}
+	return strings.Join(degraded, ", ")
+}
+
+// showDegradedWarning puts the warning about the features summary names in
+// the message bar and records it as shown, so later sessions don't repeat
+// it. A bar already holding a message (a config error, say) is left alone
+// and the warning comes back next time; it reports whether the warning is
+// now recorded.
+func showDegradedWarning(state *AppState, summary string) bool {
+	if summary != "" {
+		if state.GetLastMessage() != "" {
+			return false
+		}
+		state.SetMessage(fmt.Sprintf("Not available: %s (see lazyls --doctor)", summary))
+	}
+	state.SetDegradedWarning(summary)
+	return true
+}
+
+// logDiagnostics writes the check results to the log.
+func logDiagnostics(results []diagnostic) {
+	for _, d := range results {
+		status := "ok"
+		if !d.OK {
+			status = "FAILED"
+		}
+		log.Printf("Check %s: %s (%s)", d.Name, status, d.Detail)
+	}
+}
+
+// runDoctor prints a report of the checks for --doctor and returns the exit
+// status: 1 if any check failed.
+func runDoctor(stdout io.Writer) int {
+	results := runDiagnostics()
+	fmt.Fprintln(stdout, versionString())
+	fmt.Fprintln(stdout)
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	status := 0
+	for _, d := range results {
+		mark := "ok"
+		if !d.OK {
+			mark = "FAIL"
+			status = 1
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", mark, d.Name, d.Detail)
+	}
+	tw.Flush()
+	for _, d := range results {
+		if !d.OK {
+			fmt.Fprintf(stdout, "\n%s: %s won't work; %s.", d.Name, d.Degrades, d.Hint)
+		}
+	}
+	if status != 0 {
+		fmt.Fprintln(stdout)
+	}
+	// A Nerd Font can't be detected from inside the terminal
+	fmt.Fprintln(stdout, "\nIcons need a Nerd Font. If they show as boxes or question marks, set \"icon_set\": \"plain\" in config.json or run with --ascii.")
+	return status
+}
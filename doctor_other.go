@@ -0,0 +1,46 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// checkColors looks at $TERM, which tells termbox how to draw. Image
+// previews use 256 colors, which basic terminal types may not have.
+func checkColors() diagnostic {
+	d := diagnostic{Name: "colors", Degrades: "colors and highlighting", Hint: "set TERM to your terminal's type, e.g. xterm-256color"}
+	term := os.Getenv("TERM")
+	switch {
+	case term == "" || term == "dumb":
+		d.Detail = fmt.Sprintf("TERM=%q can't draw the UI", term)
+	case strings.Contains(term, "256color") || os.Getenv("COLORTERM") != "":
+		d.OK, d.Detail = true, "256 colors (TERM="+term+")"
+	default:
+		d.OK, d.Detail = true, "basic colors (TERM="+term+"); image previews need 256"
+	}
+	return d
+}
+
+// checkLocale looks for a UTF-8 locale, the first of $LC_ALL, $LC_CTYPE and
+// $LANG that is set, which icons and box drawing need.
+func checkLocale() diagnostic {
+	d := diagnostic{Name: "locale", Degrades: "icons and box drawing", Hint: "set LANG to a UTF-8 locale such as en_US.UTF-8, or run with --ascii"}
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			d.Detail = name + "=" + value
+			upper := strings.ToUpper(value)
+			d.OK = strings.Contains(upper, "UTF-8") || strings.Contains(upper, "UTF8")
+			break
+		}
+	}
+	if d.Detail == "" {
+		d.Detail = "no locale set"
+	}
+	if !d.OK && asciiMode {
+		d.OK, d.Detail = true, d.Detail+" (drawing in ASCII)"
+	}
+	return d
+}
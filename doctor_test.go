@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShowDegradedWarning(t *testing.T) {
+	t.Run("shown and recorded", func(t *testing.T) {
+		state := NewAppState(t.TempDir())
+		if !showDegradedWarning(state, "copying content") {
+			t.Fatal("warning not recorded with an empty message bar")
+		}
+		if msg := state.GetLastMessage(); !strings.Contains(msg, "copying content") {
+			t.Errorf("message bar holds %q", msg)
+		}
+		if got := state.Preferences().DegradedWarning; got != "copying content" {
+			t.Errorf("recorded %q", got)
+		}
+	})
+
+	t.Run("message bar taken", func(t *testing.T) {
+		state := NewAppState(t.TempDir())
+		state.SetMessage("Config error: bad JSON")
+		if showDegradedWarning(state, "copying content") {
+			t.Error("warning recorded without reaching the message bar")
+		}
+		if msg := state.GetLastMessage(); msg != "Config error: bad JSON" {
+			t.Errorf("message bar holds %q", msg)
+		}
+		if got := state.Preferences().DegradedWarning; got != "" {
+			t.Errorf("recorded %q", got)
+		}
+	})
+
+	t.Run("everything available again", func(t *testing.T) {
+		state := NewAppState(t.TempDir())
+		state.SetDegradedWarning("copying content")
+		state.SetMessage("Config error: bad JSON")
+		if !showDegradedWarning(state, "") {
+			t.Error("cleared warning not recorded")
+		}
+		if got := state.Preferences().DegradedWarning; got != "" {
+			t.Errorf("recorded %q", got)
+		}
+	})
+}
@@ -0,0 +1,13 @@
+package main
+
+// checkColors always passes on Windows: the console API draws colors without
+// a terminal type.
+func checkColors() diagnostic {
+	return diagnostic{Name: "colors", OK: true, Detail: "Windows console"}
+}
+
+// checkLocale always passes on Windows: the console API takes Unicode text
+// regardless of the code page.
+func checkLocale() diagnostic {
+	return diagnostic{Name: "locale", OK: true, Detail: "Windows console (Unicode)"}
+}
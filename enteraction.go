@@ -0,0 +1,126 @@
+// ---- File: enteraction.go ----
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Enter Action ---
+
+// What Enter does on a file ("enter_action" in config.json). Folders always
+// get the action menu, which `a` opens for files too.
+const (
+	enterActionMenu = "menu" // Open the action menu (default)
+	enterActionView = "view" // Open the file in the content viewer
+	enterActionOpen = "open" // Open the file with the system's default application
+)
+
+// enterAction is the configured behavior of Enter on files.
+var enterAction = enterActionMenu
+
+// configureEnterAction applies the "enter_action" setting.
+func configureEnterAction(cfg config) {
+	enterAction = enterActionMenu
+	switch cfg.EnterAction {
+	case "", enterActionMenu:
+	case enterActionView, enterActionOpen:
+		enterAction = cfg.EnterAction
+	default:
+		log.Printf("Warning: Unknown enter_action %q, using %q", cfg.EnterAction, enterActionMenu)
+	}
+}
+
+// enterBinding returns the action ID and help text of Enter in the lists.
+func enterBinding() (action, desc string) {
+	switch enterAction {
+	case enterActionView:
+		return "list.enter", "View the file (folders: open the action menu)"
+	case enterActionOpen:
+		return "list.enter", "Open the file with its default application (folders: open the action menu)"
+	}
+	return "list.actions", "Open the action menu"
+}
+
+// handleEnterKey runs the configured Enter action on the selected file, or
+// opens the action menu for folders, unreadable files and the default setting.
+func handleEnterKey(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || enterAction == enterActionMenu {
+		return handleEnter(g, v, state)
+	}
+	item, ok := state.SelectedItem(v.Name())
+	if !ok {
+		return nil
+	}
+	item = withEntryInfo(item)
+	if !isReadableFile(item, state) {
+		return handleEnter(g, v, state)
+	}
+
+	label, run := "View Content", viewFileContentAction
+	if enterAction == enterActionOpen {
+		label, run = "Open", openWithSystemAction
+	}
+	state.SetPreviousFocusView(v.Name()) // The viewer returns focus here
+	if err := run(g, item, state); err != nil {
+		log.Printf("Enter action '%s' failed for %s: %v", label, item.Name, err)
+		state.SetMessage(trimError(fmt.Errorf("Error: %s - %v", label, err)))
+		if isVanishedError(err) {
+			reloadDirectory(g, state, func(gui *gocui.Gui) {
+				state.SetMessage(fmt.Sprintf("'%s' no longer exists", item.Name))
+			})
+		}
+	}
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// openWithSystemAction opens item with the default application (xdg-open,
+// open or the Windows file association) without waiting for it.
+func openWithSystemAction(g *gocui.Gui, item FileInfo, state *AppState) error {
+	cmd := systemOpener(item.Path)
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("%s is not installed", cmd.Args[0])
+		}
+		return err
+	}
+	go func() {
+		// Reap the opener; it usually exits as soon as it handed the file over
+		if err := cmd.Wait(); err != nil {
+			log.Printf("Opener for %s failed: %v", item.Path, err)
+			state.SetMessage(fmt.Sprintf("Could not open '%s': %s", item.Name, trimError(err)))
+			g.Update(func(gui *gocui.Gui) error { return nil })
+		}
+	}()
+	state.SetMessage(fmt.Sprintf("Opened '%s'", item.Name))
+	return nil
+}
+
+// revealInFileManagerAction shows item in the desktop's file manager
+// (Nautilus, Finder, Explorer, ...) without waiting for it.
+func revealInFileManagerAction(g *gocui.Gui, item FileInfo, state *AppState) error {
+	cmd, err := systemRevealer(item.Path, item.IsDir)
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("%s is not installed", cmd.Args[0])
+		}
+		return err
+	}
+	go func() {
+		if err := cmd.Wait(); err != nil && revealExitStatusReliable {
+			log.Printf("Revealing %s failed: %v", item.Path, err)
+			state.SetMessage(fmt.Sprintf("Could not reveal '%s': %s", item.Name, trimError(err)))
+			g.Update(func(gui *gocui.Gui) error { return nil })
+		}
+	}()
+	state.SetMessage(fmt.Sprintf("Revealed '%s' in the file manager", item.Name))
+	return nil
+}
@@ -0,0 +1,90 @@
+// ---- File: entrycounts.go ----
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Folder Entry Counts ---
+
+// showEntryCounts controls the "(42)" after folder names. Counting reads every
+// folder on screen, which can be slow on network filesystems.
+var showEntryCounts = true
+
+// configureEntryCounts applies the "hide_entry_counts" setting.
+func configureEntryCounts(cfg config) {
+	showEntryCounts = !cfg.HideEntryCounts
+}
+
+// scheduleEntryCounts counts, in the background, the entries of the folders
+// among rows that haven't been counted yet. Each folder is read once per
+// mtime; scrolling to other folders supersedes the previous request.
+func scheduleEntryCounts(g *gocui.Gui, state *AppState, rows []FileInfo) {
+	var dirs []FileInfo
+	for _, item := range rows {
+		if item.IsDir && !item.Unreadable {
+			dirs = append(dirs, item)
+		}
+	}
+	gen, todo := state.RequestEntryCounts(dirs)
+	if len(todo) == 0 {
+		return
+	}
+	go func() {
+		for _, dir := range todo {
+			if !state.IsEntryCountGen(gen) {
+				return
+			}
+			count, err := countEntries(dir.Path)
+			state.SetEntryCount(dir.Path, dir.ModTime, count)
+			if errors.Is(err, fs.ErrPermission) {
+				state.SetDirUnreadable(dir.Path)
+			}
+			g.Update(func(gui *gocui.Gui) error { return nil })
+		}
+	}()
+}
+
+// countEntries returns the number of entries in dir, or -2 and the error if
+// it can't be read.
+func countEntries(dir string) (int, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return -2, err
+	}
+	defer f.Close()
+	count := 0
+	for {
+		names, err := f.Readdirnames(1024)
+		count += len(names)
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return -2, err
+		}
+	}
+}
+
+// entryCountLabel returns the " (42)" shown after a folder's name: " (?)" if
+// it couldn't be read, "" while it is being counted, for files, and for
+// folders already marked unreadable.
+func entryCountLabel(state *AppState, item FileInfo) string {
+	if !showEntryCounts || !item.IsDir || item.Unreadable {
+		return ""
+	}
+	count, ok := state.EntryCount(item)
+	switch {
+	case !ok:
+		return ""
+	case count < 0:
+		return " (?)"
+	}
+	return fmt.Sprintf(" (%s)", formatCount(count))
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package main
+
+// isExecutable reports whether item is a regular file with any executable bit set.
+func isExecutable(item FileInfo) bool {
+	return item.Mode.IsRegular() && item.Mode&0o111 != 0
+}
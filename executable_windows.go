@@ -0,0 +1,15 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// executableExts are the extensions Windows runs directly.
+var executableExts = map[string]bool{".exe": true, ".bat": true, ".cmd": true, ".ps1": true}
+
+// isExecutable reports whether item is a runnable file. Windows has no
+// executable bit, so this goes by extension.
+func isExecutable(item FileInfo) bool {
+	return !item.IsDir && executableExts[strings.ToLower(filepath.Ext(item.Name))]
+}
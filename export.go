@@ -0,0 +1,242 @@
+// ---- File: export.go ----
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Report Export ---
+// 'W' writes what the panes show about the CWD to a file: the listing (in the
+// current hidden mode, with folder sizes where known), the Size pane's totals,
+// the largest files, the extension breakdown and the git status. Nothing is
+// walked again; the report is made from the last stats run. A path ending in
+// .json gets the --stats JSON schema plus the entries of --list, anything else
+// Markdown.
+
+// defaultReportName is the file the export prompt suggests, in the CWD.
+const defaultReportName = "lazyls-report.md"
+
+// exportReport is a report written by 'W'.
+type exportReport struct {
+	statsReport
+	Generated string      `json:"generated"` // RFC 3339
+	Entries   []listEntry `json:"entries"`   // As listed, folders first; folder sizes are recursive
+}
+
+// newExportReport combines the stats of dir with its listing.
+func newExportReport(stats statsReport, entries []listEntry, generated time.Time) exportReport {
+	if entries == nil {
+		entries = []listEntry{}
+	}
+	return exportReport{statsReport: stats, Generated: generated.Format(time.RFC3339), Entries: entries}
+}
+
+// writeExportReport writes report as JSON or, unless format is "json", Markdown.
+func writeExportReport(w io.Writer, report exportReport, format string) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return writeExportMarkdown(w, report)
+}
+
+// writeExportMarkdown writes report as a Markdown document: a summary table,
+// then the listing, the largest files and the extension breakdown.
+func writeExportMarkdown(w io.Writer, report exportReport) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\nReport generated by lazyls on %s.\n\n", markdownText(report.Path), report.Generated)
+
+	b.WriteString("## Summary\n\n| | |\n| --- | --- |\n")
+	if report.TotalSize != nil {
+		fmt.Fprintf(&b, "| Total size | %s (%d bytes) |\n", formatSize(*report.TotalSize), *report.TotalSize)
+	} else if report.Error != nil {
+		fmt.Fprintf(&b, "| Total size | unknown (%s) |\n", markdownText(*report.Error))
+	} else {
+		b.WriteString("| Total size | unknown |\n")
+	}
+	if report.DiskSize != nil {
+		fmt.Fprintf(&b, "| On disk | %s (%d bytes) |\n", formatSize(*report.DiskSize), *report.DiskSize)
+	}
+	fmt.Fprintf(&b, "| Files | %d |\n| Folders | %d |\n", report.Files, report.Dirs)
+	fmt.Fprintf(&b, "| Git | %s |\n", markdownText(report.GitStatus))
+	if report.LastCommit != nil {
+		fmt.Fprintf(&b, "| Last commit | `%s` %s %s |\n", report.LastCommit.Hash, report.LastCommit.Time, markdownText(report.LastCommit.Subject))
+	}
+
+	fmt.Fprintf(&b, "\n## Listing\n\n")
+	if len(report.Entries) == 0 {
+		b.WriteString("(empty)\n")
+	} else {
+		b.WriteString("| Name | Type | Size | Modified |\n| --- | --- | ---: | --- |\n")
+		for _, entry := range report.Entries {
+			name := entry.Name
+			if entry.Type == "dir" {
+				name += "/"
+			}
+			if entry.Hidden {
+				name += " (hidden)"
+			}
+			size, modTime := "", ""
+			if entry.Size != nil {
+				size = formatSize(*entry.Size)
+			}
+			if entry.ModTime != nil {
+				modTime = *entry.ModTime
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", markdownText(name), entry.Type, size, modTime)
+		}
+	}
+
+	if len(report.LargestFiles) > 0 {
+		b.WriteString("\n## Largest Files\n\n| Size | Path |\n| ---: | --- |\n")
+		for _, file := range report.LargestFiles {
+			name := file.Path
+			if rel, err := filepath.Rel(report.Path, file.Path); err == nil {
+				name = rel
+			}
+			fmt.Fprintf(&b, "| %s | %s |\n", formatSize(file.Size), markdownText(name))
+		}
+	}
+	if len(report.Extensions) > 0 {
+		b.WriteString("\n## By Extension\n\n| Extension | Files | Size |\n| --- | ---: | ---: |\n")
+		for _, ext := range report.Extensions {
+			fmt.Fprintf(&b, "| %s | %d | %s |\n", markdownText(ext.Ext), ext.Count, formatSize(ext.Bytes))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownText escapes s for a Markdown table cell or heading.
+func markdownText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`", "\n", " ").Replace(s)
+}
+
+// exportFormat is the format a report is written in at path.
+func exportFormat(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return "json"
+	}
+	return "markdown"
+}
+
+// exportEntry describes a listed item for the report, keeping a folder's
+// recursive size when the Folders pane has it.
+func exportEntry(item FileInfo, hidden bool) listEntry {
+	entry := newListEntry(item, hidden)
+	if item.IsDir && item.Size >= 0 {
+		size := item.Size
+		entry.Size = &size
+	}
+	return entry
+}
+
+// handleExportReport asks where to write the report of the CWD, suggesting
+// defaultReportName there, and confirms before overwriting a file.
+func handleExportReport(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	if state.IsLoadingStats() {
+		state.SetMessage("The size scan is still running; export once it is done")
+		g.Update(func(gui *gocui.Gui) error { return nil })
+		return nil
+	}
+	cwd := state.Cwd()
+	state.OpenPrompt(promptSpec{
+		Kind:    "export",
+		Title:   " Export report to (.md or .json) ",
+		Initial: "." + string(filepath.Separator) + defaultReportName,
+		Validate: func(input string) error {
+			path, err := expandInputPath(input, cwd)
+			if err != nil {
+				return err
+			}
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				return fmt.Errorf("%s is a folder", path)
+			}
+			return nil
+		},
+		OnSubmit: func(gui *gocui.Gui, input string, state *AppState) error {
+			path, _ := expandInputPath(input, cwd) // Checked by Validate
+			if _, err := os.Lstat(path); err == nil {
+				state.OpenConfirm(confirmSpec{
+					Title:   " Export Report ",
+					Message: fmt.Sprintf("'%s' already exists. Overwrite it?", filepath.Base(path)),
+					Details: []string{path},
+					OnConfirm: func(gui *gocui.Gui, state *AppState) error {
+						exportReportTo(gui, state, path)
+						return nil
+					},
+				}, state.GetPromptPrevFocus())
+				return nil
+			}
+			exportReportTo(gui, state, path)
+			return nil
+		},
+	}, v.Name())
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// exportReportTo writes the report of the CWD to path in the background (the
+// listing's files may still need a stat each) and confirms in the message bar.
+func exportReportTo(g *gocui.Gui, state *AppState, path string) {
+	dir := state.Cwd()
+	totalSize, _, gitStatus, statsErr := state.Stats()
+	files, dirs := state.EntryCounts()
+	diskSize, ok := state.DiskUsage()
+	if !ok {
+		diskSize = -1
+	}
+	commit, _ := state.LastCommit()
+	stats := newStatsReport(dir, statsSnapshot{
+		totalSize: totalSize,
+		diskSize:  diskSize,
+		topFiles:  state.TopFiles(),
+		extStats:  state.ExtStats(),
+		fileCount: files,
+		dirCount:  dirs,
+		err:       statsErr,
+	}, gitStatus, commit)
+	items, _ := state.ListWindow(viewCombined, 0, math.MaxInt)
+	hiddenOnly := state.HiddenMode() == hiddenModeOnly
+
+	go func() {
+		entries := make([]listEntry, 0, len(items))
+		for _, item := range items {
+			entries = append(entries, exportEntry(item, item.Hidden || hiddenOnly))
+		}
+		var buf bytes.Buffer
+		err := writeExportReport(&buf, newExportReport(stats, entries, time.Now()), exportFormat(path))
+		if err == nil {
+			err = os.WriteFile(path, buf.Bytes(), 0o644)
+		}
+		g.Update(func(gui *gocui.Gui) error {
+			if err != nil {
+				log.Printf("Error: exporting report to %s: %v", path, err)
+				state.SetMessage(fmt.Sprintf("Export failed: %s", trimError(err)))
+				return nil
+			}
+			state.SetMessage(fmt.Sprintf("Report written to %s", path))
+			if filepath.Dir(path) == state.Cwd() {
+				reloadDirectory(gui, state, func(gui *gocui.Gui) {
+					state.SetMessage(fmt.Sprintf("Report written to %s", path)) // The reload cleared it
+				})
+			}
+			return nil
+		})
+	}()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// testExportReport is a report covering every section, with names that need
+// escaping in Markdown.
+func testExportReport() exportReport {
+	total, disk := int64(3_500_000), int64(3_600_384)
+	dirSize, fileSize, linkSize := int64(3_000_000), int64(500_000), int64(0)
+	modTime := "2026-01-02T03:04:05Z"
+	stats := statsReport{
+		Path:      "/home/me/project",
+		TotalSize: &total,
+		DiskSize:  &disk,
+		Files:     12,
+		Dirs:      3,
+		LargestFiles: []statsReportFile{
+			{Path: "/home/me/project/src/big_data.bin", Size: 2_000_000},
+			{Path: "/home/me/project/notes|draft.md", Size: 500_000},
+		},
+		Extensions: []statsReportExt{
+			{Ext: ".bin", Count: 1, Bytes: 2_000_000},
+			{Ext: "(none)", Count: 4, Bytes: 1_000},
+		},
+		GitStatus:  "Active: (main)",
+		LastCommit: &statsReportRev{Hash: "abc1234", Time: "2026-01-01T00:00:00Z", Subject: "Fix *all* the things"},
+	}
+	entries := []listEntry{
+		{Name: "src", Path: "/home/me/project/src", Type: "dir", Size: &dirSize, ModTime: &modTime},
+		{Name: "empty", Path: "/home/me/project/empty", Type: "dir"},
+		{Name: "notes|draft.md", Path: "/home/me/project/notes|draft.md", Type: "file", Size: &fileSize, ModTime: &modTime},
+		{Name: "latest", Path: "/home/me/project/latest", Type: "symlink", Size: &linkSize},
+		{Name: ".env", Path: "/home/me/project/.env", Type: "file", Hidden: true},
+	}
+	return newExportReport(stats, entries, time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC))
+}
+
+// testFailedExportReport is the report of an empty folder whose walk failed.
+func testFailedExportReport() exportReport {
+	walkErr := "open /tmp/empty/a|b: permission denied"
+	stats := statsReport{Path: "/tmp/empty", GitStatus: "Inactive", Error: &walkErr}
+	return newExportReport(stats, nil, time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC))
+}
+
+// checkGolden compares got with testdata/name, or rewrites it with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the output:\n%s", path, got)
+	}
+}
+
+func TestWriteExportReport(t *testing.T) {
+	tests := []struct {
+		golden string
+		report exportReport
+	}{
+		{"export_report.md", testExportReport()},
+		{"export_report.json", testExportReport()},
+		{"export_report_failed.md", testFailedExportReport()},
+		{"export_report_failed.json", testFailedExportReport()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := writeExportReport(&b, tt.report, exportFormat(tt.golden)); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tt.golden, b.Bytes())
+		})
+	}
+}
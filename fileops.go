@@ -0,0 +1,227 @@
+// ---- File: fileops.go ----
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- Duplicating Entries ---
+
+// duplicateName returns a free path next to path for its copy:
+// "report.pdf" -> "report copy.pdf", then "report copy 2.pdf" and so on.
+// Folders and dotfiles keep their whole name as the stem ("src copy").
+func duplicateName(path string, isDir bool) (string, error) {
+	dir, base := filepath.Split(path)
+	stem, ext := base, ""
+	if !isDir {
+		if e := filepath.Ext(base); e != base {
+			stem, ext = strings.TrimSuffix(base, e), e
+		}
+	}
+	for n := 1; n < 10000; n++ {
+		suffix := " copy"
+		if n > 1 {
+			suffix = fmt.Sprintf(" copy %d", n)
+		}
+		candidate := filepath.Join(dir, stem+suffix+ext)
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("no free name for a copy of %s", base)
+}
+
+// copyTree copies src to dst, recursing into directories. Symlinks are
+// recreated as links instead of being followed; permissions and modification
+// times are preserved. dst must not exist. written is told about every chunk
+// of file content copied; the copy stops once ctx is cancelled.
+func copyTree(ctx context.Context, src, dst string, written func(n int64)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	switch mode := info.Mode(); {
+	case mode&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	case mode.IsDir():
+		if err := os.Mkdir(dst, mode.Perm()|0o700); err != nil { // Owner access is needed to fill it
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := copyTree(ctx, filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name()), written); err != nil {
+				return err
+			}
+		}
+		if err := os.Chmod(dst, mode.Perm()); err != nil {
+			return err
+		}
+	case mode.IsRegular():
+		if err := copyFile(ctx, src, dst, mode.Perm(), written); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%s: cannot copy special file", src)
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// copyFile copies the content of the regular file src to the new file dst.
+func copyFile(ctx context.Context, src, dst string, perm os.FileMode, written func(n int64)) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(progressWriter{ctx: ctx, w: out, written: written}, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// duplicateEntry copies path next to itself under a generated name and
+// returns the new path. It reports the bytes copied of the total (measured
+// first for folders). A partially written or cancelled copy is removed.
+func duplicateEntry(ctx context.Context, path string, isDir bool, report func(done, total int64)) (string, error) {
+	dst, err := duplicateName(path, isDir)
+	if err != nil {
+		return "", err
+	}
+	if err := copyEntry(ctx, path, dst, isDir, report); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// copyEntry copies path to dst, which must not exist, reporting the bytes
+// copied of the total (measured first for folders). A partially written or
+// cancelled copy is removed.
+func copyEntry(ctx context.Context, path, dst string, isDir bool, report func(done, total int64)) error {
+	var total int64
+	if isDir {
+		total, _, _ = dirUsage(path, func() bool { return ctx.Err() != nil })
+	} else if info, err := os.Lstat(path); err == nil && info.Mode().IsRegular() {
+		total = info.Size()
+	}
+	var done int64
+	report(0, total)
+	written := func(n int64) {
+		done += n
+		report(done, total)
+	}
+	if err := copyTree(ctx, path, dst, written); err != nil {
+		if !errors.Is(err, fs.ErrExist) { // Otherwise dst appeared meanwhile and isn't ours
+			os.RemoveAll(dst)
+		}
+		return err
+	}
+	return nil
+}
+
+// --- Copying and Moving Between Folders ---
+
+// transferTarget returns the path item gets when copied or moved into dir,
+// refusing a name taken there and a folder going into itself.
+func transferTarget(item FileInfo, dir string) (string, error) {
+	dst := filepath.Join(dir, item.Name)
+	if filepath.Clean(dir) == filepath.Dir(item.Path) {
+		return "", fmt.Errorf("'%s' is already in %s", item.Name, dir)
+	}
+	if item.IsDir {
+		rel, err := filepath.Rel(item.Path, dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("can't put '%s' inside itself", item.Name)
+		}
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return "", fmt.Errorf("'%s' already exists in %s", item.Name, dir)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	return dst, nil
+}
+
+// moveEntry moves path to dst, which must not exist. A rename does it at
+// once; across filesystems, where renaming fails, the entry is copied and the
+// original removed once the copy is complete. Any other rename failure
+// (permissions, a folder moved into itself) is returned as is.
+func moveEntry(ctx context.Context, path, dst string, isDir bool, report func(done, total int64)) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%s: %w", dst, fs.ErrExist) // Rename would replace it
+	}
+	err := os.Rename(path, dst)
+	if err == nil || !isCrossDevice(err) {
+		return err
+	}
+	if err := copyEntry(ctx, path, dst, isDir, report); err != nil {
+		return err
+	}
+	return os.RemoveAll(path)
+}
+
+// --- Renaming Entries ---
+
+// validateNewName checks name as the new name for the entry at path: a
+// single path element that isn't taken by another entry. Changing only the
+// case is allowed on case-insensitive filesystems, where the "other" entry is
+// the same file.
+func validateNewName(path, name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return errors.New("the name can't be empty")
+	case name == "." || name == "..":
+		return fmt.Errorf("'%s' is not a valid name", name)
+	case strings.ContainsAny(name, `/`+string(filepath.Separator)):
+		return errors.New("the name can't contain a path separator")
+	case name == filepath.Base(path):
+		return nil // Unchanged; submitting is a no-op
+	}
+	existing, err := os.Lstat(filepath.Join(filepath.Dir(path), name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if current, err := os.Lstat(path); err == nil && os.SameFile(current, existing) {
+		return nil
+	}
+	return fmt.Errorf("'%s' already exists", name)
+}
+
+// renameEntry renames the entry at path to name in the same folder and
+// returns its new path.
+func renameEntry(path, name string) (string, error) {
+	if err := validateNewName(path, name); err != nil {
+		return "", err
+	}
+	newPath := filepath.Join(filepath.Dir(path), name)
+	if err := os.Rename(path, newPath); err != nil {
+		return "", err
+	}
+	return newPath, nil
+}
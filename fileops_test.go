@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveEntry(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "sub", "f.txt"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A folder can't go inside itself; the rename's error must not turn
+	// into a copy followed by deleting the source
+	if err := moveEntry(context.Background(), src, filepath.Join(src, "sub", "src"), true, nil); err == nil {
+		t.Fatal("moving a folder into itself succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(src, "sub", "f.txt")); err != nil {
+		t.Fatalf("source damaged by a failed move: %v", err)
+	}
+
+	dst := filepath.Join(dir, "dst")
+	if err := moveEntry(context.Background(), src, dst, true, nil); err != nil {
+		t.Fatalf("moveEntry: %v", err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("source still there after the move: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "sub", "f.txt")); err != nil || string(data) != "data" {
+		t.Errorf("moved file = %q, %v", data, err)
+	}
+
+	if err := moveEntry(context.Background(), dst, dir, true, nil); err == nil {
+		t.Error("moving onto an existing entry succeeded")
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE, MoveFileEx's answer to a
+// rename across volumes.
+const errorNotSameDevice = syscall.Errno(17)
+
+// isCrossDevice reports whether a rename failed because source and target
+// are on different volumes.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, errorNotSameDevice)
+}
@@ -0,0 +1,191 @@
+// ---- File: formatters.go ----
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// --- Content Viewer Formatters ---
+
+// contentFormatter renders a file's source for the content viewer at a given
+// width in cells. The viewer can toggle back to the source with R.
+type contentFormatter func(source string, width int) string
+
+// contentGutterWidth is the room left for line numbers when rendering.
+const contentGutterWidth = 6
+
+// lineNumberMode is how the content viewer numbers lines; # cycles through
+// the modes in order.
+type lineNumberMode string
+
+const (
+	lineNumbersAbsolute lineNumberMode = "absolute"
+	lineNumbersRelative lineNumberMode = "relative" // Distance from the top visible line
+	lineNumbersOff      lineNumberMode = "off"
+)
+
+// next returns the mode # switches to; unknown modes start over.
+func (m lineNumberMode) next() lineNumberMode {
+	switch m {
+	case lineNumbersAbsolute:
+		return lineNumbersRelative
+	case lineNumbersRelative:
+		return lineNumbersOff
+	default:
+		return lineNumbersAbsolute
+	}
+}
+
+// gutter returns the room the mode takes next to the text.
+func (m lineNumberMode) gutter() int {
+	if m == lineNumbersOff {
+		return 0
+	}
+	return contentGutterWidth
+}
+
+// defaultJSONFormatLimit is the largest JSON file pretty-printed by default.
+const defaultJSONFormatLimit = 5 * 1024 * 1024
+
+// jsonFormatLimit is the size above which JSON is shown unformatted
+// ("json_format_limit" in config.json).
+var jsonFormatLimit int64 = defaultJSONFormatLimit
+
+// configureFormatters applies the content viewer settings.
+func configureFormatters(cfg config) {
+	jsonFormatLimit = defaultJSONFormatLimit
+	if cfg.JSONFormatLimit > 0 {
+		jsonFormatLimit = int64(cfg.JSONFormatLimit)
+	}
+}
+
+// contentFormatterFor returns the formatter for a file, or nil to show it as is.
+func contentFormatterFor(name string, content []byte) contentFormatter {
+	ext := strings.ToLower(filepath.Ext(name))
+	switch ext {
+	case ".md", ".markdown":
+		return renderMarkdown
+	case ".csv":
+		return csvFormatter(content, ',')
+	case ".tsv":
+		return csvFormatter(content, '\t')
+	}
+	if ext == ".json" || looksLikeJSON(content) {
+		return jsonFormatter(content)
+	}
+	return nil
+}
+
+// looksLikeJSON reports whether content starts like a JSON object or array.
+func looksLikeJSON(content []byte) bool {
+	trimmed := bytes.TrimSpace(content)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+// jsonFormatter pretty-prints content once with two-space indentation, keeping
+// keys in their original order. It returns nil for invalid JSON and for files
+// over jsonFormatLimit, which are shown as they are.
+func jsonFormatter(content []byte) contentFormatter {
+	if int64(len(content)) > jsonFormatLimit {
+		log.Printf("Not formatting JSON of %d bytes (limit %d)", len(content), jsonFormatLimit)
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(content), "", "  "); err != nil {
+		return nil
+	}
+	pretty := buf.String()
+	return func(string, int) string { return pretty }
+}
+
+// CSV/TSV table preview limits.
+const (
+	csvPreviewRows  = 1000 // Rows shown in the table; the rest is summarized
+	csvMaxCellWidth = 30   // Wider cells are truncated
+)
+
+// csvFormatter parses content as CSV (or TSV with comma '\t') and returns a
+// formatter showing it as an aligned table. Content that doesn't parse, e.g.
+// rows with differing field counts, yields nil so it's shown raw.
+func csvFormatter(content []byte, comma rune) contentFormatter {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comma = comma
+	var rows [][]string
+	more := false
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("Showing CSV as raw text: %v", err)
+			return nil
+		}
+		if len(rows) == csvPreviewRows {
+			more = true
+			break
+		}
+		rows = append(rows, record)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	table := renderTable(rows, more)
+	return func(string, int) string { return table }
+}
+
+// renderTable aligns rows into columns as wide as their widest cell (up to
+// csvMaxCellWidth); the first row is taken as the header.
+func renderTable(rows [][]string, more bool) string {
+	var widths []int
+	for _, row := range rows {
+		for col, cell := range row {
+			if col == len(widths) {
+				widths = append(widths, 0)
+			}
+			if w := displayWidth(tableCell(cell)); w > widths[col] {
+				widths[col] = min(w, csvMaxCellWidth)
+			}
+		}
+	}
+
+	var sb strings.Builder
+	for i, row := range rows {
+		cells := make([]string, len(widths))
+		for col := range widths {
+			cell := ""
+			if col < len(row) {
+				cell = truncateWidth(tableCell(row[col]), widths[col])
+			}
+			cells[col] = cell + strings.Repeat(" ", max(0, widths[col]-displayWidth(cell)))
+		}
+		line := toCells(strings.TrimRight(strings.Join(cells, " "+glyph("│")+" "), " "))
+		if i == 0 {
+			line = ansiBold + line + ansiReset
+		}
+		sb.WriteString(line + "\n")
+		if i == 0 {
+			rules := make([]string, len(widths))
+			for col, w := range widths {
+				rules[col] = strings.Repeat(glyph("─"), w)
+			}
+			sb.WriteString(strings.Join(rules, glyph("─")+glyph("┼")+glyph("─")) + "\n")
+		}
+	}
+	if more {
+		sb.WriteString(fmt.Sprintf("%s%s showing the first %d rows (R for raw)%s\n", ansiDim, glyph("…"), csvPreviewRows, ansiReset))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// tableCell flattens a field for one table line: embedded newlines and tabs become spaces.
+func tableCell(field string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\t", " ").Replace(field)
+}
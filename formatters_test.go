@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderTableWideCellsAnywhere(t *testing.T) {
+	rows := [][]string{{"id", "name"}}
+	for range 300 {
+		rows = append(rows, []string{"1", "a"})
+	}
+	rows = append(rows, []string{"2", strings.Repeat("wide ", 20)}) // Past any sample of the first rows
+
+	table := renderTable(rows, false)
+	lines := strings.Split(table, "\n")
+	if got, want := len(lines), len(rows)+1; got != want {
+		t.Fatalf("got %d lines, want %d (rows plus the header rule)", got, want)
+	}
+	last := lines[len(lines)-1]
+	if !strings.Contains(last, ellipsis) {
+		t.Errorf("last row %q: wide cell not truncated", last)
+	}
+	if w := displayWidth(last); w > len("id")+3+csvMaxCellWidth {
+		t.Errorf("last row is %d cells wide, more than the capped columns", w)
+	}
+}
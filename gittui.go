@@ -0,0 +1,62 @@
+// ---- File: gittui.go ----
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Git TUI ---
+
+// defaultGitTUI is the program Ctrl+G runs when "git_tui" isn't configured.
+const defaultGitTUI = "lazygit"
+
+// gitTUI is the command Ctrl+G runs in the CWD ("git_tui" in config.json). It
+// may contain arguments but is not run through a shell.
+var gitTUI = defaultGitTUI
+
+// configureGitTUI applies the "git_tui" setting.
+func configureGitTUI(cfg config) {
+	gitTUI = defaultGitTUI
+	if command := strings.TrimSpace(cfg.GitTUI); command != "" {
+		gitTUI = command
+	}
+}
+
+// handleOpenGitTUI hands the terminal to the git TUI in the CWD. Commits,
+// checkouts and discarded changes made there leave the listing and the Git
+// Status pane stale, so both are reloaded when it exits.
+func handleOpenGitTUI(g *gocui.Gui, state *AppState) error {
+	if !state.InGitRepo() {
+		state.SetMessage("Not in a git repository")
+		g.Update(func(gui *gocui.Gui) error { return nil })
+		return nil
+	}
+	fields := strings.Fields(gitTUI)
+	program, err := exec.LookPath(fields[0])
+	if err != nil {
+		log.Printf("Git TUI unavailable: %v", err)
+		state.SetMessage(fmt.Sprintf("'%s' is not installed (set git_tui in config.json)", fields[0]))
+		g.Update(func(gui *gocui.Gui) error { return nil })
+		return nil
+	}
+	cmd := exec.Command(program, fields[1:]...)
+	cmd.Dir = state.Cwd()
+	err = runSuspended(g, cmd)
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		state.SetMessage(fmt.Sprintf("%s exited with status %d", fields[0], exitErr.ExitCode()))
+	case err != nil:
+		log.Printf("Git TUI failed to start: %v", err)
+		state.SetMessage(fmt.Sprintf("Could not run %s: %s", fields[0], trimError(err)))
+	}
+	state.InvalidateStatsCache(state.Cwd())
+	reloadDirectory(g, state, nil)
+	return nil
+}
@@ -0,0 +1,151 @@
+// ---- File: glob.go ----
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// --- File Glob Filter ---
+
+// maxGlobAlternatives caps brace expansion, so "{a,b}{c,d}..." can't explode.
+const maxGlobAlternatives = 256
+
+// globFilter restricts the files listed to names matching a glob such as
+// "*.go" or "*.{yml,yaml}". The zero value matches everything.
+type globFilter struct {
+	pattern      string   // As typed, for titles and messages
+	alternatives []string // Brace-free patterns; a name matching any of them passes
+}
+
+// compileGlob parses a filter pattern. Braces expand into alternatives
+// ("*.{yml,yaml}" is "*.yml" or "*.yaml", nesting allowed); the rest is
+// path.Match syntax (*, ?, [a-z]). A bare extension ("go" or ".go") means
+// "*.go".
+func compileGlob(pattern string) (globFilter, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return globFilter{}, nil
+	}
+	if strings.Contains(pattern, "/") {
+		return globFilter{}, fmt.Errorf("a pattern matches names, it can't contain '/'")
+	}
+	if !strings.ContainsAny(pattern, "*?[]{}\\") {
+		pattern = "*." + strings.TrimPrefix(pattern, ".")
+	}
+	alternatives, err := expandBraces(pattern)
+	if err != nil {
+		return globFilter{}, err
+	}
+	for _, alt := range alternatives {
+		if _, err := path.Match(alt, ""); err != nil {
+			return globFilter{}, fmt.Errorf("invalid pattern '%s'", alt)
+		}
+	}
+	return globFilter{pattern: pattern, alternatives: alternatives}, nil
+}
+
+// Active reports whether the filter restricts anything.
+func (f globFilter) Active() bool { return f.pattern != "" }
+
+// Pattern returns the pattern as shown to the user, e.g. "*.go".
+func (f globFilter) Pattern() string { return f.pattern }
+
+// Match reports whether name passes the filter.
+func (f globFilter) Match(name string) bool {
+	if !f.Active() {
+		return true
+	}
+	for _, alt := range f.alternatives {
+		if ok, _ := path.Match(alt, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// expandBraces expands the first top-level {a,b,...} group of pattern and
+// recurses into the results, e.g. "x.{a,{b,c}}" -> "x.a", "x.b", "x.c". A
+// group without a comma stays literal, like in the shell. Braces inside
+// [...] classes and escaped with \ are not groups; an unmatched '{' is an
+// error.
+func expandBraces(pattern string) ([]string, error) {
+	openIdx, closeIdx, parts := -1, -1, []string(nil)
+	depth, start := 0, 0
+	for i := 0; i < len(pattern) && closeIdx < 0; i++ {
+		switch pattern[i] {
+		case '\\':
+			i++ // Skip the escaped character
+		case '[':
+			if end := strings.IndexByte(pattern[i+1:], ']'); end >= 0 {
+				i += end + 1
+			}
+		case '{':
+			if depth == 0 {
+				openIdx, start = i, i+1
+			}
+			depth++
+		case ',':
+			if depth == 1 {
+				parts = append(parts, pattern[start:i])
+				start = i + 1
+			}
+		case '}':
+			if depth == 0 {
+				continue // A stray '}' is literal, like in the shell
+			}
+			if depth--; depth == 0 {
+				closeIdx = i
+				parts = append(parts, pattern[start:i])
+			}
+		}
+	}
+	if depth > 0 {
+		return nil, fmt.Errorf("unmatched '{' in '%s'", pattern)
+	}
+	if openIdx < 0 {
+		return []string{pattern}, nil
+	}
+
+	prefix, suffix := pattern[:openIdx], pattern[closeIdx+1:]
+	if len(parts) == 1 {
+		// No comma: keep the braces as literal text and expand the rest
+		rest, err := expandBraces(suffix)
+		if err != nil {
+			return nil, err
+		}
+		inner, err := expandBraces(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		return combineLiteralBraces(prefix, inner, rest)
+	}
+	var expanded []string
+	for _, part := range parts {
+		more, err := expandBraces(prefix + part + suffix)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, more...)
+		if len(expanded) > maxGlobAlternatives {
+			return nil, fmt.Errorf("'%s' expands to more than %d patterns", pattern, maxGlobAlternatives)
+		}
+	}
+	return expanded, nil
+}
+
+// combineLiteralBraces joins prefix, each of inner wrapped in braces, and
+// each of rest.
+func combineLiteralBraces(prefix string, inner, rest []string) ([]string, error) {
+	var out []string
+	for _, in := range inner {
+		for _, r := range rest {
+			out = append(out, prefix+"{"+in+"}"+r)
+			if len(out) > maxGlobAlternatives {
+				return nil, fmt.Errorf("pattern expands to more than %d patterns", maxGlobAlternatives)
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+		wantErr bool
+	}{
+		{pattern: "*.go", want: []string{"*.go"}},
+		{pattern: "*.{yml,yaml}", want: []string{"*.yml", "*.yaml"}},
+		{pattern: "{a,b}.{c,d}", want: []string{"a.c", "a.d", "b.c", "b.d"}},
+		{pattern: "x.{a,{b,c}}", want: []string{"x.a", "x.b", "x.c"}},
+		{pattern: "x.{,bak}", want: []string{"x.", "x.bak"}},
+		{pattern: "{a}.txt", want: []string{"{a}.txt"}}, // No comma: literal
+		{pattern: "{a}.{b,c}", want: []string{"{a}.b", "{a}.c"}},
+		{pattern: "[{]x,y}", want: []string{"[{]x,y}"}}, // Inside a class
+		{pattern: `\{a,b}`, want: []string{`\{a,b}`}},   // Escaped
+		{pattern: "a}b", want: []string{"a}b"}},         // Stray '}'
+		{pattern: "*.{go", wantErr: true},
+		{pattern: strings.Repeat("{a,b,c,d}", 5), wantErr: true}, // 1024 alternatives
+	}
+	for _, tt := range tests {
+		got, err := expandBraces(tt.pattern)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("expandBraces(%q): got error %v, want error: %v", tt.pattern, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !slices.Equal(got, tt.want) {
+			t.Errorf("expandBraces(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestGlobFilter(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		match      []string
+		noMatch    []string
+		wantErr    bool
+		wantActive bool
+	}{
+		{pattern: "", match: []string{"a.go", "README"}},
+		{pattern: "*.go", match: []string{"a.go", ".go"}, noMatch: []string{"a.golang", "go"}, wantActive: true},
+		{pattern: "go", match: []string{"main.go"}, noMatch: []string{"go", "main.gox"}, wantActive: true}, // A bare extension
+		{pattern: ".md", match: []string{"README.md"}, wantActive: true},
+		{pattern: "*.{yml,yaml}", match: []string{"ci.yml", "ci.yaml"}, noMatch: []string{"ci.json"}, wantActive: true},
+		{pattern: "test_?.py", match: []string{"test_a.py"}, noMatch: []string{"test_ab.py"}, wantActive: true},
+		{pattern: "[a-c]*", match: []string{"build.sh"}, noMatch: []string{"dist"}, wantActive: true},
+		{pattern: "src/*.go", wantErr: true},
+		{pattern: "[a-", wantErr: true},
+		{pattern: "*.{go", wantErr: true},
+	}
+	for _, tt := range tests {
+		glob, err := compileGlob(tt.pattern)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("compileGlob(%q): got error %v, want error: %v", tt.pattern, err, tt.wantErr)
+			continue
+		}
+		if glob.Active() != tt.wantActive {
+			t.Errorf("compileGlob(%q).Active() = %v", tt.pattern, glob.Active())
+		}
+		for _, name := range tt.match {
+			if !glob.Match(name) {
+				t.Errorf("%q doesn't match %q", tt.pattern, name)
+			}
+		}
+		for _, name := range tt.noMatch {
+			if glob.Match(name) {
+				t.Errorf("%q matches %q", tt.pattern, name)
+			}
+		}
+	}
+}
+
+func TestGlobFilterPersistsAcrossReads(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.go": "", "b.txt": "", "pkg/": ""})
+	glob, err := compileGlob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := NewAppState(dir)
+	state.SetFileGlob(glob)
+	for range 2 { // A refresh reads the folder again
+		listing, err := readDirectory(state, dir, func(int) {}, func() bool { return false })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(listing.visibleFiles) != 1 || listing.visibleFiles[0].Name != "a.go" {
+			t.Errorf("files %+v, want only a.go", listing.visibleFiles)
+		}
+		if len(listing.visibleDirs) != 1 {
+			t.Errorf("the filter left out folders: %+v", listing.visibleDirs)
+		}
+	}
+}
@@ -3,8 +3,8 @@ module github.com/AlexandrosLiaskos/lazyls
 go 1.24.2
 
 require (
-	github.com/atotto/clipboard v0.1.4 // indirect
-	github.com/jroimartin/gocui v0.5.0 // indirect
-	github.com/mattn/go-runewidth v0.0.9 // indirect
-	github.com/nsf/termbox-go v1.1.1 // indirect
+	github.com/atotto/clipboard v0.1.4
+	github.com/jroimartin/gocui v0.5.0
+	github.com/mattn/go-runewidth v0.0.9
+	github.com/nsf/termbox-go v1.1.1
 )
@@ -0,0 +1,101 @@
+// ---- File: grid.go ----
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Files Grid ---
+
+// gridGap is the space between the columns of the Files grid.
+const gridGap = 2
+
+// gridMaxNameWidth caps the width of a grid column; longer names are
+// shortened in the middle, like in the single-column list.
+const gridMaxNameWidth = 32
+
+// gridLayout returns how many columns of names longest cells wide fit in a
+// pane width cells wide, like ls -C, and the width the names get. Every cell
+// holds the icon (up to two cells), a space and the name with its marker.
+func gridLayout(width, longest int) (columns, nameWidth int) {
+	nameWidth = min(longest+1, gridMaxNameWidth)                // Room for the executable marker
+	columns = (width - 1 + gridGap) / (3 + nameWidth + gridGap) // Leading space
+	if columns < 1 {
+		return 1, max(width-4, 1)
+	}
+	return columns, nameWidth
+}
+
+// updateFileGrid renders the Files pane in grid mode: entries row by row, in
+// as many columns as the longest name allows. The origin is the index of the
+// first entry of the top row, so a row scrolls in as a whole.
+func updateFileGrid(g *gocui.Gui, snap renderSnapshot, list listSnapshot, v *gocui.View, isFocused bool) {
+	viewName := v.Name()
+	viewWidth := list.frame.width
+	columns, nameWidth := list.frame.columns, list.frame.nameWidth
+	v.SetOrigin(0, 0)
+	v.Highlight = false // The selected cell is drawn below
+
+	listLen := list.total
+	if listLen == 0 {
+		_ = v.SetCursor(0, 0)
+		drawScrollbar(g, viewName, 0, 0, snap.overlay)
+		fmt.Fprintf(v, " %s%s%s", ansiGray, truncateWidth(emptyListText(snap, list, viewName), viewWidth-1), ansiReset)
+		return
+	}
+
+	// The snapshot's origin starts a row and keeps the cursor on screen, even
+	// after a new column count (toggle, resize, another directory)
+	originY, cursorY := list.originY, list.cursorY
+	drawScrollbar(g, viewName, originY/columns, (listLen+columns-1)/columns, snap.overlay)
+	_ = v.SetCursor(0, (cursorY-originY)/columns)
+
+	// Like the single-column list: bold green when focused, green otherwise.
+	// Reverse video marks the cell, as a lone colored name is easy to miss.
+	selected := ansiGreen
+	if isFocused {
+		selected += ansiBold + ansiReverse
+	} else if plainMode {
+		selected += ansiReverse
+	}
+	var sb strings.Builder
+	for i, item := range list.rows {
+		if i%columns == 0 {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+		} else {
+			sb.WriteString(strings.Repeat(" ", gridGap-1))
+		}
+		// The cell's last leading space holds the new entry marker
+		if list.isNew[i] {
+			sb.WriteString(newEntryMarker())
+		} else {
+			sb.WriteString(" ")
+		}
+		suffix := ""
+		if isExecutable(item) {
+			suffix = "*"
+		}
+		if item.Unreadable {
+			suffix = " (unreadable)"
+		}
+		shortName := middleEllipsis(item.Name, nameWidth-len(suffix))
+		width := displayWidth(item.Icon) + 1 + displayWidth(shortName) + len(suffix)
+		padding := strings.Repeat(" ", max(nameWidth+3-width, 0))
+		name := toCells(shortName)
+		if originY+i == cursorY {
+			// The highlight covers the whole cell, so the grid's selection is a block
+			sb.WriteString(selected + item.Icon + " " + name + suffix + padding + ansiReset)
+			continue
+		}
+		if color := nameColor(item); color != "" {
+			name = color + name + ansiReset
+		}
+		sb.WriteString(item.Icon + " " + name + suffix + padding)
+	}
+	fmt.Fprint(v, sb.String())
+}
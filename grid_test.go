@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestGridLayout(t *testing.T) {
+	tests := []struct {
+		width, longest     int
+		columns, nameWidth int
+	}{
+		{80, 10, 5, 11}, // 5 cells of 3+11, 4 gaps: 5*14+4*2+1 = 79
+		{79, 10, 5, 11}, // Exactly fits
+		{78, 10, 4, 11},
+		{200, 100, 5, gridMaxNameWidth}, // Long names are capped
+		{10, 20, 1, 6},                  // Narrower than one cell: the pane width
+		{3, 20, 1, 1},
+	}
+	for _, tt := range tests {
+		columns, nameWidth := gridLayout(tt.width, tt.longest)
+		if columns != tt.columns || nameWidth != tt.nameWidth {
+			t.Errorf("gridLayout(%d, %d) = %d, %d; want %d, %d", tt.width, tt.longest, columns, nameWidth, tt.columns, tt.nameWidth)
+		}
+	}
+}
+
+func TestGridCursorMovement(t *testing.T) {
+	const height, columns = 3, 4 // 12 entries on screen
+	state := NewAppState("/work")
+	state.SetDirectoryContents(nil, numberedEntries(0, 30), nil, nil)
+	state.ToggleGridMode()
+	state.SetGridColumns(columns)
+	if got := state.GridColumns(viewFiles); got != columns {
+		t.Fatalf("GridColumns = %d, want %d", got, columns)
+	}
+	if got := state.GridColumns(viewFolders); got != 1 {
+		t.Errorf("the Folders pane has %d columns, want 1", got)
+	}
+
+	steps := []struct {
+		name         string
+		delta        int
+		cursor, orig int
+	}{
+		{"right", 1, 1, 0},
+		{"down a row", columns, 5, 0},
+		{"down to the last row on screen", columns, 9, 0},
+		{"down scrolls a whole row", columns, 13, 4},
+		{"left across the row start", -2, 11, 4},
+		{"up scrolls back", -3 * columns, 0, 0},
+		{"past the end stops at the last entry", 100, 29, 20},
+	}
+	for _, st := range steps {
+		state.moveCursorAndOrigin(viewFiles, st.delta, height)
+		cursor, origin := state.GetCurrentCursorY(viewFiles), state.GetCurrentOriginY(viewFiles)
+		if cursor != st.cursor || origin != st.orig {
+			t.Errorf("%s: cursor %d, origin %d; want %d, %d", st.name, cursor, origin, st.cursor, st.orig)
+		}
+		if origin%columns != 0 {
+			t.Errorf("%s: origin %d doesn't start a row", st.name, origin)
+		}
+	}
+
+	state.ToggleCombinedMode()
+	if got := state.GridColumns(viewFiles); got != 1 {
+		t.Errorf("the combined list has %d columns, want 1", got)
+	}
+}
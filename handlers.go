@@ -2,290 +2,437 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/jroimartin/gocui"
 )
 
-// setupKeybindings configures all application keybindings.
-func setupKeybindings(g *gocui.Gui, state *AppState) error {
-	// Quit (Global)
-	if err := g.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone, quit); err != nil {
-		return err
-	}
-	if err := g.SetKeybinding("", 'q', gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		// Allow 'q' to close the file content view if it's open
-		if state.IsFileContentViewVisible() {
-			return handleCloseFileContentView(gui, view, state) // Use the updated handler
-		}
-		// Allow 'q' to close the action menu if it's open
-		if state.IsActionMenuVisible() {
-			return handleMenuClose(gui, view, state)
-		}
-		return quit(gui, view) // Otherwise, quit the app
-	}); err != nil {
-		return err
-	}
-
-	// Esc for closing menu or file view
-	if err := g.SetKeybinding(viewActionMenu, gocui.KeyEsc, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		return handleMenuClose(gui, view, state)
-	}); err != nil {
-		return err
-	}
-	if err := g.SetKeybinding(viewFileContent, gocui.KeyEsc, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		return handleCloseFileContentView(gui, view, state) // Use the updated handler
-	}); err != nil {
-		return err
-	}
+// quit is the keybinding handler for quitting the application.
+func quit(g *gocui.Gui, v *gocui.View) error {
+	return gocui.ErrQuit
+}
 
-	// Toggle Hidden Files (Global)
-	if err := g.SetKeybinding("", '.', gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		// Don't toggle if file view or menu is open
-		if state.IsFileContentViewVisible() || state.IsActionMenuVisible() {
-			return nil
-		}
-		return handleToggleHidden(gui, state)
-	}); err != nil {
-		return err
+// handleToggleHidden processes the toggle hidden keypress, cycling through
+// visible only, all (hidden dimmed) and hidden only.
+func handleToggleHidden(g *gocui.Gui, state *AppState) error {
+	switch state.CycleHiddenMode() {
+	case hiddenModeAll:
+		state.SetMessage("Showing all entries (hidden ones dimmed)")
+	case hiddenModeOnly:
+		state.SetMessage("Showing hidden entries only")
+	default:
+		state.SetMessage("Showing visible entries only")
+	}
+	// Reset focus to the first list view for consistency after toggle
+	if _, err := g.SetCurrentView(primaryListView(state)); err != nil {
+		log.Printf("Warning: Failed to set focus to folders after toggle: %v", err)
 	}
+	// Explicitly update the view that will gain focus to reset its cursor display
+	// Update: Calling g.Update is simpler and ensures layout handles everything
+	// updateListView(g, state, viewFolders) // Force update
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Trigger layout update
+	})
 
-	// Focus Switching (Global - Tab)
-	if err := g.SetKeybinding("", gocui.KeyTab, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		// Don't switch focus if file view or menu is open
-		if state.IsFileContentViewVisible() || state.IsActionMenuVisible() {
-			return nil
-		}
-		return handleFocusSwitch(gui, state, true) // Forward
-	}); err != nil {
-		return err
-	}
+	// Let layout handle the rest
+	return nil
+}
 
-	// --- List Navigation Keybindings (Folders and Files views) ---
-	viewsToNavigate := []string{viewFolders, viewFiles}
-	for _, viewName := range viewsToNavigate {
-		// --- Cursor Movement (Updates Cursor & Origin) ---
-		bindMove := func(key interface{}, delta int) error {
-			return g.SetKeybinding(viewName, key, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-				return handleMoveCursor(gui, view, delta, state)
-			})
-		}
-		if err := bindMove(gocui.KeyArrowDown, 1); err != nil {
-			return err
-		}
-		if err := bindMove('j', 1); err != nil {
-			return err
-		}
-		if err := bindMove(gocui.KeyArrowUp, -1); err != nil {
-			return err
-		}
-		if err := bindMove('k', -1); err != nil {
-			return err
+// reloadDirectory re-reads the CWD in the background and restarts the stats and
+// folder size jobs. then, if not nil, runs once the new listing is in place,
+// unless state is no longer the one the gui runs on by then (its tab was
+// left or closed): then moves focus and selections in the shared views.
+func reloadDirectory(g *gocui.Gui, state *AppState, then func(gui *gocui.Gui)) {
+	startDirectoryLoad(g, state, func(gui *gocui.Gui) {
+		startDirSizeJob(gui, state)
+		if then != nil && tabs.IsActive(state) {
+			then(gui)
 		}
+	})
+	go calculateStats(g, state)
+}
 
-		// Page move
-		bindPageMove := func(key interface{}, multiplier int) error {
-			return g.SetKeybinding(viewName, key, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-				_, maxY := view.Size()
-				pageSize := maxY - 1
-				if pageSize < 1 {
-					pageSize = 1
-				}
-				return handleMoveCursor(gui, view, multiplier*pageSize, state)
-			})
-		}
-		if err := bindPageMove(gocui.KeyPgdn, 1); err != nil {
-			return err
-		}
-		if err := bindPageMove(gocui.KeySpace, 1); err != nil {
-			return err
-		}
-		if err := bindPageMove(gocui.KeyPgup, -1); err != nil {
-			return err
-		}
-		if err := bindPageMove('b', -1); err != nil {
-			return err
-		}
+// changeDirectory makes dir the CWD and loads its listing and stats. then, if
+// not nil, runs once the new listing is in place.
+func changeDirectory(g *gocui.Gui, state *AppState, dir string, then func(gui *gocui.Gui)) {
+	state.CancelDirSizeJob()
+	state.CancelEntryCounts()
+	state.SetCwd(dir)
+	reloadDirectory(g, state, then)
+}
 
-		// Go to Top/Bottom
-		bindTopBottom := func(key interface{}, toTop bool) error {
-			return g.SetKeybinding(viewName, key, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-				return handleGoTopBottom(gui, view, toTop, state)
-			})
-		}
-		if err := bindTopBottom('g', true); err != nil {
-			return err
-		}
-		if err := bindTopBottom(gocui.KeyHome, true); err != nil {
-			return err
-		}
-		if err := bindTopBottom('G', false); err != nil {
-			return err
-		}
-		if err := bindTopBottom(gocui.KeyEnd, false); err != nil {
-			return err
-		}
+// moveToAncestor switches to dir after the CWD disappeared (deleted or
+// unmounted) and reloads the listing and stats there.
+func moveToAncestor(g *gocui.Gui, state *AppState, dir string) {
+	log.Printf("Warning: %s no longer exists, moving to %s", state.Cwd(), dir)
+	changeDirectory(g, state, dir, func(gui *gocui.Gui) {
+		state.SetMessage(fmt.Sprintf("Directory no longer exists %s moved to %s", glyph("—"), dir))
+	})
+}
 
-		// --- Action Trigger ---
-		if err := g.SetKeybinding(viewName, gocui.KeyEnter, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-			return handleEnter(gui, view, state)
-		}); err != nil {
-			return err
-		}
+// handleGoToRepoRoot changes to the root of the git repository the CWD is
+// in, selecting the folder the CWD was under. Under a symlinked CWD the root
+// is reached through the same link.
+func handleGoToRepoRoot(g *gocui.Gui, state *AppState) error {
+	root, cwd := state.RepoRoot(), state.Cwd()
+	if root != "" {
+		root = unresolvedRepoRoot(root, cwd)
+	}
+	switch {
+	case root == "":
+		state.SetMessage("Not inside a git repository")
+	case root == cwd:
+		state.SetMessage("Already at the repository root")
+	default:
+		// The folder of root the CWD is, or is under
+		rel, _ := filepath.Rel(root, cwd)
+		top, _, _ := strings.Cut(rel, string(filepath.Separator))
+		from := filepath.Join(root, top)
+		changeDirectory(g, state, root, func(gui *gocui.Gui) {
+			selectPath(gui, state, from)
+			state.SetMessage(fmt.Sprintf("Moved to the repository root %s", root))
+		})
+		return nil
 	}
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
 
-	// --- File Content View Scroll Keybindings ---
-	fileContentViewName := viewFileContent // Use the constant
-
-	// Line Scroll
-	if err := g.SetKeybinding(fileContentViewName, gocui.KeyArrowDown, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		return handleScrollFileContentView(gui, view, state, 1, false)
-	}); err != nil {
-		return err
+// handleShowBranches opens a menu of the repository's local branches, the
+// checked-out one marked; choosing another switches to it.
+func handleShowBranches(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
 	}
-	if err := g.SetKeybinding(fileContentViewName, 'j', gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		return handleScrollFileContentView(gui, view, state, 1, false)
-	}); err != nil {
-		return err
+	if state.RepoRoot() == "" {
+		state.SetMessage("Not inside a git repository")
+		g.Update(func(gui *gocui.Gui) error { return nil })
+		return nil
 	}
-	if err := g.SetKeybinding(fileContentViewName, gocui.KeyArrowUp, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		return handleScrollFileContentView(gui, view, state, -1, false)
-	}); err != nil {
-		return err
+	dir := state.Cwd()
+	branches, current, err := GitBranches(state.Git(), dir)
+	if err != nil {
+		log.Printf("Error: listing branches in %s: %v", dir, err)
+		state.SetMessage(fmt.Sprintf("Could not list branches: %s", trimError(err)))
+		g.Update(func(gui *gocui.Gui) error { return nil })
+		return nil
 	}
-	if err := g.SetKeybinding(fileContentViewName, 'k', gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		return handleScrollFileContentView(gui, view, state, -1, false)
-	}); err != nil {
-		return err
+	if len(branches) == 0 {
+		state.SetMessage("The repository has no branches yet")
+		g.Update(func(gui *gocui.Gui) error { return nil })
+		return nil
 	}
 
-	// Page Scroll
-	if err := g.SetKeybinding(fileContentViewName, gocui.KeyPgdn, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		_, maxY := view.Size()
-		pageSize := maxY - 1
-		if pageSize < 1 {
-			pageSize = 1
-		}
-		return handleScrollFileContentView(gui, view, state, pageSize, true)
-	}); err != nil {
-		return err
-	}
-	if err := g.SetKeybinding(fileContentViewName, gocui.KeySpace, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		_, maxY := view.Size()
-		pageSize := maxY - 1
-		if pageSize < 1 {
-			pageSize = 1
+	var options []ActionMenuItem
+	selected := 0
+	for _, branch := range branches {
+		label := "  " + branch
+		if branch == current {
+			label = "* " + branch
+			selected = len(options)
 		}
-		return handleScrollFileContentView(gui, view, state, pageSize, true)
-	}); err != nil {
-		return err
+		options = append(options, ActionMenuItem{Label: label, MnemonicIdx: -1, ActionFn: switchBranchAction(branch, current)})
 	}
-	if err := g.SetKeybinding(fileContentViewName, gocui.KeyPgup, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		_, maxY := view.Size()
-		pageSize := maxY - 1
-		if pageSize < 1 {
-			pageSize = 1
+	options = append(options, ActionMenuItem{Label: "Cancel", MnemonicIdx: -1, ActionFn: func(g *gocui.Gui, _ FileInfo, state *AppState) error {
+		refocusAfterMenu(g, state)
+		return nil
+	}})
+	header := FileInfo{Name: "Switch branch", Icon: glyph("\ue702"), IsDir: true} // IsDir: no size and date in the header
+	state.OpenActionMenu(header, options, v.Name())
+	state.SelectActionMenuItem(selected)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// switchBranchAction returns the branch menu action that checks out branch
+// in the background, then reloads the listing, stats and git markers.
+func switchBranchAction(branch, current string) func(*gocui.Gui, FileInfo, *AppState) error {
+	return func(g *gocui.Gui, _ FileInfo, state *AppState) error {
+		refocusAfterMenu(g, state)
+		if branch == current {
+			state.SetMessage(fmt.Sprintf("Already on '%s'", branch))
+			return nil
 		}
-		return handleScrollFileContentView(gui, view, state, -pageSize, true)
-	}); err != nil {
-		return err
+		dir := state.Cwd()
+		state.SetMessage(fmt.Sprintf("Switching to '%s'...", branch))
+		go func() {
+			err := SwitchGitBranch(state.Git(), dir, branch)
+			g.Update(func(gui *gocui.Gui) error {
+				if err != nil {
+					log.Printf("Error: switching %s to branch %s: %v", dir, branch, err)
+					state.SetMessage(fmt.Sprintf("Could not switch to '%s': %s", branch, trimError(err)))
+					return nil
+				}
+				reloadDirectory(gui, state, func(gui *gocui.Gui) {
+					state.SetMessage(fmt.Sprintf("Switched to branch '%s'", branch))
+				})
+				return nil
+			})
+		}()
+		return nil
 	}
-	if err := g.SetKeybinding(fileContentViewName, 'b', gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		_, maxY := view.Size()
-		pageSize := maxY - 1
-		if pageSize < 1 {
-			pageSize = 1
+}
+
+// refocusAfterMenu returns focus to the pane the action menu was opened from,
+// for menus opened outside the lists the layout would otherwise fall back to.
+func refocusAfterMenu(g *gocui.Gui, state *AppState) {
+	if prev := state.GetPreviousFocusView(); prev != "" {
+		if _, err := g.SetCurrentView(prev); err != nil {
+			log.Printf("Error restoring focus to %s after the menu: %v", prev, err)
 		}
-		return handleScrollFileContentView(gui, view, state, -pageSize, true)
-	}); err != nil {
-		return err
 	}
+}
 
-	// Go To Top/Bottom (Use large delta values as signal)
-	if err := g.SetKeybinding(fileContentViewName, 'g', gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		return handleScrollFileContentView(gui, view, state, -999999, true)
-	}); err != nil {
-		return err
-	}
-	if err := g.SetKeybinding(fileContentViewName, gocui.KeyHome, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		return handleScrollFileContentView(gui, view, state, -999999, true)
-	}); err != nil {
-		return err
+// handleClearNewMarkers drops the "+" markers of entries new since the previous load.
+func handleClearNewMarkers(g *gocui.Gui, state *AppState) error {
+	if count := state.ClearNewEntries(); count > 0 {
+		state.SetMessage(fmt.Sprintf("Cleared the new marker of %s", pluralize(count, "entry", "entries")))
+	} else {
+		state.SetMessage("No entries are marked new")
 	}
-	if err := g.SetKeybinding(fileContentViewName, 'G', gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		return handleScrollFileContentView(gui, view, state, 999999, true)
-	}); err != nil {
-		return err
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleRefresh reloads the listing and rescans the tree, dropping its
+// cached stats so the scan isn't skipped.
+func handleRefresh(g *gocui.Gui, state *AppState) error {
+	state.InvalidateStatsCache(state.Cwd())
+	reloadDirectory(g, state, func(gui *gocui.Gui) {
+		state.SetMessage("Refreshed")
+	})
+	return nil
+}
+
+// handleTogglePreview shows or hides the preview pane.
+func handleTogglePreview(g *gocui.Gui, state *AppState) error {
+	if state.TogglePreviewMode() {
+		state.SetMessage("Preview on")
+	} else {
+		state.SetMessage("Preview off")
 	}
-	if err := g.SetKeybinding(fileContentViewName, gocui.KeyEnd, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		return handleScrollFileContentView(gui, view, state, 999999, true)
-	}); err != nil {
-		return err
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleToggleGitIgnore flips git ignore mode and reloads the listing and stats.
+func handleToggleGitIgnore(g *gocui.Gui, state *AppState) error {
+	enabled := state.ToggleGitIgnoreMode()
+	reloadDirectory(g, state, func(gui *gocui.Gui) {
+		switch {
+		case enabled && state.IsGitIgnoreActive():
+			state.SetMessage("Hiding git-ignored entries")
+		case enabled:
+			// Not a repo (or git failed): the listing is unchanged
+			if state.GetLastMessage() == "" {
+				state.SetMessage("Ignore mode on (not a git repository)")
+			}
+		default:
+			state.SetMessage("Showing git-ignored entries")
+		}
+	})
+
+	if _, err := g.SetCurrentView(primaryListView(state)); err != nil {
+		log.Printf("Warning: Failed to set focus to folders after toggle: %v", err)
 	}
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Trigger layout update
+	})
+	return nil
+}
 
-	// --- Action Menu Keybindings ---
-	if err := g.SetKeybinding(viewActionMenu, gocui.KeyArrowDown, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		return handleMenuNavigate(gui, view, 1, state)
-	}); err != nil {
-		return err
+// handleToggleSizeUnits switches sizes between binary (KiB) and decimal (kB) units.
+func handleToggleSizeUnits(g *gocui.Gui, state *AppState) error {
+	decimal := !decimalUnits.Load()
+	decimalUnits.Store(decimal)
+	if decimal {
+		state.SetMessage("Sizes in decimal units (kB, MB, GB)")
+	} else {
+		state.SetMessage("Sizes in binary units (KiB, MiB, GiB)")
 	}
-	if err := g.SetKeybinding(viewActionMenu, 'j', gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		return handleMenuNavigate(gui, view, 1, state)
-	}); err != nil {
-		return err
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleGlobFilter opens a prompt for a glob restricting the listed files,
+// e.g. "*.go" or "*.{yml,yaml}". Submitting an empty input clears it.
+func handleGlobFilter(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
 	}
-	if err := g.SetKeybinding(viewActionMenu, gocui.KeyArrowUp, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		return handleMenuNavigate(gui, view, -1, state)
-	}); err != nil {
-		return err
+	title := " Filter files (e.g. *.go, *.{yml,yaml}) "
+	if glob := state.FileGlob(); glob.Active() {
+		title = fmt.Sprintf(" Filter files (now %s, empty clears) ", glob.Pattern())
 	}
-	if err := g.SetKeybinding(viewActionMenu, 'k', gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		return handleMenuNavigate(gui, view, -1, state)
-	}); err != nil {
-		return err
+	state.OpenPrompt(promptSpec{
+		Kind:  "glob",
+		Title: title,
+		Validate: func(input string) error {
+			_, err := compileGlob(input)
+			return err
+		},
+		OnSubmit: func(gui *gocui.Gui, input string, state *AppState) error {
+			glob, _ := compileGlob(input) // Checked by Validate
+			state.SetFileGlob(glob)
+			reloadDirectory(gui, state, func(gui *gocui.Gui) {
+				if glob.Active() {
+					state.SetMessage(fmt.Sprintf("Showing files matching %s", glob.Pattern()))
+				} else {
+					state.SetMessage("File filter cleared")
+				}
+			})
+			return nil
+		},
+	}, v.Name())
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleToggleCombined switches between the combined list and the separate
+// Folders/Files panes. The layout creates the new views and moves focus there.
+func handleToggleCombined(g *gocui.Gui, state *AppState) error {
+	if state.IsCommanderPane() {
+		state.SetMessage("Commander mode always shows combined lists")
+		g.Update(func(gui *gocui.Gui) error { return nil })
+		return nil
 	}
-	if err := g.SetKeybinding(viewActionMenu, gocui.KeyEnter, gocui.ModNone, func(gui *gocui.Gui, view *gocui.View) error {
-		return handleMenuSelect(gui, view, state)
-	}); err != nil {
-		return err
+	if state.ToggleCombinedMode() {
+		state.SetMessage("Combined list (dirs first)")
+	} else {
+		state.SetMessage("Separate Folders/Files panes")
 	}
+	persistPreferences(state)
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Trigger layout update
+	})
+	return nil
+}
 
+// handleToggleSortOrder reverses the name order of the lists, keeping the
+// selected entry selected.
+func handleToggleSortOrder(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	item, hasSelection := state.SelectedItem(v.Name())
+	toggleSortOrder(g, state, item, hasSelection)
 	return nil
 }
 
-// quit is the keybinding handler for quitting the application.
-func quit(g *gocui.Gui, v *gocui.View) error {
-	return gocui.ErrQuit
+// toggleSortOrderAction reverses the name order from the action menu,
+// keeping item selected.
+func toggleSortOrderAction(g *gocui.Gui, item FileInfo, state *AppState) error {
+	toggleSortOrder(g, state, item, true)
+	return nil
 }
 
-// handleToggleHidden processes the toggle hidden keypress.
-func handleToggleHidden(g *gocui.Gui, state *AppState) error {
-	state.ToggleHidden()
-	// Reset focus to folders view for consistency after toggle
-	if _, err := g.SetCurrentView(viewFolders); err != nil {
-		log.Printf("Warning: Failed to set focus to folders after toggle: %v", err)
+// toggleSortOrder reverses the name order and moves the cursor back onto
+// item, which was selected before, if hasSelection.
+func toggleSortOrder(g *gocui.Gui, state *AppState, item FileInfo, hasSelection bool) {
+	if state.ToggleSortDescending() {
+		state.SetMessage("Sorted by name, descending (Z-A)")
+	} else {
+		state.SetMessage("Sorted by name, ascending (A-Z)")
 	}
-	// Explicitly update the view that will gain focus to reset its cursor display
-	// Update: Calling g.Update is simpler and ensures layout handles everything
-	// updateListView(g, state, viewFolders) // Force update
+	persistPreferences(state)
+	if !hasSelection || !selectPath(g, state, item.Path) {
+		g.Update(func(gui *gocui.Gui) error {
+			return nil // Trigger layout update
+		})
+	}
+}
+
+// handleToggleGrid switches the Files pane between the grid and a single
+// column. The single column recenters the cursor, which the grid may have
+// left below the rows a column shows.
+func handleToggleGrid(g *gocui.Gui, state *AppState) error {
+	message := "Grid layout in the Files pane"
+	if !state.ToggleGridMode() {
+		message = "Single-column Files pane"
+		if v, err := g.View(viewFiles); err == nil {
+			_, viewHeight := contentSize(v)
+			state.setCursorAndOrigin(viewFiles, state.GetCurrentCursorY(viewFiles), viewHeight)
+		}
+	}
+	if state.IsCombinedMode() {
+		message += " (the combined list stays a single column)"
+	}
+	state.SetMessage(message)
+	persistPreferences(state)
 	g.Update(func(gui *gocui.Gui) error {
 		return nil // Trigger layout update
 	})
+	return nil
+}
 
-	// Let layout handle the rest
+// handleResizePanels moves the split between the stats column and the lists.
+func handleResizePanels(g *gocui.Gui, state *AppState, delta float64) error {
+	if state.IsStatsHidden() {
+		key, _ := primaryKey(keyTable, "Lists", "layout.toggle-stats")
+		state.SetMessage(fmt.Sprintf("The stats column is hidden (%s shows it)", key))
+		g.Update(func(gui *gocui.Gui) error { return nil })
+		return nil
+	}
+	if !state.AdjustPanelRatio(delta) {
+		return nil // Already at the limit
+	}
+	persistPreferences(state)
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Layout picks up the new ratio
+	})
 	return nil
 }
 
+// persistPreferences saves the current UI preferences, reporting failures in
+// the message bar. Only the active tab's are saved: every tab holds a copy,
+// and an inactive one would overwrite the active tab's changes.
+func persistPreferences(state *AppState) {
+	if !tabs.IsActive(state) {
+		return
+	}
+	if err := savePreferences(state.Preferences()); err != nil {
+		log.Printf("Warning: Could not save preferences: %v", err)
+		state.SetMessage(fmt.Sprintf("Could not save preferences: %s", trimError(err)))
+	}
+}
+
+// primaryListView returns the list view that takes focus by default in the current layout.
+func primaryListView(state *AppState) string {
+	if state.IsCombinedMode() {
+		return viewCombined
+	}
+	return viewFolders
+}
+
 // handleMoveCursor handles arrow keys, page up/down, space, j, k, etc. for list views.
+// delta counts rows, which hold several entries in the Files grid.
 func handleMoveCursor(g *gocui.Gui, v *gocui.View, delta int, state *AppState) error {
 	if v == nil {
 		return nil
 	}
-	_, viewHeight := v.Size()
+	_, viewHeight := contentSize(v)
+	changed := state.moveCursorAndOrigin(v.Name(), delta*state.GridColumns(v.Name()), viewHeight)
+	// Only trigger update if state actually changed
+	if changed {
+		g.Update(func(gui *gocui.Gui) error {
+			return nil // Trigger layout update
+		})
+	}
+	return nil
+}
+
+// handleMoveColumn handles h, l and the left/right arrows, which move across
+// the columns of the Files grid. A single-column list has nowhere to go.
+func handleMoveColumn(g *gocui.Gui, v *gocui.View, delta int, state *AppState) error {
+	if v == nil || state.GridColumns(v.Name()) == 1 {
+		return nil
+	}
+	_, viewHeight := contentSize(v)
 	changed := state.moveCursorAndOrigin(v.Name(), delta, viewHeight)
 	// Only trigger update if state actually changed
 	if changed {
@@ -301,9 +448,8 @@ func handleGoTopBottom(g *gocui.Gui, v *gocui.View, toTop bool, state *AppState)
 	if v == nil {
 		return nil
 	}
-	_, viewHeight := v.Size()
-	list := state.GetCurrentList(v.Name())
-	listLen := len(list)
+	_, viewHeight := contentSize(v)
+	listLen := state.ListLen(v.Name())
 	newCursorY := 0
 	if !toTop {
 		if listLen > 0 {
@@ -327,29 +473,14 @@ func handleEnter(g *gocui.Gui, v *gocui.View, state *AppState) error {
 	}
 
 	viewName := v.Name()
-	currentList := state.GetCurrentList(viewName)
-	cursorY := state.GetCurrentCursorY(viewName)
-
-	if len(currentList) == 0 {
+	item, ok := state.SelectedItem(viewName)
+	if !ok {
 		return nil // Cannot select anything from an empty list
 	}
+	selectedItem := withEntryInfo(item)
 
-	if cursorY < 0 || cursorY >= len(currentList) {
-		log.Printf("Enter pressed with invalid cursor index %d for list length %d", cursorY, len(currentList))
-		return nil // Index out of bounds
-	}
-
-	selectedItem := currentList[cursorY]
-
-	// Define menu options based on item type
-	var options []ActionMenuItem
-	options = append(options, ActionMenuItem{Label: "Copy Full Path", ActionFn: copyFullPath})
-	options = append(options, ActionMenuItem{Label: "Copy Relative Path", ActionFn: copyRelativePath})
-	if !selectedItem.IsDir {
-		options = append(options, ActionMenuItem{Label: "View Content", ActionFn: viewFileContentAction})
-		options = append(options, ActionMenuItem{Label: "Copy Content (UTF-8)", ActionFn: copyContent})
-	}
-	options = append(options, ActionMenuItem{Label: "Cancel", ActionFn: func(*gocui.Gui, FileInfo, *AppState) error { return nil }}) // No-op cancel
+	// Only the registry actions that apply to the item are offered
+	options := actionsFor(selectedItem, state)
 
 	if len(options) > 0 {
 		state.OpenActionMenu(selectedItem, options, viewName)
@@ -361,6 +492,29 @@ func handleEnter(g *gocui.Gui, v *gocui.View, state *AppState) error {
 	return nil
 }
 
+// handleMarkForCompare marks the selected file as the left side of a diff, or
+// unmarks it if it already is.
+func handleMarkForCompare(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil {
+		return nil
+	}
+	item, ok := state.SelectedItem(v.Name())
+	if !ok {
+		return nil
+	}
+	if item.IsDir {
+		state.SetMessage("Only files can be compared")
+	} else if marked, ok := state.CompareMark(); ok && marked.Path == item.Path {
+		state.ClearCompareMark()
+		state.SetMessage(fmt.Sprintf("Unmarked '%s'", item.Name))
+	} else {
+		state.SetCompareMark(item)
+		state.SetMessage(fmt.Sprintf("Marked '%s'; choose 'Compare with Selected' on another file", item.Name))
+	}
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
 // handleMenuNavigate moves the selection in the action menu.
 func handleMenuNavigate(g *gocui.Gui, v *gocui.View, delta int, state *AppState) error {
 	state.NavigateActionMenu(delta)
@@ -386,7 +540,7 @@ func handleMenuSelect(g *gocui.Gui, v *gocui.View, state *AppState) error {
 
 	// Close the menu *before* executing the action (usually)
 	// except for actions that open a new view like "View Content"
-	closeMenuFirst := actionLabel != "View Content"
+	closeMenuFirst := actionLabel != "View Content" || selectedOption.Custom
 	if closeMenuFirst {
 		// Need to close menu and trigger update *before* executing action
 		state.CloseActionMenu()
@@ -404,7 +558,13 @@ func handleMenuSelect(g *gocui.Gui, v *gocui.View, state *AppState) error {
 	if actionErr != nil {
 		log.Printf("Action '%s' failed for %s: %v", actionLabel, targetItem.Name, actionErr)
 		errMsg := fmt.Sprintf("Error: %s - %v", actionLabel, actionErr)
-		state.SetMessage(trimError(fmt.Errorf(errMsg)))
+		state.SetMessage(trimError(errors.New(errMsg)))
+		if isVanishedError(actionErr) {
+			// The entry (or the whole CWD) is gone: refresh so the listing stops showing it
+			reloadDirectory(g, state, func(gui *gocui.Gui) {
+				state.SetMessage(fmt.Sprintf("'%s' no longer exists", targetItem.Name))
+			})
+		}
 		// If the failed action was view content, we still need to ensure the menu closes.
 		if actionLabel == "View Content" && state.IsActionMenuVisible() {
 			state.CloseActionMenu() // Force close state
@@ -414,15 +574,17 @@ func handleMenuSelect(g *gocui.Gui, v *gocui.View, state *AppState) error {
 		// View Content Action was successful, state.SetFileContentView was called by the action.
 		// Now close the action menu *after* successfully preparing the content view state.
 		state.CloseActionMenu()
-		state.ClearMessage() // Clear message after opening viewer
+		// When the pager ran instead, its exit status stays in the message bar
+		if state.IsFileContentViewVisible() {
+			state.ClearMessage() // Clear message after opening viewer
+		}
 		// Trigger layout update to show content view and hide menu
 		g.Update(func(gui *gocui.Gui) error { return nil })
-	} else if actionLabel != "Cancel" {
-		// Successful action other than View Content or Cancel
+	} else if strings.HasPrefix(actionLabel, "Copy") && !strings.HasPrefix(actionLabel, "Copy Content") && !selectedOption.Custom && !state.IsOverlayVisible() {
+		// Successful copy action. Other actions report their own outcome, as do
+		// copies that first ask something in a dialog and Copy Content's
+		// background read
 		successMsg := fmt.Sprintf("'%s' copied to clipboard", actionLabel)
-		if strings.HasPrefix(actionLabel, "Copy Content") {
-			successMsg = fmt.Sprintf("Content of '%s' copied", targetItem.Name)
-		}
 		state.SetMessage(successMsg)
 		// Menu was already closed and updated if closeMenuFirst was true.
 		// If it wasn't (e.g. cancel action), we still need an update for the message.
@@ -430,7 +592,7 @@ func handleMenuSelect(g *gocui.Gui, v *gocui.View, state *AppState) error {
 			g.Update(func(gui *gocui.Gui) error { return nil }) // Update UI for success message
 		}
 	} else {
-		// Cancel action - menu should be closed if closeMenuFirst was true
+		// Cancel or a non-copy action - menu should be closed if closeMenuFirst was true
 		// If not (logic error?), ensure update happens.
 		if !closeMenuFirst && state.IsActionMenuVisible() { // Should not happen for Cancel, but defensively...
 			state.CloseActionMenu()
@@ -441,6 +603,28 @@ func handleMenuSelect(g *gocui.Gui, v *gocui.View, state *AppState) error {
 	return nil // Errors handled via state.SetMessage
 }
 
+// handleMenuShortcut runs the action menu entry numbered key (1-9) or whose
+// mnemonic is key, as if it had been selected with Enter.
+func handleMenuShortcut(g *gocui.Gui, v *gocui.View, key rune, state *AppState) error {
+	options := state.GetActionMenuOptions()
+	idx := -1
+	if key >= '1' && key <= '9' {
+		idx = int(key - '1')
+	} else {
+		for i, option := range options {
+			if option.mnemonic() == unicode.ToLower(key) {
+				idx = i
+				break
+			}
+		}
+	}
+	if idx < 0 || idx >= len(options) {
+		return nil // No entry for this key
+	}
+	state.SelectActionMenuItem(idx)
+	return handleMenuSelect(g, v, state)
+}
+
 // handleMenuClose closes the action menu and returns focus.
 func handleMenuClose(g *gocui.Gui, v *gocui.View, state *AppState) error {
 	prevFocus := state.GetPreviousFocusView() // Get focus target BEFORE clearing state
@@ -513,34 +697,892 @@ func handleCloseFileContentView(g *gocui.Gui, v *gocui.View, state *AppState) er
 	return nil
 }
 
-// handleFocusSwitch switches focus between folders and files views using Tab.
-func handleFocusSwitch(g *gocui.Gui, state *AppState, forward bool) error {
-	// Don't switch focus if the action menu or file view is visible
-	if state.IsActionMenuVisible() || state.IsFileContentViewVisible() {
+// handlePromptSubmit validates the prompt's text and passes it to its submit
+// callback. On error the prompt stays open and the error is shown in the
+// message bar; on success the input is remembered for Up/Down recall.
+func handlePromptSubmit(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || !state.IsPromptVisible() {
+		return nil
+	}
+	input := strings.TrimSpace(v.Buffer())
+	spec := state.GetPrompt()
+	err := error(nil)
+	if spec.Validate != nil {
+		err = spec.Validate(input)
+	}
+	if err == nil && spec.OnSubmit != nil {
+		err = spec.OnSubmit(g, input, state)
+	}
+	if err != nil {
+		log.Printf("Prompt input %q rejected: %v", input, err)
+		state.SetMessage(fmt.Sprintf("Error: %s", trimError(err)))
+		g.Update(func(gui *gocui.Gui) error { return nil }) // Show error, keep prompt open
 		return nil
 	}
+	state.RecordPromptInput(input)
+	return closePrompt(g, state) // Keep the message set by OnSubmit
+}
 
-	currentView := g.CurrentView()
-	if currentView == nil {
-		_, err := g.SetCurrentView(viewFolders) // Default to folders if no focus
-		// Trigger UI update to reflect focus change (highlighting)
-		if err == nil {
-			g.Update(func(gui *gocui.Gui) error { return nil })
-		}
+// handlePromptCancel closes the prompt without submitting.
+func handlePromptCancel(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	onCancel := state.GetPrompt().OnCancel
+	state.ClearMessage()
+	if err := closePrompt(g, state); err != nil {
 		return err
 	}
+	if onCancel != nil {
+		onCancel(g, state)
+	}
+	return nil
+}
 
-	views := []string{viewFolders, viewFiles} // The views we cycle through
-	currentIdx := -1
-	for i, name := range views {
-		if name == currentView.Name() {
+// handlePromptHistory replaces the prompt's text with an older (delta < 0) or
+// newer input given to the same kind of prompt.
+func handlePromptHistory(g *gocui.Gui, v *gocui.View, delta int, state *AppState) error {
+	if v == nil {
+		return nil
+	}
+	if text, ok := state.StepPromptHistory(delta, strings.TrimRight(v.Buffer(), "\n")); ok {
+		setPromptText(v, text)
+	}
+	return nil
+}
+
+// setPromptText replaces the text of the prompt view v, with the cursor at its end.
+func setPromptText(v *gocui.View, text string) {
+	v.Clear()
+	_ = v.SetOrigin(0, 0)
+	fmt.Fprint(v, text)
+	_ = v.SetCursor(len([]rune(text)), 0)
+}
+
+// closePrompt hides the prompt and restores focus to the view it was opened from.
+func closePrompt(g *gocui.Gui, state *AppState) error {
+	prevFocus := state.GetPromptPrevFocus()
+	state.ClosePrompt()
+	restoreFocus(g, prevFocus, "prompt")
+	return nil
+}
+
+// handleConfirmKey answers a confirmation dialog with y or n.
+func handleConfirmKey(g *gocui.Gui, v *gocui.View, ch rune, state *AppState) error {
+	if !state.IsConfirmVisible() {
+		return nil
+	}
+	return finishConfirm(g, state, state.GetConfirm().decideKey(ch))
+}
+
+// handleConfirmTyped checks the text typed into a typed confirmation dialog.
+// A mismatch keeps the dialog open.
+func handleConfirmTyped(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || !state.IsConfirmVisible() {
+		return nil
+	}
+	spec := state.GetConfirm()
+	decision := spec.decideTyped(v.Buffer())
+	if decision == confirmPending {
+		state.SetMessage(fmt.Sprintf("Type '%s' exactly to confirm, or press Esc to cancel", spec.TypeToConfirm))
+		g.Update(func(gui *gocui.Gui) error { return nil })
+		return nil
+	}
+	return finishConfirm(g, state, decision)
+}
+
+// handleConfirmCancel dismisses a confirmation dialog.
+func handleConfirmCancel(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	return finishConfirm(g, state, confirmRejected)
+}
+
+// finishConfirm closes the dialog, restores focus and runs the callback for
+// decision. OnConfirm errors are shown in the message bar.
+func finishConfirm(g *gocui.Gui, state *AppState, decision confirmDecision) error {
+	if decision == confirmPending {
+		return nil
+	}
+	spec := state.GetConfirm()
+	state.CloseConfirm()
+	state.ClearMessage()
+	restoreFocus(g, state.GetConfirmPrevFocus(), "confirmation")
+	switch {
+	case decision == confirmAccepted && spec.OnConfirm != nil:
+		if err := spec.OnConfirm(g, state); err != nil {
+			log.Printf("Confirmed operation '%s' failed: %v", strings.TrimSpace(spec.Title), err)
+			state.SetMessage(fmt.Sprintf("Error: %s", trimError(err)))
+		}
+	case decision == confirmRejected && spec.OnCancel != nil:
+		spec.OnCancel(g, state)
+	}
+	return nil
+}
+
+// restoreFocus returns focus to prevFocus after an overlay (named by what) closes,
+// falling back to the folders view, and triggers a layout update.
+func restoreFocus(g *gocui.Gui, prevFocus string, what string) {
+	targetFocusView := viewFolders // Default fallback
+	if prevFocus != "" {
+		if _, err := g.View(prevFocus); err == nil {
+			targetFocusView = prevFocus
+		} else {
+			log.Printf("Warning: Previous focus view '%s' not found, defaulting to '%s'", prevFocus, viewFolders)
+		}
+	}
+	if _, err := g.SetCurrentView(targetFocusView); err != nil {
+		log.Printf("Error restoring focus to %s after closing %s: %v", targetFocusView, what, err)
+	}
+
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Trigger layout update to hide the overlay
+	})
+}
+
+// handleStartMark waits for the letter to record the selected item under.
+func handleStartMark(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	if _, ok := state.SelectedItem(v.Name()); !ok {
+		state.SetMessage("Nothing to mark")
+		return nil
+	}
+	state.StartMarkKey('m', v.Name())
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleStartJump waits for the letter of the mark to jump to.
+func handleStartJump(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	state.StartMarkKey('`', v.Name())
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleMarkLetter completes a pending mark or jump with letter. A jump
+// only looks in the current listing, switching the hidden mode if needed.
+func handleMarkLetter(g *gocui.Gui, v *gocui.View, letter rune, state *AppState) error {
+	kind, prevFocus := state.MarkKeyPending(), state.GetMarkPrevFocus()
+	state.EndMarkKey()
+	restoreFocus(g, prevFocus, "mark")
+	switch kind {
+	case 'm':
+		item, ok := state.SelectedItem(prevFocus)
+		if !ok {
+			return nil
+		}
+		state.SetMark(letter, item.Path)
+		state.SetMessage(fmt.Sprintf("Mark '%c' set on '%s'", letter, item.Name))
+	case '`':
+		path, ok := state.Mark(letter)
+		switch {
+		case !ok:
+			state.SetMessage(fmt.Sprintf("mark '%c' not set", letter))
+		case !selectPath(g, state, path):
+			state.SetMessage(fmt.Sprintf("mark '%c' no longer present", letter))
+		default:
+			state.ClearMessage()
+		}
+	}
+	return nil
+}
+
+// handleCancelMark drops a pending mark or jump.
+func handleCancelMark(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	state.EndMarkKey()
+	restoreFocus(g, state.GetMarkPrevFocus(), "mark")
+	return nil
+}
+
+// handleMarkPassthrough drops a pending mark or jump for a key that isn't a
+// mark letter, then runs handler, the key's binding in the list, there.
+func handleMarkPassthrough(g *gocui.Gui, handler func(*gocui.Gui, *gocui.View) error, state *AppState) error {
+	prevFocus := state.GetMarkPrevFocus()
+	state.EndMarkKey()
+	restoreFocus(g, prevFocus, "mark")
+	v, err := g.View(prevFocus)
+	if err != nil {
+		return nil // The list went away meanwhile
+	}
+	return handler(g, v)
+}
+
+// markKeyEditor gets the keys bound neither to mark letters nor in the
+// lists while m or ` waits: they just drop the pending mark or jump.
+func markKeyEditor(g *gocui.Gui, state *AppState) gocui.Editor {
+	return gocui.EditorFunc(func(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+		state.EndMarkKey()
+		restoreFocus(g, state.GetMarkPrevFocus(), "mark")
+	})
+}
+
+// handleShowProperties opens the properties popup for the selected list item.
+func handleShowProperties(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	item, ok := state.SelectedItem(v.Name())
+	if !ok {
+		return nil
+	}
+	openProperties(g, item, state, v.Name())
+	return nil
+}
+
+// showPropertiesAction is the action-menu entry for the properties popup.
+func showPropertiesAction(g *gocui.Gui, item FileInfo, state *AppState) error {
+	openProperties(g, item, state, state.GetPreviousFocusView())
+	return nil
+}
+
+// openProperties gathers metadata for item, shows the popup and, for directories,
+// starts the recursive size calculation in the background.
+func openProperties(g *gocui.Gui, item FileInfo, state *AppState, prevFocus string) {
+	props := gatherProperties(item)
+	gen := state.OpenProperties(item, props, prevFocus)
+
+	if props.Kind == "Directory" {
+		go func() {
+			size, items, err := dirUsage(item.Path, func() bool { return !state.IsPropertiesGen(gen) })
+			if err != nil {
+				log.Printf("Warning: Recursive size of %s failed: %v", item.Path, err)
+			}
+			state.SetPropertiesDirUsage(gen, size, items)
+			g.Update(func(gui *gocui.Gui) error { return nil })
+		}()
+	}
+
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Trigger layout update to show the popup
+	})
+}
+
+// handleCloseProperties closes the properties popup and restores focus.
+func handleCloseProperties(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	prevFocus := state.GetPropertiesPrevFocus()
+	state.CloseProperties()
+	restoreFocus(g, prevFocus, "properties")
+	return nil
+}
+
+// handleShowTopFiles opens the largest files overlay.
+func handleShowTopFiles(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	state.OpenTopFiles(v.Name())
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Trigger layout update to show the overlay
+	})
+	return nil
+}
+
+// handleTopFilesNavigate moves the selection in the largest files overlay.
+func handleTopFilesNavigate(g *gocui.Gui, v *gocui.View, delta int, state *AppState) error {
+	state.NavigateTopFiles(delta)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleTopFilesSelect jumps to the selected file if it is listed in the CWD,
+// otherwise copies its path to the clipboard.
+func handleTopFilesSelect(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	files := state.TopFiles()
+	idx := state.GetTopFilesSelectedIdx()
+	if idx < 0 || idx >= len(files) {
+		return handleCloseTopFiles(g, v, state)
+	}
+	file := files[idx]
+
+	state.CloseTopFiles()
+	if selectPath(g, state, file.Path) {
+		state.ClearMessage()
+		return nil
+	}
+
+	restoreFocus(g, state.GetTopFilesPrevFocus(), "largest files")
+	if err := copyToClipboard(state.Clipboard(), clipboardPath(file.Path)); err != nil {
+		state.SetMessage(fmt.Sprintf("Error: %s", trimError(err)))
+	} else {
+		state.SetMessage(fmt.Sprintf("Path of '%s' copied to clipboard", file.Name))
+	}
+	return nil
+}
+
+// handleCopyCwd copies the full path of the CWD, shown shortened in the Root Folder pane.
+func handleCopyCwd(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	path := clipboardPath(state.Cwd())
+	if err := copyToClipboard(state.Clipboard(), path); err != nil {
+		state.SetMessage(fmt.Sprintf("Error copying path: %s", trimError(err)))
+	} else {
+		state.SetMessage(fmt.Sprintf("'%s' copied to clipboard", path))
+	}
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleLargestLine moves the Largest File pane's selection between the file
+// and the folder line.
+func handleLargestLine(g *gocui.Gui, state *AppState, onDir bool) error {
+	state.SetLargestDirSelected(onDir)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleLargestSelect acts on the Largest File pane's selection. The file is
+// selected, changing to its folder first when it is below the CWD, and its
+// action menu opened; the folder is selected in the Folders pane.
+func handleLargestSelect(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if state.IsLoadingStats() {
+		return nil
+	}
+	if state.IsLargestDirSelected() {
+		dir := state.LargestDir()
+		if !selectPath(g, state, dir.Path) {
+			state.SetMessage(fmt.Sprintf("'%s' is not in the listing", dir.Name))
+		}
+		return nil
+	}
+	_, largest, _, _ := state.Stats()
+	if largest.Path == "" {
+		return nil // Empty directory or stats error
+	}
+	openMenu := func(gui *gocui.Gui) {
+		if !selectPath(gui, state, largest.Path) {
+			state.SetMessage(fmt.Sprintf("'%s' is not in the listing", largest.Name))
+			return
+		}
+		if err := handleEnter(gui, gui.CurrentView(), state); err != nil {
+			log.Printf("Error opening the action menu for %s: %v", largest.Path, err)
+		}
+	}
+	if dir := filepath.Dir(largest.Path); dir != state.Cwd() {
+		changeDirectory(g, state, dir, openMenu)
+		return nil
+	}
+	openMenu(g)
+	return nil
+}
+
+// handleCloseTopFiles closes the largest files overlay and restores focus.
+func handleCloseTopFiles(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	state.CloseTopFiles()
+	restoreFocus(g, state.GetTopFilesPrevFocus(), "largest files")
+	return nil
+}
+
+// fileHistoryAction opens the history overlay for a tracked file and starts
+// reading its commits.
+func fileHistoryAction(g *gocui.Gui, item FileInfo, state *AppState) error {
+	state.OpenHistory(item, state.GetPreviousFocusView())
+	loadHistoryPage(g, state)
+	return nil
+}
+
+// loadHistoryPage reads the next page of the open history in the background
+// if the selection is close to the end of the commits loaded so far.
+func loadHistoryPage(g *gocui.Gui, state *AppState) {
+	gen, target, after, ok := state.BeginHistoryPage()
+	if !ok {
+		return
+	}
+	go func() {
+		entries, err := loadFileHistory(state.Git(), target.Path, after, historyPageSize)
+		if err != nil {
+			log.Printf("Warning: Could not read the history of %s: %v", target.Path, err)
+		}
+		state.AddHistoryPage(gen, entries, err)
+		g.Update(func(gui *gocui.Gui) error { return nil })
+	}()
+}
+
+// handleHistoryNavigate moves the selection in the history overlay, loading
+// more commits as it nears the end.
+func handleHistoryNavigate(g *gocui.Gui, v *gocui.View, delta int, state *AppState) error {
+	_, viewHeight := contentSize(v)
+	state.NavigateHistory(delta, viewHeight)
+	loadHistoryPage(g, state)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleHistorySelect shows the selected commit's version of the file in the
+// content viewer; closing the viewer returns to the history.
+func handleHistorySelect(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	target, entries, _, _, _ := state.History()
+	idx, _ := state.HistoryPosition()
+	if idx < 0 || idx >= len(entries) {
+		return nil
+	}
+	entry := entries[idx]
+	content, err := loadFileRevision(state.Git(), filepath.Dir(target.Path), entry, state.ViewLimit())
+	if err == nil {
+		title := fmt.Sprintf("%s @ %s", target.Name, entry.Hash)
+		err = showTextContent(title, filepath.Base(entry.Path), content, viewHistory, state)
+	}
+	if err != nil {
+		state.SetMessage(fmt.Sprintf("Error: %s", trimError(err)))
+	}
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleCloseHistory closes the history overlay and restores focus.
+func handleCloseHistory(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	state.CloseHistory()
+	restoreFocus(g, state.GetHistoryPrevFocus(), "file history")
+	return nil
+}
+
+// handleShowMounts opens the drive / mount point picker and gathers the
+// mounts and their free space in the background.
+func handleShowMounts(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	state.OpenMounts(v.Name())
+	go func() {
+		entries, err := loadMounts()
+		if err != nil {
+			log.Printf("Warning: Could not list mount points: %v", err)
+		}
+		g.Update(func(gui *gocui.Gui) error {
+			if state.IsMountsVisible() {
+				state.SetMounts(entries, err)
+			}
+			return nil
+		})
+	}()
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Trigger layout update to show the overlay
+	})
+	return nil
+}
+
+// handleMountsNavigate moves the selection in the mount point picker.
+func handleMountsNavigate(g *gocui.Gui, v *gocui.View, delta int, state *AppState) error {
+	state.NavigateMounts(delta)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleMountsSelect changes to the selected mount point, or reports why it
+// can't be browsed (e.g. permission denied) and leaves the picker open.
+func handleMountsSelect(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	entries, _, _ := state.Mounts()
+	idx := state.GetMountsSelectedIdx()
+	if idx < 0 || idx >= len(entries) {
+		return nil
+	}
+	target := entries[idx].Path
+	if err := checkReadableDir(target); err != nil {
+		log.Printf("Cannot open mount point %s: %v", target, err)
+		state.SetMessage(fmt.Sprintf("Error: Cannot open %s - %s", target, trimError(err)))
+		g.Update(func(gui *gocui.Gui) error { return nil })
+		return nil
+	}
+
+	state.CloseMounts()
+	restoreFocus(g, primaryListView(state), "mount points")
+	changeDirectory(g, state, target, nil)
+	return nil
+}
+
+// handleCloseMounts closes the mount point picker and restores focus.
+func handleCloseMounts(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	state.CloseMounts()
+	restoreFocus(g, state.GetMountsPrevFocus(), "mount points")
+	return nil
+}
+
+// handleShowTasks opens the list of running and finished background tasks.
+func handleShowTasks(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	if len(state.Tasks()) == 0 {
+		state.SetMessage("No background tasks")
+	} else {
+		state.OpenTasks(v.Name())
+	}
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Trigger layout update to show the overlay
+	})
+	return nil
+}
+
+// handleTasksNavigate moves the selection in the task list.
+func handleTasksNavigate(g *gocui.Gui, v *gocui.View, delta int, state *AppState) error {
+	state.NavigateTasks(delta)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleCancelTask cancels the selected task. It stops at its next progress
+// step and then shows up as cancelled.
+func handleCancelTask(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if name, ok := state.CancelSelectedTask(); ok {
+		state.SetMessage(fmt.Sprintf("Cancelling: %s", name))
+	} else {
+		state.SetMessage("The selected task is not running")
+	}
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleCloseTasks closes the task list and restores focus.
+func handleCloseTasks(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	state.CloseTasks()
+	restoreFocus(g, state.GetTasksPrevFocus(), "tasks")
+	return nil
+}
+
+// handleShowMessages opens the message history overlay.
+func handleShowMessages(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	state.OpenMessages(v.Name())
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Trigger layout update to show the overlay
+	})
+	return nil
+}
+
+// handleScrollMessages scrolls the message history overlay by delta lines.
+func handleScrollMessages(g *gocui.Gui, v *gocui.View, delta int, state *AppState) error {
+	if v == nil {
+		return nil
+	}
+	_, viewHeight := contentSize(v)
+	state.ScrollMessages(delta, viewHeight)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleCloseMessages closes the message history overlay and restores focus.
+func handleCloseMessages(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	state.CloseMessages()
+	restoreFocus(g, state.GetMessagesPrevFocus(), "message history")
+	return nil
+}
+
+// handleShowStatsErrors opens the overlay listing the paths the stats walk
+// could not read.
+func handleShowStatsErrors(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	if _, count := state.StatsErrors(); count == 0 {
+		state.SetMessage("The last stats scan had no errors")
+	} else {
+		state.OpenStatsErrors(v.Name())
+	}
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Trigger layout update to show the overlay
+	})
+	return nil
+}
+
+// handleScrollStatsErrors scrolls the stats errors overlay by delta lines.
+func handleScrollStatsErrors(g *gocui.Gui, v *gocui.View, delta int, state *AppState) error {
+	if v == nil {
+		return nil
+	}
+	_, viewHeight := contentSize(v)
+	state.ScrollStatsErrors(delta, viewHeight)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleCloseStatsErrors closes the stats errors overlay and restores focus.
+func handleCloseStatsErrors(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	state.CloseStatsErrors()
+	restoreFocus(g, state.GetStatsErrorsPrevFocus(), "stats errors")
+	return nil
+}
+
+// handleShowCleanup opens the report of empty folders and zero-byte files
+// the last stats scan found.
+func handleShowCleanup(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	if _, dirCount, fileCount := state.CleanupEntries(); dirCount+fileCount > 0 {
+		state.OpenCleanup(v.Name())
+	} else if state.IsLoadingStats() {
+		state.SetMessage("The size scan is still looking for empty entries")
+	} else {
+		state.SetMessage("No empty folders or zero-byte files found")
+	}
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Trigger layout update to show the overlay
+	})
+	return nil
+}
+
+// handleCleanupNavigate moves the selection in the cleanup report.
+func handleCleanupNavigate(g *gocui.Gui, v *gocui.View, delta int, state *AppState) error {
+	_, viewHeight := contentSize(v)
+	state.NavigateCleanup(delta, viewHeight)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleCleanupSelect closes the report and selects the chosen entry,
+// changing to its parent folder first when that isn't the CWD.
+func handleCleanupSelect(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	entries, _, _ := state.CleanupEntries()
+	idx, _ := state.CleanupPosition()
+	if idx < 0 || idx >= len(entries) {
+		return handleCloseCleanup(g, v, state)
+	}
+	entry := entries[idx]
+
+	state.CloseCleanup()
+	restoreFocus(g, state.GetCleanupPrevFocus(), "cleanup report")
+	selectEntry := func(gui *gocui.Gui) {
+		if selectPath(gui, state, entry.Path) {
+			state.ClearMessage()
+		} else {
+			state.SetMessage(fmt.Sprintf("'%s' is not in the listing", entry.Name))
+		}
+	}
+	if dir := filepath.Dir(entry.Path); dir != state.Cwd() {
+		changeDirectory(g, state, dir, selectEntry)
+		return nil
+	}
+	selectEntry(g)
+	return nil
+}
+
+// handleCloseCleanup closes the cleanup report and restores focus.
+func handleCloseCleanup(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	state.CloseCleanup()
+	restoreFocus(g, state.GetCleanupPrevFocus(), "cleanup report")
+	return nil
+}
+
+// handleShowBrokenLinks opens the list of symlinks the last stats scan found dangling.
+func handleShowBrokenLinks(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	if _, count := state.BrokenLinks(); count > 0 {
+		state.OpenBrokenLinks(v.Name())
+	} else if state.IsLoadingStats() {
+		state.SetMessage("The size scan is still looking for broken links")
+	} else {
+		state.SetMessage("No broken links found")
+	}
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Trigger layout update to show the overlay
+	})
+	return nil
+}
+
+// handleBrokenLinksNavigate moves the selection in the broken links overlay.
+func handleBrokenLinksNavigate(g *gocui.Gui, v *gocui.View, delta int, state *AppState) error {
+	_, viewHeight := contentSize(v)
+	state.NavigateBrokenLinks(delta, viewHeight)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleBrokenLinksSelect closes the overlay, selects the chosen link in its
+// folder and opens the action menu for it, where it can be retargeted or deleted.
+func handleBrokenLinksSelect(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	links, _ := state.BrokenLinks()
+	idx, _ := state.BrokenLinksPosition()
+	if idx < 0 || idx >= len(links) {
+		return handleCloseBrokenLinks(g, v, state)
+	}
+	link := links[idx]
+
+	state.CloseBrokenLinks()
+	restoreFocus(g, state.GetBrokenLinksPrevFocus(), "broken links")
+	openMenu := func(gui *gocui.Gui) {
+		if !selectPath(gui, state, link.Path) {
+			state.SetMessage(fmt.Sprintf("'%s' is not in the listing", filepath.Base(link.Path)))
+			return
+		}
+		if err := handleEnter(gui, gui.CurrentView(), state); err != nil {
+			log.Printf("Error opening the action menu for %s: %v", link.Path, err)
+		}
+	}
+	if dir := filepath.Dir(link.Path); dir != state.Cwd() {
+		changeDirectory(g, state, dir, openMenu)
+		return nil
+	}
+	openMenu(g)
+	return nil
+}
+
+// handleCloseBrokenLinks closes the broken links overlay and restores focus.
+func handleCloseBrokenLinks(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	state.CloseBrokenLinks()
+	restoreFocus(g, state.GetBrokenLinksPrevFocus(), "broken links")
+	return nil
+}
+
+// handleShowPalette opens the command palette for the focused list.
+func handleShowPalette(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	state.OpenPalette(paletteEntries(state, v.Name()), v.Name())
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handlePaletteNavigate moves the highlight in the palette.
+func handlePaletteNavigate(g *gocui.Gui, v *gocui.View, delta int, state *AppState) error {
+	state.NavigatePalette(delta)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handlePaletteRun closes the palette and runs the highlighted command from
+// the view the palette was opened from.
+func handlePaletteRun(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	matches, selectedIdx := state.PaletteMatches()
+	if selectedIdx < 0 || selectedIdx >= len(matches) {
+		return nil // Nothing matches the query
+	}
+	if err := handleClosePalette(g, v, state); err != nil {
+		return err
+	}
+	return matches[selectedIdx].Run(g, state)
+}
+
+// handleClosePalette hides the palette and restores focus.
+func handleClosePalette(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	prevFocus := state.GetPalettePrevFocus()
+	state.ClosePalette()
+	restoreFocus(g, prevFocus, "command palette")
+	return nil
+}
+
+// handleShowHelp opens the keybinding help overlay.
+func handleShowHelp(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	state.OpenHelp(v.Name())
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleScrollHelp scrolls the help overlay by delta lines.
+func handleScrollHelp(g *gocui.Gui, v *gocui.View, delta int, state *AppState) error {
+	if v == nil {
+		return nil
+	}
+	_, viewHeight := contentSize(v)
+	state.ScrollHelp(delta, len(helpLines(keyTable)), viewHeight)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleCloseHelp closes the help overlay and restores focus.
+func handleCloseHelp(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	state.CloseHelp()
+	restoreFocus(g, state.GetHelpPrevFocus(), "help")
+	return nil
+}
+
+// handleShowExtStats opens the extension breakdown overlay.
+func handleShowExtStats(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	state.OpenExtStats(v.Name())
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Trigger layout update to show the overlay
+	})
+	return nil
+}
+
+// handleScrollExtStats scrolls the extension breakdown overlay by delta lines.
+func handleScrollExtStats(g *gocui.Gui, v *gocui.View, delta int, state *AppState) error {
+	if v == nil {
+		return nil
+	}
+	_, viewHeight := contentSize(v)
+	state.ScrollExtStats(delta, viewHeight)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleCloseExtStats closes the extension breakdown overlay and restores focus.
+func handleCloseExtStats(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	state.CloseExtStats()
+	restoreFocus(g, state.GetExtStatsPrevFocus(), "extension stats")
+	return nil
+}
+
+// selectPath moves the cursor of the list containing path onto it, switching the
+// hidden mode if necessary, and focuses that list. Returns false if path isn't listed.
+func selectPath(g *gocui.Gui, state *AppState, path string) bool {
+	viewName, idx, mode, found := state.FindInLists(path)
+	if !found {
+		return false
+	}
+	if mode != state.HiddenMode() {
+		state.SetHiddenMode(mode)
+	}
+	viewHeight := 1
+	if v, err := g.View(viewName); err == nil {
+		_, viewHeight = contentSize(v)
+	}
+	state.setCursorAndOrigin(viewName, idx, viewHeight)
+	if _, err := g.SetCurrentView(viewName); err != nil {
+		log.Printf("Error focusing %s to select %s: %v", viewName, path, err)
+	}
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Trigger layout update to show the new selection
+	})
+	return true
+}
+
+// handleFocusSwitch cycles focus through the list views and the Largest File pane using Tab.
+func handleFocusSwitch(g *gocui.Gui, state *AppState, forward bool) error {
+	// Don't switch focus if the action menu or file view is visible
+	if state.IsActionMenuVisible() || state.IsFileContentViewVisible() {
+		return nil
+	}
+	// In commander mode Tab switches between the two browsers
+	if state.IsCommanderPane() {
+		return switchCommanderPane(g)
+	}
+	views := []string{viewFolders, viewFiles, viewLargest, viewStatus, viewGit} // The views we cycle through
+	if state.IsCombinedMode() {
+		views = []string{viewCombined, viewLargest, viewStatus, viewGit}
+	}
+	if _, err := g.View(viewStatus); err != nil {
+		views = views[:len(views)-3] // The stats column is hidden, or too short to show
+	}
+
+	currentView := g.CurrentView()
+	if currentView == nil {
+		_, err := g.SetCurrentView(views[0]) // Default to the first list if no focus
+		// Trigger UI update to reflect focus change (highlighting)
+		if err == nil {
+			g.Update(func(gui *gocui.Gui) error { return nil })
+		}
+		return err
+	}
+
+	currentIdx := -1
+	for i, name := range views {
+		if name == currentView.Name() {
 			currentIdx = i
 			break
 		}
 	}
 
 	if currentIdx == -1 { // Current view is not one of the cyclable views
-		_, err := g.SetCurrentView(viewFolders) // Default to folders
+		_, err := g.SetCurrentView(views[0]) // Default to the first list
 		if err == nil {
 			g.Update(func(gui *gocui.Gui) error { return nil })
 		}
@@ -575,7 +1617,7 @@ func handleFocusSwitch(g *gocui.Gui, state *AppState, forward bool) error {
 
 // copyFullPath copies the item's absolute path to the clipboard.
 func copyFullPath(g *gocui.Gui, item FileInfo, state *AppState) error {
-	return copyToClipboard(item.Path)
+	return copyToClipboard(state.Clipboard(), clipboardPath(item.Path))
 }
 
 // copyRelativePath copies the item's path relative to CWD to the clipboard.
@@ -585,76 +1627,450 @@ func copyRelativePath(g *gocui.Gui, item FileInfo, state *AppState) error {
 		log.Printf("Error getting relative path for '%s' from '%s': %v", item.Path, state.Cwd(), err)
 		return fmt.Errorf("could not determine relative path")
 	}
-	return copyToClipboard(relPath)
+	return copyToClipboard(state.Clipboard(), clipboardPath(relPath))
+}
+
+// copyFileURL copies the item's file:// URL to the clipboard.
+func copyFileURL(g *gocui.Gui, item FileInfo, state *AppState) error {
+	return copyToClipboard(state.Clipboard(), fileURL(item.Path))
+}
+
+// copyScpPath copies the item's path as an scp source, user@host:/abs/path,
+// to the clipboard.
+func copyScpPath(g *gocui.Gui, item FileInfo, state *AppState) error {
+	path, err := scpPath(item.Path)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return err
+	}
+	return copyToClipboard(state.Clipboard(), path)
 }
 
-// copyContent reads a file's content and copies it to the clipboard.
+// copyContent reads a file's content and copies it to the clipboard, in the
+// background (cancel it from the task list). A file over the copy limit isn't
+// refused outright: a dialog offers its first bytes.
 func copyContent(g *gocui.Gui, item FileInfo, state *AppState) error {
 	if item.IsDir {
 		return fmt.Errorf("cannot copy content of a directory")
 	}
+	limit := state.CopyLimit()
+	if info, err := state.Files().Stat(item.Path); err == nil && info.Size() > limit {
+		state.OpenConfirm(confirmSpec{
+			Title:   " Copy Content ",
+			Message: fmt.Sprintf("'%s' is %s, over the %s copy limit.", item.Name, formatSize(info.Size()), formatSize(limit)),
+			Details: []string{fmt.Sprintf("Copy the first %s anyway?", formatSize(limit))},
+			OnConfirm: func(gui *gocui.Gui, state *AppState) error {
+				startTask(gui, state, fmt.Sprintf("Copy the first %s of '%s'", formatSize(limit), item.Name), copyHeadBytesTask(item, state), nil)
+				return nil
+			},
+		}, state.GetPreviousFocusView())
+		return nil
+	}
+	startTask(g, state, fmt.Sprintf("Copy content of '%s'", item.Name), copyContentTask(item, state), nil)
+	return nil
+}
+
+// copyContentTask reads the whole of item, which is within the copy limit,
+// in chunks, and copies it to the clipboard.
+func copyContentTask(item FileInfo, state *AppState) taskFunc {
+	return func(ctx context.Context, report func(done, total int64)) (string, error) {
+		content, err := readWithinLimit(ctx, state.Files(), item.Path, state.CopyLimit(), report)
+		if err != nil {
+			return "", err // Also when cancelled during the read: the clipboard is left alone
+		}
+		// Clipboard interaction might fail with non-UTF8, but let the clipboard library handle it.
+		if err := copyToClipboard(state.Clipboard(), string(content)); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Content of '%s' copied", item.Name), nil
+	}
+}
+
+// copyHeadBytesTask copies the first copy-limit bytes of item.
+func copyHeadBytesTask(item FileInfo, state *AppState) taskFunc {
+	return func(ctx context.Context, report func(done, total int64)) (string, error) {
+		content, err := readHeadBytes(ctx, state.Files(), item.Path, state.CopyLimit(), report)
+		if err != nil {
+			return "", err
+		}
+		if err := copyToClipboard(state.Clipboard(), string(content)); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("First %s of '%s' copied", formatSize(int64(len(content))), item.Name), nil
+	}
+}
+
+// defaultCopyLines pre-fills the line count prompt of the head and tail copies.
+const defaultCopyLines = 100
+
+// copyHeadAction prompts for a line count and copies that many lines from the start of the file.
+func copyHeadAction(g *gocui.Gui, item FileInfo, state *AppState) error {
+	openCopyLines(item, state, false)
+	return nil
+}
+
+// copyTailAction prompts for a line count and copies that many lines from the end of the file.
+func copyTailAction(g *gocui.Gui, item FileInfo, state *AppState) error {
+	openCopyLines(item, state, true)
+	return nil
+}
+
+// openCopyLines asks how many lines of item to copy, from its end if fromEnd.
+// Only those lines are read, in the background, so this works on files of any
+// size; the copy is still capped at the copy limit.
+func openCopyLines(item FileInfo, state *AppState, fromEnd bool) {
+	which := "first"
+	if fromEnd {
+		which = "last"
+	}
+	state.OpenPrompt(promptSpec{
+		Kind:    "copy-lines",
+		Title:   fmt.Sprintf(" Copy the %s N lines of %s ", which, item.Name),
+		Initial: strconv.Itoa(defaultCopyLines),
+		Validate: func(input string) error {
+			if n, err := strconv.Atoi(strings.TrimSpace(input)); err != nil || n < 1 {
+				return fmt.Errorf("enter a number of lines (1 or more)")
+			}
+			return nil
+		},
+		OnSubmit: func(gui *gocui.Gui, input string, state *AppState) error {
+			n, _ := strconv.Atoi(strings.TrimSpace(input)) // Checked by Validate
+			name := fmt.Sprintf("Copy the %s %s of '%s'", which, pluralize(n, "line", "lines"), item.Name)
+			startTask(gui, state, name, copyLinesTask(item, state, n, fromEnd), nil)
+			return nil
+		},
+	}, state.GetPreviousFocusView())
+}
+
+// copyLinesTask copies the first n lines of item, or the last n if fromEnd.
+func copyLinesTask(item FileInfo, state *AppState, n int, fromEnd bool) taskFunc {
+	which, read := "first", readHeadLines
+	if fromEnd {
+		which, read = "last", readTailLines
+	}
+	return func(ctx context.Context, report func(done, total int64)) (string, error) {
+		limit := state.CopyLimit()
+		content, capped, err := read(ctx, state.Files(), item.Path, n, limit, report)
+		if err != nil {
+			return "", err
+		}
+		if err := copyToClipboard(state.Clipboard(), string(content)); err != nil {
+			return "", err
+		}
+		if capped {
+			return fmt.Sprintf("The %s %s of '%s' copied (capped at %s)", which, pluralize(n, "line", "lines"), item.Name, formatSize(limit)), nil
+		}
+		return fmt.Sprintf("The %s %s of '%s' copied", which, pluralize(n, "line", "lines"), item.Name), nil
+	}
+}
+
+// compareWithMarkAction shows a unified diff from the file marked for compare
+// to item in the content viewer. The diff is computed in a background task,
+// which can be cancelled like a copy.
+func compareWithMarkAction(g *gocui.Gui, item FileInfo, state *AppState) error {
+	marked, ok := state.CompareMark()
+	if !ok {
+		return fmt.Errorf("no file marked for compare (press d)")
+	}
+	prevFocus := state.GetPreviousFocusView()
+	if prevFocus == "" {
+		prevFocus = primaryListView(state)
+	}
+	var diff string
+	run := func(ctx context.Context, report func(done, total int64)) (string, error) {
+		var err error
+		diff, err = diffFiles(ctx, state.Files(), marked.Path, item.Path, marked.Name, item.Name)
+		if errors.Is(err, errFilesIdentical) {
+			return fmt.Sprintf("'%s' and '%s': files are identical", marked.Name, item.Name), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Compared '%s' with '%s'", marked.Name, item.Name), nil
+	}
+	startTask(g, state, fmt.Sprintf("Compare '%s' with '%s'", marked.Name, item.Name), run, func(gui *gocui.Gui, err error) {
+		if err != nil || diff == "" {
+			return
+		}
+		title := fmt.Sprintf("%s vs %s", marked.Name, item.Name)
+		state.SetFileContentView(title, colorizeDiff(strings.ReplaceAll(diff, "\t", "    ")), prevFocus)
+	})
+	return nil
+}
+
+// duplicateAction copies the item next to itself in the background, then
+// reloads the listing and selects the copy.
+func duplicateAction(g *gocui.Gui, item FileInfo, state *AppState) error {
+	var dst, result string
+	run := func(ctx context.Context, report func(done, total int64)) (string, error) {
+		var err error
+		dst, err = duplicateEntry(ctx, item.Path, item.IsDir, report)
+		if err != nil {
+			log.Printf("Error duplicating %s: %v", item.Path, err)
+			return "", err
+		}
+		result = fmt.Sprintf("Duplicated '%s' as '%s'", item.Name, filepath.Base(dst))
+		return result, nil
+	}
+	startTask(g, state, fmt.Sprintf("Duplicate '%s'", item.Name), run, func(gui *gocui.Gui, err error) {
+		if err != nil {
+			return
+		}
+		reloadDirectory(gui, state, func(gui *gocui.Gui) {
+			selectPath(gui, state, dst)
+			state.SetMessage(result)
+		})
+	})
+	return nil
+}
+
+// handleRename starts renaming the selected entry in place, without going
+// through the action menu.
+func handleRename(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	item, ok := state.SelectedItem(v.Name())
+	if !ok {
+		return nil
+	}
+	openRename(g, item, state, v.Name())
+	return nil
+}
+
+// renameAction is the action-menu entry for renaming.
+func renameAction(g *gocui.Gui, item FileInfo, state *AppState) error {
+	openRename(g, item, state, state.GetPreviousFocusView())
+	return nil
+}
+
+// openRename shows a prompt pre-filled with item's name over its row in
+// viewName. Once renamed, the cursor follows the entry to its new position.
+func openRename(g *gocui.Gui, item FileInfo, state *AppState, viewName string) {
+	state.OpenPrompt(promptSpec{
+		Kind:    "rename",
+		Title:   fmt.Sprintf(" Rename %s ", item.Name),
+		Initial: item.Name,
+		Anchor:  viewName,
+		Validate: func(input string) error {
+			return validateNewName(item.Path, input)
+		},
+		OnSubmit: func(gui *gocui.Gui, input string, state *AppState) error {
+			if input == item.Name {
+				state.SetMessage("Name unchanged")
+				return nil
+			}
+			newPath, err := renameEntry(item.Path, input)
+			if err != nil {
+				return err
+			}
+			if marked, ok := state.CompareMark(); ok && marked.Path == item.Path {
+				state.ClearCompareMark() // The mark would point at a path that no longer exists
+			}
+			reloadDirectory(gui, state, func(gui *gocui.Gui) {
+				selectPath(gui, state, newPath)
+				state.SetMessage(fmt.Sprintf("Renamed '%s' to '%s'", item.Name, input))
+			})
+			return nil
+		},
+	}, viewName)
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Trigger layout update to show the prompt
+	})
+}
 
-	// Use the shared ReadFileWithLimit function
-	content, err := ReadFileWithLimit(item.Path, maxCopySize)
+// retargetLinkAction opens a prompt for a new symlink target and swaps it in
+// by renaming a fresh link over the old one, so the link never goes missing.
+func retargetLinkAction(g *gocui.Gui, item FileInfo, state *AppState) error {
+	current, err := os.Readlink(item.Path)
 	if err != nil {
-		return err // Error already formatted by ReadFileWithLimit
+		return fmt.Errorf("could not read link: %w", err)
+	}
+	state.OpenPrompt(promptSpec{
+		Kind:    "symlink",
+		Title:   fmt.Sprintf(" Target of %s ", item.Name),
+		Initial: current,
+		Validate: func(input string) error {
+			if strings.TrimSpace(input) == "" {
+				return errors.New("the target can't be empty")
+			}
+			return nil
+		},
+		OnSubmit: func(gui *gocui.Gui, input string, state *AppState) error {
+			target := strings.TrimSpace(input)
+			tmp := filepath.Join(filepath.Dir(item.Path), fmt.Sprintf(".%s.lazyls-%d", item.Name, os.Getpid()))
+			if err := os.Symlink(target, tmp); err != nil {
+				return err
+			}
+			if err := os.Rename(tmp, item.Path); err != nil {
+				os.Remove(tmp)
+				return err
+			}
+			message := fmt.Sprintf("'%s' now points to %s", item.Name, target)
+			if _, broken := checkSymlink(item.Path); broken {
+				message += " (still broken)"
+			}
+			reloadDirectory(gui, state, func(gui *gocui.Gui) {
+				selectPath(gui, state, item.Path)
+				state.SetMessage(message)
+			})
+			return nil
+		},
+	}, state.GetPreviousFocusView())
+	return nil
+}
+
+// deleteLinkAction asks for confirmation and removes a symlink (never its target).
+func deleteLinkAction(g *gocui.Gui, item FileInfo, state *AppState) error {
+	state.OpenConfirm(removalConfirm(item, 0, 0, func(gui *gocui.Gui, state *AppState) error {
+		if err := os.Remove(item.Path); err != nil {
+			return err
+		}
+		reloadDirectory(gui, state, func(gui *gocui.Gui) {
+			state.SetMessage(fmt.Sprintf("Deleted link '%s'", item.Name))
+		})
+		return nil
+	}), state.GetPreviousFocusView())
+	return nil
+}
+
+// deleteAction asks for confirmation and removes item, a folder with
+// everything in it. A folder is measured first so the dialog can say what
+// goes; a big one must have its name typed. The removal runs as a task.
+func deleteAction(g *gocui.Gui, item FileInfo, state *AppState) error {
+	prevFocus := state.GetPreviousFocusView()
+	remove := func(gui *gocui.Gui, state *AppState) error {
+		run := func(ctx context.Context, report func(done, total int64)) (string, error) {
+			if err := os.RemoveAll(item.Path); err != nil {
+				log.Printf("Error deleting %s: %v", item.Path, err)
+				return "", err
+			}
+			return fmt.Sprintf("Deleted '%s'", item.Name), nil
+		}
+		startTask(gui, state, fmt.Sprintf("Delete '%s'", item.Name), run, func(gui *gocui.Gui, err error) {
+			reloadDirectory(gui, state, nil) // Also after a failure, which may have removed part of a folder
+		})
+		return nil
+	}
+	if !item.IsDir {
+		state.OpenConfirm(removalConfirm(item, 0, 0, remove), prevFocus)
+		return nil
 	}
+	state.SetMessage(fmt.Sprintf("Measuring '%s'...", item.Name))
+	go func() {
+		size, entries, _ := dirUsage(item.Path, nil) // Walk errors are logged; the counts stay partial
+		g.Update(func(gui *gocui.Gui) error {
+			state.ClearMessage()
+			if state.IsOverlayVisible() {
+				return nil // Something else was opened meanwhile; the delete is dropped
+			}
+			state.OpenConfirm(removalConfirm(item, entries, size, remove), prevFocus)
+			return nil
+		})
+	}()
+	return nil
+}
 
-	if content == nil { // File was empty
-		return copyToClipboard("")
+// changePermissions opens a prompt for a new mode (octal or symbolic) and applies it with os.Chmod.
+func changePermissions(g *gocui.Gui, item FileInfo, state *AppState) error {
+	info, err := os.Stat(item.Path) // chmod follows symlinks, so Stat the target
+	if err != nil {
+		return fmt.Errorf("could not stat: %w", err)
 	}
+	current := info.Mode()
 
-	// Clipboard interaction might fail with non-UTF8, but let the clipboard library handle it.
-	return copyToClipboard(string(content))
+	title := fmt.Sprintf(" Mode for %s (%s) ", item.Name, current.Perm().String())
+	state.OpenPrompt(promptSpec{
+		Kind:    "chmod",
+		Title:   title,
+		Initial: formatOctalMode(current),
+		Validate: func(input string) error {
+			_, err := parsePermissions(input, current, info.IsDir())
+			return err
+		},
+		OnSubmit: func(gui *gocui.Gui, input string, state *AppState) error {
+			newMode, _ := parsePermissions(input, current, info.IsDir()) // Checked by Validate
+			if err := os.Chmod(item.Path, newMode); err != nil {
+				return err
+			}
+			updated, err := os.Stat(item.Path)
+			if err != nil {
+				state.SetMessage(fmt.Sprintf("Permissions of '%s' changed", item.Name))
+				return nil
+			}
+			state.SetMessage(fmt.Sprintf("Permissions of '%s' set to %s", item.Name, updated.Mode().String()))
+			return nil
+		},
+	}, state.GetPreviousFocusView())
+	return nil
 }
 
-// viewFileContentAction reads a file and updates the state to show the content view.
+// viewFileContentAction reads a file and updates the state to show the content view,
+// or hands it to the pager when that is the configured default viewer.
 // NOTE: This function now only updates the state. The menu closing and UI update
 // are handled in handleMenuSelect *after* this function returns successfully.
 func viewFileContentAction(g *gocui.Gui, item FileInfo, state *AppState) error {
 	if item.IsDir {
 		return fmt.Errorf("cannot view content of a directory")
 	}
+	if usePagerByDefault && runPager(g, item, state) {
+		return nil // Viewed in the pager; the built-in viewer is the fallback
+	}
+	return showFileContent(item, state)
+}
+
+// showFileContent loads a file into the built-in content viewer.
+func showFileContent(item FileInfo, state *AppState) error {
+	// Get the current focus *before* the menu closes in handleMenuSelect
+	// This requires knowing the focus *before* the action menu was opened.
+	currentFocus := state.GetPreviousFocusView() // Focus from before menu opened
+	if currentFocus == "" {                      // Fallback if state wasn't set correctly
+		log.Println("Warning: Previous focus view unknown when opening file content, defaulting to the main list")
+		currentFocus = primaryListView(state)
+	}
+	if err := loadFileContent(item, currentFocus, state); err != nil {
+		return err
+	}
+	state.SetFileContentItem(item)
+	return nil
+}
 
-	// Use the shared ReadFileWithLimit function
-	contentBytes, err := ReadFileWithLimit(item.Path, maxViewSize) // Use maxViewSize limit
+// loadFileContent reads a file into the content viewer, returning focus to
+// prevFocus when it closes.
+func loadFileContent(item FileInfo, prevFocus string, state *AppState) error {
+	if isPreviewableImage(item.Name) {
+		return showImagePreview(item, prevFocus, state)
+	}
+	contentBytes, err := readFileWithSetting(state.Files(), item.Path, state.ViewLimit())
 	if err != nil {
 		return err // Return the formatted error
 	}
+	return showTextContent(item.Name, item.Name, contentBytes, prevFocus, state)
+}
 
+// showTextContent puts text into the content viewer under title, formatted by
+// the formatter for name (Markdown, JSON, ...) if there is one. The content's
+// sniffed MIME type is added to the title; binary content is refused.
+func showTextContent(title, name string, contentBytes []byte, prevFocus string, state *AppState) error {
 	var content string
-	if contentBytes != nil {
-		// Naive check for binary content (look for null bytes).
-		isNullTerminated := false
-		for _, b := range contentBytes {
-			if b == 0 {
-				isNullTerminated = true
-				break
-			}
-		}
-
-		if isNullTerminated {
-			return fmt.Errorf("cannot display binary file content")
-		} else {
-			content = string(contentBytes)
-			// Replace tabs with spaces for consistent rendering
-			content = strings.ReplaceAll(content, "\t", "    ")
+	if len(contentBytes) > 0 {
+		text, mimeType, ok := textContent(contentBytes)
+		if !ok {
+			return fmt.Errorf("cannot display binary file content (%s)", mimeType)
 		}
-
+		contentBytes = text
+		title = fmt.Sprintf("%s (%s)", title, mimeType)
+		// Replace tabs with spaces for consistent rendering
+		content = strings.ReplaceAll(string(contentBytes), "\t", "    ")
 	} else {
 		content = "[Empty File]" // Indicate empty file explicitly
 	}
 
-	// Get the current focus *before* the menu closes in handleMenuSelect
-	// This requires knowing the focus *before* the action menu was opened.
-	currentFocus := state.GetPreviousFocusView() // Focus from before menu opened
-	if currentFocus == "" {                      // Fallback if state wasn't set correctly
-		log.Println("Warning: Previous focus view unknown when opening file content, defaulting to folders")
-		currentFocus = viewFolders
-	}
-
 	// Prepare state for the content view
-	state.SetFileContentView(item.Name, content, currentFocus)
+	state.SetFileContentView(title, content, prevFocus)
+	if formatter := contentFormatterFor(name, contentBytes); formatter != nil && len(contentBytes) > 0 {
+		state.SetFileContentFormatter(formatter)
+	}
 
 	// IMPORTANT: Do NOT trigger g.Update here.
 	// It will be triggered in handleMenuSelect after this function returns successfully,
@@ -662,6 +2078,27 @@ func viewFileContentAction(g *gocui.Gui, item FileInfo, state *AppState) error {
 	return nil
 }
 
+// showImagePreview decodes an image and shows it in the content view.
+func showImagePreview(item FileInfo, prevFocus string, state *AppState) error {
+	img, err := loadImagePreview(item.Path)
+	if err != nil {
+		return err
+	}
+	bounds := img.Bounds()
+	info := fmt.Sprintf("%dx%d, %s", bounds.Dx(), bounds.Dy(), formatSize(item.Size))
+	if plainMode {
+		// Images are drawn with colored blocks, which plain mode doesn't allow
+		state.SetFileContentView(item.Name, fmt.Sprintf("Image: %s\n(previews need colors, which are off in plain mode)", info), prevFocus)
+		return nil
+	}
+	if asciiMode {
+		state.SetFileContentView(item.Name, fmt.Sprintf("Image: %s\n(previews need block characters, which are off in ASCII mode)", info), prevFocus)
+		return nil
+	}
+	state.SetFileContentImage(item.Name, img, info, prevFocus)
+	return nil
+}
+
 // --- File Content View Handlers ---
 
 // handleScrollFileContentView scrolls the content view by delta lines.
@@ -669,7 +2106,7 @@ func handleScrollFileContentView(g *gocui.Gui, v *gocui.View, state *AppState, d
 	if v == nil || !state.IsFileContentViewVisible() {
 		return nil
 	}
-	_, viewHeight := v.Size()
+	_, viewHeight := contentSize(v)
 	totalLines := state.GetFileContentViewTotalLines()
 
 	// Disable scrolling if content fits in view
@@ -700,15 +2137,96 @@ func handleScrollFileContentView(g *gocui.Gui, v *gocui.View, state *AppState, d
 	return nil
 }
 
+// handleReloadFileContent reads the viewed file again, keeping the scroll
+// position where the new content allows. A file that is gone closes the viewer.
+func handleReloadFileContent(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	width, height := contentSize(v)
+	reloadFileContent(state, width, height)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// reloadFileContent re-reads the viewed file for a width x height viewer. The
+// raw/rendered choice is kept, and the scroll position is clamped to the
+// content as rendered for the viewer, not to its source lines.
+func reloadFileContent(state *AppState, width, height int) {
+	item, ok := state.FileContentItem()
+	if !ok {
+		state.SetMessage("Only files can be reloaded")
+		return
+	}
+	originY := state.GetFileContentViewOriginY()
+	prevFocus := state.GetFileContentViewPrevFocus()
+	raw := state.FileContentRenderMode() == "raw"
+	info, err := state.Files().Stat(item.Path)
+	switch {
+	case os.IsNotExist(err):
+		state.CloseFileContentView()
+		state.SetMessage(fmt.Sprintf("'%s' no longer exists; closed the viewer", item.Name))
+	case err != nil:
+		state.SetMessage(fmt.Sprintf("Could not reload '%s': %s", item.Name, trimError(err)))
+	default:
+		item.Size, item.ModTime = info.Size(), info.ModTime()
+		if err := loadFileContent(item, prevFocus, state); err != nil {
+			state.SetMessage(fmt.Sprintf("Could not reload '%s': %s", item.Name, trimError(err)))
+			break
+		}
+		state.SetFileContentItem(item)
+		if raw {
+			state.ToggleFileContentRaw()
+		}
+		if gutter := state.FileContentLineNumbers().gutter(); width > gutter {
+			state.RenderFileContent(width, gutter, height)
+		}
+		state.ScrollFileContentView(originY, height)
+		state.SetMessage(fmt.Sprintf("Reloaded '%s'", item.Name))
+	}
+}
+
+// handleToggleContentRaw flips the content viewer between rendered and source form.
+func handleToggleContentRaw(g *gocui.Gui, state *AppState) error {
+	if !state.ToggleFileContentRaw() {
+		state.SetMessage("This file has no rendered view")
+	}
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleCycleLineNumbers switches the viewer between absolute, relative and no
+// line numbers, and remembers the choice.
+func handleCycleLineNumbers(g *gocui.Gui, state *AppState) error {
+	state.SetMessage(fmt.Sprintf("Line numbers: %s", state.CycleFileContentLineNumbers()))
+	persistPreferences(state)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// handleToggleContentWhitespace shows or hides the CR and trailing whitespace markers.
+func handleToggleContentWhitespace(g *gocui.Gui, state *AppState) error {
+	if state.ToggleFileContentWhitespace() {
+		state.SetMessage("Whitespace markers on")
+	} else {
+		state.SetMessage("Whitespace markers off")
+	}
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
 // --- Helper for Reading Files ---
 
-const maxCopySize = 5 * 1024 * 1024  // 5 MB limit for copying
-const maxViewSize = 20 * 1024 * 1024 // 20 MB limit for viewing
+// Default limits for copying and viewing; "max_copy_size" and
+// "max_view_size" in config.json override them (see AppState.CopyLimit).
+const defaultMaxCopySize = 5 * 1024 * 1024  // 5 MB limit for copying
+const defaultMaxViewSize = 20 * 1024 * 1024 // 20 MB limit for viewing
+const maxDiffSize = 2 * 1024 * 1024         // 2 MB limit per file for comparing
+
+// errFileTooLarge is returned by ReadFileWithLimit for files over the limit.
+var errFileTooLarge = errors.New("file too large")
 
 // ReadFileWithLimit reads a file up to a specified size limit.
 // Returns the content as bytes, or nil if empty, or an error.
-func ReadFileWithLimit(path string, limitBytes int64) ([]byte, error) {
-	info, err := os.Stat(path) // Use Stat, not Lstat, to get size of actual file if symlink
+func ReadFileWithLimit(files FileReader, path string, limitBytes int64) ([]byte, error) {
+	info, err := files.Stat(path) // Use Stat, not Lstat, to get size of actual file if symlink
 	if err != nil {
 		return nil, fmt.Errorf("could not stat file: %w", err)
 	}
@@ -716,16 +2234,25 @@ func ReadFileWithLimit(path string, limitBytes int64) ([]byte, error) {
 		return nil, fmt.Errorf("path is a directory")
 	}
 	if info.Size() > limitBytes {
-		limitMB := limitBytes / (1024 * 1024)
-		return nil, fmt.Errorf("file too large (> %d MiB)", limitMB)
+		return nil, fmt.Errorf("%w (> %s)", errFileTooLarge, formatSize(limitBytes))
 	}
 	if info.Size() == 0 {
 		return nil, nil // Return nil for empty file, no error
 	}
 
-	content, err := os.ReadFile(path)
+	content, err := files.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("could not read file: %w", err)
 	}
 	return content, nil
 }
+
+// readFileWithSetting is ReadFileWithLimit for a limit set in config.json,
+// saying so when the file is over it.
+func readFileWithSetting(files FileReader, path string, limitBytes int64) ([]byte, error) {
+	content, err := ReadFileWithLimit(files, path, limitBytes)
+	if errors.Is(err, errFileTooLarge) {
+		return nil, fmt.Errorf("%w (> %s, configurable)", errFileTooLarge, formatSize(limitBytes))
+	}
+	return content, err
+}
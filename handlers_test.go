@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+// fakeFiles is an in-memory FileReader: files maps paths to contents, dirs
+// holds the folders.
+type fakeFiles struct {
+	files map[string]string
+	dirs  map[string]bool
+}
+
+// fakeFileInfo is the os.FileInfo of a fakeFiles entry.
+type fakeFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (fi fakeFileInfo) Name() string { return fi.name }
+func (fi fakeFileInfo) Size() int64  { return fi.size }
+func (fi fakeFileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0o755
+	}
+	return 0o644
+}
+func (fi fakeFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi fakeFileInfo) IsDir() bool        { return fi.dir }
+func (fi fakeFileInfo) Sys() any           { return nil }
+
+// fakeFile is an open fakeFiles file.
+type fakeFile struct {
+	*strings.Reader
+	info fakeFileInfo
+}
+
+func (f fakeFile) Close() error               { return nil }
+func (f fakeFile) Stat() (os.FileInfo, error) { return f.info, nil }
+
+func (f fakeFiles) Stat(path string) (os.FileInfo, error) {
+	if content, ok := f.files[path]; ok {
+		return fakeFileInfo{name: filepath.Base(path), size: int64(len(content))}, nil
+	}
+	if f.dirs[path] {
+		return fakeFileInfo{name: filepath.Base(path), dir: true}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: path, Err: fs.ErrNotExist}
+}
+
+func (f fakeFiles) ReadFile(path string) ([]byte, error) {
+	if content, ok := f.files[path]; ok {
+		return []byte(content), nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+}
+
+func (f fakeFiles) Open(path string) (File, error) {
+	if content, ok := f.files[path]; ok {
+		return fakeFile{strings.NewReader(content), fakeFileInfo{name: filepath.Base(path), size: int64(len(content))}}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+}
+
+// newFakeState returns a state in /work that reads from files and copies to cb,
+// with a 16-byte view and copy limit.
+func newFakeState(files fakeFiles, cb *fakeClipboard) *AppState {
+	state := NewAppState("/work")
+	state.files = files
+	state.clipboard = cb
+	state.SetSizeLimits(16, 16)
+	return state
+}
+
+// openTestViewer writes content to a file named name and opens it in the
+// content viewer of a new state, as the viewer action does.
+func openTestViewer(t *testing.T, name, content string) *AppState {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	state := NewAppState(dir)
+	item := FileInfo{Name: name, Path: path}
+	if err := loadFileContent(item, viewFiles, state); err != nil {
+		t.Fatal(err)
+	}
+	state.SetFileContentItem(item)
+	return state
+}
+
+func TestReloadFileContent(t *testing.T) {
+	const width, height = 80, 10
+	numbers := make([]string, 100)
+	for i := range numbers {
+		numbers[i] = "1"
+	}
+	json := "[" + strings.Join(numbers, ",") + "]" // One source line, 102 rendered ones
+
+	t.Run("keeps raw mode", func(t *testing.T) {
+		state := openTestViewer(t, "data.json", json)
+		state.ToggleFileContentRaw()
+		reloadFileContent(state, width, height)
+		if mode := state.FileContentRenderMode(); mode != "raw" {
+			t.Errorf("got %q mode after reloading, want raw", mode)
+		}
+	})
+
+	t.Run("keeps the position in rendered lines", func(t *testing.T) {
+		state := openTestViewer(t, "data.json", json)
+		state.RenderFileContent(width, state.FileContentLineNumbers().gutter(), height)
+		state.ScrollFileContentView(50, height)
+		reloadFileContent(state, width, height)
+		if got := state.GetFileContentViewOriginY(); got != 50 {
+			t.Errorf("got origin %d after reloading, want 50", got)
+		}
+	})
+
+	t.Run("closes the viewer of a removed file", func(t *testing.T) {
+		state := openTestViewer(t, "gone.txt", "text")
+		item, _ := state.FileContentItem()
+		if err := os.Remove(item.Path); err != nil {
+			t.Fatal(err)
+		}
+		reloadFileContent(state, width, height)
+		if state.IsFileContentViewVisible() {
+			t.Error("the viewer of a removed file stayed open")
+		}
+	})
+}
+
+func TestCopyActions(t *testing.T) {
+	files := fakeFiles{
+		files: map[string]string{
+			"/work/notes.txt": "hello",
+			"/work/empty.txt": "",
+			"/work/big.log":   strings.Repeat("x", 17),
+		},
+		dirs: map[string]bool{"/work/src": true},
+	}
+	failing := errors.New("no display")
+	// copyContentNow runs Copy Content's background read in place
+	copyContentNow := func(g *gocui.Gui, item FileInfo, state *AppState) error {
+		_, err := copyContentTask(item, state)(context.Background(), func(done, total int64) {})
+		return err
+	}
+
+	tests := []struct {
+		name    string
+		action  func(g *gocui.Gui, item FileInfo, state *AppState) error
+		path    string
+		isDir   bool
+		cbErr   error
+		want    string // Clipboard text after the action
+		wantErr bool
+		wantDlg bool // The over-the-limit dialog opens instead
+	}{
+		{"full path", copyFullPath, "/work/notes.txt", false, nil, "/work/notes.txt", false, false},
+		{"full path, clipboard failure", copyFullPath, "/work/notes.txt", false, failing, "", true, false},
+		{"relative path", copyRelativePath, "/work/src/main.go", false, nil, filepath.FromSlash("src/main.go"), false, false},
+		{"relative path, clipboard failure", copyRelativePath, "/work/src", true, failing, "", true, false},
+		{"content", copyContentNow, "/work/notes.txt", false, nil, "hello", false, false},
+		{"empty content", copyContentNow, "/work/empty.txt", false, nil, "", false, false},
+		{"content of a directory", copyContent, "/work/src", true, nil, "", true, false},
+		{"content of a missing file", copyContentNow, "/work/gone.txt", false, nil, "", true, false},
+		{"content over the copy limit", copyContent, "/work/big.log", false, nil, "", false, true},
+		{"content, clipboard failure", copyContentNow, "/work/notes.txt", false, failing, "", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb := &fakeClipboard{err: tt.cbErr}
+			state := newFakeState(files, cb)
+			item := FileInfo{Name: filepath.Base(tt.path), Path: tt.path, IsDir: tt.isDir}
+			err := tt.action(nil, item, state)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, tt.wantErr)
+			}
+			if got := cb.Text(); got != tt.want {
+				t.Errorf("clipboard holds %q, want %q", got, tt.want)
+			}
+			if got := state.IsConfirmVisible(); got != tt.wantDlg {
+				t.Errorf("dialog shown: %v, want %v", got, tt.wantDlg)
+			}
+		})
+	}
+}
+
+func TestCopyLinesTaskMessage(t *testing.T) {
+	files := fakeFiles{files: map[string]string{
+		"/work/notes.txt": "a\nb\nc\n",
+		"/work/big.log":   strings.Repeat("x", 17),
+	}}
+	tests := []struct {
+		path    string
+		fromEnd bool
+		want    string
+	}{
+		{"/work/notes.txt", false, "The first 2 lines of 'notes.txt' copied"},
+		{"/work/notes.txt", true, "The last 2 lines of 'notes.txt' copied"},
+		{"/work/big.log", false, "The first 2 lines of 'big.log' copied (capped at " + formatSize(16) + ")"},
+	}
+	for _, tt := range tests {
+		state := newFakeState(files, &fakeClipboard{})
+		item := FileInfo{Name: filepath.Base(tt.path), Path: tt.path}
+		got, err := copyLinesTask(item, state, 2, tt.fromEnd)(context.Background(), func(done, total int64) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("message %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestViewFileContentAction(t *testing.T) {
+	files := fakeFiles{
+		files: map[string]string{
+			"/work/notes.txt": "hello\nworld\n",
+			"/work/big.log":   strings.Repeat("x\n", 9),
+			"/work/a.bin":     "\x00\x01\x02",
+		},
+		dirs: map[string]bool{"/work/src": true},
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		isDir     bool
+		wantErr   error // nil for any error when wantFail is set
+		wantFail  bool
+		wantLines int
+	}{
+		{"text file", "/work/notes.txt", false, nil, false, 2},
+		{"directory", "/work/src", true, nil, true, 0},
+		{"over the view limit", "/work/big.log", false, errFileTooLarge, true, 0},
+		{"binary file", "/work/a.bin", false, nil, true, 0},
+		{"missing file", "/work/gone.txt", false, fs.ErrNotExist, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := newFakeState(files, &fakeClipboard{})
+			item := FileInfo{Name: filepath.Base(tt.path), Path: tt.path, IsDir: tt.isDir}
+			err := viewFileContentAction(nil, item, state)
+			if (err != nil) != tt.wantFail || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Fatalf("got error %v, want %v (fails: %v)", err, tt.wantErr, tt.wantFail)
+			}
+			if got := state.IsFileContentViewVisible(); got == tt.wantFail {
+				t.Errorf("viewer open: %v", got)
+			}
+			if !tt.wantFail {
+				if got := state.GetFileContentViewTotalLines(); got != tt.wantLines {
+					t.Errorf("viewer shows %d lines, want %d", got, tt.wantLines)
+				}
+				if got, _ := state.FileContentItem(); got.Path != tt.path {
+					t.Errorf("viewer reads from %q, want %q", got.Path, tt.path)
+				}
+			}
+		})
+	}
+}
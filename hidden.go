@@ -0,0 +1,35 @@
+// ---- File: hidden.go ----
+package main
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// fileAttributeHidden is Windows' FILE_ATTRIBUTE_HIDDEN bit.
+const fileAttributeHidden = 0x2
+
+// hiddenSystemFiles are Explorer's bookkeeping files, hidden even when their
+// attribute was lost (e.g. after copying from a share).
+var hiddenSystemFiles = map[string]bool{"desktop.ini": true, "thumbs.db": true}
+
+// attributeProvider returns an entry's Windows file attributes; ok is false
+// where the platform has none.
+type attributeProvider func(entry fs.DirEntry) (attrs uint32, ok bool)
+
+// isHiddenEntry reports whether a directory entry belongs in the hidden lists.
+func isHiddenEntry(entry fs.DirEntry) bool {
+	return isHiddenWith(entry, entryAttributes)
+}
+
+// isHiddenWith applies the hidden rules with attributes from attributes:
+// dot-prefixed names everywhere and, where entries have attributes, the
+// hidden attribute and Explorer's bookkeeping files.
+func isHiddenWith(entry fs.DirEntry, attributes attributeProvider) bool {
+	name := entry.Name()
+	if isDotName(name) {
+		return true
+	}
+	attrs, ok := attributes(entry)
+	return ok && (attrs&fileAttributeHidden != 0 || hiddenSystemFiles[strings.ToLower(name)])
+}
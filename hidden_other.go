@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import "io/fs"
+
+// entryAttributes has nothing to report: Unix has no hidden attribute, so
+// only the dot-prefix convention applies.
+func entryAttributes(fs.DirEntry) (uint32, bool) {
+	return 0, false
+}
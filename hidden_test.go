@@ -0,0 +1,37 @@
+package main
+
+import (
+	"io/fs"
+	"testing"
+)
+
+func TestIsHiddenWith(t *testing.T) {
+	// A fake provider gives the Windows rules coverage on any platform
+	hiddenAttr := map[string]uint32{"secret.txt": fileAttributeHidden, "plain.txt": 0x20, "Desktop.ini": 0x20, "Thumbs.db": 0x20}
+	windows := func(entry fs.DirEntry) (uint32, bool) { return hiddenAttr[entry.Name()], true }
+	unix := func(fs.DirEntry) (uint32, bool) { return 0, false }
+
+	tests := []struct {
+		name          string
+		dir           bool
+		windows, unix bool
+	}{
+		{"main.go", false, false, false},
+		{".env", false, true, true},
+		{".git", true, true, true},
+		{"secret.txt", false, true, false},
+		{"plain.txt", false, false, false},
+		{"Desktop.ini", false, true, false},
+		{"Thumbs.db", false, true, false},
+		{"..", true, false, false},
+	}
+	for _, tt := range tests {
+		entry := fs.FileInfoToDirEntry(fakeFileInfo{name: tt.name, dir: tt.dir})
+		if got := isHiddenWith(entry, windows); got != tt.windows {
+			t.Errorf("%s with attributes: hidden %v, want %v", tt.name, got, tt.windows)
+		}
+		if got := isHiddenWith(entry, unix); got != tt.unix {
+			t.Errorf("%s without attributes: hidden %v, want %v", tt.name, got, tt.unix)
+		}
+	}
+}
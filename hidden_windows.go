@@ -0,0 +1,20 @@
+package main
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// entryAttributes returns the entry's file attributes. They come with ReadDir
+// on Windows, so Info costs no extra syscall.
+func entryAttributes(entry fs.DirEntry) (uint32, bool) {
+	info, err := entry.Info()
+	if err != nil {
+		return 0, false
+	}
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return 0, false
+	}
+	return data.FileAttributes, true
+}
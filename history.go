@@ -0,0 +1,138 @@
+// ---- File: history.go ----
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// --- File History ---
+
+// historyPageSize is how many commits are read from git log at a time; the
+// next page is loaded when the selection gets close to the end.
+const historyPageSize = 200
+
+// historyPrefetchRows is how close to the last loaded commit the selection
+// gets before the next page is requested.
+const historyPrefetchRows = 20
+
+// historyEntry is a commit that changed a file.
+type historyEntry struct {
+	Hash    string
+	Date    string
+	Author  string
+	Subject string
+	Path    string // The file's path from the repository root in this commit (renames are followed)
+}
+
+// isTrackedFile reports whether item is a file in a git repository that git
+// tracks. Untracked files and files outside repositories have no history.
+// The menu and the palette ask on every open, so the tracked names of a
+// folder are read once per listing load.
+func isTrackedFile(item FileInfo, state *AppState) bool {
+	if item.IsDir || !state.InGitRepo() {
+		return false
+	}
+	dir := filepath.Dir(item.Path)
+	names, ok := state.TrackedNames(dir)
+	if !ok {
+		names = gitTrackedNames(state.Git(), dir)
+		state.SetTrackedNames(dir, names)
+	}
+	return names[item.Name]
+}
+
+// gitTrackedNames returns the names of the files directly in dir that git
+// tracks; none if dir isn't in a repository.
+func gitTrackedNames(git GitRunner, dir string) map[string]bool {
+	names := make(map[string]bool)
+	// In glob magic "*" doesn't cross "/", so only dir's own files are listed
+	output, err := git.Run(dir, nil, "ls-files", "-z", "--", ":(glob)*")
+	if err != nil {
+		return names
+	}
+	for _, name := range strings.Split(string(output), "\x00") {
+		if name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// literalPathspec makes name a pathspec matching only itself: a bare one is
+// a glob, where "a[1].txt" would match "a1.txt".
+func literalPathspec(name string) string {
+	return ":(literal)" + name
+}
+
+// gitFullName returns path relative to the root of its repository.
+func gitFullName(git GitRunner, path string) (string, error) {
+	output, err := git.Run(filepath.Dir(path), nil, "ls-files", "--full-name", "--", literalPathspec(filepath.Base(path)))
+	if err != nil {
+		return "", fmt.Errorf("git ls-files failed: %w", err)
+	}
+	name := strings.TrimSpace(string(output))
+	if name == "" {
+		return "", fmt.Errorf("'%s' is not tracked", filepath.Base(path))
+	}
+	return name, nil
+}
+
+// loadFileHistory reads up to limit commits that changed the file at path,
+// newest first: the latest ones, or with after set, those following that
+// commit (the last one of the previous page). git log drops the renames it
+// follows inside a --skip range, so a page starts at after's commit, under
+// the file's name there, instead of skipping the commits already read.
+func loadFileHistory(git GitRunner, path string, after *historyEntry, limit int) ([]historyEntry, error) {
+	// Commits that list no path (merges) keep the path of the commit after them
+	rev, lastPath, count := "HEAD", "", limit
+	if after != nil {
+		rev, lastPath, count = after.Hash, after.Path, limit+1 // The page starts with after itself
+	} else {
+		name, err := gitFullName(git, path)
+		if err != nil {
+			return nil, err
+		}
+		lastPath = name
+	}
+	output, err := git.Run(filepath.Dir(path), nil, "log", "--follow", "--name-only",
+		"--format=%x00%h|%ad|%an|%s", "--date=short", fmt.Sprintf("--max-count=%d", count),
+		rev, "--", ":(top,literal)"+lastPath)
+	if err != nil {
+		return nil, fmt.Errorf("git log failed: %w", err)
+	}
+	var entries []historyEntry
+	for _, record := range strings.Split(string(output), "\x00")[1:] {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		fields := strings.SplitN(lines[0], "|", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		entry := historyEntry{Hash: fields[0], Date: fields[1], Author: fields[2], Subject: fields[3], Path: lastPath}
+		for _, line := range lines[1:] {
+			if line = strings.TrimSpace(line); line != "" {
+				entry.Path = line
+			}
+		}
+		lastPath = entry.Path
+		entries = append(entries, entry)
+	}
+	if after != nil && len(entries) > 0 && entries[0].Hash == after.Hash {
+		entries = entries[1:]
+	}
+	return entries, nil
+}
+
+// loadFileRevision returns the content of entry's version of the file, run
+// from dir (any directory inside the repository), refusing revisions over limit bytes.
+func loadFileRevision(git GitRunner, dir string, entry historyEntry, limit int64) ([]byte, error) {
+	output, err := git.Run(dir, nil, "show", entry.Hash+":"+entry.Path)
+	if err != nil {
+		return nil, fmt.Errorf("git show failed: %w", err)
+	}
+	if int64(len(output)) > limit {
+		return nil, fmt.Errorf("revision is too large to view (%s)", formatSize(int64(len(output))))
+	}
+	return output, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testRepo creates a git repository in a temporary folder and returns a
+// function running git in it.
+func testRepo(t *testing.T) (string, func(args ...string)) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+	git("init", "-q")
+	return dir, git
+}
+
+// commitFile writes content to name in dir and commits it as message.
+func commitFile(t *testing.T, dir string, git func(args ...string), name, content, message string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "--", name)
+	git("commit", "-q", "-m", message)
+}
+
+func TestFileHistoryLiteralNames(t *testing.T) {
+	dir, git := testRepo(t)
+	commitFile(t, dir, git, "a1.txt", "x", "add a1")
+	if err := os.WriteFile(filepath.Join(dir, "a[1].txt"), []byte("y"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Untracked, though the glob a[1].txt matches the tracked a1.txt
+	if name, err := gitFullName(execGit{}, filepath.Join(dir, "a[1].txt")); err == nil {
+		t.Errorf("gitFullName(a[1].txt) = %q, want an error", name)
+	}
+	names := gitTrackedNames(execGit{}, dir)
+	if !names["a1.txt"] || names["a[1].txt"] {
+		t.Errorf("tracked names = %v", names)
+	}
+
+	git("add", "--", "a[1].txt")
+	git("commit", "-q", "-m", "add a[1]")
+	entries, err := loadFileHistory(execGit{}, filepath.Join(dir, "a[1].txt"), nil, 10)
+	if err != nil || len(entries) != 1 || entries[0].Subject != "add a[1]" {
+		t.Errorf("history of a[1].txt = %+v, %v", entries, err)
+	}
+}
+
+func TestFileHistoryPagesAcrossRenames(t *testing.T) {
+	dir, git := testRepo(t)
+	for i := range 5 {
+		commitFile(t, dir, git, "old.txt", strings.Repeat("line\n", i+1), "edit "+string(rune('a'+i)))
+	}
+	git("mv", "old.txt", "new.txt")
+	git("commit", "-q", "-m", "rename")
+	commitFile(t, dir, git, "new.txt", "changed\n", "edit f")
+
+	var all []historyEntry
+	var after *historyEntry
+	for range 10 {
+		page, err := loadFileHistory(execGit{}, filepath.Join(dir, "new.txt"), after, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, page...)
+		if len(page) < 2 {
+			break
+		}
+		after = &page[len(page)-1]
+	}
+	var subjects, paths []string
+	for _, entry := range all {
+		subjects = append(subjects, entry.Subject)
+		paths = append(paths, entry.Path)
+	}
+	if got, want := strings.Join(subjects, ","), "edit f,rename,edit e,edit d,edit c,edit b,edit a"; got != want {
+		t.Errorf("commits = %s, want %s", got, want)
+	}
+	if got, want := strings.Join(paths, ","), "new.txt,new.txt,old.txt,old.txt,old.txt,old.txt,old.txt"; got != want {
+		t.Errorf("paths = %s, want %s", got, want)
+	}
+}
+
+// countingGit runs git from PATH and counts the runs.
+type countingGit struct{ calls int }
+
+func (c *countingGit) Run(dir string, stdin io.Reader, args ...string) ([]byte, error) {
+	c.calls++
+	return execGit{}.Run(dir, stdin, args...)
+}
+
+func TestIsTrackedFileCachesPerLoad(t *testing.T) {
+	dir, git := testRepo(t)
+	commitFile(t, dir, git, "tracked.txt", "x", "add")
+	if err := os.WriteFile(filepath.Join(dir, "new.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runner := &countingGit{}
+	state := NewAppState(dir)
+	state.git = runner
+	state.gitStatus = "Active: (main)"
+
+	for range 3 {
+		if !isTrackedFile(FileInfo{Name: "tracked.txt", Path: filepath.Join(dir, "tracked.txt")}, state) {
+			t.Error("tracked.txt not tracked")
+		}
+		if isTrackedFile(FileInfo{Name: "new.txt", Path: filepath.Join(dir, "new.txt")}, state) {
+			t.Error("new.txt tracked")
+		}
+	}
+	if runner.calls != 1 {
+		t.Errorf("git ran %d times for one listing, want 1", runner.calls)
+	}
+	state.BeginDirLoad()
+	isTrackedFile(FileInfo{Name: "tracked.txt", Path: filepath.Join(dir, "tracked.txt")}, state)
+	if runner.calls != 2 {
+		t.Errorf("git ran %d times after a reload, want 2", runner.calls)
+	}
+}
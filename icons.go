@@ -0,0 +1,199 @@
+// ---- File: icons.go ----
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// --- Icon Mapping (Requires Nerd Fonts) ---
+
+// iconSet maps names to icons. Lookups are by lowercase extension (".go"),
+// exact lowercase file name ("makefile") and directory name (".git").
+type iconSet struct {
+	extensions  map[string]string
+	files       map[string]string
+	dirs        map[string]string
+	defaultFile string
+	defaultDir  string
+	symlink     string // Replaces the icon of symlinks, if set
+}
+
+// nerdIcons is the built-in Nerd Font icon set.
+var nerdIcons = iconSet{
+	extensions: map[string]string{
+		// Languages
+		".go":   "",
+		".py":   "",
+		".js":   "",
+		".ts":   "",
+		".jsx":  "",
+		".tsx":  "",
+		".rs":   "",
+		".c":    "",
+		".h":    "",
+		".cpp":  "",
+		".cc":   "",
+		".hpp":  "",
+		".java": "",
+		".rb":   "",
+		".php":  "",
+		".sql":  "",
+		".sh":   "",
+		".bash": "",
+		".zsh":  "",
+		".fish": "",
+		// Markup, styles and data
+		".json": "",
+		".html": "",
+		".css":  "",
+		".scss": "",
+		".md":   "",
+		".yml":  "",
+		".yaml": "",
+		".toml": "",
+		".env":  "", // Env files similar to config
+		".lock": "",
+		".log":  "",
+		".pdf":  "",
+		// Archives
+		".zip": "",
+		".tar": "",
+		".gz":  "",
+		".bz2": "",
+		".xz":  "",
+		".rar": "",
+		".7z":  "",
+		// Images
+		".png":  "",
+		".jpg":  "",
+		".jpeg": "",
+		".gif":  "",
+		".svg":  "",
+		".webp": "",
+		".bmp":  "",
+		".ico":  "",
+	},
+	files: map[string]string{
+		"makefile":          "",
+		"dockerfile":        "",
+		"license":           "",
+		"readme.md":         "", // Prioritize Readme icon
+		".gitignore":        "",
+		".gitattributes":    "",
+		".gitmodules":       "",
+		"package-lock.json": "",
+		"yarn.lock":         "",
+		"cargo.lock":        "",
+		"go.sum":            "",
+	},
+	dirs: map[string]string{
+		"node_modules": "", // npm icon
+		".git":         "", // Git icon
+	},
+	defaultFile: "",
+	defaultDir:  "",
+}
+
+// plainIcons uses ASCII markers for terminals without a Nerd Font.
+var plainIcons = iconSet{
+	extensions:  map[string]string{},
+	files:       map[string]string{},
+	dirs:        map[string]string{},
+	defaultFile: "-",
+	defaultDir:  "/",
+}
+
+// asciiIcons tags entries by type in ASCII mode, which ignores icon overrides.
+var asciiIcons = iconSet{
+	extensions:  map[string]string{},
+	files:       map[string]string{},
+	dirs:        map[string]string{},
+	defaultFile: "[F]",
+	defaultDir:  "[D]",
+	symlink:     "[L]",
+}
+
+// activeIcons is the icon set getIcon consults, built by configureIcons.
+var activeIcons = nerdIcons
+
+// configureIcons selects the base icon set named in cfg ("nerd" or "plain")
+// and merges the user's icon overrides on top of it.
+func configureIcons(cfg config) {
+	base := nerdIcons
+	if strings.EqualFold(cfg.IconSet, "plain") {
+		base = plainIcons
+	}
+
+	merged := iconSet{
+		extensions:  mergeIcons(base.extensions, cfg.Icons.Extensions, true),
+		files:       mergeIcons(base.files, cfg.Icons.Files, false),
+		dirs:        mergeIcons(base.dirs, cfg.Icons.Dirs, false),
+		defaultFile: base.defaultFile,
+		defaultDir:  base.defaultDir,
+	}
+	if icon, ok := cfg.Icons.Files["*"]; ok {
+		merged.defaultFile = icon
+	}
+	if icon, ok := cfg.Icons.Dirs["*"]; ok {
+		merged.defaultDir = icon
+	}
+	activeIcons = merged
+}
+
+// mergeIcons copies base and applies overrides with lowercase keys; extension
+// keys get a leading dot if the user left it off.
+func mergeIcons(base, overrides map[string]string, isExt bool) map[string]string {
+	merged := make(map[string]string, len(base)+len(overrides))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		if k == "*" {
+			continue // Default icon, handled by the caller
+		}
+		k = strings.ToLower(k)
+		if isExt && !strings.HasPrefix(k, ".") {
+			k = "." + k
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// getEntryIcon is getIcon for a listed entry whose type bits are known, so
+// icon sets can mark symlinks.
+func getEntryIcon(name string, isDir bool, mode fs.FileMode) string {
+	if mode&fs.ModeSymlink != 0 && activeIcons.symlink != "" {
+		return activeIcons.symlink
+	}
+	return getIcon(name, isDir)
+}
+
+func getIcon(name string, isDir bool) string {
+	lowerName := strings.ToLower(name)
+
+	if isDir {
+		if icon, ok := activeIcons.dirs[lowerName]; ok { // Check dir name (e.g., ".git")
+			return icon
+		}
+		return activeIcons.defaultDir // Default dir icon
+	}
+
+	// Check full name first (e.g., "README.md")
+	if icon, ok := activeIcons.files[lowerName]; ok {
+		return icon
+	}
+
+	// Check extension for files
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext != "" {
+		if icon, ok := activeIcons.extensions[ext]; ok {
+			return icon
+		}
+	}
+
+	// Fallback to default file icon
+	return activeIcons.defaultFile
+}
@@ -0,0 +1,197 @@
+// ---- File: ignore.go ----
+package main
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFileName is the per-directory (and global, in the config dir) ignore file.
+const ignoreFileName = ".lazylsignore"
+
+// ignorePattern is a single gitignore-style line from a .lazylsignore file.
+type ignorePattern struct {
+	segments []string // Slash-separated glob segments; "**" matches any number of directories
+	negate   bool     // "!pattern" re-includes a previously ignored path
+	dirOnly  bool     // "pattern/" only matches directories
+	anchored bool     // Contains a slash, so it matches relative to the ignore file's directory
+}
+
+// ignoreRules is the parsed content of one ignore file.
+type ignoreRules struct {
+	patterns []ignorePattern
+}
+
+// scopedIgnoreRules ties rules to the directory their paths are relative to.
+type scopedIgnoreRules struct {
+	base  string
+	rules *ignoreRules
+}
+
+// parseIgnoreRules parses gitignore-style patterns, one per line. Blank lines
+// and lines starting with "#" are skipped.
+func parseIgnoreRules(content string) *ignoreRules {
+	rules := &ignoreRules{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var p ignorePattern
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:] // Escaped leading "!" or "#"
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			p.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		p.segments = strings.Split(line, "/")
+		rules.patterns = append(rules.patterns, p)
+	}
+	return rules
+}
+
+// Match reports whether rel (slash-separated, relative to the rules' directory)
+// is ignored, and whether any pattern matched at all. As in gitignore, the
+// last matching pattern wins.
+func (r *ignoreRules) Match(rel string, isDir bool) (ignored bool, matched bool) {
+	if r == nil {
+		return false, false
+	}
+	parts := strings.Split(rel, "/")
+	for _, p := range r.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		var ok bool
+		if p.anchored {
+			ok = matchSegments(p.segments, parts)
+		} else {
+			ok = matchSegments(p.segments, parts[len(parts)-1:])
+		}
+		if ok {
+			ignored, matched = !p.negate, true
+		}
+	}
+	return ignored, matched
+}
+
+// matchSegments matches glob segments against path segments, with "**"
+// standing for zero or more whole segments.
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			if len(rest) == 0 {
+				return len(parts) > 0 // "dir/**" matches everything inside dir
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], parts[0]); err != nil || !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// isIgnoredBy applies a stack of scoped rules (outermost first) to fullPath;
+// rules from deeper directories override those further up.
+func isIgnoredBy(stack []scopedIgnoreRules, fullPath string, isDir bool) bool {
+	ignored := false
+	for _, scoped := range stack {
+		rel, err := filepath.Rel(scoped.base, fullPath)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if ign, matched := scoped.rules.Match(filepath.ToSlash(rel), isDir); matched {
+			ignored = ign
+		}
+	}
+	return ignored
+}
+
+// globalIgnoreFile returns the path of the ignore file in the user's config directory.
+func globalIgnoreFile() (string, bool) {
+	dir, err := appConfigDir()
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(dir, ignoreFileName), true
+}
+
+// readIgnoreFile returns the parsed rules at file, or nil if there is none.
+func readIgnoreFile(file string) *ignoreRules {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: Could not read ignore file %s: %v", file, err)
+		}
+		return nil
+	}
+	return parseIgnoreRules(string(data))
+}
+
+// loadIgnoreFile is readIgnoreFile with the parsed rules cached in state until
+// the file's mtime changes.
+func loadIgnoreFile(state *AppState, file string) *ignoreRules {
+	info, err := os.Stat(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: Could not stat ignore file %s: %v", file, err)
+		}
+		return nil
+	}
+	if rules, ok := state.CachedIgnoreRules(file, info.ModTime()); ok {
+		return rules
+	}
+	rules := readIgnoreFile(file)
+	if rules != nil {
+		state.SetIgnoreRules(file, info.ModTime(), rules)
+	}
+	return rules
+}
+
+// baseIgnoreStack returns the rules that apply to the entries of dir: the
+// global ignore file (relative to dir) followed by dir's own .lazylsignore.
+func baseIgnoreStack(state *AppState, dir string) []scopedIgnoreRules {
+	return ignoreStack(dir, func(file string) *ignoreRules { return loadIgnoreFile(state, file) })
+}
+
+// ignoreStack is baseIgnoreStack with the rule files read by load.
+func ignoreStack(dir string, load func(file string) *ignoreRules) []scopedIgnoreRules {
+	var stack []scopedIgnoreRules
+	if file, ok := globalIgnoreFile(); ok {
+		if rules := load(file); rules != nil {
+			stack = append(stack, scopedIgnoreRules{base: dir, rules: rules})
+		}
+	}
+	if rules := load(filepath.Join(dir, ignoreFileName)); rules != nil {
+		stack = append(stack, scopedIgnoreRules{base: dir, rules: rules})
+	}
+	return stack
+}
@@ -0,0 +1,132 @@
+// ---- File: image.go ----
+package main
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif" // Registers the GIF decoder
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- Image Preview ---
+
+// maxImagePixels bounds the images decoded for preview (about 50 megapixels).
+const maxImagePixels = 50_000_000
+
+// imageExtsForPreview are the extensions "View Content" previews as images.
+var imageExtsForPreview = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true}
+
+// isPreviewableImage reports whether name looks like an image the viewer previews.
+func isPreviewableImage(name string) bool {
+	return imageExtsForPreview[strings.ToLower(filepath.Ext(name))]
+}
+
+// loadImagePreview decodes the image at path. Formats without a decoder (WebP)
+// and oversized or corrupt images are reported as errors.
+func loadImagePreview(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open image: %w", err)
+	}
+	defer file.Close()
+
+	cfg, format, err := image.DecodeConfig(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot preview %s images", strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."))
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, fmt.Errorf("cannot preview images over %d megapixels", maxImagePixels/1_000_000)
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot preview %s image: %w", format, err)
+	}
+	return img, nil
+}
+
+// renderImageBlocks draws img scaled to fit width x height cells using upper
+// half blocks: each cell shows two pixels, the top one as the foreground and
+// the bottom one as the background, in the 256-color palette. Images are
+// never scaled up.
+func renderImageBlocks(img image.Image, width, height int) string {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW == 0 || srcH == 0 || width <= 0 || height <= 0 {
+		return ""
+	}
+	scale := min(float64(width)/float64(srcW), float64(height*2)/float64(srcH), 1)
+	dstW, dstH := max(1, int(float64(srcW)*scale)), max(2, int(float64(srcH)*scale))
+
+	var sb strings.Builder
+	for y := 0; y < dstH; y += 2 {
+		for x := 0; x < dstW; x++ {
+			top := averageColor(img, x, y, dstW, dstH)
+			bottom := top
+			if y+1 < dstH {
+				bottom = averageColor(img, x, y+1, dstW, dstH)
+			}
+			fmt.Fprintf(&sb, "\x1b[38;5;%dm\x1b[48;5;%dm▀", top, bottom)
+		}
+		sb.WriteString(ansiReset + "\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// averageColor returns the palette color of the destination pixel (x, y) of a
+// dstW x dstH scaling of img, averaging up to 4x4 source samples.
+func averageColor(img image.Image, x, y, dstW, dstH int) int {
+	bounds := img.Bounds()
+	x0 := bounds.Min.X + x*bounds.Dx()/dstW
+	x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/dstW)
+	y0 := bounds.Min.Y + y*bounds.Dy()/dstH
+	y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/dstH)
+	stepX, stepY := max(1, (x1-x0)/4), max(1, (y1-y0)/4)
+
+	var r, g, b, n uint64
+	for sy := y0; sy < y1; sy += stepY {
+		for sx := x0; sx < x1; sx += stepX {
+			cr, cg, cb, _ := img.At(sx, sy).RGBA() // Premultiplied, so transparency blends to black
+			r, g, b, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), n+1
+		}
+	}
+	return xterm256(uint8(r/n>>8), uint8(g/n>>8), uint8(b/n>>8))
+}
+
+// xterm256 maps an RGB color to the nearest entry of the xterm 6x6x6 color
+// cube or the grayscale ramp.
+func xterm256(r, g, b uint8) int {
+	cube := func(v uint8) int {
+		if v < 48 {
+			return 0
+		}
+		if v < 115 {
+			return 1
+		}
+		return (int(v) - 35) / 40
+	}
+	levels := []int{0, 95, 135, 175, 215, 255}
+	cr, cg, cb := cube(r), cube(g), cube(b)
+	cubeIdx := 16 + 36*cr + 6*cg + cb
+	cubeDist := sqDist(levels[cr], levels[cg], levels[cb], r, g, b)
+
+	avg := (int(r) + int(g) + int(b)) / 3
+	gray := min(23, max(0, (avg-3)/10))
+	grayLevel := 8 + 10*gray
+	if sqDist(grayLevel, grayLevel, grayLevel, r, g, b) < cubeDist {
+		return 232 + gray
+	}
+	return cubeIdx
+}
+
+// sqDist is the squared distance between two RGB colors.
+func sqDist(r1, g1, b1 int, r2, g2, b2 uint8) int {
+	dr, dg, db := r1-int(r2), g1-int(g2), b1-int(b2)
+	return dr*dr + dg*dg + db*db
+}
@@ -0,0 +1,883 @@
+// ---- File: keys.go ----
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Keybinding Table ---
+
+// keyBinding is one row of the keybinding table. The same rows are bound by
+// setupKeybindings and rendered by the help overlay, so the two can't drift.
+type keyBinding struct {
+	views   []string // "" binds globally
+	key     interface{}
+	mod     gocui.Modifier
+	action  string // Stable ID; rows sharing it in one context form one help line
+	desc    string // Help text; empty rows are bound but not listed
+	handler func(*gocui.Gui, *gocui.View) error
+}
+
+// keyTable is the table installed by setupKeybindings, kept for the help overlay.
+var keyTable []keyBinding
+
+// listViews are the panes the cursor moves in.
+var listViews = []string{viewFolders, viewFiles, viewCombined}
+
+// keyBindings builds the keybinding table for state. Row order is the order
+// contexts and actions appear in the help overlay.
+func keyBindings(state *AppState) []keyBinding {
+	global := []string{""}
+	viewer := []string{viewFileContent}
+	menu := []string{viewActionMenu}
+	prompt := []string{viewPrompt}
+	props := []string{viewProperties}
+
+	// unlessOverlay drops keys typed into a main pane while an overlay is open
+	unlessOverlay := func(handler func(*gocui.Gui) error) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error {
+			if state.IsOverlayVisible() {
+				return nil
+			}
+			return handler(gui)
+		}
+	}
+	onView := func(handler func(*gocui.Gui, *gocui.View, *AppState) error) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handler(gui, view, state) }
+	}
+	largestLine := func(onDir bool) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handleLargestLine(gui, state, onDir) }
+	}
+	move := func(delta int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handleMoveCursor(gui, view, delta, state) }
+	}
+	movePage := func(multiplier int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error {
+			return handleMoveCursor(gui, view, multiplier*pageHeight(view), state)
+		}
+	}
+	moveColumn := func(delta int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handleMoveColumn(gui, view, delta, state) }
+	}
+	topBottom := func(toTop bool) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handleGoTopBottom(gui, view, toTop, state) }
+	}
+	scrollViewer := func(delta int, isPage bool) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error {
+			return handleScrollFileContentView(gui, view, state, delta, isPage)
+		}
+	}
+	scrollViewerPage := func(multiplier int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error {
+			return handleScrollFileContentView(gui, view, state, multiplier*pageHeight(view), true)
+		}
+	}
+	navigateMenu := func(delta int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handleMenuNavigate(gui, view, delta, state) }
+	}
+	navigateTopFiles := func(delta int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handleTopFilesNavigate(gui, view, delta, state) }
+	}
+	navigateHistory := func(delta int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handleHistoryNavigate(gui, view, delta, state) }
+	}
+	navigateHistoryPage := func(multiplier int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error {
+			return handleHistoryNavigate(gui, view, multiplier*pageHeight(view), state)
+		}
+	}
+	navigateMounts := func(delta int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handleMountsNavigate(gui, view, delta, state) }
+	}
+	navigateCleanup := func(delta int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handleCleanupNavigate(gui, view, delta, state) }
+	}
+	navigateCleanupPage := func(multiplier int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error {
+			return handleCleanupNavigate(gui, view, multiplier*pageHeight(view), state)
+		}
+	}
+	navigateBrokenLinks := func(delta int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error {
+			return handleBrokenLinksNavigate(gui, view, delta, state)
+		}
+	}
+	navigateBrokenLinksPage := func(multiplier int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error {
+			return handleBrokenLinksNavigate(gui, view, multiplier*pageHeight(view), state)
+		}
+	}
+	navigateDirCompare := func(delta int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error {
+			return handleDirCompareNavigate(gui, view, delta, state)
+		}
+	}
+	navigateDirComparePage := func(multiplier int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error {
+			return handleDirCompareNavigate(gui, view, multiplier*pageHeight(view), state)
+		}
+	}
+	navigateTasks := func(delta int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handleTasksNavigate(gui, view, delta, state) }
+	}
+	navigatePalette := func(delta int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handlePaletteNavigate(gui, view, delta, state) }
+	}
+	recallInput := func(delta int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handlePromptHistory(gui, view, delta, state) }
+	}
+	answer := func(ch rune) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handleConfirmKey(gui, view, ch, state) }
+	}
+	transfer := func(move bool) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handleCommanderTransfer(gui, view, state, move) }
+	}
+	crumb := func(delta int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handleMoveBreadcrumb(gui, state, delta) }
+	}
+	cycleTab := func(delta int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return handleCycleTab(gui, view, state, delta) }
+	}
+	resize := func(delta float64) func(*gocui.Gui, *gocui.View) error {
+		return unlessOverlay(func(gui *gocui.Gui) error { return handleResizePanels(gui, state, delta) })
+	}
+
+	enterAction, enterDesc := enterBinding()
+
+	bindings := []keyBinding{
+		// --- Global ---
+		{global, gocui.KeyCtrlC, gocui.ModNone, "app.quit", "Quit", quit},
+		{global, gocui.KeyTab, gocui.ModNone, "focus.next", "Switch focus between the panes",
+			unlessOverlay(func(gui *gocui.Gui) error { return handleFocusSwitch(gui, state, true) })},
+		// Only quitting works while the terminal is too small
+		{[]string{viewTooSmall}, 'q', gocui.ModNone, "app.quit", "", quit},
+
+		// --- Lists ---
+		// 'q' is bound per view (not globally) so it can still be typed into the prompt
+		{listViews, 'q', gocui.ModNone, "app.quit", "Quit", quit},
+		{listViews, 'j', gocui.ModNone, "list.down", "Move down", move(1)},
+		{listViews, gocui.KeyArrowDown, gocui.ModNone, "list.down", "", move(1)},
+		{listViews, 'k', gocui.ModNone, "list.up", "Move up", move(-1)},
+		{listViews, gocui.KeyArrowUp, gocui.ModNone, "list.up", "", move(-1)},
+		{listViews, 'h', gocui.ModNone, "list.left", "Move left (grid layout)", moveColumn(-1)},
+		{listViews, gocui.KeyArrowLeft, gocui.ModNone, "list.left", "", moveColumn(-1)},
+		{listViews, 'l', gocui.ModNone, "list.right", "Move right (grid layout)", moveColumn(1)},
+		{listViews, gocui.KeyArrowRight, gocui.ModNone, "list.right", "", moveColumn(1)},
+		{listViews, gocui.KeyPgdn, gocui.ModNone, "list.page-down", "Move down one page", movePage(1)},
+		{listViews, gocui.KeySpace, gocui.ModNone, "list.page-down", "", movePage(1)},
+		{listViews, gocui.KeyPgup, gocui.ModNone, "list.page-up", "Move up one page", movePage(-1)},
+		{listViews, 'b', gocui.ModNone, "list.page-up", "", movePage(-1)},
+		{listViews, 'g', gocui.ModNone, "list.top", "Go to the top of the list", topBottom(true)},
+		{listViews, gocui.KeyHome, gocui.ModNone, "list.top", "", topBottom(true)},
+		{listViews, 'G', gocui.ModNone, "list.bottom", "Go to the bottom of the list", topBottom(false)},
+		{listViews, gocui.KeyEnd, gocui.ModNone, "list.bottom", "", topBottom(false)},
+		{listViews, gocui.KeyEnter, gocui.ModNone, enterAction, enterDesc, onView(handleEnterKey)},
+		{listViews, 'a', gocui.ModNone, "list.actions", "Open the action menu", onView(handleEnter)},
+		{listViews, 'p', gocui.ModNone, "list.properties", "Show properties", onView(handleShowProperties)},
+		{listViews, 'R', gocui.ModNone, "list.rename", "Rename in place", onView(handleRename)},
+		{listViews, gocui.KeyF2, gocui.ModNone, "list.rename", "", onView(handleRename)},
+		{listViews, 'm', gocui.ModNone, "list.set-mark", "Set a mark on the selected item (m + letter)", onView(handleStartMark)},
+		{listViews, '`', gocui.ModNone, "list.jump-mark", "Jump to a mark in the listing (` + letter)", onView(handleStartJump)},
+		{listViews, 'd', gocui.ModNone, "list.mark-compare", "Mark/unmark for comparing", onView(handleMarkForCompare)},
+		{listViews, '.', gocui.ModNone, "toggle.hidden", "Cycle hidden entries: visible only, all (dimmed), hidden only",
+			unlessOverlay(func(gui *gocui.Gui) error { return handleToggleHidden(gui, state) })},
+		{listViews, 'o', gocui.ModNone, "toggle.sort-order", "Toggle ascending / descending name order", onView(handleToggleSortOrder)},
+		{listViews, 'N', gocui.ModNone, "list.clear-new", "Clear the markers of entries new since the previous load",
+			unlessOverlay(func(gui *gocui.Gui) error { return handleClearNewMarkers(gui, state) })},
+		{listViews, 'r', gocui.ModNone, "list.refresh", "Reload the listing and rescan the directory statistics",
+			unlessOverlay(func(gui *gocui.Gui) error { return handleRefresh(gui, state) })},
+		{listViews, '*', gocui.ModNone, "filter.glob", "Filter files by glob (*.go, *.{yml,yaml}; empty clears)", onView(handleGlobFilter)},
+		{listViews, 'I', gocui.ModNone, "toggle.gitignore", "Toggle hiding git-ignored entries",
+			unlessOverlay(func(gui *gocui.Gui) error { return handleToggleGitIgnore(gui, state) })},
+		{listViews, 'V', gocui.ModNone, "toggle.combined", "Toggle the combined list",
+			unlessOverlay(func(gui *gocui.Gui) error { return handleToggleCombined(gui, state) })},
+		{listViews, 'C', gocui.ModNone, "toggle.grid", "Toggle the grid layout of the Files pane",
+			unlessOverlay(func(gui *gocui.Gui) error { return handleToggleGrid(gui, state) })},
+		{listViews, 'U', gocui.ModNone, "toggle.size-units", "Toggle binary (KiB) / decimal (kB) size units",
+			unlessOverlay(func(gui *gocui.Gui) error { return handleToggleSizeUnits(gui, state) })},
+		{listViews, 'P', gocui.ModNone, "toggle.preview", "Toggle the preview pane",
+			unlessOverlay(func(gui *gocui.Gui) error { return handleTogglePreview(gui, state) })},
+		{listViews, 'z', gocui.ModNone, "layout.toggle-stats", "Hide / show the stats column (a status line stands in for it)",
+			unlessOverlay(func(gui *gocui.Gui) error { return handleToggleStats(gui, state) })},
+		{listViews, '<', gocui.ModNone, "layout.shrink-stats", "Shrink the stats column", resize(-panelRatioStep)},
+		{listViews, '>', gocui.ModNone, "layout.grow-stats", "Grow the stats column", resize(panelRatioStep)},
+		{listViews, 'L', gocui.ModNone, "show.top-files", "Show the largest files", onView(handleShowTopFiles)},
+		{listViews, 'S', gocui.ModNone, "show.ext-stats", "Show the extension breakdown", onView(handleShowExtStats)},
+		{listViews, 'M', gocui.ModNone, "show.messages", "Show the message history", onView(handleShowMessages)},
+		{listViews, 'E', gocui.ModNone, "show.stats-errors", "Show the paths the size scan could not read", onView(handleShowStatsErrors)},
+		{listViews, 'Z', gocui.ModNone, "show.cleanup", "Show empty folders and zero-byte files", onView(handleShowCleanup)},
+		{listViews, 'B', gocui.ModNone, "show.broken-links", "Show broken symlinks", onView(handleShowBrokenLinks)},
+		{listViews, 'W', gocui.ModNone, "report.export", "Export a report of the root folder (Markdown, or JSON for a .json path)", onView(handleExportReport)},
+		{listViews, 'X', gocui.ModNone, "compare.dirs", "Compare the root folder with another one (the other browser's in commander mode)", onView(handleCompareDirs)},
+		{listViews, 'T', gocui.ModNone, "show.tasks", "Show background tasks (copies)", onView(handleShowTasks)},
+		{listViews, 'D', gocui.ModNone, "show.mounts", "Open the drive / mount point picker", onView(handleShowMounts)},
+		{listViews, 't', gocui.ModNone, "tab.new", "Open a tab at the selected folder (or the root folder)", onView(handleNewTab)},
+		{listViews, ']', gocui.ModNone, "tab.next", "Switch to the next tab", cycleTab(1)},
+		{listViews, '[', gocui.ModNone, "tab.prev", "Switch to the previous tab", cycleTab(-1)},
+		{listViews, gocui.KeyCtrlW, gocui.ModNone, "tab.close", "Close the tab (closing the last one quits)", onView(handleCloseTab)},
+		{listViews, 'c', gocui.ModNone, "commander.toggle", "Toggle commander mode: two folder browsers side by side", onView(handleToggleCommander)},
+		{listViews, gocui.KeyF5, gocui.ModNone, "commander.copy", "Copy the selected item to the other browser's folder (commander mode)", transfer(false)},
+		{listViews, 'y', gocui.ModNone, "commander.copy", "", transfer(false)},
+		{listViews, gocui.KeyF6, gocui.ModNone, "commander.move", "Move the selected item to the other browser's folder (commander mode)", transfer(true)},
+		{listViews, '?', gocui.ModNone, "show.help", "Show this help", onView(handleShowHelp)},
+		{listViews, gocui.KeyCtrlG, gocui.ModNone, "git.tui", "Open lazygit (or the configured git TUI) in the repository",
+			unlessOverlay(func(gui *gocui.Gui) error { return handleOpenGitTUI(gui, state) })},
+		{listViews, gocui.KeyCtrlR, gocui.ModNone, "git.root", "Go to the root of the git repository",
+			unlessOverlay(func(gui *gocui.Gui) error { return handleGoToRepoRoot(gui, state) })},
+		{listViews, gocui.KeyCtrlV, gocui.ModNone, "list.paste-path", "Go to the path in the clipboard (a file is selected in its folder)", onView(handlePasteClipboardPath)},
+		{listViews, gocui.KeyCtrlK, gocui.ModNone, "show.palette", "Open the command palette", onView(handleShowPalette)},
+
+		// --- Largest File Pane ---
+		{[]string{viewLargest}, gocui.KeyEnter, gocui.ModNone, "largest.select", "Select the file and open its action menu, or select the folder", onView(handleLargestSelect)},
+		{[]string{viewLargest}, 'j', gocui.ModNone, "largest.down", "Move to the largest folder", largestLine(true)},
+		{[]string{viewLargest}, gocui.KeyArrowDown, gocui.ModNone, "largest.down", "", largestLine(true)},
+		{[]string{viewLargest}, 'k', gocui.ModNone, "largest.up", "Move to the largest file", largestLine(false)},
+		{[]string{viewLargest}, gocui.KeyArrowUp, gocui.ModNone, "largest.up", "", largestLine(false)},
+		{[]string{viewLargest}, 'L', gocui.ModNone, "show.top-files", "Show the largest files", onView(handleShowTopFiles)},
+		{[]string{viewLargest}, 'q', gocui.ModNone, "app.quit", "Quit", quit},
+		{[]string{viewStatus}, gocui.KeyEnter, gocui.ModNone, "status.open", "Go to the selected folder of the path (on the last one, copy the full path)", onView(handleOpenBreadcrumb)},
+		{[]string{viewStatus}, 'h', gocui.ModNone, "status.left", "Select the parent folder in the path", crumb(-1)},
+		{[]string{viewStatus}, gocui.KeyArrowLeft, gocui.ModNone, "status.left", "", crumb(-1)},
+		{[]string{viewStatus}, 'l', gocui.ModNone, "status.right", "Select the next folder down the path", crumb(1)},
+		{[]string{viewStatus}, gocui.KeyArrowRight, gocui.ModNone, "status.right", "", crumb(1)},
+		{[]string{viewStatus}, 'y', gocui.ModNone, "status.copy-path", "Copy the full path of the root folder", onView(handleCopyCwd)},
+		{[]string{viewStatus}, 'q', gocui.ModNone, "app.quit", "Quit", quit},
+		{[]string{viewGit}, gocui.KeyEnter, gocui.ModNone, "git.branches", "Switch to another local branch", onView(handleShowBranches)},
+		{[]string{viewGit}, 'b', gocui.ModNone, "git.branches", "", onView(handleShowBranches)},
+		{[]string{viewGit}, 'q', gocui.ModNone, "app.quit", "Quit", quit},
+
+		// --- File Viewer ---
+		{viewer, 'j', gocui.ModNone, "viewer.down", "Scroll down", scrollViewer(1, false)},
+		{viewer, gocui.KeyArrowDown, gocui.ModNone, "viewer.down", "", scrollViewer(1, false)},
+		{viewer, 'k', gocui.ModNone, "viewer.up", "Scroll up", scrollViewer(-1, false)},
+		{viewer, gocui.KeyArrowUp, gocui.ModNone, "viewer.up", "", scrollViewer(-1, false)},
+		{viewer, gocui.KeyPgdn, gocui.ModNone, "viewer.page-down", "Scroll down one page", scrollViewerPage(1)},
+		{viewer, gocui.KeySpace, gocui.ModNone, "viewer.page-down", "", scrollViewerPage(1)},
+		{viewer, gocui.KeyPgup, gocui.ModNone, "viewer.page-up", "Scroll up one page", scrollViewerPage(-1)},
+		{viewer, 'b', gocui.ModNone, "viewer.page-up", "", scrollViewerPage(-1)},
+		{viewer, 'g', gocui.ModNone, "viewer.top", "Go to the start", scrollViewer(-999999, true)},
+		{viewer, gocui.KeyHome, gocui.ModNone, "viewer.top", "", scrollViewer(-999999, true)},
+		{viewer, 'G', gocui.ModNone, "viewer.bottom", "Go to the end", scrollViewer(999999, true)},
+		{viewer, gocui.KeyEnd, gocui.ModNone, "viewer.bottom", "", scrollViewer(999999, true)},
+		{viewer, 'R', gocui.ModNone, "viewer.toggle-raw", "Toggle rendered / raw content",
+			func(gui *gocui.Gui, view *gocui.View) error { return handleToggleContentRaw(gui, state) }},
+		{viewer, 'r', gocui.ModNone, "viewer.reload", "Reload the file", onView(handleReloadFileContent)},
+		{viewer, '#', gocui.ModNone, "viewer.line-numbers", "Cycle line numbers: absolute, relative, off",
+			func(gui *gocui.Gui, view *gocui.View) error { return handleCycleLineNumbers(gui, state) }},
+		{viewer, gocui.KeyCtrlW, gocui.ModNone, "viewer.toggle-whitespace", "Toggle line-ending and trailing whitespace markers",
+			func(gui *gocui.Gui, view *gocui.View) error { return handleToggleContentWhitespace(gui, state) }},
+		{viewer, 'q', gocui.ModNone, "viewer.close", "Close the viewer", onView(handleCloseFileContentView)},
+		{viewer, gocui.KeyEsc, gocui.ModNone, "viewer.close", "", onView(handleCloseFileContentView)},
+
+		// --- Action Menu ---
+		{menu, 'j', gocui.ModNone, "menu.down", "Move down", navigateMenu(1)},
+		{menu, gocui.KeyArrowDown, gocui.ModNone, "menu.down", "", navigateMenu(1)},
+		{menu, 'k', gocui.ModNone, "menu.up", "Move up", navigateMenu(-1)},
+		{menu, gocui.KeyArrowUp, gocui.ModNone, "menu.up", "", navigateMenu(-1)},
+		{menu, gocui.KeyEnter, gocui.ModNone, "menu.run", "Run the selected action", onView(handleMenuSelect)},
+		{menu, 'q', gocui.ModNone, "menu.close", "Close the menu", onView(handleMenuClose)},
+		{menu, gocui.KeyEsc, gocui.ModNone, "menu.close", "", onView(handleMenuClose)},
+
+		// --- Prompt ---
+		{prompt, gocui.KeyEnter, gocui.ModNone, "prompt.submit", "Submit the input", onView(handlePromptSubmit)},
+		{prompt, gocui.KeyEsc, gocui.ModNone, "prompt.cancel", "Cancel", onView(handlePromptCancel)},
+		{prompt, gocui.KeyArrowUp, gocui.ModNone, "prompt.history-back", "Recall the previous input", recallInput(-1)},
+		{prompt, gocui.KeyArrowDown, gocui.ModNone, "prompt.history-forward", "Recall the next input", recallInput(1)},
+
+		// --- Confirmation Dialog ---
+		{[]string{viewConfirm}, 'y', gocui.ModNone, "confirm.yes", "Confirm", answer('y')},
+		{[]string{viewConfirm}, 'Y', gocui.ModNone, "confirm.yes", "", answer('y')},
+		{[]string{viewConfirm}, 'n', gocui.ModNone, "confirm.no", "Cancel", answer('n')},
+		{[]string{viewConfirm}, 'N', gocui.ModNone, "confirm.no", "", answer('n')},
+		{[]string{viewConfirm}, gocui.KeyEsc, gocui.ModNone, "confirm.no", "", onView(handleConfirmCancel)},
+		{[]string{viewConfirm}, 'q', gocui.ModNone, "confirm.no", "", onView(handleConfirmCancel)},
+		{[]string{viewConfirmText}, gocui.KeyEnter, gocui.ModNone, "confirm.typed", "Confirm once the text matches", onView(handleConfirmTyped)},
+		{[]string{viewConfirmText}, gocui.KeyEsc, gocui.ModNone, "confirm.cancel", "Cancel", onView(handleConfirmCancel)},
+
+		// --- Properties ---
+		{props, 'q', gocui.ModNone, "properties.close", "Close the popup", onView(handleCloseProperties)},
+		{props, gocui.KeyEsc, gocui.ModNone, "properties.close", "", onView(handleCloseProperties)},
+
+		// --- Command Palette ---
+		// Letters are typed into the query, so only non-printing keys are bound
+		{[]string{viewPalette}, gocui.KeyArrowDown, gocui.ModNone, "palette.down", "Move down", navigatePalette(1)},
+		{[]string{viewPalette}, gocui.KeyCtrlN, gocui.ModNone, "palette.down", "", navigatePalette(1)},
+		{[]string{viewPalette}, gocui.KeyArrowUp, gocui.ModNone, "palette.up", "Move up", navigatePalette(-1)},
+		{[]string{viewPalette}, gocui.KeyCtrlP, gocui.ModNone, "palette.up", "", navigatePalette(-1)},
+		{[]string{viewPalette}, gocui.KeyEnter, gocui.ModNone, "palette.run", "Run the highlighted command", onView(handlePaletteRun)},
+		{[]string{viewPalette}, gocui.KeyEsc, gocui.ModNone, "palette.close", "Close", onView(handleClosePalette)},
+
+		// --- Largest Files ---
+		{[]string{viewTopFiles}, 'j', gocui.ModNone, "top-files.down", "Move down", navigateTopFiles(1)},
+		{[]string{viewTopFiles}, gocui.KeyArrowDown, gocui.ModNone, "top-files.down", "", navigateTopFiles(1)},
+		{[]string{viewTopFiles}, 'k', gocui.ModNone, "top-files.up", "Move up", navigateTopFiles(-1)},
+		{[]string{viewTopFiles}, gocui.KeyArrowUp, gocui.ModNone, "top-files.up", "", navigateTopFiles(-1)},
+		{[]string{viewTopFiles}, gocui.KeyEnter, gocui.ModNone, "top-files.select", "Select the file or copy its path", onView(handleTopFilesSelect)},
+		{[]string{viewTopFiles}, 'q', gocui.ModNone, "top-files.close", "Close", onView(handleCloseTopFiles)},
+		{[]string{viewTopFiles}, gocui.KeyEsc, gocui.ModNone, "top-files.close", "", onView(handleCloseTopFiles)},
+
+		// --- File History ---
+		{[]string{viewHistory}, 'j', gocui.ModNone, "history.down", "Move down", navigateHistory(1)},
+		{[]string{viewHistory}, gocui.KeyArrowDown, gocui.ModNone, "history.down", "", navigateHistory(1)},
+		{[]string{viewHistory}, 'k', gocui.ModNone, "history.up", "Move up", navigateHistory(-1)},
+		{[]string{viewHistory}, gocui.KeyArrowUp, gocui.ModNone, "history.up", "", navigateHistory(-1)},
+		{[]string{viewHistory}, gocui.KeyPgdn, gocui.ModNone, "history.page-down", "Move down one page", navigateHistoryPage(1)},
+		{[]string{viewHistory}, gocui.KeyPgup, gocui.ModNone, "history.page-up", "Move up one page", navigateHistoryPage(-1)},
+		{[]string{viewHistory}, gocui.KeyEnter, gocui.ModNone, "history.select", "View the file as of the commit", onView(handleHistorySelect)},
+		{[]string{viewHistory}, 'q', gocui.ModNone, "history.close", "Close", onView(handleCloseHistory)},
+		{[]string{viewHistory}, gocui.KeyEsc, gocui.ModNone, "history.close", "", onView(handleCloseHistory)},
+
+		// --- Drive Picker ---
+		{[]string{viewMounts}, 'j', gocui.ModNone, "mounts.down", "Move down", navigateMounts(1)},
+		{[]string{viewMounts}, gocui.KeyArrowDown, gocui.ModNone, "mounts.down", "", navigateMounts(1)},
+		{[]string{viewMounts}, 'k', gocui.ModNone, "mounts.up", "Move up", navigateMounts(-1)},
+		{[]string{viewMounts}, gocui.KeyArrowUp, gocui.ModNone, "mounts.up", "", navigateMounts(-1)},
+		{[]string{viewMounts}, gocui.KeyEnter, gocui.ModNone, "mounts.select", "Change to the drive or mount point", onView(handleMountsSelect)},
+		{[]string{viewMounts}, 'q', gocui.ModNone, "mounts.close", "Close", onView(handleCloseMounts)},
+		{[]string{viewMounts}, gocui.KeyEsc, gocui.ModNone, "mounts.close", "", onView(handleCloseMounts)},
+
+		// --- Cleanup Report ---
+		{[]string{viewCleanup}, 'j', gocui.ModNone, "cleanup.down", "Move down", navigateCleanup(1)},
+		{[]string{viewCleanup}, gocui.KeyArrowDown, gocui.ModNone, "cleanup.down", "", navigateCleanup(1)},
+		{[]string{viewCleanup}, 'k', gocui.ModNone, "cleanup.up", "Move up", navigateCleanup(-1)},
+		{[]string{viewCleanup}, gocui.KeyArrowUp, gocui.ModNone, "cleanup.up", "", navigateCleanup(-1)},
+		{[]string{viewCleanup}, gocui.KeyPgdn, gocui.ModNone, "cleanup.page-down", "Move down one page", navigateCleanupPage(1)},
+		{[]string{viewCleanup}, gocui.KeyPgup, gocui.ModNone, "cleanup.page-up", "Move up one page", navigateCleanupPage(-1)},
+		{[]string{viewCleanup}, 'g', gocui.ModNone, "cleanup.top", "Go to the first entry", navigateCleanup(-999999)},
+		{[]string{viewCleanup}, 'G', gocui.ModNone, "cleanup.bottom", "Go to the last entry", navigateCleanup(999999)},
+		{[]string{viewCleanup}, gocui.KeyEnter, gocui.ModNone, "cleanup.select", "Jump to the entry in its folder", onView(handleCleanupSelect)},
+		{[]string{viewCleanup}, 'q', gocui.ModNone, "cleanup.close", "Close", onView(handleCloseCleanup)},
+		{[]string{viewCleanup}, gocui.KeyEsc, gocui.ModNone, "cleanup.close", "", onView(handleCloseCleanup)},
+
+		// --- Broken Links ---
+		{[]string{viewBrokenLinks}, 'j', gocui.ModNone, "broken-links.down", "Move down", navigateBrokenLinks(1)},
+		{[]string{viewBrokenLinks}, gocui.KeyArrowDown, gocui.ModNone, "broken-links.down", "", navigateBrokenLinks(1)},
+		{[]string{viewBrokenLinks}, 'k', gocui.ModNone, "broken-links.up", "Move up", navigateBrokenLinks(-1)},
+		{[]string{viewBrokenLinks}, gocui.KeyArrowUp, gocui.ModNone, "broken-links.up", "", navigateBrokenLinks(-1)},
+		{[]string{viewBrokenLinks}, gocui.KeyPgdn, gocui.ModNone, "broken-links.page-down", "Move down one page", navigateBrokenLinksPage(1)},
+		{[]string{viewBrokenLinks}, gocui.KeyPgup, gocui.ModNone, "broken-links.page-up", "Move up one page", navigateBrokenLinksPage(-1)},
+		{[]string{viewBrokenLinks}, gocui.KeyEnter, gocui.ModNone, "broken-links.select", "Select the link and open its action menu", onView(handleBrokenLinksSelect)},
+		{[]string{viewBrokenLinks}, 'q', gocui.ModNone, "broken-links.close", "Close", onView(handleCloseBrokenLinks)},
+		{[]string{viewBrokenLinks}, gocui.KeyEsc, gocui.ModNone, "broken-links.close", "", onView(handleCloseBrokenLinks)},
+		{[]string{viewDirCompare}, 'j', gocui.ModNone, "compare.down", "Move down", navigateDirCompare(1)},
+		{[]string{viewDirCompare}, gocui.KeyArrowDown, gocui.ModNone, "compare.down", "", navigateDirCompare(1)},
+		{[]string{viewDirCompare}, 'k', gocui.ModNone, "compare.up", "Move up", navigateDirCompare(-1)},
+		{[]string{viewDirCompare}, gocui.KeyArrowUp, gocui.ModNone, "compare.up", "", navigateDirCompare(-1)},
+		{[]string{viewDirCompare}, gocui.KeyPgdn, gocui.ModNone, "compare.page-down", "Move down one page", navigateDirComparePage(1)},
+		{[]string{viewDirCompare}, gocui.KeyPgup, gocui.ModNone, "compare.page-up", "Move up one page", navigateDirComparePage(-1)},
+		{[]string{viewDirCompare}, gocui.KeyEnter, gocui.ModNone, "compare.select", "Jump to the entry, in B if it is only there", onView(handleDirCompareSelect)},
+		{[]string{viewDirCompare}, 'c', gocui.ModNone, "compare.contents", "Compare again, checking the contents of same-size files", onView(handleDirCompareContents)},
+		{[]string{viewDirCompare}, 'q', gocui.ModNone, "compare.close", "Close (stops a running comparison)", onView(handleCloseDirCompare)},
+		{[]string{viewDirCompare}, gocui.KeyEsc, gocui.ModNone, "compare.close", "", onView(handleCloseDirCompare)},
+
+		// --- Background Tasks ---
+		{[]string{viewTasks}, 'j', gocui.ModNone, "tasks.down", "Move down", navigateTasks(1)},
+		{[]string{viewTasks}, gocui.KeyArrowDown, gocui.ModNone, "tasks.down", "", navigateTasks(1)},
+		{[]string{viewTasks}, 'k', gocui.ModNone, "tasks.up", "Move up", navigateTasks(-1)},
+		{[]string{viewTasks}, gocui.KeyArrowUp, gocui.ModNone, "tasks.up", "", navigateTasks(-1)},
+		{[]string{viewTasks}, 'x', gocui.ModNone, "tasks.cancel", "Cancel the selected task", onView(handleCancelTask)},
+		{[]string{viewTasks}, 'q', gocui.ModNone, "tasks.close", "Close", onView(handleCloseTasks)},
+		{[]string{viewTasks}, gocui.KeyEsc, gocui.ModNone, "tasks.close", "", onView(handleCloseTasks)},
+	}
+
+	// Digits pick a menu entry by number, letters by mnemonic (j/k/q stay navigation keys)
+	var shortcutKeys []rune
+	for ch := '1'; ch <= '9'; ch++ {
+		shortcutKeys = append(shortcutKeys, ch)
+	}
+	for ch := 'a'; ch <= 'z'; ch++ {
+		if !menuReservedKeys[ch] {
+			shortcutKeys = append(shortcutKeys, ch, unicode.ToUpper(ch))
+		}
+	}
+	for i, ch := range shortcutKeys {
+		key := ch
+		desc := ""
+		if i == 0 {
+			desc = "Run the numbered entry or the one with that underlined letter"
+		}
+		bindings = append(bindings, keyBinding{menu, key, gocui.ModNone, "menu.shortcut", desc,
+			func(gui *gocui.Gui, view *gocui.View) error { return handleMenuShortcut(gui, view, key, state) }})
+	}
+
+	// Any letter completes a pending m or `
+	for ch := 'a'; ch <= 'z'; ch++ {
+		letter := ch
+		desc := ""
+		if letter == 'a' {
+			desc = "Set or jump to the mark with that letter"
+		}
+		bindings = append(bindings, keyBinding{[]string{viewMarkKey}, letter, gocui.ModNone, "marks.letter", desc,
+			func(gui *gocui.Gui, view *gocui.View) error { return handleMarkLetter(gui, view, letter, state) }})
+	}
+	bindings = append(bindings, keyBinding{[]string{viewMarkKey}, gocui.KeyEsc, gocui.ModNone, "marks.cancel", "Cancel", onView(handleCancelMark)})
+	// Other keys of the lists cancel it and do what they do there; keys bound
+	// nowhere cancel it through markKeyEditor
+	for _, b := range bindings {
+		if !slices.Contains(b.views, viewFolders) || b.key == gocui.KeyEsc {
+			continue
+		}
+		if ch, ok := b.key.(rune); ok && ch >= 'a' && ch <= 'z' && b.mod == gocui.ModNone {
+			continue
+		}
+		handler := b.handler
+		bindings = append(bindings, keyBinding{[]string{viewMarkKey}, b.key, b.mod, "marks.pass", "",
+			func(gui *gocui.Gui, view *gocui.View) error { return handleMarkPassthrough(gui, handler, state) }})
+	}
+
+	bindings = append(bindings, scrollBindings(viewExtStats, "ext-stats",
+		func(gui *gocui.Gui, view *gocui.View, delta int) error {
+			return handleScrollExtStats(gui, view, delta, state)
+		}, onView(handleCloseExtStats))...)
+	bindings = append(bindings, scrollBindings(viewMessages, "messages",
+		func(gui *gocui.Gui, view *gocui.View, delta int) error {
+			return handleScrollMessages(gui, view, delta, state)
+		}, onView(handleCloseMessages))...)
+	bindings = append(bindings, scrollBindings(viewStatsErrors, "stats-errors",
+		func(gui *gocui.Gui, view *gocui.View, delta int) error {
+			return handleScrollStatsErrors(gui, view, delta, state)
+		}, onView(handleCloseStatsErrors))...)
+	bindings = append(bindings, scrollBindings(viewHelp, "help",
+		func(gui *gocui.Gui, view *gocui.View, delta int) error {
+			return handleScrollHelp(gui, view, delta, state)
+		}, onView(handleCloseHelp))...)
+	bindings = append(bindings, keyBinding{[]string{viewHelp}, '?', gocui.ModNone, "help.close", "", onView(handleCloseHelp)})
+
+	return bindings
+}
+
+// scrollBindings returns the keys shared by the scrollable read-only overlays.
+// Action IDs are prefixed with prefix.
+func scrollBindings(viewName, prefix string, scroll func(*gocui.Gui, *gocui.View, int) error, closeOverlay func(*gocui.Gui, *gocui.View) error) []keyBinding {
+	line := func(delta int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return scroll(gui, view, delta) }
+	}
+	page := func(multiplier int) func(*gocui.Gui, *gocui.View) error {
+		return func(gui *gocui.Gui, view *gocui.View) error { return scroll(gui, view, multiplier*pageHeight(view)) }
+	}
+	views := []string{viewName}
+	return []keyBinding{
+		{views, 'j', gocui.ModNone, prefix + ".down", "Scroll down", line(1)},
+		{views, gocui.KeyArrowDown, gocui.ModNone, prefix + ".down", "", line(1)},
+		{views, 'k', gocui.ModNone, prefix + ".up", "Scroll up", line(-1)},
+		{views, gocui.KeyArrowUp, gocui.ModNone, prefix + ".up", "", line(-1)},
+		{views, gocui.KeyPgdn, gocui.ModNone, prefix + ".page-down", "Scroll down one page", page(1)},
+		{views, gocui.KeySpace, gocui.ModNone, prefix + ".page-down", "", page(1)},
+		{views, gocui.KeyPgup, gocui.ModNone, prefix + ".page-up", "Scroll up one page", page(-1)},
+		{views, 'b', gocui.ModNone, prefix + ".page-up", "", page(-1)},
+		{views, 'g', gocui.ModNone, prefix + ".top", "Go to the top", line(-999999)},
+		{views, 'G', gocui.ModNone, prefix + ".bottom", "Go to the bottom", line(999999)},
+		{views, 'q', gocui.ModNone, prefix + ".close", "Close", closeOverlay},
+		{views, gocui.KeyEsc, gocui.ModNone, prefix + ".close", "", closeOverlay},
+	}
+}
+
+// pageHeight is how far a page key moves in v: its height minus one line of overlap.
+func pageHeight(v *gocui.View) int {
+	_, height := contentSize(v)
+	return clampHeight(height - 1)
+}
+
+// setupKeybindings installs the keybinding table. A key bound twice in the
+// same view is logged and only its first binding is kept.
+func setupKeybindings(g *gocui.Gui, state *AppState) error {
+	type boundKey struct {
+		view string
+		key  interface{}
+		mod  gocui.Modifier
+	}
+	seen := make(map[boundKey]string)
+
+	keyTable = keyBindings(state)
+	for _, b := range keyTable {
+		for _, viewName := range b.views {
+			id := boundKey{viewName, b.key, b.mod}
+			if action, ok := seen[id]; ok {
+				log.Printf("Warning: %s in view %q is bound to both %s and %s, keeping %s",
+					keyLabel(b.key, b.mod), viewName, action, b.action, action)
+				continue
+			}
+			seen[id] = b.action
+			if err := g.SetKeybinding(viewName, b.key, b.mod, b.handler); err != nil {
+				return fmt.Errorf("binding %s in view %q: %w", keyLabel(b.key, b.mod), viewName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// --- Help Text ---
+
+// keyNames are the display names of the special keys used in the table.
+var keyNames = map[gocui.Key]string{
+	gocui.KeyCtrlC:      "Ctrl+C",
+	gocui.KeyCtrlG:      "Ctrl+G",
+	gocui.KeyCtrlK:      "Ctrl+K",
+	gocui.KeyCtrlN:      "Ctrl+N",
+	gocui.KeyCtrlP:      "Ctrl+P",
+	gocui.KeyCtrlR:      "Ctrl+R",
+	gocui.KeyCtrlV:      "Ctrl+V",
+	gocui.KeyCtrlW:      "Ctrl+W",
+	gocui.KeyTab:        "Tab",
+	gocui.KeyEnter:      "Enter",
+	gocui.KeyEsc:        "Esc",
+	gocui.KeySpace:      "Space",
+	gocui.KeyArrowUp:    "↑",
+	gocui.KeyArrowDown:  "↓",
+	gocui.KeyArrowLeft:  "←",
+	gocui.KeyArrowRight: "→",
+	gocui.KeyPgup:       "PgUp",
+	gocui.KeyPgdn:       "PgDn",
+	gocui.KeyHome:       "Home",
+	gocui.KeyEnd:        "End",
+	gocui.KeyF2:         "F2",
+	gocui.KeyF5:         "F5",
+	gocui.KeyF6:         "F6",
+}
+
+// helpKeyOverrides replace the key list of actions bound to too many keys to list.
+var helpKeyOverrides = map[string]string{
+	"menu.shortcut": "1-9 / letter",
+	"marks.letter":  "a-z",
+}
+
+// keyLabel returns how a key is written in help text, e.g. "G", "PgDn" or "Alt+x".
+func keyLabel(key interface{}, mod gocui.Modifier) string {
+	prefix := ""
+	if mod == gocui.ModAlt {
+		prefix = "Alt+"
+	}
+	switch k := key.(type) {
+	case rune:
+		return prefix + string(k)
+	case gocui.Key:
+		if name, ok := keyNames[k]; ok {
+			return prefix + name
+		}
+		return fmt.Sprintf("%skey %d", prefix, k)
+	}
+	return fmt.Sprint(key)
+}
+
+// bindingContext names the part of the UI a binding applies to in help text.
+func bindingContext(views []string) string {
+	switch views[0] {
+	case "":
+		return "Global"
+	case viewFolders, viewFiles, viewCombined:
+		return "Lists"
+	case viewLargest:
+		return "Largest File Pane"
+	case viewStatus:
+		return "Root Folder Pane"
+	case viewGit:
+		return "Git Status Pane"
+	case viewFileContent:
+		return "File Viewer"
+	case viewActionMenu:
+		return "Action Menu"
+	case viewPrompt:
+		return "Prompt"
+	case viewConfirm:
+		return "Confirmation"
+	case viewConfirmText:
+		return "Typed Confirmation"
+	case viewProperties:
+		return "Properties"
+	case viewPalette:
+		return "Command Palette"
+	case viewTopFiles:
+		return "Largest Files"
+	case viewHistory:
+		return "File History"
+	case viewMounts:
+		return "Drive Picker"
+	case viewTasks:
+		return "Tasks"
+	case viewCleanup:
+		return "Cleanup Report"
+	case viewBrokenLinks:
+		return "Broken Links"
+	case viewDirCompare:
+		return "Directory Comparison"
+	case viewMarkKey:
+		return "Marks"
+	case viewExtStats:
+		return "Extensions"
+	case viewMessages:
+		return "Messages"
+	case viewStatsErrors:
+		return "Scan Errors"
+	case viewHelp:
+		return "Help"
+	}
+	return views[0]
+}
+
+// helpEntry is one line of the help overlay: every key of an action in a context.
+type helpEntry struct {
+	context string
+	action  string
+	keys    []string
+	desc    string
+}
+
+// keyText is the key column of a help line.
+func (e helpEntry) keyText() string {
+	if override, ok := helpKeyOverrides[e.action]; ok {
+		return override
+	}
+	return strings.Join(e.keys, " / ")
+}
+
+// helpEntries groups bindings into help lines in table order, skipping
+// actions without a description.
+func helpEntries(bindings []keyBinding) []helpEntry {
+	type entryKey struct{ context, action string }
+	index := make(map[entryKey]int)
+	var entries []helpEntry
+	for _, b := range bindings {
+		id := entryKey{bindingContext(b.views), b.action}
+		i, ok := index[id]
+		if !ok {
+			i = len(entries)
+			index[id] = i
+			entries = append(entries, helpEntry{context: id.context, action: b.action})
+		}
+		entries[i].keys = append(entries[i].keys, keyLabel(b.key, b.mod))
+		if entries[i].desc == "" {
+			entries[i].desc = b.desc
+		}
+	}
+
+	kept := entries[:0]
+	for _, e := range entries {
+		if e.desc != "" {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// helpLines renders the keybinding table as the help overlay's text, one
+// section per context, with the version at the end.
+func helpLines(bindings []keyBinding) []string {
+	entries := helpEntries(bindings)
+	keyWidth := 0
+	for _, e := range entries {
+		if w := displayWidth(e.keyText()); w > keyWidth {
+			keyWidth = w
+		}
+	}
+
+	var lines []string
+	context := ""
+	for _, e := range entries {
+		if e.context != context {
+			if context != "" {
+				lines = append(lines, "")
+			}
+			context = e.context
+			lines = append(lines, ansiBold+ansiYellow+context+ansiReset)
+		}
+		keys := e.keyText()
+		pad := strings.Repeat(" ", keyWidth-displayWidth(keys))
+		lines = append(lines, fmt.Sprintf("  %s%s%s%s  %s", ansiCyan, keys, ansiReset, pad, e.desc))
+	}
+	return append(lines, "", ansiGray+versionString()+ansiReset)
+}
+
+// --- Hint Bar ---
+
+// showKeyHints controls the key-hint line above the message bar.
+var showKeyHints = true
+
+// configureHints applies the "hide_key_hints" setting.
+func configureHints(cfg config) {
+	showKeyHints = !cfg.HideKeyHints
+}
+
+// keyHint is one item of the hint bar: the first key of each action,
+// joined by "/", followed by a short label.
+type keyHint struct {
+	actions []string
+	label   string
+}
+
+// scrollHints are the hints of the read-only overlays built by scrollBindings.
+func scrollHints(prefix string) []keyHint {
+	return []keyHint{
+		{[]string{prefix + ".down", prefix + ".up"}, "scroll"},
+		{[]string{prefix + ".page-down", prefix + ".page-up"}, "page"},
+		{[]string{prefix + ".top", prefix + ".bottom"}, "top/bottom"},
+		{[]string{prefix + ".close"}, "close"},
+	}
+}
+
+// contextHints lists the most relevant keys per context, most important first;
+// the keys themselves are looked up in the keybinding table.
+var contextHints = map[string][]keyHint{
+	"Lists": {
+		{[]string{"list.down", "list.up"}, "move"},
+		{[]string{"list.actions"}, "actions"},
+		{[]string{"focus.next"}, "switch"},
+		{[]string{"toggle.hidden"}, "hidden"},
+		{[]string{"show.palette"}, "commands"},
+		{[]string{"show.help"}, "help"},
+		{[]string{"app.quit"}, "quit"},
+	},
+	"Largest File Pane": {
+		{[]string{"largest.down", "largest.up"}, "file/folder"},
+		{[]string{"largest.select"}, "select"},
+		{[]string{"show.top-files"}, "top files"},
+		{[]string{"focus.next"}, "switch"},
+		{[]string{"app.quit"}, "quit"},
+	},
+	"Root Folder Pane": {
+		{[]string{"status.left", "status.right"}, "select folder"},
+		{[]string{"status.open"}, "go"},
+		{[]string{"status.copy-path"}, "copy path"},
+		{[]string{"focus.next"}, "switch"},
+		{[]string{"app.quit"}, "quit"},
+	},
+	"Git Status Pane": {
+		{[]string{"git.branches"}, "branches"},
+		{[]string{"focus.next"}, "switch"},
+		{[]string{"app.quit"}, "quit"},
+	},
+	"File Viewer": {
+		{[]string{"viewer.down", "viewer.up"}, "scroll"},
+		{[]string{"viewer.page-down", "viewer.page-up"}, "page"},
+		{[]string{"viewer.top", "viewer.bottom"}, "top/bottom"},
+		{[]string{"viewer.toggle-raw"}, "raw"},
+		{[]string{"viewer.reload"}, "reload"},
+		{[]string{"viewer.line-numbers"}, "numbers"},
+		{[]string{"viewer.toggle-whitespace"}, "whitespace"},
+		{[]string{"viewer.close"}, "close"},
+	},
+	"Action Menu": {
+		{[]string{"menu.down", "menu.up"}, "move"},
+		{[]string{"menu.run"}, "run"},
+		{[]string{"menu.close"}, "close"},
+	},
+	"Prompt": {
+		{[]string{"prompt.submit"}, "submit"},
+		{[]string{"prompt.history-back", "prompt.history-forward"}, "history"},
+		{[]string{"prompt.cancel"}, "cancel"},
+	},
+	"Confirmation": {
+		{[]string{"confirm.yes"}, "yes"},
+		{[]string{"confirm.no"}, "no"},
+	},
+	"Typed Confirmation": {
+		{[]string{"confirm.typed"}, "confirm"},
+		{[]string{"confirm.cancel"}, "cancel"},
+	},
+	"Properties": {
+		{[]string{"properties.close"}, "close"},
+	},
+	"Command Palette": {
+		{[]string{"palette.down", "palette.up"}, "move"},
+		{[]string{"palette.run"}, "run"},
+		{[]string{"palette.close"}, "close"},
+	},
+	"File History": {
+		{[]string{"history.down", "history.up"}, "move"},
+		{[]string{"history.page-down", "history.page-up"}, "page"},
+		{[]string{"history.select"}, "view"},
+		{[]string{"history.close"}, "close"},
+	},
+	"Largest Files": {
+		{[]string{"top-files.down", "top-files.up"}, "move"},
+		{[]string{"top-files.select"}, "select"},
+		{[]string{"top-files.close"}, "close"},
+	},
+	"Drive Picker": {
+		{[]string{"mounts.down", "mounts.up"}, "move"},
+		{[]string{"mounts.select"}, "open"},
+		{[]string{"mounts.close"}, "close"},
+	},
+	"Tasks": {
+		{[]string{"tasks.down", "tasks.up"}, "move"},
+		{[]string{"tasks.cancel"}, "cancel"},
+		{[]string{"tasks.close"}, "close"},
+	},
+	"Cleanup Report": {
+		{[]string{"cleanup.down", "cleanup.up"}, "move"},
+		{[]string{"cleanup.page-down", "cleanup.page-up"}, "page"},
+		{[]string{"cleanup.select"}, "jump"},
+		{[]string{"cleanup.close"}, "close"},
+	},
+	"Marks": {
+		{[]string{"marks.letter"}, "mark letter"},
+		{[]string{"marks.cancel"}, "cancel"},
+	},
+	"Broken Links": {
+		{[]string{"broken-links.down", "broken-links.up"}, "move"},
+		{[]string{"broken-links.page-down", "broken-links.page-up"}, "page"},
+		{[]string{"broken-links.select"}, "actions"},
+		{[]string{"broken-links.close"}, "close"},
+	},
+	"Directory Comparison": {
+		{[]string{"compare.down", "compare.up"}, "move"},
+		{[]string{"compare.page-down", "compare.page-up"}, "page"},
+		{[]string{"compare.select"}, "jump"},
+		{[]string{"compare.contents"}, "by contents"},
+		{[]string{"compare.close"}, "close"},
+	},
+	"Extensions":  scrollHints("ext-stats"),
+	"Messages":    scrollHints("messages"),
+	"Scan Errors": scrollHints("stats-errors"),
+	"Help":        scrollHints("help"),
+}
+
+// primaryKey returns the label of the first key bound to action, preferring
+// bindings in context. ok is false if the action is not bound at all.
+func primaryKey(bindings []keyBinding, context, action string) (label string, ok bool) {
+	for _, b := range bindings {
+		if b.action == action && bindingContext(b.views) == context {
+			return keyLabel(b.key, b.mod), true
+		}
+	}
+	for _, b := range bindings {
+		if b.action == action {
+			return keyLabel(b.key, b.mod), true
+		}
+	}
+	return "", false
+}
+
+// hintLine renders the hints for the view named viewName, dropping items
+// from the end so the line fits in width cells.
+func hintLine(bindings []keyBinding, viewName string, width int) string {
+	context := bindingContext([]string{viewName})
+	var b strings.Builder
+	used := 0
+	for _, hint := range contextHints[context] {
+		var keys []string
+		for _, action := range hint.actions {
+			if override, ok := helpKeyOverrides[action]; ok {
+				keys = append(keys, override)
+			} else if key, ok := primaryKey(bindings, context, action); ok {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		keyText := strings.Join(keys, "/")
+		itemWidth := 1 + displayWidth(keyText) + 1 + displayWidth(hint.label) + 1
+		if used+itemWidth > width {
+			break
+		}
+		used += itemWidth
+		fmt.Fprintf(&b, " %s%s%s %s ", ansiCyan, keyText, ansiReset, hint.label)
+	}
+	return b.String()
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jroimartin/gocui"
+)
+
+func TestMarkKeyBindings(t *testing.T) {
+	actions := make(map[interface{}]string) // Key -> action while m or ` waits
+	for _, b := range keyBindings(NewAppState(t.TempDir())) {
+		for _, view := range b.views {
+			if view == viewMarkKey && b.mod == gocui.ModNone {
+				if _, ok := actions[b.key]; !ok {
+					actions[b.key] = b.action
+				}
+			}
+		}
+	}
+	tests := []struct {
+		key  interface{}
+		want string
+	}{
+		{'a', "marks.letter"},
+		{'j', "marks.letter"}, // Letters complete the mark, even those the lists use
+		{gocui.KeyEsc, "marks.cancel"},
+		{gocui.KeyArrowDown, "marks.pass"},
+		{'.', "marks.pass"},
+		{'V', "marks.pass"},
+		{'q', "marks.letter"},
+	}
+	for _, tt := range tests {
+		if got := actions[tt.key]; got != tt.want {
+			t.Errorf("%s while a mark waits: got %q, want %q", keyLabel(tt.key, gocui.ModNone), got, tt.want)
+		}
+	}
+}
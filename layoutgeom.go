@@ -0,0 +1,267 @@
+// ---- File: layoutgeom.go ----
+package main
+
+import "github.com/jroimartin/gocui"
+
+// --- Layout Geometry ---
+// computeLayout does the arithmetic of layout: where every view goes for a
+// terminal size and the current state. layout only creates, fills and focuses
+// the views, so the math can be checked without a terminal.
+
+// rect is a view's frame in gocui coordinates, as passed to SetView: the
+// corners are on the frame, the content is inside it.
+type rect struct {
+	x0, y0, x1, y1 int
+}
+
+// layoutGeometry holds the frames of the views to show. Views missing from
+// views aren't shown in this state (or, for tooSmall, are replaced by the notice).
+type layoutGeometry struct {
+	tooSmall     bool
+	mainAreaMaxY int // Bottom frame row of the panes, above the hint and message bars
+	views        map[string]rect
+}
+
+// setView creates the named view at its frame, or moves it there, like g.SetView.
+func (geo layoutGeometry) setView(g *gocui.Gui, name string) (*gocui.View, error) {
+	r := geo.views[name]
+	return g.SetView(name, r.x0, r.y0, r.x1, r.y1)
+}
+
+// clampHeight returns a view height that is safe to divide by and to page
+// with: at least one row. gocui reports zero or negative heights while a
+// rapid resize shrinks a view past its frame.
+func clampHeight(height int) int {
+	return max(height, 1)
+}
+
+// sizedView is the part of a *gocui.View contentSize uses.
+type sizedView interface {
+	Size() (x, y int)
+}
+
+// contentSize returns v's content size like v.Size, with the height clamped.
+func contentSize(v sizedView) (width, height int) {
+	width, height = v.Size()
+	return width, clampHeight(height)
+}
+
+// shows reports whether the named view is part of this layout.
+func (geo layoutGeometry) shows(name string) bool {
+	_, ok := geo.views[name]
+	return ok
+}
+
+// minStatsAreaHeight is the fewest rows the three stats boxes below the root
+// folder fit in with a line of content each. In a shorter terminal the stats
+// column gives way to the one-line summary, as when it is hidden.
+const minStatsAreaHeight = 7
+
+// computeLayout returns where the views go in a maxX x maxY terminal. tabBar
+// puts the tab bar on the top row, above the panes; commander is the side of
+// the active browser in commander mode.
+func computeLayout(maxX, maxY int, tabBar bool, commander commanderSide, state *AppState) layoutGeometry {
+	geo := layoutGeometry{views: make(map[string]rect)}
+	if maxX < minTerminalWidth || maxY < minTerminalHeight {
+		geo.tooSmall = true
+		geo.views[viewTooSmall] = rect{-1, -1, maxX, maxY}
+		return geo
+	}
+
+	// A frameless view draws inside its bounds, so the message bar spans
+	// maxY-2..maxY to put its single line on the last row of the terminal
+	bottomLineY := maxY - 2
+	geo.views[viewMessage] = rect{-1, bottomLineY, maxX, maxY}
+	if state.MarkKeyPending() != 0 {
+		geo.views[viewMarkKey] = rect{-1, bottomLineY, maxX, maxY}
+	}
+	mainAreaMaxY := bottomLineY
+	if showKeyHints && maxY >= minHintBarHeight {
+		mainAreaMaxY--
+		geo.views[viewHints] = rect{-1, mainAreaMaxY, maxX, bottomLineY + 1}
+	}
+	geo.mainAreaMaxY = mainAreaMaxY
+	top := 0 // First row of the panes
+	if tabBar {
+		geo.views[viewTabs] = rect{-1, -1, maxX, 1}
+		top = 1
+	}
+
+	// The content viewer takes the whole main area; nothing else is drawn with it
+	if state.IsFileContentViewVisible() {
+		geo.views[viewFileContent] = rect{0, top, maxX - 1, mainAreaMaxY}
+		return geo
+	}
+
+	// --- Panes ---
+	rightPanelX0 := 0
+	statusY1 := top + 2 // Label + value
+	statsAreaY0 := statusY1 + 1
+	statsAreaHeight := mainAreaMaxY - statsAreaY0
+	if state.IsStatsHidden() || statsAreaHeight < minStatsAreaHeight {
+		// The lists take the full width below a one-line summary of the stats
+		geo.views[viewStatusLine] = rect{-1, top - 1, maxX, top + 1}
+		top++
+	} else {
+		leftPanelWidth := max(int(float64(maxX)*state.PanelRatio()), minLeftPanelWidth)
+		leftPanelWidth = min(leftPanelWidth, maxX-minRightPanelWidth) // The right panel keeps some space
+		rightPanelX0 = leftPanelWidth + 1
+
+		// Root folder on top, then three stats boxes sharing the rest of the
+		// column; the last one gets the rows left over
+		geo.views[viewStatus] = rect{0, top, leftPanelWidth, statusY1}
+		boxHeight := min(statsAreaHeight/3, (statsAreaHeight-3)/2)
+		sizeY1 := statsAreaY0 + boxHeight
+		geo.views[viewSize] = rect{0, statsAreaY0, leftPanelWidth, sizeY1}
+		largestY1 := sizeY1 + 1 + boxHeight
+		geo.views[viewLargest] = rect{0, sizeY1 + 1, leftPanelWidth, largestY1}
+		geo.views[viewGit] = rect{0, largestY1 + 1, leftPanelWidth, mainAreaMaxY}
+	}
+	filesX0 := rightPanelX0 + (maxX-1-rightPanelX0)/2
+
+	// In commander mode the two browsers split the lists' area; the active one
+	// is the combined list
+	if commander != commanderOff {
+		left := rect{rightPanelX0, top, filesX0 - 1, mainAreaMaxY}
+		right := rect{filesX0, top, maxX - 1, mainAreaMaxY}
+		if commander == commanderRight {
+			left, right = right, left
+		}
+		geo.views[viewCombined] = left
+		geo.views[viewCommander] = right
+		geo.addOverlays(maxX, state)
+		return geo
+	}
+
+	// The preview takes the bottom half of the Files column (or of the combined list)
+	listsMaxY := mainAreaMaxY
+	if state.IsPreviewMode() {
+		listsMaxY = top + (mainAreaMaxY-top)/2
+		previewX0 := filesX0
+		if state.IsCombinedMode() {
+			previewX0 = rightPanelX0
+		}
+		geo.views[viewPreview] = rect{previewX0, listsMaxY + 1, maxX - 1, mainAreaMaxY}
+	}
+	if state.IsCombinedMode() {
+		geo.views[viewCombined] = rect{rightPanelX0, top, maxX - 1, listsMaxY}
+	} else {
+		geo.views[viewFolders] = rect{rightPanelX0, top, filesX0 - 1, mainAreaMaxY}
+		geo.views[viewFiles] = rect{filesX0, top, maxX - 1, listsMaxY}
+	}
+
+	geo.addOverlays(maxX, state)
+	return geo
+}
+
+// addOverlays adds the frames of the open overlays, which fit their content
+// within the main area.
+func (geo layoutGeometry) addOverlays(maxX int, state *AppState) {
+	mainAreaMaxY := geo.mainAreaMaxY
+	centered := func(name string, width, height int) {
+		geo.views[name] = overlayRect(maxX, mainAreaMaxY, width, height)
+	}
+
+	if state.IsActionMenuVisible() {
+		// Wide enough for the header and the longest label
+		options := state.GetActionMenuOptions()
+		width := max(40, displayWidth(actionMenuHeader(state.GetActionMenuItemTarget()))+3)
+		for _, option := range options {
+			width = max(width, displayWidth(option.Label)+6)
+		}
+		centered(viewActionMenu, width, len(options)+3) // Header + rule + options + frame
+	}
+
+	if state.IsPromptVisible() {
+		width := min(50, maxX-2)
+		x0 := (maxX - width) / 2
+		y0 := (mainAreaMaxY+1)/2 - 1
+		// An anchored prompt puts its input line on the list row it edits
+		if anchor := state.GetPrompt().Anchor; anchor != "" {
+			if a, ok := geo.views[anchor]; ok {
+				row := (state.GetCurrentCursorY(anchor) - state.GetCurrentOriginY(anchor)) / state.GridColumns(anchor)
+				width = min(max(a.x1-a.x0, 40), maxX-2)
+				x0 = max(min(a.x0, maxX-width-1), 0)
+				y0 = max(min(a.y0+row, mainAreaMaxY-3), 0)
+			}
+		}
+		geo.views[viewPrompt] = rect{x0, y0, x0 + width, y0 + 2}
+	}
+
+	if state.IsPaletteVisible() {
+		// The query line, with the matches below it
+		matches, _ := state.PaletteMatches()
+		width := min(60, maxX-2)
+		x0 := (maxX - width) / 2
+		y0 := max(mainAreaMaxY/6, 0)
+		listHeight := min(max(len(matches), 1)+1, mainAreaMaxY-y0-3)
+		geo.views[viewPalette] = rect{x0, y0, x0 + width, y0 + 2}
+		geo.views[viewPaletteList] = rect{x0, y0 + 3, x0 + width, y0 + 3 + listHeight}
+	}
+
+	if state.IsConfirmVisible() {
+		spec := state.GetConfirm()
+		width := min(60, maxX-2)
+		height := min(len(confirmLines(spec))+1, mainAreaMaxY-4)
+		x0 := (maxX - width) / 2
+		y0 := max((mainAreaMaxY+1-height)/2-1, 0)
+		geo.views[viewConfirm] = rect{x0, y0, x0 + width, y0 + height}
+		if spec.TypeToConfirm != "" {
+			geo.views[viewConfirmText] = rect{x0, y0 + height + 1, x0 + width, y0 + height + 3}
+		}
+	}
+
+	if state.IsPropertiesVisible() {
+		centered(viewProperties, 64, propertiesLineCount+1)
+	}
+	if state.IsTopFilesVisible() {
+		centered(viewTopFiles, max(maxX*2/3, 40), max(len(state.TopFiles())+1, 2)) // Room for the "no files" line
+	}
+	if state.IsHistoryVisible() {
+		_, entries, _, _, _ := state.History()
+		height := max(min(len(entries)+2, mainAreaMaxY-2), 2) // Room for the loading / "no commits" line
+		centered(viewHistory, max(maxX*4/5, 40), height)
+	}
+	if state.IsMountsVisible() {
+		entries, _, _ := state.Mounts()
+		centered(viewMounts, max(maxX*2/3, 50), min(max(len(entries), 1)+1, mainAreaMaxY-1))
+	}
+	if state.IsTasksVisible() {
+		centered(viewTasks, max(maxX*2/3, 60), min(max(len(state.Tasks()), 1)+1, mainAreaMaxY-1))
+	}
+	if state.IsCleanupVisible() {
+		entries, _, _ := state.CleanupEntries()
+		centered(viewCleanup, max(maxX*2/3, 60), min(max(len(entries), 1)+1, mainAreaMaxY-1))
+	}
+	if state.IsBrokenLinksVisible() {
+		links, _ := state.BrokenLinks()
+		centered(viewBrokenLinks, max(maxX*3/4, 60), min(max(len(links), 1)+1, mainAreaMaxY-1))
+	}
+	if state.IsDirCompareVisible() {
+		centered(viewDirCompare, max(maxX*3/4, 60), min(max(len(state.DirCompare().Diffs), 1)+1, mainAreaMaxY-1))
+	}
+	if state.IsExtStatsVisible() {
+		centered(viewExtStats, 56, min(max(len(state.ExtStats())+1, 2), mainAreaMaxY-1))
+	}
+	if state.IsMessagesVisible() {
+		centered(viewMessages, 80, min(max(len(state.MessageHistory())+1, 2), mainAreaMaxY-1))
+	}
+	if state.IsStatsErrorsVisible() {
+		errs, _ := state.StatsErrors()
+		centered(viewStatsErrors, 90, min(max(len(errs)+1, 2), mainAreaMaxY-1))
+	}
+	if state.IsHelpVisible() {
+		centered(viewHelp, 70, min(len(helpLines(keyTable))+1, mainAreaMaxY-1))
+	}
+}
+
+// overlayRect returns the frame of a width x height overlay centered over the
+// main area (the rows above the hint and message bars), narrowed and
+// shortened to fit it.
+func overlayRect(maxX, mainAreaMaxY, width, height int) rect {
+	width = min(width, maxX-2)
+	height = min(height, max(mainAreaMaxY-1, 1))
+	x0 := (maxX - width) / 2
+	y0 := max((mainAreaMaxY+1-height)/2, 0)
+	return rect{x0, y0, x0 + width, y0 + height}
+}
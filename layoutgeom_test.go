@@ -0,0 +1,226 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"testing"
+)
+
+// fixedSize is a view of a fixed size, as v.Size reports it.
+type fixedSize struct{ x, y int }
+
+func (s fixedSize) Size() (x, y int) { return s.x, s.y }
+
+func TestContentSize(t *testing.T) {
+	tests := []struct {
+		x, y          int
+		width, height int
+	}{
+		{80, 24, 80, 24},
+		{80, 1, 80, 1},
+		{80, 0, 80, 1},  // Shrunk past its frame mid-resize
+		{80, -3, 80, 1}, // Ditto, further
+		{-1, 5, -1, 5},  // Widths are left alone
+	}
+	for _, tt := range tests {
+		if width, height := contentSize(fixedSize{tt.x, tt.y}); width != tt.width || height != tt.height {
+			t.Errorf("contentSize(%dx%d) = %dx%d, want %dx%d", tt.x, tt.y, width, height, tt.width, tt.height)
+		}
+	}
+}
+
+func TestComputeLayout(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxX, maxY int
+		tabBar     bool
+		commander  commanderSide
+		setup      func(state *AppState)
+		views      []string        // Exactly the views shown
+		rects      map[string]rect // Some of their frames
+	}{
+		{
+			name: "too narrow", maxX: minTerminalWidth - 1, maxY: 30,
+			views: []string{viewTooSmall},
+		},
+		{
+			name: "too short", maxX: 120, maxY: minTerminalHeight - 1,
+			views: []string{viewTooSmall},
+		},
+		{
+			name: "smallest terminal has no room for the stats boxes", maxX: minTerminalWidth, maxY: minTerminalHeight,
+			views: []string{viewMessage, viewStatusLine, viewFolders, viewFiles},
+			rects: map[string]rect{
+				viewStatusLine: {-1, -1, minTerminalWidth, 1},
+				viewFolders:    {0, 1, 18, 8},
+				viewFiles:      {19, 1, 39, 8},
+			},
+		},
+		{
+			name: "shortest stats column", maxX: minTerminalWidth, maxY: 12,
+			views: []string{viewMessage, viewStatus, viewSize, viewLargest, viewGit, viewFolders, viewFiles},
+			rects: map[string]rect{
+				viewStatus:  {0, 0, minLeftPanelWidth, 2},
+				viewSize:    {0, 3, minLeftPanelWidth, 5},
+				viewLargest: {0, 6, minLeftPanelWidth, 8},
+				viewGit:     {0, 9, minLeftPanelWidth, 10},
+				viewFolders: {minLeftPanelWidth + 1, 0, 29, 10},
+				viewFiles:   {30, 0, 39, 10},
+			},
+		},
+		{
+			name: "tab bar takes a stats row", maxX: 80, maxY: 12, tabBar: true,
+			views: []string{viewMessage, viewTabs, viewStatusLine, viewFolders, viewFiles},
+		},
+		{
+			name: "hint bar from its minimum height", maxX: 80, maxY: minHintBarHeight,
+			views: []string{viewMessage, viewHints, viewStatus, viewSize, viewLargest, viewGit, viewFolders, viewFiles},
+			rects: map[string]rect{viewHints: {-1, 10, 80, 12}, viewGit: {0, 9, 26, 10}},
+		},
+		{
+			name: "odd width", maxX: 121, maxY: 30,
+			views: []string{viewMessage, viewHints, viewStatus, viewSize, viewLargest, viewGit, viewFolders, viewFiles},
+			rects: map[string]rect{
+				viewFolders: {41, 0, 79, 27},
+				viewFiles:   {80, 0, 120, 27},
+			},
+		},
+		{
+			name: "wide left panel leaves the right one its minimum", maxX: 60, maxY: 30,
+			setup: func(s *AppState) { s.ApplyPreferences(preferences{PanelRatio: 0.9}) },
+			views: []string{viewMessage, viewHints, viewStatus, viewSize, viewLargest, viewGit, viewFolders, viewFiles},
+			rects: map[string]rect{viewStatus: {0, 0, 60 - minRightPanelWidth, 2}},
+		},
+		{
+			name: "tab bar and stats hidden", maxX: 100, maxY: 30, tabBar: true,
+			setup: func(s *AppState) { s.SetStatsHidden(true) },
+			views: []string{viewMessage, viewHints, viewTabs, viewStatusLine, viewFolders, viewFiles},
+			rects: map[string]rect{
+				viewTabs:       {-1, -1, 100, 1},
+				viewStatusLine: {-1, 0, 100, 2},
+				viewFolders:    {0, 2, 48, 27},
+			},
+		},
+		{
+			name: "combined list with preview", maxX: 100, maxY: 30,
+			setup: func(s *AppState) { s.ToggleCombinedMode(); s.TogglePreviewMode() },
+			views: []string{viewMessage, viewHints, viewStatus, viewSize, viewLargest, viewGit, viewCombined, viewPreview},
+			rects: map[string]rect{
+				viewCombined: {34, 0, 99, 13},
+				viewPreview:  {34, 14, 99, 27},
+			},
+		},
+		{
+			name: "commander, active on the right", maxX: 100, maxY: 30, commander: commanderRight,
+			views: []string{viewMessage, viewHints, viewStatus, viewSize, viewLargest, viewGit, viewCombined, viewCommander},
+			rects: map[string]rect{
+				viewCommander: {34, 0, 65, 27},
+				viewCombined:  {66, 0, 99, 27},
+			},
+		},
+		{
+			name: "content viewer takes the main area", maxX: 100, maxY: 30,
+			setup: func(s *AppState) { s.SetFileContentView("a.txt", "text", viewFiles) },
+			views: []string{viewMessage, viewHints, viewFileContent},
+			rects: map[string]rect{viewFileContent: {0, 0, 99, 27}},
+		},
+		{
+			name: "help overlay fits a small terminal", maxX: minTerminalWidth, maxY: minTerminalHeight,
+			setup: func(s *AppState) { s.OpenHelp(viewFolders) },
+			views: []string{viewMessage, viewStatusLine, viewFolders, viewFiles, viewHelp},
+		},
+		{
+			name: "typed confirmation", maxX: 100, maxY: 30,
+			setup: func(s *AppState) {
+				s.OpenConfirm(removalConfirm(FileInfo{Name: "big", IsDir: true}, typedConfirmThreshold+1, 0, nil), viewFolders)
+			},
+			views: []string{viewMessage, viewHints, viewStatus, viewSize, viewLargest, viewGit, viewFolders, viewFiles, viewConfirm, viewConfirmText},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := NewAppState(t.TempDir())
+			if tt.setup != nil {
+				tt.setup(state)
+			}
+			geo := computeLayout(tt.maxX, tt.maxY, tt.tabBar, tt.commander, state)
+
+			var names []string
+			for name := range geo.views {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			want := slices.Clone(tt.views)
+			sort.Strings(want)
+			if !slices.Equal(names, want) {
+				t.Fatalf("views %q, want %q", names, want)
+			}
+			if geo.tooSmall != (tt.views[0] == viewTooSmall) {
+				t.Errorf("tooSmall is %v", geo.tooSmall)
+			}
+			for name, r := range geo.views {
+				if r.x0 < -1 || r.y0 < -1 || r.x1 > tt.maxX || r.y1 > tt.maxY || r.x0 >= r.x1 || r.y0 >= r.y1 {
+					t.Errorf("%s at %v, outside the %dx%d terminal or empty", name, r, tt.maxX, tt.maxY)
+				}
+			}
+			for name, want := range tt.rects {
+				if got := geo.views[name]; got != want {
+					t.Errorf("%s at %v, want %v", name, got, want)
+				}
+			}
+			if !geo.tooSmall {
+				for _, name := range []string{viewHelp, viewConfirm, viewConfirmText} {
+					if r, ok := geo.views[name]; ok && r.y1 > geo.mainAreaMaxY+1 {
+						t.Errorf("%s at %v runs past the main area (bottom %d)", name, r, geo.mainAreaMaxY)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestOverlaysAcrossResizes resizes the terminal step by step with overlays
+// open: at every size each one is re-centered within the main area, and each
+// is one raiseOverlays puts back on top.
+func TestOverlaysAcrossResizes(t *testing.T) {
+	state := NewAppState(t.TempDir())
+	item := FileInfo{Name: "notes.txt", Path: "/work/notes.txt"}
+	state.OpenActionMenu(item, actionsFor(item, state), viewFiles)
+	state.OpenConfirm(removalConfirm(item, 0, 0, nil), viewActionMenu)
+	state.OpenProperties(item, fileProperties{}, viewFiles)
+	state.OpenMessages(viewFiles)
+	open := []string{viewActionMenu, viewConfirm, viewProperties, viewMessages}
+
+	sizes := []struct{ maxX, maxY int }{
+		{120, 40}, {200, 60}, {80, 24}, {minTerminalWidth, minTerminalHeight},
+		{minTerminalWidth - 1, minTerminalHeight}, // Too small: the notice replaces everything
+		{100, 12}, {300, 90}, {120, 40},
+	}
+	for _, size := range sizes {
+		geo := computeLayout(size.maxX, size.maxY, false, commanderOff, state)
+		if geo.tooSmall {
+			if len(geo.views) != 1 {
+				t.Errorf("%dx%d: %d views next to the too-small notice", size.maxX, size.maxY, len(geo.views))
+			}
+			continue
+		}
+		for _, name := range open {
+			r, ok := geo.views[name]
+			if !ok {
+				t.Errorf("%dx%d: %s lost across the resize", size.maxX, size.maxY, name)
+				continue
+			}
+			if r.x0 < 0 || r.y0 < 0 || r.x1 >= size.maxX || r.y1 > geo.mainAreaMaxY+1 || r.x0 >= r.x1 || r.y0 >= r.y1 {
+				t.Errorf("%dx%d: %s at %v, outside the main area (bottom %d)", size.maxX, size.maxY, name, r, geo.mainAreaMaxY)
+			}
+			if left, right := r.x0, size.maxX-1-r.x1; left-right < -1 || left-right > 1 {
+				t.Errorf("%dx%d: %s at %v isn't centered", size.maxX, size.maxY, name, r)
+			}
+		}
+		for name := range geo.views {
+			if isOverlay := slices.Contains(open, name) || name == viewConfirmText; isOverlay && !slices.Contains(overlayViews, name) {
+				t.Errorf("%s is not raised over the panes", name)
+			}
+		}
+	}
+}
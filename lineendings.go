@@ -0,0 +1,83 @@
+// ---- File: lineendings.go ----
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// --- Line Endings ---
+
+// lineEndings records how the lines of viewed content were terminated.
+type lineEndings struct {
+	lf, crlf  int
+	crlfLines []bool // Per line, nil if no line ends in CRLF
+}
+
+// splitLineEndings removes the CR of CRLF line breaks from content, so the
+// view does not swallow the lines, and records which lines had one.
+func splitLineEndings(content string) (string, lineEndings) {
+	if !strings.Contains(content, "\r\n") {
+		return content, lineEndings{lf: strings.Count(content, "\n")}
+	}
+	lines := strings.Split(content, "\n")
+	endings := lineEndings{crlfLines: make([]bool, len(lines))}
+	for i := 0; i < len(lines)-1; i++ { // The last line has no line break
+		if strings.HasSuffix(lines[i], "\r") {
+			lines[i] = strings.TrimSuffix(lines[i], "\r")
+			endings.crlfLines[i] = true
+			endings.crlf++
+		} else {
+			endings.lf++
+		}
+	}
+	return strings.Join(lines, "\n"), endings
+}
+
+// Label names the line endings for the viewer title: "LF", "CRLF", or the
+// counts of each for mixed content. It is empty without line breaks.
+func (e lineEndings) Label() string {
+	switch {
+	case e.crlf == 0 && e.lf == 0:
+		return ""
+	case e.crlf == 0:
+		return "LF"
+	case e.lf == 0:
+		return "CRLF"
+	default:
+		return fmt.Sprintf("mixed: %d LF, %d CRLF", e.lf, e.crlf)
+	}
+}
+
+// IsCRLF reports whether line i (0-based) ended in CRLF.
+func (e lineEndings) IsCRLF(i int) bool {
+	return i < len(e.crlfLines) && e.crlfLines[i]
+}
+
+// Deviates reports whether line i of mixed content ends differently from the
+// majority of lines. Ties count LF as the majority.
+func (e lineEndings) Deviates(i int) bool {
+	if e.crlf == 0 || e.lf == 0 || i >= len(e.crlfLines)-1 {
+		return false
+	}
+	return e.crlfLines[i] != (e.crlf > e.lf)
+}
+
+// decorateLine prepares line i of the viewer. Stray CRs are always shown as
+// ␍ (the view would clear the line at them); with showWhitespace, trailing
+// blanks are highlighted and CRLF line ends get a ␍ marker.
+func decorateLine(line string, i int, endings lineEndings, showWhitespace bool) string {
+	if strings.Contains(line, "\r") {
+		line = strings.ReplaceAll(line, "\r", ansiDim+glyph("␍")+ansiReset)
+	}
+	if !showWhitespace {
+		return line
+	}
+	if trimmed := strings.TrimRight(line, " \t"); len(trimmed) < len(line) {
+		line = trimmed + ansiRed + ansiReverse + line[len(trimmed):] + ansiReset
+	}
+	if endings.IsCRLF(i) {
+		line += ansiDim + glyph("␍") + ansiReset
+	}
+	return line
+}
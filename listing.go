@@ -0,0 +1,142 @@
+// ---- File: listing.go ----
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// --- Non-interactive Listing (--list) ---
+
+// listFormats are the output formats of --list.
+var listFormats = map[string]bool{"json": true, "csv": true}
+
+// listEntry is one entry of --list output. The field names are a stable
+// schema for scripts; Size and ModTime are null when unknown (folders have no
+// size here, since their recursive size is not computed).
+type listEntry struct {
+	Name    string  `json:"name"`
+	Path    string  `json:"path"`
+	Type    string  `json:"type"` // "dir", "file", "symlink" or "other"
+	Size    *int64  `json:"size"`
+	ModTime *string `json:"mtime"` // RFC 3339
+	Hidden  bool    `json:"hidden"`
+}
+
+// entryType names the kind of item for --list.
+func entryType(item FileInfo) string {
+	switch {
+	case item.IsDir:
+		return "dir"
+	case item.Mode&os.ModeSymlink != 0:
+		return "symlink"
+	case item.Mode.IsRegular():
+		return "file"
+	default:
+		return "other"
+	}
+}
+
+// newListEntry describes item, stat'ing it for its size and mtime.
+func newListEntry(item FileInfo, hidden bool) listEntry {
+	if !item.Unreadable {
+		item = withEntryInfo(item)
+	}
+	entry := listEntry{Name: item.Name, Path: item.Path, Type: entryType(item), Hidden: hidden}
+	if entry.Type == "file" && item.HasInfo {
+		size := item.Size
+		entry.Size = &size
+	}
+	if !item.ModTime.IsZero() {
+		modTime := item.ModTime.Format(time.RFC3339)
+		entry.ModTime = &modTime
+	}
+	return entry
+}
+
+// listDirectory reads dir the way the TUI lists it (ignore rules, sort
+// order): folders, then files, then with includeHidden the hidden folders and
+// files.
+func listDirectory(dir string, includeHidden bool) ([]listEntry, string, error) {
+	state := NewAppState(dir)
+	listing, err := readDirectory(state, dir, func(int) {}, func() bool { return false })
+	if err != nil {
+		return nil, "", err
+	}
+	entries := []listEntry{}
+	for _, item := range append(listing.visibleDirs, listing.visibleFiles...) {
+		entries = append(entries, newListEntry(item, false))
+	}
+	if includeHidden {
+		for _, item := range append(listing.hiddenDirs, listing.hiddenFiles...) {
+			entries = append(entries, newListEntry(item, true))
+		}
+	}
+	return entries, listing.warning, nil
+}
+
+// writeListing prints entries to w as a JSON array or as CSV with a header row.
+func writeListing(w io.Writer, entries []listEntry, format string) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"name", "path", "type", "size", "mtime", "hidden"}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		var size, modTime string
+		if e.Size != nil {
+			size = strconv.FormatInt(*e.Size, 10)
+		}
+		if e.ModTime != nil {
+			modTime = *e.ModTime
+		}
+		if err := writer.Write([]string{e.Name, e.Path, e.Type, size, modTime, strconv.FormatBool(e.Hidden)}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// runList implements --list: it prints the listing of path (the CWD if
+// empty) to stdout without starting the TUI and returns the exit status.
+func runList(path, format string, includeHidden bool, stdout, stderr io.Writer) int {
+	if format == "" {
+		format = "json"
+	}
+	if !listFormats[format] {
+		fmt.Fprintf(stderr, "lazyls: unknown --format %q (use json or csv)\n", format)
+		return 2
+	}
+	if path == "" {
+		path = "."
+	}
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "lazyls: %v\n", err)
+		return 1
+	}
+	entries, warning, err := listDirectory(dir, includeHidden)
+	if err != nil {
+		fmt.Fprintf(stderr, "lazyls: %v\n", err)
+		return 1
+	}
+	if warning != "" {
+		fmt.Fprintf(stderr, "lazyls: %s\n", warning)
+	}
+	if err := writeListing(stdout, entries, format); err != nil {
+		fmt.Fprintf(stderr, "lazyls: %v\n", err)
+		return 1
+	}
+	return 0
+}
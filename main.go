@@ -2,6 +2,8 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"os"
 
@@ -9,6 +11,59 @@ import (
 )
 
 func main() {
+	flag.BoolVar(&unixPaths, "unix-paths", false, "copy paths with forward slashes and /c/ drives (for Git Bash on Windows)")
+	plain := flag.Bool("plain", false, "draw without colors (also enabled by a non-empty $NO_COLOR)")
+	ascii := flag.Bool("ascii", false, "draw with ASCII characters only, for terminals without Unicode or Nerd Fonts")
+	list := flag.Bool("list", false, "print the listing of [path] (default: the current directory) and exit")
+	stats := flag.Bool("stats", false, "print size, counts, largest files and git status of [path] and exit")
+	format := flag.String("format", "", "output format: json or csv for --list (default json), text or json for --stats (default text)")
+	listHidden := flag.Bool("hidden", false, "include hidden entries in --list output")
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	doctor := flag.Bool("doctor", false, "check for git, a clipboard tool, color support and a UTF-8 locale, print a report and exit")
+	si := flag.Bool("si", false, "show sizes in powers of 1000 (kB, MB, GB) instead of 1024 (KiB, MiB, GiB)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: lazyls [flags]\n       lazyls --list|--stats [flags] [path]\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println(versionString())
+		return
+	}
+	if *doctor {
+		cfg, _ := loadConfig() // Only the ascii setting matters here
+		configureCharset(cfg, *ascii)
+		os.Exit(runDoctor(os.Stdout))
+	}
+	// Flags after the path end flag parsing, so they would be taken as more arguments
+	maxArgs := 0
+	if *list || *stats {
+		maxArgs = 1
+	}
+	if flag.NArg() > maxArgs {
+		if maxArgs == 0 {
+			fmt.Fprintf(os.Stderr, "lazyls: unexpected argument %q (only --list and --stats take a path)\n", flag.Arg(0))
+		} else {
+			fmt.Fprintf(os.Stderr, "lazyls: unexpected argument %q (flags go before the path)\n", flag.Arg(maxArgs))
+		}
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// --list and --stats print and exit without the TUI (and without a log file in the listed dir)
+	if *list || *stats {
+		log.SetFlags(0)
+		log.SetPrefix("lazyls: ")
+		cfg, _ := loadConfig() // Only the size units and name order matter here; a broken config just keeps the defaults
+		configureSizeUnits(cfg, *si)
+		configureNameOrder(cfg)
+		if *stats {
+			os.Exit(runStats(flag.Arg(0), *format, os.Stdout, os.Stderr))
+		}
+		os.Exit(runList(flag.Arg(0), *format, *listHidden, os.Stdout, os.Stderr))
+	}
+
 	// Setup logging
 	logFile, err := os.OpenFile("lazyls.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND|os.O_TRUNC, 0666)
 	if err == nil {
@@ -31,18 +86,48 @@ func main() {
 		log.Fatalf("FATAL: Failed to get current working directory: %v", err)
 	}
 
+	// Load user configuration (icons are resolved while listing, so do this first)
+	cfg, cfgErr := loadConfig()
+	if cfgErr != nil {
+		log.Printf("Warning: Could not load config, using defaults: %v", cfgErr)
+	}
+	configureIcons(cfg)
+	configureCharset(cfg, *ascii) // After configureIcons: ASCII mode replaces the icon set
+	configureColors(cfg)
+	configureRecent(cfg)
+	configureActions(cfg)
+	configurePager(cfg)
+	configureGitTUI(cfg)
+	configureScpHost(cfg)
+	configureSizeUnits(cfg, *si)
+	configureNameOrder(cfg)
+	configureEnterAction(cfg)
+	configureFormatters(cfg)
+	configureHints(cfg)
+	configureEntryCounts(cfg)
+	configureStyle(*plain) // After configureColors: plain mode turns file-type colors off
+
 	// Init State
 	appState := NewAppState(cwd)
-
-	// Initial Load
-	err = loadDirectoryContents(appState)
+	appState.SetSizeLimits(cfg.sizeLimits())
+	prefs, err := loadPreferences()
 	if err != nil {
-		// Logged within loadDirectoryContents if using state.SetMessage
-		log.Printf("Error: Failed to initially load directory contents: %v", err)
+		log.Printf("Warning: Could not load preferences, using defaults: %v", err)
 	}
+	appState.ApplyPreferences(prefs)
+	if cfg.HideStats {
+		appState.SetStatsHidden(true) // Whatever the last session left
+	}
+	tabs.add(appState, "")
+
+	// Missing tools degrade features silently, so say which once; the warning
+	// comes back only when the findings change
+	diagnostics := runDiagnostics()
+	logDiagnostics(diagnostics)
+	degradedWarning := degradedSummary(diagnostics)
 
 	// Init gocui
-	g, err := gocui.NewGui(gocui.OutputNormal)
+	g, err := gocui.NewGui(gocui.Output256) // 256 colors for image previews
 	if err != nil {
 		log.Panicln("FATAL: Failed to initialize gocui:", err)
 	}
@@ -54,7 +139,12 @@ func main() {
 	g.Highlight = true                // Enable highlighting globally (views can override)
 	g.SelFgColor = gocui.ColorGreen   // Global default for selection foreground
 	g.SelBgColor = gocui.ColorDefault // Global default for selection background
-	// g.ASCII = true // Uncomment if Unicode icons cause issues
+	if plainMode {
+		g.SelFgColor = gocui.AttrBold // The focused view's frame is drawn bold instead of green
+	}
+	// Deliver a lone Esc as a key; the default Alt mode holds it back to prefix the next key
+	g.InputEsc = true
+	g.ASCII = asciiMode // Frames drawn with - | +
 
 	// Set Layout Manager
 	g.SetManagerFunc(func(gui *gocui.Gui) error {
@@ -67,8 +157,20 @@ func main() {
 		log.Panicln("FATAL: Failed to set keybindings:", err)
 	}
 
-	// Start background tasks
-	go calculateStats(g, appState)
+	// Initial load and background tasks; the listing arrives asynchronously.
+	// The warning waits for it, as loading clears the message bar
+	reloadDirectory(g, appState, func(gui *gocui.Gui) {
+		if degradedWarning == prefs.DegradedWarning || !showDegradedWarning(appState, degradedWarning) {
+			return
+		}
+		if err := savePreferences(appState.Preferences()); err != nil {
+			log.Printf("Warning: Could not save preferences: %v", err)
+		}
+	})
+	startRecentRedraw(g)
+	if cfgErr != nil {
+		appState.SetMessage(fmt.Sprintf("Config error: %s", trimError(cfgErr)))
+	}
 
 	// Initial focus setting is now handled within the layout function's logic,
 	// ensuring views exist before focus is set.
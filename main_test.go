@@ -0,0 +1,15 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+)
+
+// TestMain keeps the log messages of the code under test (lazyls.log in the
+// app) out of the test output.
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
@@ -0,0 +1,241 @@
+// ---- File: markdown.go ----
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// --- Markdown Rendering ---
+
+// mdSpan is a run of inline text with the ANSI style it's drawn in.
+type mdSpan struct {
+	text  string
+	style string
+}
+
+var (
+	mdHeading       = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdListItem      = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	mdRule          = regexp.MustCompile(`^\s{0,3}([-*_])(\s*([-*_]))*\s*$`)
+	mdHeadingStyles = []string{ansiBold + ansiMagenta, ansiBold + ansiCyan, ansiBold + ansiYellow}
+)
+
+// renderMarkdown formats Markdown source for the content viewer: headings bold
+// and colored, list items indented with bullets, code blocks dimmed, quotes
+// behind a bar and links underlined. Paragraphs are wrapped to width cells.
+func renderMarkdown(source string, width int) string {
+	if width < 20 {
+		width = 20
+	}
+	var out []string
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			out = append(out, wrapSpans(parseInline(strings.Join(paragraph, " "), ""), width, "", "")...)
+			paragraph = nil
+		}
+	}
+	blank := func() {
+		if len(out) > 0 && out[len(out)-1] != "" {
+			out = append(out, "")
+		}
+	}
+
+	fence := "" // Non-empty inside a fenced code block
+	for _, line := range strings.Split(source, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+				blank()
+				continue
+			}
+			out = append(out, "    "+ansiDim+line+ansiReset)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			blank()
+			fence = trimmed[:3]
+		case trimmed == "":
+			flush()
+			blank()
+		case len(paragraph) == 0 && strings.HasPrefix(line, "    "):
+			out = append(out, "    "+ansiDim+strings.TrimPrefix(line, "    ")+ansiReset) // Indented code
+		case mdHeading.MatchString(trimmed):
+			flush()
+			m := mdHeading.FindStringSubmatch(trimmed)
+			style := ansiBold
+			if level := len(m[1]); level <= len(mdHeadingStyles) {
+				style = mdHeadingStyles[level-1]
+			}
+			blank()
+			out = append(out, wrapSpans(parseInline(m[2], style), width, "", "")...)
+		case mdRule.MatchString(line) && strings.Count(strings.ReplaceAll(trimmed, " ", ""), trimmed[:1]) >= 3:
+			flush()
+			out = append(out, ansiDim+strings.Repeat(glyph("─"), width)+ansiReset)
+		case mdListItem.MatchString(line):
+			flush()
+			m := mdListItem.FindStringSubmatch(line)
+			indent := strings.Repeat(" ", 2+len(strings.ReplaceAll(m[1], "\t", "    "))/2*2)
+			marker := m[2]
+			if strings.ContainsAny(marker, "-*+") {
+				marker = glyph("•")
+			}
+			out = append(out, wrapSpans(parseInline(m[3], ""), width, indent+marker+" ", indent+strings.Repeat(" ", displayWidth(marker)+1))...)
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			text := strings.TrimSpace(strings.TrimLeft(trimmed, "> "))
+			out = append(out, wrapSpans(parseInline(text, ""), width, glyph("│")+" ", glyph("│")+" ")...)
+		case strings.HasPrefix(trimmed, "|"):
+			flush()
+			out = append(out, line) // Tables are shown as written
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	return strings.Join(out, "\n")
+}
+
+// parseInline splits Markdown inline markup into styled spans: `code`,
+// **bold**, *emphasis*, [links](url) and ![images](url). style is the
+// surrounding style, added to by nested markup.
+func parseInline(text, style string) []mdSpan {
+	var spans []mdSpan
+	var plain strings.Builder
+	emit := func(s mdSpan) {
+		if plain.Len() > 0 {
+			spans = append(spans, mdSpan{plain.String(), style})
+			plain.Reset()
+		}
+		spans = append(spans, s)
+	}
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && rest[1] < utf8.RuneSelf && unicode.IsPunct(rune(rest[1])):
+			plain.WriteByte(rest[1])
+			i += 2
+			continue
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				emit(mdSpan{rest[1 : 1+end], style + ansiDim})
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if end := strings.Index(rest[2:], rest[:2]); end > 0 {
+				for _, s := range parseInline(rest[2:2+end], style+ansiBold) {
+					emit(s)
+				}
+				i += end + 4
+				continue
+			}
+		case (rest[0] == '*' || rest[0] == '_') && len(rest) > 1 && rest[1] != ' ' && (i == 0 || text[i-1] == ' '):
+			if end := strings.IndexByte(rest[1:], rest[0]); end > 0 {
+				for _, s := range parseInline(rest[1:1+end], style) {
+					emit(s) // The viewer has no italics; emphasis keeps the surrounding style
+				}
+				i += end + 2
+				continue
+			}
+		case rest[0] == '[' || strings.HasPrefix(rest, "!["):
+			image := rest[0] == '!'
+			open := strings.IndexByte(rest, '[')
+			if label, n, ok := parseLink(rest[open:]); ok {
+				if image {
+					emit(mdSpan{"[image: " + label + "]", style + ansiDim})
+				} else {
+					for _, s := range parseInline(label, style+ansiUnderline) {
+						emit(s)
+					}
+				}
+				i += open + n
+				continue
+			}
+		}
+		plain.WriteByte(text[i])
+		i++
+	}
+	if plain.Len() > 0 {
+		spans = append(spans, mdSpan{plain.String(), style})
+	}
+	return spans
+}
+
+// parseLink parses "[label](url)" at the start of s, returning the label and
+// the length of the whole link.
+func parseLink(s string) (label string, length int, ok bool) {
+	closeLabel := strings.Index(s, "](")
+	if closeLabel < 0 {
+		return "", 0, false
+	}
+	closeURL := strings.IndexByte(s[closeLabel+2:], ')')
+	if closeURL < 0 {
+		return "", 0, false
+	}
+	return s[1:closeLabel], closeLabel + 2 + closeURL + 1, true
+}
+
+// wrapSpans word-wraps styled spans to width cells. The first line starts with
+// first, continuation lines with rest.
+func wrapSpans(spans []mdSpan, width int, first, rest string) []string {
+	// Split into words, each a list of styled pieces
+	var words [][]mdSpan
+	var word []mdSpan
+	for _, span := range spans {
+		for j, part := range strings.Split(span.text, " ") {
+			if j > 0 && len(word) > 0 {
+				words = append(words, word)
+				word = nil
+			}
+			if part != "" {
+				word = append(word, mdSpan{part, span.style})
+			}
+		}
+	}
+	if len(word) > 0 {
+		words = append(words, word)
+	}
+
+	var lines []string
+	var line strings.Builder
+	line.WriteString(first)
+	lineWidth, empty := displayWidth(first), true
+	for _, w := range words {
+		wordWidth := 0
+		for _, piece := range w {
+			wordWidth += displayWidth(piece.text)
+		}
+		if !empty && lineWidth+1+wordWidth > width {
+			lines = append(lines, line.String())
+			line.Reset()
+			line.WriteString(rest)
+			lineWidth, empty = displayWidth(rest), true
+		}
+		if !empty {
+			line.WriteByte(' ')
+			lineWidth++
+		}
+		for _, piece := range w {
+			if piece.style != "" {
+				line.WriteString(piece.style + piece.text + ansiReset)
+			} else {
+				line.WriteString(piece.text)
+			}
+		}
+		lineWidth += wordWidth
+		empty = false
+	}
+	return append(lines, line.String())
+}
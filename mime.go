@@ -0,0 +1,97 @@
+// ---- File: mime.go ----
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// --- Content Sniffing ---
+// Whether a file is text is decided from its first bytes, like a browser does
+// (http.DetectContentType), plus a byte order mark check so UTF-16 text isn't
+// mistaken for binary for its NUL bytes.
+
+// sniffLen is how much of a file content sniffing looks at.
+const sniffLen = 512
+
+// textMIMETypes are the non-"text/" types whose content is still text.
+var textMIMETypes = map[string]bool{
+	"application/json":       true,
+	"application/javascript": true,
+	"application/xml":        true,
+	"application/postscript": true,
+	"image/svg+xml":          true,
+}
+
+// sniffMIME returns the MIME type of content from its first sniffLen bytes,
+// e.g. "text/plain; charset=utf-8" or "image/png". UTF-16 text with a byte
+// order mark is "text/plain; charset=utf-16le" (or utf-16be).
+func sniffMIME(data []byte) string {
+	switch {
+	case len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE:
+		return "text/plain; charset=utf-16le"
+	case len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF:
+		return "text/plain; charset=utf-16be"
+	}
+	return http.DetectContentType(data[:min(len(data), sniffLen)])
+}
+
+// isTextMIME reports whether content of the MIME type mimeType can be shown as text.
+func isTextMIME(mimeType string) bool {
+	base, _, _ := strings.Cut(mimeType, ";")
+	return strings.HasPrefix(base, "text/") || textMIMETypes[base]
+}
+
+// utf8BOM is the byte order mark some editors put at the start of UTF-8 text.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// textContent returns data as UTF-8 text, transcoding UTF-16 and dropping a
+// UTF-8 byte order mark, along with its MIME type. ok is false for binary
+// content.
+func textContent(data []byte) (text []byte, mimeType string, ok bool) {
+	mimeType = sniffMIME(data)
+	if !isTextMIME(mimeType) {
+		return nil, mimeType, false
+	}
+	switch {
+	case strings.HasSuffix(mimeType, "charset=utf-16le"):
+		return decodeUTF16(data[2:], binary.LittleEndian), mimeType, true
+	case strings.HasSuffix(mimeType, "charset=utf-16be"):
+		return decodeUTF16(data[2:], binary.BigEndian), mimeType, true
+	}
+	return bytes.TrimPrefix(data, utf8BOM), mimeType, true
+}
+
+// decodeUTF16 converts UTF-16 text (without its byte order mark) to UTF-8.
+// A trailing odd byte is dropped.
+func decodeUTF16(data []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+// fileMIME sniffs the MIME type of the file at path, or returns "" if it
+// can't be read.
+func fileMIME(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return ""
+	}
+	if n == 0 {
+		return "" // Empty files have no type to speak of
+	}
+	return sniffMIME(head[:n])
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestTextContent(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	tests := []struct {
+		name     string
+		data     string
+		wantText string
+		wantMIME string
+		wantOK   bool
+	}{
+		{"plain text", "hello\n", "hello\n", "text/plain; charset=utf-8", true},
+		{"UTF-8 with a BOM", "\xEF\xBB\xBFhello", "hello", "text/plain; charset=utf-8", true},
+		{"UTF-16LE with a BOM", "\xFF\xFEh\x00\xE9\x00", "hé", "text/plain; charset=utf-16le", true},
+		{"UTF-16BE with a BOM", "\xFE\xFF\x00h\x00\xE9", "hé", "text/plain; charset=utf-16be", true},
+		{"UTF-16LE with an odd trailing byte", "\xFF\xFEh\x00i\x00!", "hi", "text/plain; charset=utf-16le", true},
+		{"NUL in the first 512 bytes", "abc\x00def", "", "application/octet-stream", false},
+		{"NUL past the first 512 bytes", strings.Repeat("a", sniffLen) + "\x00", strings.Repeat("a", sniffLen) + "\x00", "text/plain; charset=utf-8", true},
+		{"JSON", `{"a": 1}`, `{"a": 1}`, "text/plain; charset=utf-8", true},
+		{"SVG", `<?xml version="1.0"?><svg/>`, `<?xml version="1.0"?><svg/>`, "text/xml; charset=utf-8", true},
+		{"PNG", png, "", "image/png", false},
+	}
+	for _, tt := range tests {
+		text, mimeType, ok := textContent([]byte(tt.data))
+		if ok != tt.wantOK || mimeType != tt.wantMIME || string(text) != tt.wantText {
+			t.Errorf("%s: got %q, %q, %v; want %q, %q, %v", tt.name, text, mimeType, ok, tt.wantText, tt.wantMIME, tt.wantOK)
+		}
+	}
+}
+
+func TestIsTextMIME(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		want     bool
+	}{
+		{"text/plain; charset=utf-8", true},
+		{"text/html; charset=utf-8", true},
+		{"application/json", true},
+		{"image/svg+xml", true},
+		{"image/png", false},
+		{"application/octet-stream", false},
+		{"application/pdf", false},
+	}
+	for _, tt := range tests {
+		if got := isTextMIME(tt.mimeType); got != tt.want {
+			t.Errorf("isTextMIME(%q) = %v, want %v", tt.mimeType, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeUTF16(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		order binary.ByteOrder
+		want  string
+	}{
+		{"little endian", "a\x00b\x00", binary.LittleEndian, "ab"},
+		{"big endian", "\x00a\x00b", binary.BigEndian, "ab"},
+		{"surrogate pair", "\x3D\xD8\x00\xDE", binary.LittleEndian, "😀"},
+		{"odd trailing byte", "a\x00b", binary.LittleEndian, "a"},
+		{"empty", "", binary.LittleEndian, ""},
+	}
+	for _, tt := range tests {
+		if got := string(decodeUTF16([]byte(tt.data), tt.order)); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
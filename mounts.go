@@ -0,0 +1,119 @@
+// ---- File: mounts.go ----
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- Drives and Mount Points ---
+
+// mountPoint is a filesystem root offered by the drive picker.
+type mountPoint struct {
+	Path   string
+	Device string // e.g. /dev/sda1; empty for Windows drives
+	FSType string // e.g. ext4; empty when unknown
+}
+
+// mountEntry is a mount point with its free space, as shown in the picker.
+type mountEntry struct {
+	mountPoint
+	Free, Total uint64
+	HasSpace    bool // False when the space query failed (e.g. unreadable mount)
+}
+
+// mountSpaceTimeout is how long the picker waits for the free space of the
+// mount points; a hung network mount is listed without it.
+var mountSpaceTimeout = 2 * time.Second
+
+// loadMounts lists the mount points with their free space.
+func loadMounts() ([]mountEntry, error) {
+	points, err := listMounts()
+	if err != nil {
+		return nil, err
+	}
+	return mountEntries(points, filesystemSpace), nil
+}
+
+// mountEntries queries the space of every point at once through space, and
+// returns the entries once all have answered or mountSpaceTimeout has passed.
+// Queries still running then are left to finish on their own.
+func mountEntries(points []mountPoint, space func(path string) (free, total uint64, err error)) []mountEntry {
+	type answer struct {
+		idx         int
+		free, total uint64
+		err         error
+	}
+	entries := make([]mountEntry, len(points))
+	answers := make(chan answer, len(points)) // Late answers don't block
+	for i, p := range points {
+		entries[i].mountPoint = p
+		go func() {
+			free, total, err := space(p.Path)
+			answers <- answer{i, free, total, err}
+		}()
+	}
+	deadline := time.After(mountSpaceTimeout)
+	for range points {
+		select {
+		case a := <-answers:
+			if a.err == nil {
+				entries[a.idx].Free, entries[a.idx].Total, entries[a.idx].HasSpace = a.free, a.total, true
+			}
+		case <-deadline:
+			log.Printf("Warning: Some mount points did not report their free space within %v", mountSpaceTimeout)
+			return entries
+		}
+	}
+	return entries
+}
+
+// isWithinDir reports whether path is dir or lies below it.
+func isWithinDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkReadableDir reports why dir can't be browsed, or nil if it can.
+func checkReadableDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.ReadDir(1); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("reading %s: %w", dir, err)
+	}
+	return nil
+}
+
+// pseudoFilesystems are Linux/BSD filesystem types with nothing to browse.
+var pseudoFilesystems = map[string]bool{
+	"proc": true, "sysfs": true, "devtmpfs": true, "devpts": true, "devfs": true,
+	"cgroup": true, "cgroup2": true, "securityfs": true, "debugfs": true, "tracefs": true,
+	"pstore": true, "bpf": true, "mqueue": true, "hugetlbfs": true, "configfs": true,
+	"fusectl": true, "binfmt_misc": true, "autofs": true, "rpc_pipefs": true, "nsfs": true,
+	"efivarfs": true, "ramfs": true, "squashfs": true, "nullfs": true,
+}
+
+// isBrowsableMount filters out pseudo filesystems and the system trees they live in.
+func isBrowsableMount(p mountPoint) bool {
+	if pseudoFilesystems[p.FSType] {
+		return false
+	}
+	if p.FSType == "tmpfs" && p.Path != "/tmp" {
+		return false // /run, /dev/shm and friends
+	}
+	for _, prefix := range []string{"/proc", "/sys", "/dev", "/run", "/snap", "/System/Volumes/"} {
+		if p.Path == prefix || strings.HasPrefix(p.Path, prefix+"/") || (strings.HasSuffix(prefix, "/") && strings.HasPrefix(p.Path, prefix)) {
+			return false
+		}
+	}
+	return true
+}
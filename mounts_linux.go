@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listMounts returns the browsable filesystems from /proc/mounts.
+func listMounts() ([]mountPoint, error) {
+	data, err := os.ReadFile("/proc/mounts")
+	if err != nil {
+		return nil, err
+	}
+	return parseProcMounts(string(data)), nil
+}
+
+// parseProcMounts parses /proc/mounts lines ("device path type options 0 0"),
+// skipping pseudo filesystems. A path mounted twice is listed once.
+func parseProcMounts(data string) []mountPoint {
+	var points []mountPoint
+	seen := map[string]bool{}
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		p := mountPoint{Device: unescapeMountField(fields[0]), Path: unescapeMountField(fields[1]), FSType: fields[2]}
+		if seen[p.Path] || !isBrowsableMount(p) {
+			continue
+		}
+		seen[p.Path] = true
+		points = append(points, p)
+	}
+	return points
+}
+
+// unescapeMountField decodes the octal escapes (\040 for space, \011 for tab,
+// \012 for newline, \134 for backslash) used in /proc/mounts.
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+4 <= len(field) {
+			if n, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(field[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProcMounts(t *testing.T) {
+	data := `sysfs /sys sysfs rw,nosuid,nodev,noexec,relatime 0 0
+proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0
+/dev/nvme0n1p2 / ext4 rw,relatime 0 0
+tmpfs /run tmpfs rw,nosuid,nodev 0 0
+tmpfs /tmp tmpfs rw,nosuid,nodev 0 0
+/dev/sdb1 /media/me/My\040Disk vfat rw 0 0
+/dev/sdc1 /mnt/tab\011and\134slash ext4 rw 0 0
+/dev/nvme0n1p2 / ext4 rw,relatime 0 0
+short line
+
+`
+	want := []mountPoint{
+		{Path: "/", Device: "/dev/nvme0n1p2", FSType: "ext4"},
+		{Path: "/tmp", Device: "tmpfs", FSType: "tmpfs"},
+		{Path: "/media/me/My Disk", Device: "/dev/sdb1", FSType: "vfat"},
+		{Path: "/mnt/tab\tand\\slash", Device: "/dev/sdc1", FSType: "ext4"},
+	}
+	if got := parseProcMounts(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestUnescapeMountField(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"/plain", "/plain"},
+		{`/a\040b`, "/a b"},
+		{`/new\012line`, "/new\nline"},
+		{`/trailing\04`, `/trailing\04`}, // Too short to be an escape
+		{`/not\999octal`, `/not\999octal`},
+	}
+	for _, tt := range tests {
+		if got := unescapeMountField(tt.in); got != tt.want {
+			t.Errorf("unescapeMountField(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+//go:build !linux && !windows
+
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// listMounts returns the browsable filesystems reported by `df -P`.
+func listMounts() ([]mountPoint, error) {
+	output, err := exec.Command("df", "-P").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseDfOutput(string(output)), nil
+}
+
+// parseDfOutput parses POSIX df output ("Filesystem 512-blocks Used Available
+// Capacity Mounted on"). Both the device (macOS "map auto_home") and the mount
+// point may contain spaces, so the line is split around the capacity column.
+// df doesn't report types; devfs and automounter maps are recognized by device.
+func parseDfOutput(output string) []mountPoint {
+	var points []mountPoint
+	seen := map[string]bool{}
+	lines := strings.Split(output, "\n")
+	for _, line := range lines[min(1, len(lines)):] { // Skip the header
+		device, path, ok := splitDfLine(line)
+		if !ok {
+			continue
+		}
+		p := mountPoint{Device: device, Path: path}
+		switch {
+		case device == "devfs":
+			p.FSType = "devfs"
+		case strings.HasPrefix(device, "map "):
+			p.FSType = "autofs"
+		}
+		if seen[p.Path] || !isBrowsableMount(p) {
+			continue
+		}
+		seen[p.Path] = true
+		points = append(points, p)
+	}
+	return points
+}
+
+// splitDfLine finds the capacity column (e.g. "42%") preceded by three numeric
+// columns and returns the text before those as the device and after it as the path.
+func splitDfLine(line string) (device, path string, ok bool) {
+	fields := strings.Fields(line)
+	for i := 4; i < len(fields)-1; i++ {
+		if !strings.HasSuffix(fields[i], "%") {
+			continue
+		}
+		// Cut the line after the capacity column, counting fields to find it
+		rest := line
+		for j := 0; j <= i; j++ {
+			rest = strings.TrimLeft(rest, " \t")
+			rest = rest[len(fields[j]):]
+		}
+		return strings.Join(fields[:i-3], " "), strings.TrimSpace(rest), true
+	}
+	return "", "", false
+}
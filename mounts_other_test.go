@@ -0,0 +1,29 @@
+//go:build !linux && !windows
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDfOutput(t *testing.T) {
+	output := `Filesystem     512-blocks      Used Available Capacity  Mounted on
+/dev/disk3s1s1  965595304  20097632 391217400     5%    /
+devfs                 411       411         0   100%    /dev
+/dev/disk3s5    965595304 547019424 391217400    59%    /System/Volumes/Data
+map auto_home           0         0         0   100%    /System/Volumes/Data/home
+map -hosts              0         0         0   100%    /net
+/dev/disk4s1       245504    196608     48896    81%    /Volumes/My Disk
+//me@nas/share 1000000000 500000000 500000000    50%    /Volumes/share
+not a df line
+`
+	want := []mountPoint{
+		{Path: "/", Device: "/dev/disk3s1s1"},
+		{Path: "/Volumes/My Disk", Device: "/dev/disk4s1"},
+		{Path: "/Volumes/share", Device: "//me@nas/share"},
+	}
+	if got := parseDfOutput(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
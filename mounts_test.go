@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIsBrowsableMount(t *testing.T) {
+	tests := []struct {
+		point mountPoint
+		want  bool
+	}{
+		{mountPoint{Path: "/", FSType: "ext4"}, true},
+		{mountPoint{Path: "/home", FSType: "btrfs"}, true},
+		{mountPoint{Path: "/tmp", FSType: "tmpfs"}, true},
+		{mountPoint{Path: "/run/user/1000", FSType: "tmpfs"}, false},
+		{mountPoint{Path: "/proc", FSType: "proc"}, false},
+		{mountPoint{Path: "/sys/fs/cgroup", FSType: "cgroup2"}, false},
+		{mountPoint{Path: "/devices", FSType: "ext4"}, true}, // Not under /dev
+		{mountPoint{Path: "/dev/shm", FSType: "ext4"}, false},
+		{mountPoint{Path: "/snap/core/1", FSType: "squashfs"}, false},
+		{mountPoint{Path: "/System/Volumes/Data"}, false},
+		{mountPoint{Path: "/Volumes/USB"}, true},
+	}
+	for _, tt := range tests {
+		if got := isBrowsableMount(tt.point); got != tt.want {
+			t.Errorf("isBrowsableMount(%s %s) = %v, want %v", tt.point.Path, tt.point.FSType, got, tt.want)
+		}
+	}
+}
+
+func TestMountEntriesTimeout(t *testing.T) {
+	defer func(saved time.Duration) { mountSpaceTimeout = saved }(mountSpaceTimeout)
+	mountSpaceTimeout = 50 * time.Millisecond
+
+	hang := make(chan struct{})
+	defer close(hang)
+	space := func(path string) (free, total uint64, err error) {
+		switch path {
+		case "/mnt/nfs":
+			<-hang // A network mount that doesn't answer
+		case "/mnt/locked":
+			return 0, 0, errors.New("permission denied")
+		}
+		return 1, 2, nil
+	}
+	points := []mountPoint{{Path: "/"}, {Path: "/mnt/nfs"}, {Path: "/mnt/locked"}, {Path: "/home"}}
+
+	start := time.Now()
+	entries := mountEntries(points, space)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("took %v despite the timeout", elapsed)
+	}
+	want := []bool{true, false, false, true}
+	for i, entry := range entries {
+		if entry.Path != points[i].Path || entry.HasSpace != want[i] {
+			t.Errorf("entry %d: %s with space %v, want %s with space %v", i, entry.Path, entry.HasSpace, points[i].Path, want[i])
+		}
+	}
+	if entries[0].Free != 1 || entries[0].Total != 2 {
+		t.Errorf("space of /: got %d of %d", entries[0].Free, entries[0].Total)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import "syscall"
+
+var procGetLogicalDrives = syscall.NewLazyDLL("kernel32.dll").NewProc("GetLogicalDrives")
+
+// listMounts returns the drive letters in use, from GetLogicalDrives.
+func listMounts() ([]mountPoint, error) {
+	mask, _, callErr := procGetLogicalDrives.Call()
+	if mask == 0 {
+		return nil, callErr
+	}
+	return drivesFromMask(uint32(mask)), nil
+}
+
+// drivesFromMask turns the GetLogicalDrives bitmask (bit 0 is A:) into drive roots.
+func drivesFromMask(mask uint32) []mountPoint {
+	var points []mountPoint
+	for i := 0; i < 26; i++ {
+		if mask&(1<<i) != 0 {
+			points = append(points, mountPoint{Path: string(rune('A'+i)) + `:\`})
+		}
+	}
+	return points
+}
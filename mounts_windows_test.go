@@ -0,0 +1,26 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDrivesFromMask(t *testing.T) {
+	tests := []struct {
+		mask uint32
+		want []string
+	}{
+		{0, nil},
+		{1 << 2, []string{`C:\`}},
+		{1<<0 | 1<<2 | 1<<25, []string{`A:\`, `C:\`, `Z:\`}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, p := range drivesFromMask(tt.mask) {
+			got = append(got, p.Path)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("drivesFromMask(%#x) = %q, want %q", tt.mask, got, tt.want)
+		}
+	}
+}
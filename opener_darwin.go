@@ -0,0 +1,17 @@
+package main
+
+import "os/exec"
+
+// revealExitStatusReliable reports whether a failing exit status of the
+// revealer means it failed; open exits non-zero when Finder can't show the path.
+const revealExitStatusReliable = true
+
+// systemOpener returns the command that opens path with the default application.
+func systemOpener(path string) *exec.Cmd {
+	return exec.Command("open", path)
+}
+
+// systemRevealer returns the command that selects path in a Finder window.
+func systemRevealer(path string, isDir bool) (*exec.Cmd, error) {
+	return exec.Command("open", "-R", path), nil
+}
@@ -0,0 +1,32 @@
+//go:build !darwin && !windows
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// revealExitStatusReliable reports whether a failing exit status of the
+// revealer means it failed; xdg-open exits non-zero when it can't open anything.
+const revealExitStatusReliable = true
+
+// systemOpener returns the command that opens path with the desktop's default application.
+func systemOpener(path string) *exec.Cmd {
+	return exec.Command("xdg-open", path)
+}
+
+// systemRevealer returns the command that shows path in the file manager.
+// xdg-open can't highlight an entry, so folders are opened themselves and
+// files their containing folder.
+func systemRevealer(path string, isDir bool) (*exec.Cmd, error) {
+	if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		return nil, errors.New("no graphical session")
+	}
+	if !isDir {
+		path = filepath.Dir(path)
+	}
+	return exec.Command("xdg-open", path), nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// revealExitStatusReliable reports whether a failing exit status of the
+// revealer means it failed; explorer exits with 1 even when it worked.
+const revealExitStatusReliable = false
+
+// systemOpener returns the command that opens path with its associated
+// application. Unlike "cmd /c start", it needs no quoting of the path.
+func systemOpener(path string) *exec.Cmd {
+	return exec.Command("rundll32", "url.dll,FileProtocolHandler", path)
+}
+
+// systemRevealer returns the command that selects path in an Explorer
+// window. Explorer parses its own command line and wants the quotes after
+// the comma, so the line is written out rather than quoted by exec.
+func systemRevealer(path string, isDir bool) (*exec.Cmd, error) {
+	cmd := exec.Command("explorer")
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: fmt.Sprintf(`explorer /select,"%s"`, path)}
+	return cmd, nil
+}
@@ -0,0 +1,107 @@
+// ---- File: pager.go ----
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+	"github.com/nsf/termbox-go"
+)
+
+// --- External Pager ---
+
+// defaultPager is used when $PAGER is unset.
+const defaultPager = "less -R"
+
+// usePagerByDefault makes "View Content" open files in the pager
+// ("default_viewer": "pager" in config.json).
+var usePagerByDefault bool
+
+// configurePager applies the "default_viewer" setting.
+func configurePager(cfg config) {
+	switch strings.ToLower(cfg.DefaultViewer) {
+	case "", "builtin":
+		usePagerByDefault = false
+	case "pager":
+		usePagerByDefault = true
+	default:
+		log.Printf("Warning: Unknown default_viewer %q, using the built-in viewer", cfg.DefaultViewer)
+		usePagerByDefault = false
+	}
+}
+
+// pagerCommand returns the command showing path in $PAGER (or less -R).
+// $PAGER may contain arguments but is not run through a shell.
+func pagerCommand(path string) (*exec.Cmd, error) {
+	pager := strings.TrimSpace(os.Getenv("PAGER"))
+	if pager == "" {
+		pager = defaultPager
+	}
+	fields := strings.Fields(pager)
+	program, err := exec.LookPath(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	return exec.Command(program, append(fields[1:], path)...), nil
+}
+
+// runPager shows item in the pager and reports whether it ran; false means
+// the pager is missing or failed to start, so the caller should fall back to
+// the built-in viewer.
+func runPager(g *gocui.Gui, item FileInfo, state *AppState) bool {
+	cmd, err := pagerCommand(item.Path)
+	if err != nil {
+		log.Printf("Pager unavailable, using the built-in viewer: %v", err)
+		return false
+	}
+	cmd.Dir = state.Cwd()
+	err = runSuspended(g, cmd)
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		log.Printf("Pager failed to start, using the built-in viewer: %v", err)
+		return false
+	}
+	if err != nil {
+		state.SetMessage(fmt.Sprintf("Pager exited with status %d", exitErr.ExitCode()))
+	}
+	return true
+}
+
+// openInPagerAction views a file in the pager, falling back to the built-in viewer.
+func openInPagerAction(g *gocui.Gui, item FileInfo, state *AppState) error {
+	if runPager(g, item, state) {
+		return nil
+	}
+	return showFileContent(item, state)
+}
+
+// --- Suspending the UI ---
+
+// runSuspended hands the terminal to cmd until it exits, then restores the UI
+// with g's input mode. gocui redraws everything on its next flush.
+func runSuspended(g *gocui.Gui, cmd *exec.Cmd) error {
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	termbox.Close()
+	err := cmd.Run()
+	if initErr := termbox.Init(); initErr != nil {
+		log.Panicln("FATAL: Failed to restore the terminal:", initErr)
+	}
+	// As set by gocui's MainLoop: with Alt mode back, a lone Esc would wait
+	// for the next key instead of closing overlays
+	inputMode := termbox.InputAlt
+	if g.InputEsc {
+		inputMode = termbox.InputEsc
+	}
+	if g.Mouse {
+		inputMode |= termbox.InputMouse
+	}
+	termbox.SetInputMode(inputMode)
+	termbox.SetOutputMode(termbox.Output256) // As passed to gocui.NewGui
+	return err
+}
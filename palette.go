@@ -0,0 +1,169 @@
+// ---- File: palette.go ----
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Command Palette ---
+
+// paletteEntry is a command offered by the palette: a registry action for the
+// selected item or an app-level entry of the keybinding table.
+type paletteEntry struct {
+	Label string
+	Keys  string // Keys the command is bound to, "" for registry actions
+	Run   func(g *gocui.Gui, state *AppState) error
+}
+
+// paletteContexts are the keybinding table contexts whose actions the palette offers.
+var paletteContexts = map[string]bool{"Global": true, "Lists": true}
+
+// paletteSkipped are table actions that make no sense outside their key:
+// cursor movement, and opening the menu or palette itself.
+var paletteSkipped = map[string]bool{
+	"list.down": true, "list.up": true, "list.left": true, "list.right": true, "list.page-down": true, "list.page-up": true,
+	"list.top": true, "list.bottom": true, "list.actions": true, "list.enter": true, "show.palette": true,
+	"list.set-mark": true, "list.jump-mark": true,
+}
+
+// paletteRepoOnly are table actions offered only inside a git repository.
+var paletteRepoOnly = map[string]bool{"git.tui": true, "git.root": true}
+
+// paletteEntries collects the commands available from the list view
+// prevFocus: the registry actions applying to its selected item (if any),
+// then the app-level actions of the keybinding table.
+func paletteEntries(state *AppState, prevFocus string) []paletteEntry {
+	var entries []paletteEntry
+	if item, ok := state.SelectedItem(prevFocus); ok {
+		target := withEntryInfo(item)
+		options := actionsFor(target, state)
+		for i, option := range options {
+			if option.Label == "Cancel" {
+				continue
+			}
+			i := i
+			entries = append(entries, paletteEntry{
+				Label: option.Label,
+				Run: func(g *gocui.Gui, state *AppState) error {
+					// Run it exactly as if it had been picked from the action menu
+					state.OpenActionMenu(target, options, prevFocus)
+					state.SelectActionMenuItem(i)
+					return handleMenuSelect(g, nil, state)
+				},
+			})
+		}
+	}
+
+	seen := make(map[string]int) // Action -> index, to merge an action bound in several contexts
+	for _, e := range helpEntries(keyTable) {
+		if !paletteContexts[e.context] || paletteSkipped[e.action] || (paletteRepoOnly[e.action] && !state.InGitRepo()) {
+			continue
+		}
+		if i, ok := seen[e.action]; ok {
+			entries[i].Keys += " / " + e.keyText()
+			continue
+		}
+		seen[e.action] = len(entries)
+		handler := actionHandler(keyTable, e.context, e.action)
+		if handler == nil {
+			continue
+		}
+		entries = append(entries, paletteEntry{
+			Label: e.desc,
+			Keys:  e.keyText(),
+			Run: func(g *gocui.Gui, state *AppState) error {
+				v, err := g.View(prevFocus)
+				if err != nil {
+					return nil // The list went away (e.g. combined mode was toggled)
+				}
+				return handler(g, v)
+			},
+		})
+	}
+	return entries
+}
+
+// actionHandler returns the handler of the first binding of action in context.
+func actionHandler(bindings []keyBinding, context, action string) func(*gocui.Gui, *gocui.View) error {
+	for _, b := range bindings {
+		if b.action == action && bindingContext(b.views) == context {
+			return b.handler
+		}
+	}
+	return nil
+}
+
+// fuzzyScore matches query against label as a case-insensitive subsequence.
+// Lower scores are better: matches starting early, at word starts and with
+// few gaps between the matched letters rank first.
+func fuzzyScore(query, label string) (int, bool) {
+	query = strings.ToLower(query)
+	target := []rune(strings.ToLower(label))
+	score := 0
+	pos := 0
+	last := -1
+	for _, qr := range query {
+		if unicode.IsSpace(qr) {
+			continue
+		}
+		found := false
+		for ; pos < len(target); pos++ {
+			if target[pos] != qr {
+				continue
+			}
+			switch {
+			case last < 0:
+				score += pos
+			case pos > last+1:
+				score += pos - last - 1
+			}
+			if pos > 0 && target[pos-1] != ' ' && (last < 0 || pos > last+1) {
+				score += 2 // Jumped into the middle of a word
+			}
+			last = pos
+			pos++
+			found = true
+			break
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return score, true
+}
+
+// filterPalette returns the entries matching query, best match first. An
+// empty query keeps every entry in its original order.
+func filterPalette(entries []paletteEntry, query string) []paletteEntry {
+	if strings.TrimSpace(query) == "" {
+		return entries
+	}
+	type match struct {
+		entry paletteEntry
+		score int
+	}
+	var matches []match
+	for _, e := range entries {
+		if score, ok := fuzzyScore(query, e.Label); ok {
+			matches = append(matches, match{e, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score < matches[j].score })
+	filtered := make([]paletteEntry, len(matches))
+	for i, m := range matches {
+		filtered[i] = m.entry
+	}
+	return filtered
+}
+
+// paletteEditor edits the palette's query line and refilters on every change.
+func paletteEditor(state *AppState) gocui.Editor {
+	return gocui.EditorFunc(func(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+		gocui.DefaultEditor.Edit(v, key, ch, mod)
+		state.SetPaletteQuery(strings.TrimRight(v.Buffer(), "\n"))
+	})
+}
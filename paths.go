@@ -0,0 +1,197 @@
+// ---- File: paths.go ----
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// --- Copied Path Style ---
+
+// unixPaths makes copied paths use forward slashes and /c/-style drives, the
+// form Git Bash and other MSYS shells expect on Windows. Set by --unix-paths.
+var unixPaths bool
+
+// clipboardPath returns path as it should be copied: native (backslashes on
+// Windows, which paste into cmd and PowerShell as is) unless --unix-paths is set.
+func clipboardPath(path string) string {
+	if !unixPaths || filepath.Separator != '\\' {
+		return path
+	}
+	return toUnixPath(path)
+}
+
+// toUnixPath converts a Windows path to MSYS form: C:\Users\me becomes
+// /c/Users/me, \\server\share\x becomes //server/share/x and dir\file dir/file.
+func toUnixPath(path string) string {
+	path = strings.ReplaceAll(path, `\`, "/")
+	if len(path) >= 2 && path[1] == ':' && isASCIILetter(path[0]) {
+		rest := path[2:]
+		if !strings.HasPrefix(rest, "/") {
+			rest = "/" + rest
+		}
+		return "/" + strings.ToLower(path[:1]) + rest
+	}
+	return path
+}
+
+// isASCIILetter reports whether c is a drive letter candidate.
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// --- URLs and Remote Paths ---
+
+// scpHost is the host, or user@host, that Copy scp Path puts before a path
+// ("scp_host" in config.json); empty means the current user at $HOSTNAME.
+var scpHost string
+
+// configureScpHost applies the "scp_host" setting.
+func configureScpHost(cfg config) {
+	scpHost = strings.TrimSpace(cfg.ScpHost)
+}
+
+// fileURL returns the file:// URL of the absolute path, percent-encoding
+// what a URL path can't hold as is: file:///home/me/a%20b.txt,
+// file:///C:/Users/me or, for a UNC path, file://server/share/x.
+func fileURL(path string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	if volume := filepath.VolumeName(path); strings.HasPrefix(volume, `\\`) {
+		host, share, _ := strings.Cut(strings.TrimPrefix(filepath.ToSlash(volume), "//"), "/")
+		u.Host, u.Path = host, "/"+share+filepath.ToSlash(path[len(volume):])
+	} else if volume != "" {
+		u.Path = "/" + u.Path // Drive paths start with a slash: /C:/Users
+	}
+	return u.String()
+}
+
+// scpPath returns the absolute path as an scp source on this machine,
+// user@host:/abs/path, with the host (and user) of scpHost if set. Windows
+// paths are written the way OpenSSH's server takes them: /C:/Users/me. A path
+// with characters a shell treats specially is single-quoted for the POSIX
+// shell it is pasted into, user@host:'/tmp/a b', which leaves scp the plain
+// path; with the legacy protocol (scp -O) the remote shell splits it again.
+func scpPath(path string) (string, error) {
+	host := scpHost
+	if host == "" {
+		name := os.Getenv("HOSTNAME")
+		if name == "" {
+			var err error
+			if name, err = os.Hostname(); err != nil {
+				return "", fmt.Errorf("could not determine the host name: %w", err)
+			}
+		}
+		host = name
+		if login := currentUserName(); login != "" {
+			host = login + "@" + name
+		}
+	}
+	remote := filepath.ToSlash(path)
+	if filepath.VolumeName(path) != "" && !strings.HasPrefix(remote, "/") {
+		remote = "/" + remote
+	}
+	return host + ":" + scpQuote(remote), nil
+}
+
+// scpQuote single-quotes path for a POSIX shell unless every character in it
+// is one no shell treats specially.
+func scpQuote(path string) string {
+	plain := strings.IndexFunc(path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("/._-+:,@%", r))
+	}) < 0
+	if plain && path != "" {
+		return path
+	}
+	return "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
+
+// currentUserName returns the login name of the user running lazyls, or "".
+func currentUserName() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		_, name, _ := strings.Cut(u.Username, `\`) // DOMAIN\name on Windows
+		if name == "" {
+			name = u.Username
+		}
+		return name
+	}
+	for _, key := range []string{"USER", "USERNAME"} {
+		if name := os.Getenv(key); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// --- Typed Paths ---
+
+// expandInputPath turns a path typed into a prompt into a clean absolute one:
+// "~" is the home folder and relative paths start at cwd.
+func expandInputPath(input, cwd string) (string, error) {
+	path := strings.TrimSpace(input)
+	if path == "" {
+		return "", errors.New("enter a path")
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cwd, path)
+	}
+	return filepath.Clean(path), nil
+}
+
+// --- Shortened Paths ---
+
+// shortenPath fits path into width cells the way fish's prompt does: the home
+// directory becomes "~", then the folders between the root and the last one
+// are cut to their first letter (two for dot folders) from the left, until it
+// fits: /home/me/projects/work/lazyls becomes ~/p/w/lazyls. The drive or UNC
+// share and the last folder are kept whole; if even that is too wide, the
+// start is cut off.
+func shortenPath(path, home string, width int) string {
+	sep := string(filepath.Separator)
+	if home = strings.TrimSuffix(home, sep); home != "" && (path == home || strings.HasPrefix(path, home+sep)) {
+		path = "~" + path[len(home):]
+	}
+	if displayWidth(path) <= width {
+		return path
+	}
+
+	volume := filepath.VolumeName(path)
+	parts := strings.Split(path[len(volume):], sep)
+	// parts[0] is "" for rooted paths ("/x", `C:\x`) and "~" under home
+	for i := 1; i < len(parts)-1; i++ {
+		parts[i] = abbreviateFolder(parts[i])
+		if short := volume + strings.Join(parts, sep); displayWidth(short) <= width {
+			return short
+		}
+	}
+	short := volume + strings.Join(parts, sep)
+	if width <= displayWidth(ellipsis) {
+		return lastCells(short, width)
+	}
+	return ellipsis + lastCells(short, width-displayWidth(ellipsis))
+}
+
+// abbreviateFolder returns the first letter of name, keeping the dot of
+// hidden folders (".config" becomes ".c").
+func abbreviateFolder(name string) string {
+	runes := []rune(name)
+	n := 1
+	if len(runes) > 1 && runes[0] == '.' {
+		n = 2
+	}
+	if len(runes) <= n {
+		return name
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,149 @@
+//go:build !windows
+
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestScpPath(t *testing.T) {
+	defer func(host string) { scpHost = host }(scpHost)
+	scpHost = "me@build"
+
+	tests := []struct {
+		path, want string
+	}{
+		{"/home/me/notes.txt", "me@build:/home/me/notes.txt"},
+		{"/srv/v1.2/a-b_c+d,e@f%g", "me@build:/srv/v1.2/a-b_c+d,e@f%g"},
+		{"/tmp/a b", "me@build:'/tmp/a b'"},
+		{"/tmp/$HOME;rm *", "me@build:'/tmp/$HOME;rm *'"},
+		{"/tmp/it's", `me@build:'/tmp/it'\''s'`},
+		{"/tmp/café", "me@build:'/tmp/café'"},
+	}
+	for _, tt := range tests {
+		got, err := scpPath(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("scpPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFileURL(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/home/me/notes.txt", "file:///home/me/notes.txt"},
+		{"/tmp/a b#1?.txt", "file:///tmp/a%20b%231%3F.txt"},
+		{"/tmp/café", "file:///tmp/caf%C3%A9"},
+	}
+	for _, tt := range tests {
+		if got := fileURL(tt.path); got != tt.want {
+			t.Errorf("fileURL(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestToUnixPath(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{`C:\Users\me`, "/c/Users/me"},
+		{`d:\`, "/d/"},
+		{`E:`, "/e/"},
+		{`E:file.txt`, "/e/file.txt"},
+		{`\\server\share\x`, "//server/share/x"},
+		{`src\main.go`, "src/main.go"},
+		{"1:x", "1:x"},
+	}
+	for _, tt := range tests {
+		if got := toUnixPath(tt.path); got != tt.want {
+			t.Errorf("toUnixPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestClipboardPathUnchangedOnUnix(t *testing.T) {
+	defer func(saved bool) { unixPaths = saved }(unixPaths)
+	for _, unixPaths = range []bool{false, true} {
+		if got := clipboardPath(`/tmp/a\b`); got != `/tmp/a\b` {
+			t.Errorf("with --unix-paths %v: got %q", unixPaths, got)
+		}
+	}
+}
+
+func TestBreadcrumbs(t *testing.T) {
+	tests := []struct {
+		path, home string
+		want       []string
+	}{
+		{"/", "/home/me", []string{"/"}},
+		{"/srv/www", "/home/me", []string{"/", "srv", "www"}},
+		{"/home/me/src/lazyls", "/home/me", []string{"~", "src", "lazyls"}},
+		{"/home/me", "/home/me/", []string{"~"}},
+		{"/home/meta", "/home/me", []string{"/", "home", "meta"}},
+	}
+	for _, tt := range tests {
+		var labels []string
+		for _, crumb := range breadcrumbs(tt.path, tt.home) {
+			labels = append(labels, crumb.label)
+		}
+		if !slices.Equal(labels, tt.want) {
+			t.Errorf("breadcrumbs(%q, %q) = %q, want %q", tt.path, tt.home, labels, tt.want)
+		}
+	}
+}
+
+func TestBreadcrumbLineFitsWidth(t *testing.T) {
+	plain := strings.NewReplacer(ansiDim, "", ansiReset, "", ansiGreen, "", ansiReverse, "").Replace
+	tests := []struct {
+		path  string
+		width int
+		want  string
+	}{
+		{"/srv/www", 20, "/ › srv › www"},
+		{"/srv/www/a-folder-name-wider-than-the-bar", 20, "/ › … › a-folder-na…"},
+		{"/a-folder-name-wider-than-the-bar", 10, "/ › a-fol…"},
+		{"/srv", 1, "/"},
+	}
+	for _, tt := range tests {
+		crumbs := breadcrumbs(tt.path, "/home/me")
+		got := plain(breadcrumbLine(crumbs, len(crumbs)-1, tt.width, true))
+		if got != tt.want {
+			t.Errorf("%s in %d cells: %q, want %q", tt.path, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestShortenPath(t *testing.T) {
+	tests := []struct {
+		path, home string
+		width      int
+		want       string
+	}{
+		{"/home/me/projects/work/lazyls", "/home/me", 100, "~/projects/work/lazyls"},
+		{"/home/me/projects/work/lazyls", "/home/me", 15, "~/p/work/lazyls"},
+		{"/home/me/projects/work/lazyls", "/home/me", 12, "~/p/w/lazyls"},
+		{"/home/me/projects/work/lazyls", "/home/me/", 12, "~/p/w/lazyls"},
+		{"/home/me", "/home/me", 10, "~"},
+		{"/home/meta/x", "/home/me", 20, "/home/meta/x"}, // Not under home
+		{"/usr/local/share/.config/app", "", 20, "/u/l/s/.config/app"},
+		{"/usr/local/share/.config/app", "", 12, "…/l/s/.c/app"}, // Dot folders keep two letters
+		{"/srv/averyveryverylongname", "", 10, "…ylongname"},     // The last folder is never abbreviated
+		{"/srv/averyveryverylongname", "", 1, "e"},
+		{"/", "/home/me", 10, "/"},
+	}
+	for _, tt := range tests {
+		got := shortenPath(tt.path, tt.home, tt.width)
+		if got != tt.want {
+			t.Errorf("shortenPath(%q, %q, %d) = %q, want %q", tt.path, tt.home, tt.width, got, tt.want)
+		}
+		if w := displayWidth(got); w > tt.width {
+			t.Errorf("shortenPath(%q, %q, %d) is %d cells wide", tt.path, tt.home, tt.width, w)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestClipboardPath(t *testing.T) {
+	defer func(saved bool) { unixPaths = saved }(unixPaths)
+	tests := []struct {
+		path string
+		unix bool
+		want string
+	}{
+		{`C:\Users\me\a.txt`, false, `C:\Users\me\a.txt`},
+		{`C:\Users\me\a.txt`, true, "/c/Users/me/a.txt"},
+		{`src\main.go`, false, `src\main.go`},
+		{`src\main.go`, true, "src/main.go"},
+	}
+	for _, tt := range tests {
+		unixPaths = tt.unix
+		if got := clipboardPath(tt.path); got != tt.want {
+			t.Errorf("clipboardPath(%q) with --unix-paths %v = %q, want %q", tt.path, tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCopyRelativePathUsesBackslashes(t *testing.T) {
+	cb := &fakeClipboard{}
+	state := NewAppState(`C:\work`)
+	state.clipboard = cb
+	item := FileInfo{Name: "main.go", Path: `C:\work\src\main.go`}
+	if err := copyRelativePath(nil, item, state); err != nil {
+		t.Fatal(err)
+	}
+	if got := cb.Text(); got != `src\main.go` {
+		t.Errorf("copied %q, want %q", got, `src\main.go`)
+	}
+}
+
+func TestWindowsFileURL(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{`C:\Users\me\a b.txt`, "file:///C:/Users/me/a%20b.txt"},
+		{`\\server\share\x.txt`, "file://server/share/x.txt"},
+	}
+	for _, tt := range tests {
+		if got := fileURL(tt.path); got != tt.want {
+			t.Errorf("fileURL(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestBreadcrumbsDriveRoot(t *testing.T) {
+	tests := []struct {
+		path, home string
+		want       []string
+	}{
+		{`C:\`, `C:\Users\me`, []string{`C:\`}},
+		{`D:\data\logs`, `C:\Users\me`, []string{`D:\`, "data", "logs"}},
+		{`C:\Users\me\src`, `C:\Users\me`, []string{"~", "src"}},
+	}
+	for _, tt := range tests {
+		var labels []string
+		for _, crumb := range breadcrumbs(tt.path, tt.home) {
+			labels = append(labels, crumb.label)
+		}
+		if !slices.Equal(labels, tt.want) {
+			t.Errorf("breadcrumbs(%q, %q) = %q, want %q", tt.path, tt.home, labels, tt.want)
+		}
+	}
+}
+
+func TestShortenPathDrives(t *testing.T) {
+	tests := []struct {
+		path, home string
+		width      int
+		want       string
+	}{
+		{`C:\Users\me\projects\work\lazyls`, `C:\Users\me`, 12, `~\p\w\lazyls`},
+		{`D:\data\logs\2026\app`, `C:\Users\me`, 15, `D:\d\l\2026\app`},
+		{`C:\`, `C:\Users\me`, 10, `C:\`},
+		{`\\server\share\projects\lazyls`, `C:\Users\me`, 25, `\\server\share\p\lazyls`},
+	}
+	for _, tt := range tests {
+		if got := shortenPath(tt.path, tt.home, tt.width); got != tt.want {
+			t.Errorf("shortenPath(%q, %q, %d) = %q, want %q", tt.path, tt.home, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Unix permission bit layout used while parsing chmod-style modes.
+// os.FileMode stores setuid/setgid/sticky outside the low 12 bits, so modes
+// are converted to this layout for parsing and back again afterwards.
+const (
+	unixSetuid = 04000
+	unixSetgid = 02000
+	unixSticky = 01000
+)
+
+// toUnixMode converts an os.FileMode into the classic 12-bit chmod layout.
+func toUnixMode(mode os.FileMode) uint32 {
+	m := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		m |= unixSetuid
+	}
+	if mode&os.ModeSetgid != 0 {
+		m |= unixSetgid
+	}
+	if mode&os.ModeSticky != 0 {
+		m |= unixSticky
+	}
+	return m
+}
+
+// fromUnixMode converts a 12-bit chmod value into an os.FileMode suitable for os.Chmod.
+func fromUnixMode(m uint32) os.FileMode {
+	mode := os.FileMode(m & 0777)
+	if m&unixSetuid != 0 {
+		mode |= os.ModeSetuid
+	}
+	if m&unixSetgid != 0 {
+		mode |= os.ModeSetgid
+	}
+	if m&unixSticky != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+// formatOctalMode renders a mode the way chmod accepts it (e.g. "755", "4755").
+func formatOctalMode(mode os.FileMode) string {
+	m := toUnixMode(mode)
+	if m > 0777 {
+		return fmt.Sprintf("%04o", m)
+	}
+	return fmt.Sprintf("%03o", m)
+}
+
+// parsePermissions interprets a chmod-style mode relative to the current mode.
+// Octal ("755", "0644") and symbolic ("u+x", "go-w", "a=rX,u+w") notations are
+// accepted. As with chmod, an omitted "who" means all (umask is not applied).
+func parsePermissions(spec string, current os.FileMode, isDir bool) (os.FileMode, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return 0, fmt.Errorf("empty mode")
+	}
+
+	if spec[0] >= '0' && spec[0] <= '9' {
+		if len(spec) > 4 {
+			return 0, fmt.Errorf("octal mode too long: %q", spec)
+		}
+		n, err := strconv.ParseUint(spec, 8, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid octal mode: %q", spec)
+		}
+		return fromUnixMode(uint32(n)), nil
+	}
+
+	m := toUnixMode(current)
+	for _, clause := range strings.Split(spec, ",") {
+		var err error
+		m, err = applySymbolicClause(m, clause, isDir)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return fromUnixMode(m), nil
+}
+
+// applySymbolicClause applies a single clause such as "ug+rw" or "o=" to m.
+func applySymbolicClause(m uint32, clause string, isDir bool) (uint32, error) {
+	if clause == "" {
+		return 0, fmt.Errorf("empty clause in mode")
+	}
+
+	// 1. Who: any combination of u, g, o, a (empty means all)
+	i := 0
+	var who uint32 // Bit 4 = user, 2 = group, 1 = other
+whoLoop:
+	for ; i < len(clause); i++ {
+		switch clause[i] {
+		case 'u':
+			who |= 4
+		case 'g':
+			who |= 2
+		case 'o':
+			who |= 1
+		case 'a':
+			who |= 7
+		default:
+			break whoLoop
+		}
+	}
+	if who == 0 {
+		who = 7
+	}
+	if i >= len(clause) {
+		return 0, fmt.Errorf("missing operator in %q", clause)
+	}
+
+	// 2. One or more operator + permission groups, e.g. "+x-w"
+	for i < len(clause) {
+		op := clause[i]
+		if op != '+' && op != '-' && op != '=' {
+			return 0, fmt.Errorf("invalid operator %q in %q", op, clause)
+		}
+		i++
+
+		// Whether execute is already set anywhere, for the conditional 'X'
+		hasExec := isDir || m&0111 != 0
+
+		var bits uint32
+		for ; i < len(clause) && !strings.ContainsRune("+-=", rune(clause[i])); i++ {
+			switch clause[i] {
+			case 'r':
+				bits |= whoBits(who, 0444)
+			case 'w':
+				bits |= whoBits(who, 0222)
+			case 'x':
+				bits |= whoBits(who, 0111)
+			case 'X':
+				if hasExec {
+					bits |= whoBits(who, 0111)
+				}
+			case 's':
+				if who&4 != 0 {
+					bits |= unixSetuid
+				}
+				if who&2 != 0 {
+					bits |= unixSetgid
+				}
+			case 't':
+				if who&1 != 0 {
+					bits |= unixSticky
+				}
+			default:
+				return 0, fmt.Errorf("invalid permission %q in %q", clause[i], clause)
+			}
+		}
+
+		switch op {
+		case '+':
+			m |= bits
+		case '-':
+			m &^= bits
+		case '=':
+			// Clear everything the "who" controls before setting
+			reset := whoBits(who, 0777)
+			if who&4 != 0 {
+				reset |= unixSetuid
+			}
+			if who&2 != 0 {
+				reset |= unixSetgid
+			}
+			if who&1 != 0 {
+				reset |= unixSticky
+			}
+			m = (m &^ reset) | bits
+		}
+	}
+	return m, nil
+}
+
+// whoBits masks a permission pattern (e.g. 0444 for read) to the selected classes.
+func whoBits(who uint32, pattern uint32) uint32 {
+	var mask uint32
+	if who&4 != 0 {
+		mask |= 0700
+	}
+	if who&2 != 0 {
+		mask |= 0070
+	}
+	if who&1 != 0 {
+		mask |= 0007
+	}
+	return pattern & mask
+}
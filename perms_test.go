@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParsePermissions(t *testing.T) {
+	tests := []struct {
+		spec    string
+		current os.FileMode
+		isDir   bool
+		want    os.FileMode
+		wantErr bool
+	}{
+		{spec: "755", current: 0o600, want: 0o755},
+		{spec: "0644", current: 0o777, want: 0o644},
+		{spec: " 700 ", current: 0o644, want: 0o700},
+		{spec: "4755", current: 0o644, want: os.ModeSetuid | 0o755},
+		{spec: "1777", isDir: true, want: os.ModeSticky | 0o777},
+		{spec: "u+x", current: 0o644, want: 0o744},
+		{spec: "+x", current: 0o644, want: 0o755},
+		{spec: "go-w", current: 0o666, want: 0o644},
+		{spec: "a=r", current: 0o755, want: 0o444},
+		{spec: "u=rwx,go=", current: 0o644, want: 0o700},
+		{spec: "u+x-w", current: 0o644, want: 0o544},
+		{spec: "o=", current: 0o777, want: 0o770},
+		{spec: "a=rX", current: 0o644, want: 0o444},
+		{spec: "a=rX", current: 0o744, want: 0o555},
+		{spec: "a=rX", current: 0o600, isDir: true, want: 0o555},
+		{spec: "u+s", current: 0o755, want: os.ModeSetuid | 0o755},
+		{spec: "g+s", current: 0o755, want: os.ModeSetgid | 0o755},
+		{spec: "+t", current: 0o777, isDir: true, want: os.ModeSticky | 0o777},
+		{spec: "u=rw", current: os.ModeSetuid | 0o755, want: 0o655},
+		{spec: "", wantErr: true},
+		{spec: "888", wantErr: true},
+		{spec: "07777", wantErr: true},
+		{spec: "u", wantErr: true},
+		{spec: "u*x", wantErr: true},
+		{spec: "u+q", wantErr: true},
+		{spec: "u+x,", wantErr: true},
+		{spec: "z+x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePermissions(tt.spec, tt.current, tt.isDir)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePermissions(%q, %v): got error %v, want error: %v", tt.spec, tt.current, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parsePermissions(%q, %v) = %v, want %v", tt.spec, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestFormatOctalMode(t *testing.T) {
+	tests := []struct {
+		mode os.FileMode
+		want string
+	}{
+		{0o644, "644"},
+		{0o7, "007"},
+		{os.ModeDir | 0o755, "755"},
+		{os.ModeSetuid | 0o755, "4755"},
+		{os.ModeSetgid | os.ModeSticky | 0o770, "3770"},
+	}
+	for _, tt := range tests {
+		if got := formatOctalMode(tt.mode); got != tt.want {
+			t.Errorf("formatOctalMode(%v) = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// ---- File: prefs.go ----
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// preferencesFileName is the file UI preferences are stored in, inside the config dir.
+const preferencesFileName = "preferences.json"
+
+// Panel ratio bounds and step for resizing the stats column.
+const (
+	defaultPanelRatio = 1.0 / 3
+	minPanelRatio     = 0.15
+	maxPanelRatio     = 0.75
+	panelRatioStep    = 0.05
+)
+
+// preferences are UI settings remembered between runs.
+type preferences struct {
+	PanelRatio      float64        `json:"panel_ratio"`                // Width of the left stats column as a fraction of the terminal
+	StatsHidden     bool           `json:"stats_hidden"`               // Stats column collapsed into a one-line status bar
+	CombinedMode    bool           `json:"combined_mode"`              // Single combined list instead of Folders/Files panes
+	GridMode        bool           `json:"grid_mode"`                  // Files pane laid out in columns, like ls -C
+	SortDescending  bool           `json:"sort_descending"`            // Names listed Z-A instead of A-Z
+	LineNumbers     lineNumberMode `json:"line_numbers"`               // Content viewer line numbers: absolute, relative or off
+	DegradedWarning string         `json:"degraded_warning,omitempty"` // Features last reported unavailable at startup, so the warning isn't repeated
+}
+
+// defaultPreferences returns the settings used when nothing has been saved yet.
+func defaultPreferences() preferences {
+	return preferences{PanelRatio: defaultPanelRatio, LineNumbers: lineNumbersAbsolute}
+}
+
+// appConfigDir returns lazyls' directory inside the user's config directory.
+func appConfigDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lazyls"), nil
+}
+
+// loadPreferences reads the saved preferences. A missing file yields the
+// defaults; invalid values are replaced by their defaults.
+func loadPreferences() (preferences, error) {
+	prefs := defaultPreferences()
+	dir, err := appConfigDir()
+	if err != nil {
+		return prefs, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, preferencesFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return prefs, nil
+		}
+		return prefs, err
+	}
+	if err := json.Unmarshal(data, &prefs); err != nil {
+		return defaultPreferences(), fmt.Errorf("parsing %s: %w", preferencesFileName, err)
+	}
+	if prefs.PanelRatio < minPanelRatio || prefs.PanelRatio > maxPanelRatio {
+		prefs.PanelRatio = defaultPanelRatio
+	}
+	switch prefs.LineNumbers {
+	case lineNumbersAbsolute, lineNumbersRelative, lineNumbersOff:
+	default:
+		prefs.LineNumbers = lineNumbersAbsolute
+	}
+	return prefs, nil
+}
+
+// savePreferences writes prefs to the config dir, creating it if needed.
+func savePreferences(prefs preferences) error {
+	dir, err := appConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(prefs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, preferencesFileName), append(data, '\n'), 0o644)
+}
@@ -0,0 +1,105 @@
+// ---- File: preview.go ----
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Preview Pane ---
+
+// Preview limits: only the start of small files is read, and reads wait
+// until the cursor has rested for previewDebounce.
+const (
+	previewDebounce = 120 * time.Millisecond
+	previewMaxSize  = 256 * 1024
+	previewMaxLines = 100
+)
+
+// schedulePreview requests a preview of item. The read starts after
+// previewDebounce unless another request supersedes it, so holding j
+// doesn't read every file passed over.
+func schedulePreview(g *gocui.Gui, state *AppState, item FileInfo) {
+	gen, changed := state.RequestPreview(item.Path)
+	if !changed {
+		return
+	}
+	go func() {
+		time.Sleep(previewDebounce)
+		if !state.IsPreviewGen(gen) {
+			return
+		}
+		content := loadPreview(state.Files(), item)
+		g.Update(func(gui *gocui.Gui) error {
+			state.SetPreview(gen, content)
+			return nil
+		})
+	}()
+}
+
+// loadPreview returns the preview text for item: the first lines of a text
+// file, the entries of a directory, or metadata for anything else.
+func loadPreview(files FileReader, item FileInfo) string {
+	item = withEntryInfo(item)
+	if item.IsDir {
+		return previewDirectory(item.Path)
+	}
+	data, err := ReadFileWithLimit(files, item.Path, previewMaxSize)
+	switch {
+	case err != nil:
+		return previewMetadata(item, trimError(err))
+	case data == nil:
+		return ansiDim + "[Empty File]" + ansiReset
+	}
+	data, mimeType, ok := textContent(data)
+	if !ok {
+		return previewMetadata(item, "binary file ("+mimeType+")")
+	}
+	text := firstLines(strings.ReplaceAll(string(data), "\t", "    "), previewMaxLines)
+	return strings.ReplaceAll(text, "\x1b", "^[") // Don't let the file drive the terminal
+}
+
+// previewDirectory lists up to previewMaxLines entries of dir, folders first.
+func previewDirectory(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ansiRed + "Cannot read folder: " + trimError(err) + ansiReset
+	}
+	if len(entries) == 0 {
+		return ansiDim + "[Empty Folder]" + ansiReset
+	}
+	var dirs, files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, ansiBlue+entry.Name()+"/"+ansiReset)
+		} else {
+			files = append(files, entry.Name())
+		}
+	}
+	lines := append(dirs, files...)
+	if len(lines) > previewMaxLines {
+		more := len(lines) - previewMaxLines
+		lines = append(lines[:previewMaxLines], fmt.Sprintf("%s%s %d more%s", ansiDim, glyph("…"), more, ansiReset))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// previewMetadata describes a file that can't be shown as text.
+func previewMetadata(item FileInfo, reason string) string {
+	lines := []string{
+		fmt.Sprintf("%s%s%s", ansiDim, reason, ansiReset),
+		"",
+		fmt.Sprintf("%sType%s      %s", ansiBold, ansiReset, strings.TrimPrefix(strings.ToLower(filepath.Ext(item.Name)), ".")),
+		fmt.Sprintf("%sSize%s      %s", ansiBold, ansiReset, formatSize(item.Size)),
+		fmt.Sprintf("%sModified%s  %s", ansiBold, ansiReset, item.ModTime.Format("2006-01-02 15:04:05")),
+	}
+	if item.Mode != 0 {
+		lines = append(lines, fmt.Sprintf("%sMode%s      %s", ansiBold, ansiReset, item.Mode.String()))
+	}
+	return strings.Join(lines, "\n")
+}
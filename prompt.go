@@ -0,0 +1,65 @@
+// ---- File: prompt.go ----
+package main
+
+import "github.com/jroimartin/gocui"
+
+// --- Input Prompt ---
+
+// promptHistoryLimit is how many past inputs are kept per kind of prompt.
+const promptHistoryLimit = 50
+
+// promptSpec describes a single-line input prompt. Features that need text
+// from the user fill one in and pass it to AppState.OpenPrompt.
+type promptSpec struct {
+	Kind     string // Prompts of the same kind share their Up/Down input history
+	Title    string
+	Initial  string                                                  // Text pre-filled when the prompt opens
+	Anchor   string                                                  // List view whose selected row the prompt covers; centered when empty
+	Validate func(input string) error                                // Optional check run before OnSubmit
+	OnSubmit func(g *gocui.Gui, input string, state *AppState) error // Returning an error keeps the prompt open
+	OnCancel func(g *gocui.Gui, state *AppState)                     // Optional, run when the prompt is dismissed with Esc
+}
+
+// inputHistory remembers the inputs given to one kind of prompt, oldest
+// first, and where Up/Down browsing currently is.
+type inputHistory struct {
+	entries []string
+	pos     int    // len(entries) while editing a fresh input
+	draft   string // What was typed before browsing started, restored past the newest entry
+}
+
+// add records a submitted input, skipping blanks and immediate repeats, and
+// resets browsing.
+func (h *inputHistory) add(input string) {
+	if input != "" && (len(h.entries) == 0 || h.entries[len(h.entries)-1] != input) {
+		h.entries = append(h.entries, input)
+		if len(h.entries) > promptHistoryLimit {
+			h.entries = h.entries[len(h.entries)-promptHistoryLimit:]
+		}
+	}
+	h.reset()
+}
+
+// reset starts browsing afresh from the newest entry.
+func (h *inputHistory) reset() {
+	h.pos = len(h.entries)
+	h.draft = ""
+}
+
+// step moves delta entries through the history (negative is older) and returns
+// the text to show. current is the text being edited, saved as the draft when
+// browsing starts. ok is false when there is nothing further in that direction.
+func (h *inputHistory) step(delta int, current string) (text string, ok bool) {
+	next := min(max(h.pos+delta, 0), len(h.entries))
+	if next == h.pos {
+		return "", false
+	}
+	if h.pos == len(h.entries) {
+		h.draft = current
+	}
+	h.pos = next
+	if h.pos == len(h.entries) {
+		return h.draft, true
+	}
+	return h.entries[h.pos], true
+}
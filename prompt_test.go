@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestInputHistory(t *testing.T) {
+	var h inputHistory
+	for _, input := range []string{"a", "", "b", "b", "c"} {
+		h.add(input)
+	}
+	if got := fmt.Sprint(h.entries); got != "[a b c]" {
+		t.Fatalf("entries %s, want [a b c]: blanks and repeats are skipped", got)
+	}
+
+	steps := []struct {
+		delta  int
+		want   string
+		wantOK bool
+	}{
+		{+1, "", false}, // Already past the newest
+		{-1, "c", true},
+		{-1, "b", true},
+		{-1, "a", true},
+		{-1, "", false}, // No older input
+		{+1, "b", true},
+		{+1, "c", true},
+		{+1, "draft", true}, // Back to what was being typed
+		{+1, "", false},
+	}
+	for i, st := range steps {
+		got, ok := h.step(st.delta, "draft")
+		if got != st.want || ok != st.wantOK {
+			t.Errorf("step %d (%+d) = %q, %v; want %q, %v", i, st.delta, got, ok, st.want, st.wantOK)
+		}
+	}
+
+	h.step(-1, "x")
+	h.add("d")
+	if got, _ := h.step(-1, ""); got != "d" {
+		t.Errorf("after submitting, browsing starts from %q, want the newest input d", got)
+	}
+}
+
+func TestInputHistoryLimit(t *testing.T) {
+	var h inputHistory
+	for i := range promptHistoryLimit + 5 {
+		h.add(fmt.Sprint(i))
+	}
+	if len(h.entries) != promptHistoryLimit || h.entries[0] != "5" {
+		t.Errorf("kept %d entries from %q, want %d from \"5\"", len(h.entries), h.entries[0], promptHistoryLimit)
+	}
+}
+
+func TestPromptHistoryPerKind(t *testing.T) {
+	state := NewAppState("/work")
+	state.OpenPrompt(promptSpec{Kind: "rename"}, viewFiles)
+	state.RecordPromptInput("new.txt")
+	state.ClosePrompt()
+
+	state.OpenPrompt(promptSpec{Kind: "filter"}, viewFiles)
+	if text, ok := state.StepPromptHistory(-1, ""); ok {
+		t.Errorf("filter prompt recalled %q from another kind", text)
+	}
+	state.ClosePrompt()
+
+	state.OpenPrompt(promptSpec{Kind: "rename"}, viewFiles)
+	if text, ok := state.StepPromptHistory(-1, ""); !ok || text != "new.txt" {
+		t.Errorf("rename prompt recalled %q, %v; want new.txt", text, ok)
+	}
+	if prev := state.GetPromptPrevFocus(); prev != viewFiles {
+		t.Errorf("focus returns to %q, want %q", prev, viewFiles)
+	}
+}
@@ -0,0 +1,100 @@
+// ---- File: scrollbar.go ----
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Scrollbars ---
+
+// scrollbarSuffix names the scrollbar view drawn for another view.
+const scrollbarSuffix = ".scrollbar"
+
+// scrollbarThumb returns where the thumb starts and how many of height rows
+// it covers when total lines are shown from originY. ok is false when
+// everything fits and no scrollbar is needed.
+func scrollbarThumb(originY, height, total int) (start, length int, ok bool) {
+	if height <= 0 || total <= height {
+		return 0, 0, false
+	}
+	length = height * height / total
+	if length < 1 {
+		length = 1
+	}
+	maxOriginY := total - height
+	if originY > maxOriginY {
+		originY = maxOriginY
+	} else if originY < 0 {
+		originY = 0
+	}
+	start = (height - length) * originY / maxOriginY
+	return start, length, true
+}
+
+// scrollPercent is how far through totalLines a view of viewHeight lines
+// scrolled to originY is, from 0 at the top to 100 at the bottom; content that
+// fits is 100. It stays within 0-100 whatever the arguments.
+func scrollPercent(originY, totalLines, viewHeight int) int {
+	maxOriginY := totalLines - clampHeight(viewHeight)
+	if maxOriginY <= 0 {
+		return 100
+	}
+	return min(max(originY, 0), maxOriginY) * 100 / maxOriginY
+}
+
+// drawScrollbar keeps a scrollbar over the right frame edge of the named view,
+// so it costs no content width. It is removed when the content fits or when
+// covered is set (an overlay is drawn above the view).
+func drawScrollbar(g *gocui.Gui, viewName string, originY, total int, covered bool) {
+	parent, err := g.View(viewName)
+	if err != nil {
+		return
+	}
+	_, height := parent.Size()
+	start, length, ok := scrollbarThumb(originY, height, total)
+	if !ok || covered {
+		removeScrollbars(g, viewName)
+		return
+	}
+	x0, y0, x1, y1, err := g.ViewPosition(viewName)
+	if err != nil || x1-x0 < 2 {
+		return
+	}
+	// A frameless view writes from x0+1, which puts its single column on the border
+	name := viewName + scrollbarSuffix
+	v, err := g.SetView(name, x1-1, y0, x1+1, y1)
+	if err != nil && err != gocui.ErrUnknownView {
+		return
+	}
+	v.Frame = false
+	v.Wrap = false
+	// The track stands in for the frame edge, so it takes the frame's color
+	v.FgColor = g.FgColor
+	if g.Highlight && g.CurrentView() == parent {
+		v.FgColor = g.SelFgColor
+	}
+	// Drawn after the parent's frame, so it has to come after it in the view order
+	if _, err := g.SetViewOnTop(name); err != nil {
+		return
+	}
+	v.Clear()
+	var sb strings.Builder
+	for row := 0; row < height; row++ {
+		if row >= start && row < start+length {
+			sb.WriteString(ansiGray + glyph("█") + ansiReset + "\n")
+		} else {
+			sb.WriteString(glyph("│") + "\n")
+		}
+	}
+	fmt.Fprint(v, sb.String())
+}
+
+// removeScrollbars deletes the scrollbars of the named views, if any.
+func removeScrollbars(g *gocui.Gui, viewNames ...string) {
+	for _, name := range viewNames {
+		_ = g.DeleteView(name + scrollbarSuffix)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestScrollPercent(t *testing.T) {
+	tests := []struct {
+		originY, totalLines, viewHeight int
+		want                            int
+	}{
+		{0, 100, 10, 0},
+		{45, 100, 10, 50},
+		{90, 100, 10, 100},
+		{500, 100, 10, 100}, // Origin past the end
+		{-5, 100, 10, 0},
+		{0, 5, 10, 100},  // Content fits
+		{0, 10, 10, 100}, // Exactly
+		{0, 0, 10, 100},
+		{0, 100, 0, 0}, // Degenerate heights count as one row
+		{99, 100, 0, 100},
+		{50, 100, -4, 50},
+		{0, 1, 0, 100},
+	}
+	for _, tt := range tests {
+		got := scrollPercent(tt.originY, tt.totalLines, tt.viewHeight)
+		if got != tt.want {
+			t.Errorf("scrollPercent(%d, %d, %d) = %d, want %d", tt.originY, tt.totalLines, tt.viewHeight, got, tt.want)
+		}
+	}
+}
+
+func TestScrollbarThumb(t *testing.T) {
+	tests := []struct {
+		originY, height, total int
+		start, length          int
+		ok                     bool
+	}{
+		{0, 10, 100, 0, 1, true},
+		{90, 10, 100, 9, 1, true},
+		{45, 10, 100, 4, 1, true},
+		{0, 10, 20, 0, 5, true},
+		{10, 10, 20, 5, 5, true},
+		{500, 10, 20, 5, 5, true}, // Origin past the end
+		{-3, 10, 20, 0, 5, true},
+		{0, 10, 10, 0, 0, false}, // Fits
+		{0, 0, 100, 0, 0, false}, // No rows to draw in
+		{0, -2, 100, 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, length, ok := scrollbarThumb(tt.originY, tt.height, tt.total)
+		if start != tt.start || length != tt.length || ok != tt.ok {
+			t.Errorf("scrollbarThumb(%d, %d, %d) = %d, %d, %v; want %d, %d, %v",
+				tt.originY, tt.height, tt.total, start, length, ok, tt.start, tt.length, tt.ok)
+		}
+	}
+}
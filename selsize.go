@@ -0,0 +1,65 @@
+// ---- File: selsize.go ----
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Selected Item Size ---
+
+// selectionSizeDebounce is how long the cursor must rest on an item before
+// it is measured, so holding j doesn't walk every folder passed over.
+const selectionSizeDebounce = 150 * time.Millisecond
+
+// scheduleSelectionSize measures item for the Size pane after
+// selectionSizeDebounce unless another request supersedes it. Folders are
+// sized recursively, reusing and filling the folder size cache.
+func scheduleSelectionSize(g *gocui.Gui, state *AppState, item FileInfo) {
+	gen, changed := state.RequestSelectionSize(item.Path)
+	if !changed {
+		return
+	}
+	go func() {
+		time.Sleep(selectionSizeDebounce)
+		if !state.IsSelectionSizeGen(gen) {
+			return
+		}
+		size := measureItem(state, item, func() bool { return !state.IsSelectionSizeGen(gen) })
+		if !state.IsSelectionSizeGen(gen) {
+			return // Partial result from an abandoned walk
+		}
+		state.SetSelectionSize(gen, size)
+		g.Update(func(gui *gocui.Gui) error { return nil })
+	}()
+}
+
+// measureItem returns item's own size, or the recursive size of a folder
+// (-2 if it couldn't be read). cancelled stops a folder walk early.
+func measureItem(state *AppState, item FileInfo, cancelled func() bool) int64 {
+	item = withEntryInfo(item)
+	if !item.IsDir {
+		if item.Unreadable {
+			return -2
+		}
+		return item.Size
+	}
+	if item.Size >= 0 {
+		return item.Size // The folder size job already measured it
+	}
+	if size, ok := state.CachedDirSize(item.Path, item.ModTime); ok {
+		return size
+	}
+	size, _, err := dirUsage(item.Path, cancelled)
+	if cancelled() {
+		return size
+	}
+	if err != nil {
+		log.Printf("Warning: Could not size directory %s: %v", item.Path, err)
+		size = -2
+	}
+	state.SetDirSize(item.Path, item.ModTime, size)
+	return size
+}
@@ -0,0 +1,71 @@
+// ---- File: services.go ----
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/atotto/clipboard"
+)
+
+// --- External Services ---
+// Actions reach the clipboard, the filesystem and git through these small
+// interfaces (held by AppState), so they can be exercised with fakes.
+
+// Clipboard reads and writes text on the system clipboard.
+type Clipboard interface {
+	ReadAll() (string, error)
+	WriteAll(text string) error
+}
+
+// FileReader reads files for the viewer, preview, compare and copy actions.
+type FileReader interface {
+	Stat(path string) (os.FileInfo, error)
+	ReadFile(path string) ([]byte, error)
+	Open(path string) (File, error)
+}
+
+// File is an open file, read from its start or, by the tail copies, at
+// offsets from its end.
+type File interface {
+	io.ReadCloser
+	io.ReaderAt
+	Stat() (os.FileInfo, error)
+}
+
+// GitRunner runs a git subcommand in dir, feeding it stdin (may be nil), and
+// returns its stdout. Failures are reported like exec.Cmd.Output: an
+// *exec.ExitError carrying stderr for a non-zero exit.
+type GitRunner interface {
+	Run(dir string, stdin io.Reader, args ...string) ([]byte, error)
+}
+
+// systemClipboard is the Clipboard backed by atotto/clipboard.
+type systemClipboard struct{}
+
+func (systemClipboard) ReadAll() (string, error)   { return clipboard.ReadAll() }
+func (systemClipboard) WriteAll(text string) error { return clipboard.WriteAll(text) }
+
+// osFileReader is the FileReader backed by the os package.
+type osFileReader struct{}
+
+func (osFileReader) Stat(path string) (os.FileInfo, error) { return os.Stat(path) }
+func (osFileReader) ReadFile(path string) ([]byte, error)  { return os.ReadFile(path) }
+
+func (osFileReader) Open(path string) (File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err // Not a nil *os.File in a non-nil File
+	}
+	return f, nil
+}
+
+// execGit is the GitRunner that runs the git binary from PATH.
+type execGit struct{}
+
+func (execGit) Run(dir string, stdin io.Reader, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdin = stdin
+	return cmd.Output()
+}
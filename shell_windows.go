@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// shellCommand returns a command running cmdline through cmd.exe. The command
+// line is passed verbatim, since Go's argument escaping would break cmd's quoting.
+func shellCommand(cmdline string) *exec.Cmd {
+	cmd := exec.Command("cmd.exe")
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: `cmd.exe /S /C "` + cmdline + `"`}
+	return cmd
+}
+
+// shellQuote quotes s for cmd.exe. Windows file names can't contain '"', but
+// they can contain '%', which cmd expands even inside quotes ("%PATH%.txt"),
+// so each one is escaped with '^' outside the quotes.
+func shellQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, "%", `"^%"`) + `"`
+}
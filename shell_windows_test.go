@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{`C:\Users\me\notes.txt`, `"C:\Users\me\notes.txt"`},
+		{`C:\a b\c&d.txt`, `"C:\a b\c&d.txt"`},
+		{`C:\tmp\%PATH%.txt`, `"C:\tmp\"^%"PATH"^%".txt"`},
+		{`100%`, `"100"^%""`},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.s); got != tt.want {
+			t.Errorf("shellQuote(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
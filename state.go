@@ -2,26 +2,134 @@
 package main
 
 import (
-	"path/filepath"
+	"context"
+	"errors"
+	"image"
+	"os"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/jroimartin/gocui"
 )
 
 // FileInfo holds processed information about a file or directory.
 type FileInfo struct {
-	Name  string
-	Path  string // Full path for size calculation/access
-	IsDir bool
-	Size  int64 // Only calculated for files during largest file scan
-	Icon  string
+	Name    string
+	Path    string // Full path for size calculation/access
+	IsDir   bool
+	Size    int64 // For directories: recursive size, -1 while pending, -2 on error
+	Icon    string
+	ModTime time.Time
+	Mode    os.FileMode // Type and permission bits from Lstat (zero if unknown)
+	HasInfo bool        // Size, ModTime and permission bits are loaded; files get them lazily
+	Seen    time.Time   // When the metadata was loaded; a future ModTime counts from here
+	Repo    repoKind    // Folders: whether the folder is a git repository of its own
+	Hidden  bool        // A hidden entry listed among the visible ones (hiddenModeAll), drawn in gray
+
+	// Unreadable entries could not be stat'ed or, for folders, opened (e.g. permission denied)
+	Unreadable bool
+}
+
+// dirSizeCacheEntry remembers a computed directory size for a given mtime.
+type dirSizeCacheEntry struct {
+	modTime  time.Time
+	storedAt time.Time
+	size     int64
+}
+
+// dirSizeCacheLimit bounds how many folders' sizes are kept; the oldest entry
+// is evicted first.
+const dirSizeCacheLimit = 4096
+
+// entryCountCacheEntry remembers a folder's number of entries for a given mtime.
+type entryCountCacheEntry struct {
+	modTime  time.Time
+	storedAt time.Time
+	count    int // -2 if the folder couldn't be read
+}
+
+// entryCountCacheLimit bounds how many folders' entry counts are kept; the
+// oldest entry is evicted first.
+const entryCountCacheLimit = 4096
+
+// repoKindCacheEntry remembers what git said a folder with a .git file is,
+// for a given mtime of the folder.
+type repoKindCacheEntry struct {
+	modTime  time.Time
+	storedAt time.Time
+	kind     repoKind
+}
+
+// repoKindCacheLimit bounds how many folders' repository kinds are kept; the
+// oldest entry is evicted first.
+const repoKindCacheLimit = 1024
+
+// ignoreRulesCacheEntry remembers a parsed .lazylsignore file for a given mtime.
+type ignoreRulesCacheEntry struct {
+	modTime  time.Time
+	storedAt time.Time
+	rules    *ignoreRules
+}
+
+// ignoreRulesCacheLimit bounds how many parsed ignore files are kept; the
+// oldest entry is evicted first.
+const ignoreRulesCacheLimit = 64
+
+// statsCacheKey identifies a stats result: git ignore mode changes what the walk counts.
+type statsCacheKey struct {
+	path      string
+	gitIgnore bool
+}
+
+// statsCacheEntry remembers a finished stats walk for a given root directory mtime.
+type statsCacheEntry struct {
+	modTime  time.Time
+	storedAt time.Time
+	stats    statsSnapshot
+}
+
+// statsCacheLimit bounds how many directories' stats are kept; the oldest entry is evicted first.
+const statsCacheLimit = 32
+
+// messageHistoryLimit is how many past messages the history overlay keeps.
+const messageHistoryLimit = 100
+
+// messageEntry is a message bar message remembered for the history overlay.
+type messageEntry struct {
+	Time    time.Time
+	Text    string
+	IsError bool
 }
 
 // ActionMenuItem defines an option in the action menu.
 type ActionMenuItem struct {
-	Label    string
-	ActionFn func(g *gocui.Gui, item FileInfo, state *AppState) error // Function to execute, now includes *gocui.Gui
+	Label       string
+	ActionFn    func(g *gocui.Gui, item FileInfo, state *AppState) error // Function to execute, now includes *gocui.Gui
+	Custom      bool                                                     // User-defined action; reports its own outcome
+	MnemonicIdx int                                                      // Byte index of the shortcut letter in Label, -1 if none
+}
+
+// hiddenMode is which entries the lists show, cycled with '.'.
+type hiddenMode int
+
+const (
+	hiddenModeVisible hiddenMode = iota // Only the visible entries
+	hiddenModeAll                       // Visible and hidden entries together, the hidden ones dimmed
+	hiddenModeOnly                      // Only the hidden entries
+	hiddenModeCount
+)
+
+// label names the mode in the pane titles.
+func (m hiddenMode) label() string {
+	switch m {
+	case hiddenModeAll:
+		return "All"
+	case hiddenModeOnly:
+		return "Hidden"
+	}
+	return "Visible"
 }
 
 // AppState holds the application's state.
@@ -29,18 +137,129 @@ type AppState struct {
 	sync.RWMutex // Embed RWMutex for protecting state access
 
 	cwd          string
+	crumbIdx     int // Breadcrumb of the CWD selected in the Root Folder pane, -1 for the CWD itself
 	visibleFiles []FileInfo
 	visibleDirs  []FileInfo
 	hiddenFiles  []FileInfo
 	hiddenDirs   []FileInfo
-	showHidden   bool
+	allFiles     []FileInfo // Visible and hidden merged in sort order, for hiddenModeAll
+	allDirs      []FileInfo
+	hiddenMode   hiddenMode
+	naturalSort  bool // Compare digit runs numerically when sorting names
+	sortDesc     bool // Names in descending order (folders still come first)
+
+	// External services used by actions and background jobs (fakes in tests)
+	clipboard Clipboard
+	files     FileReader
+	git       GitRunner
+
+	// Size limits from config.json: the largest file viewed, or copied whole
+	viewLimit int64
+	copyLimit int64
+
+	// Background directory load: a newer load bumps the generation so a stale one is dropped
+	dirLoadGen     int
+	isLoadingDir   bool
+	dirLoadEntries int // Entries processed so far by the running load
+
+	// Entries suppressed by .lazylsignore rules in the current listing
+	ignoredDirCount  int
+	ignoredFileCount int
+	ignoreRulesCache map[string]ignoreRulesCacheEntry // Keyed by ignore file path, valid while the mtime matches
+
+	// Git ignore mode: hide entries git ignores (only takes effect inside a work tree)
+	gitIgnoreMode   bool
+	gitIgnoreActive bool // Whether the current listing actually had ignored entries filtered
+
+	// Glob filter on file names ('*'); it applies to every reload until cleared
+	fileGlob globFilter
+
+	// Entries that appeared since the previous load of the same folder, marked
+	// with a "+" until the next load (or until cleared)
+	knownDir   string              // The folder knownNames were read from
+	knownNames map[string]bool     // Every entry of knownDir at its last load, filtered out or not
+	newEntries map[string]newEntry // The listed entries missing from knownNames, by name
 
 	// Stats related fields
 	totalSize      int64
 	largestFile    FileInfo
+	largestDir     FileInfo   // The immediate subdirectory holding the most bytes
+	largestOnDir   bool       // The Largest File pane's selection is on the folder line
+	topFiles       []FileInfo // The largest files found by the walk, biggest first
+	extStats       []extStat  // Per-extension breakdown, biggest total first
+	fileCount      int
+	diskUsage      int64 // Allocated size of the files found by the stats walk, -1 if unknown
+	dirCount       int
+	diskFree       uint64
+	diskTotal      uint64
+	hasDiskSpace   bool // False when the filesystem query failed or is unsupported
 	gitStatus      string
 	isLoadingStats bool
 	statsError     error // Store errors from background tasks
+	statsGen       int   // Bumped per stats run so a superseded walk can bail out
+
+	// Last commit in a repository; lastCommitErr is set when it couldn't be read
+	lastCommit    gitCommit
+	lastCommitErr error
+	repoRoot      string // Root of the work tree the CWD is in, "" outside a repository
+
+	// Paths the stats walk could not read (capped at maxStatsErrors) and the overlay listing them
+	statsErrors          []statsWalkError
+	statsErrorCount      int // All errors, including those not kept
+	isStatsErrorsVisible bool
+	statsErrorsOriginY   int
+	statsErrorsPrevFocus string
+
+	// Empty folders and zero-byte files found by the stats walk (capped at
+	// maxCleanupEntries each, with full counts) and the report overlay listing them
+	emptyDirs          []FileInfo
+	emptyDirCount      int
+	emptyFiles         []FileInfo
+	emptyFileCount     int
+	isCleanupVisible   bool
+	cleanupSelectedIdx int
+	cleanupOriginY     int
+	cleanupPrevFocus   string
+
+	// Symlinks the stats walk found dangling (capped at maxCleanupEntries) and the overlay listing them
+	brokenLinks            []brokenLink
+	brokenLinkCount        int
+	isBrokenLinksVisible   bool
+	brokenLinksSelectedIdx int
+	brokenLinksOriginY     int
+	brokenLinksPrevFocus   string
+
+	// Directory comparison ('X') and the report overlay listing what it found
+	dirCompare            dirCompareReport
+	dirCompareGen         int                // Bumped per comparison, so a superseded one stops reporting
+	dirCompareCancel      context.CancelFunc // Stops the running comparison, if any
+	isDirCompareVisible   bool
+	dirCompareSelectedIdx int
+	dirCompareOriginY     int
+	dirComparePrevFocus   string
+
+	// Background tasks, newest first, and the overlay listing them
+	tasks            []task
+	nextTaskID       int
+	isTasksVisible   bool
+	tasksSelectedIdx int
+	tasksPrevFocus   string
+
+	// Stats cache: finished walks per directory, reused while the root's mtime matches
+	statsCache        map[statsCacheKey]statsCacheEntry
+	statsRevalidating bool // Showing a cached result while a fresh walk runs
+
+	// Per-directory sizes for the Folders pane
+	dirSizeCache  map[string]dirSizeCacheEntry // Keyed by path, valid while the mtime matches
+	dirSizeCancel context.CancelFunc           // Cancels the running size job, if any
+
+	// Submodule or separate-git-dir repository, per folder with a .git file; asking git costs a process
+	repoKindCache map[string]repoKindCacheEntry // Keyed by path, valid while the mtime matches
+
+	// Per-directory entry counts for the Folders pane, read for the rows on screen
+	entryCountCache   map[string]entryCountCacheEntry // Keyed by path, valid while the mtime matches
+	entryCountPending map[string]bool                 // Being counted by the current request
+	entryCountGen     int                             // Bumped per request so superseded ones stop
 
 	// UI related fields - Separate origins and cursors for each list
 	visibleFoldersOriginY int
@@ -51,6 +270,30 @@ type AppState struct {
 	visibleFilesCursorY   int // Absolute index in the list
 	hiddenFoldersCursorY  int // Absolute index in the list
 	hiddenFilesCursorY    int // Absolute index in the list
+	allFoldersOriginY     int
+	allFilesOriginY       int
+	allFoldersCursorY     int // Absolute index in the list
+	allFilesCursorY       int // Absolute index in the list
+
+	// Width of the left stats column as a fraction of the terminal width
+	panelRatio  float64
+	statsHidden bool // The stats column is collapsed into a one-line status bar
+
+	// Combined single-pane mode: one list with directories first, then files
+	combinedMode           bool
+	commanderPane          bool // One of the commander mode browsers, which are combined lists whatever combinedMode says
+	visibleCombinedOriginY int
+	hiddenCombinedOriginY  int
+	visibleCombinedCursorY int // Absolute index in the combined list
+	hiddenCombinedCursorY  int // Absolute index in the combined list
+	allCombinedOriginY     int
+	allCombinedCursorY     int // Absolute index in the combined list
+
+	// Grid layout of the Files pane: entries row by row in as many columns as fit
+	gridMode    bool
+	gridColumns int // Columns of the last drawn grid, for cursor movement
+
+	degradedWarning string // Features the last startup warning named as unavailable (saved with the preferences)
 
 	// Action Menu State
 	isActionMenuVisible   bool
@@ -67,26 +310,153 @@ type AppState struct {
 	fileContentViewOriginY    int    // Scroll position (top visible line index)
 	fileContentViewPrevFocus  string // View to return focus to after closing content view
 
+	// File the content was read from, for reloading; zero for other content
+	fileContentViewItem FileInfo
+
+	// Formatted content (Markdown, ...): the source is kept for the raw toggle
+	// and re-rendered when the view width changes
+	fileContentViewSource      string
+	fileContentViewFormatter   contentFormatter // nil for plain text
+	fileContentViewShowRaw     bool
+	fileContentViewRenderWidth int // Width the content was rendered at, 0 when stale
+
+	// Image preview: drawn instead of text, re-rendered when the view size changes
+	fileContentViewImage        image.Image
+	fileContentViewImageInfo    string // Dimensions and file size for the title
+	fileContentViewRenderHeight int
+
+	// Line endings of the text, and whether whitespace markers are shown
+	fileContentViewEndings        lineEndings
+	fileContentViewShowWhitespace bool // Kept across files until toggled off
+	fileContentViewLineNumbers    lineNumberMode
+
+	// Prompt State (single-line input overlay)
+	isPromptVisible bool
+	prompt          promptSpec
+	promptPrevFocus string                   // View to return focus to after closing the prompt
+	promptHistory   map[string]*inputHistory // Past inputs per prompt kind
+
+	// Properties Popup State
+	isPropertiesVisible bool
+	propertiesTarget    FileInfo
+	propertiesDetails   fileProperties
+	propertiesDirSize   int64 // -1 while the recursive size is being calculated
+	propertiesDirItems  int
+	propertiesGen       int    // Incremented per open so stale async results are dropped
+	propertiesPrevFocus string // View to return focus to after closing the popup
+
+	// Largest Files Overlay State
+	isTopFilesVisible   bool
+	topFilesSelectedIdx int
+	topFilesPrevFocus   string
+
+	// File History Overlay State (commits are read from git log a page at a time)
+	isHistoryVisible   bool
+	historyTarget      FileInfo
+	historyEntries     []historyEntry
+	historyLoading     bool  // A page is being read
+	historyComplete    bool  // Every commit is loaded
+	historyErr         error // Why the last page couldn't be read
+	historySelectedIdx int
+	historyOriginY     int
+	historyGen         int // Incremented per open so stale pages are dropped
+	historyPrevFocus   string
+
+	// Names git tracks in trackedDir, for the History action; valid for the
+	// listing load trackedGen
+	trackedDir   string
+	trackedNames map[string]bool
+	trackedGen   int
+
+	// Drive / Mount Point Picker State
+	isMountsVisible   bool
+	mounts            []mountEntry
+	mountsLoading     bool  // The mount list and free space are gathered in the background
+	mountsErr         error // Why the mount list couldn't be read
+	mountsSelectedIdx int
+	mountsPrevFocus   string
+
+	// Command Palette State
+	isPaletteVisible   bool
+	paletteEntries     []paletteEntry // Every command, unfiltered
+	paletteQuery       string
+	paletteSelectedIdx int // Index into the filtered matches
+	palettePrevFocus   string
+
+	// Extension Breakdown Overlay State
+	isExtStatsVisible bool
+	extStatsOriginY   int
+	extStatsPrevFocus string
+
+	// Terminal too small: a full-screen notice replaces the layout
+	isTooSmall        bool
+	tooSmallPrevFocus string
+
+	// Compare mark: a file picked with 'd' as the left side of a diff
+	compareMark    FileInfo
+	hasCompareMark bool
+
+	// Vim-style marks: letter -> path of the item selected when it was set.
+	// markPending is 'm' or '`' while waiting for the letter that follows.
+	marks         map[rune]string
+	markPending   rune
+	markPrevFocus string
+
+	// Size of the item selected in the Files pane, shown in the Size pane while it has focus
+	selSizePath string // Item the size was last requested for
+	selSize     int64  // -1 while it is being calculated, -2 if it couldn't be
+	selSizeGen  int    // Bumped per request so superseded calculations are dropped
+
+	// Preview pane below the lists, showing the selected item
+	previewMode    bool
+	previewPath    string // Item the preview was last requested for
+	previewContent string
+	previewGen     int // Bumped per request so superseded reads are dropped
+
 	// Help View State
-	helpVisible bool
+	helpVisible   bool
+	helpOriginY   int
+	helpPrevFocus string
 
-	// Confirm Delete State
-	confirmDeleteVisible bool
-	itemToDelete         *FileInfo // Store the item pending deletion
+	// Confirmation Dialog State
+	isConfirmVisible bool
+	confirm          confirmSpec
+	confirmPrevFocus string
 
 	// Message Bar State
 	lastMessage string // For temporary messages (e.g., copy status)
+
+	// Message history: a ring buffer of the last messageHistoryLimit messages
+	messageHistory      []messageEntry
+	messageHistoryStart int // Index of the oldest entry once the buffer is full
+	isMessagesVisible   bool
+	messagesOriginY     int
+	messagesPrevFocus   string
 	// messageTimer *sync.Mutex // Using mutex as a simple timer signal mechanism (needs improvement for real timer)
 }
 
 // NewAppState creates and initializes a new AppState.
 func NewAppState(cwd string) *AppState {
 	return &AppState{
-		cwd:            cwd,
-		showHidden:     false,
-		isLoadingStats: true, // Start in loading state
-		gitStatus:      "Checking...",
-		totalSize:      -1, // Indicate not calculated yet
+		cwd:              cwd,
+		crumbIdx:         -1,
+		clipboard:        systemClipboard{},
+		files:            osFileReader{},
+		git:              execGit{},
+		hiddenMode:       hiddenModeVisible,
+		naturalSort:      naturalSortDefault,
+		viewLimit:        defaultMaxViewSize,
+		copyLimit:        defaultMaxCopySize,
+		panelRatio:       defaultPanelRatio,
+		isLoadingStats:   true, // Start in loading state
+		gitStatus:        "Checking...",
+		totalSize:        -1, // Indicate not calculated yet
+		diskUsage:        -1,
+		dirSizeCache:     make(map[string]dirSizeCacheEntry),
+		entryCountCache:  make(map[string]entryCountCacheEntry),
+		repoKindCache:    make(map[string]repoKindCacheEntry),
+		statsCache:       make(map[statsCacheKey]statsCacheEntry),
+		ignoreRulesCache: make(map[string]ignoreRulesCacheEntry),
 		// Initialize all origins and cursors to 0
 		visibleFoldersOriginY: 0,
 		visibleFilesOriginY:   0,
@@ -105,16 +475,53 @@ func NewAppState(cwd string) *AppState {
 
 // --- State Query Methods (Read operations) ---
 
-func (s *AppState) Cwd() string {
+// ViewLimit returns the size of the largest file the content viewer opens.
+func (s *AppState) ViewLimit() int64 {
 	s.RLock()
 	defer s.RUnlock()
-	return s.cwd
+	return s.viewLimit
+}
+
+// CopyLimit returns the size of the largest file Copy Content copies whole.
+func (s *AppState) CopyLimit() int64 {
+	s.RLock()
+	defer s.RUnlock()
+	return s.copyLimit
+}
+
+// SetSizeLimits applies the view and copy limits from config.json.
+func (s *AppState) SetSizeLimits(viewLimit, copyLimit int64) {
+	s.Lock()
+	defer s.Unlock()
+	s.viewLimit = viewLimit
+	s.copyLimit = copyLimit
+}
+
+// Clipboard returns the clipboard actions copy to.
+func (s *AppState) Clipboard() Clipboard {
+	s.RLock()
+	defer s.RUnlock()
+	return s.clipboard
+}
+
+// Files returns the reader used for file contents.
+func (s *AppState) Files() FileReader {
+	s.RLock()
+	defer s.RUnlock()
+	return s.files
+}
+
+// Git returns the runner used for git queries.
+func (s *AppState) Git() GitRunner {
+	s.RLock()
+	defer s.RUnlock()
+	return s.git
 }
 
-func (s *AppState) BaseDir() string {
+func (s *AppState) Cwd() string {
 	s.RLock()
 	defer s.RUnlock()
-	return filepath.Base(s.cwd)
+	return s.cwd
 }
 
 func (s *AppState) IsLoadingStats() bool {
@@ -129,10 +536,34 @@ func (s *AppState) Stats() (totalSize int64, largestFile FileInfo, gitStatus str
 	return s.totalSize, s.largestFile, s.gitStatus, s.statsError
 }
 
-func (s *AppState) IsShowingHidden() bool {
+// EntryCounts returns the number of files and directories found by the stats walk.
+func (s *AppState) EntryCounts() (files, dirs int) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.fileCount, s.dirCount
+}
+
+// DiskUsage returns the space the files found by the stats walk take on disk;
+// ok is false while unknown or where the platform doesn't report it.
+func (s *AppState) DiskUsage() (size int64, ok bool) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.diskUsage, s.diskUsage >= 0
+}
+
+// DiskSpace returns the free and total space of the CWD's filesystem; ok is
+// false when it could not be determined.
+func (s *AppState) DiskSpace() (free, total uint64, ok bool) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.diskFree, s.diskTotal, s.hasDiskSpace
+}
+
+// HiddenMode returns which entries the lists show.
+func (s *AppState) HiddenMode() hiddenMode {
 	s.RLock()
 	defer s.RUnlock()
-	return s.showHidden
+	return s.hiddenMode
 }
 
 func (s *AppState) VisibleDirs() []FileInfo {
@@ -221,69 +652,336 @@ func (s *AppState) HiddenFilesCursorY() int {
 func (s *AppState) GetCurrentCursorY(viewName string) int {
 	s.RLock()
 	defer s.RUnlock()
-	isHidden := s.showHidden
-	switch viewName {
-	case viewFolders:
-		if isHidden {
-			return s.hiddenFoldersCursorY
-		}
-		return s.visibleFoldersCursorY
-	case viewFiles:
-		if isHidden {
-			return s.hiddenFilesCursorY
-		}
-		return s.visibleFilesCursorY
+	_, _, pCursorY := s.listState(viewName)
+	if pCursorY == nil {
+		return 0 // Should not happen
 	}
-	return 0 // Should not happen
+	return *pCursorY
 }
 
 // GetCurrentOriginY returns the origin Y for the currently relevant list.
 func (s *AppState) GetCurrentOriginY(viewName string) int {
 	s.RLock()
 	defer s.RUnlock()
-	isHidden := s.showHidden
+	_, pOriginY, _ := s.listState(viewName)
+	if pOriginY == nil {
+		return 0 // Should not happen
+	}
+	return *pOriginY
+}
+
+// ListWindow returns a copy of the entries in [from, to) of viewName's list and
+// the list's total length, without copying the rest of the list.
+func (s *AppState) ListWindow(viewName string, from, to int) (window []FileInfo, total int) {
+	s.RLock()
+	defer s.RUnlock()
+	list, _, _ := s.listState(viewName)
+	return list.window(from, to), list.len()
+}
+
+// ListLen returns the number of entries in viewName's list.
+func (s *AppState) ListLen(viewName string) int {
+	s.RLock()
+	defer s.RUnlock()
+	list, _, _ := s.listState(viewName)
+	return list.len()
+}
+
+// LongestName returns the display width of the longest name in viewName's
+// list, looking no further once a name reaches limit.
+func (s *AppState) LongestName(viewName string, limit int) int {
+	s.RLock()
+	defer s.RUnlock()
+	list, _, _ := s.listState(viewName)
+	longest := 0
+	for i := 0; i < list.len() && longest < limit; i++ {
+		// A name is never wider than its length in bytes, so most need no measuring
+		if name := list.at(i).Name; len(name) > longest {
+			longest = max(longest, displayWidth(name))
+		}
+	}
+	return min(longest, limit)
+}
+
+// OtherModeLen returns how many entries of viewName's kind the current hidden
+// mode leaves out: the hidden ones when showing only the visible ones and vice
+// versa, none when showing all.
+func (s *AppState) OtherModeLen(viewName string) int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.otherModeLenLocked(viewName)
+}
+
+// otherModeLenLocked is OtherModeLen for callers holding the lock.
+func (s *AppState) otherModeLenLocked(viewName string) int {
+	var dirs, files []FileInfo
+	switch s.hiddenMode {
+	case hiddenModeVisible:
+		dirs, files = s.hiddenDirs, s.hiddenFiles
+	case hiddenModeOnly:
+		dirs, files = s.visibleDirs, s.visibleFiles
+	}
 	switch viewName {
 	case viewFolders:
-		if isHidden {
-			return s.hiddenFoldersOriginY
-		}
-		return s.visibleFoldersOriginY
+		return len(dirs)
 	case viewFiles:
-		if isHidden {
-			return s.hiddenFilesOriginY
-		}
-		return s.visibleFilesOriginY
+		return len(files)
 	}
-	return 0 // Should not happen
+	return len(dirs) + len(files)
 }
 
-// GetCurrentList returns the currently relevant list based on view name and hidden state.
-func (s *AppState) GetCurrentList(viewName string) []FileInfo {
+// SelectedItem returns the item under the cursor in the given list view.
+func (s *AppState) SelectedItem(viewName string) (FileInfo, bool) {
 	s.RLock()
 	defer s.RUnlock()
-	isHidden := s.showHidden
+	list, _, pCursorY := s.listState(viewName)
+	if pCursorY == nil || *pCursorY < 0 || *pCursorY >= list.len() {
+		return FileInfo{}, false
+	}
+	return *list.at(*pCursorY), true
+}
+
+// LoadEntryInfo lstats the entries in [from, to) of viewName's list whose
+// metadata hasn't been loaded yet, so only the rows on screen cost a syscall.
+func (s *AppState) LoadEntryInfo(viewName string, from, to int) {
+	s.RLock()
+	list, _, _ := s.listState(viewName)
+	pending := map[int]FileInfo{}
+	for i := max(from, 0); i < min(to, list.len()); i++ {
+		if item := list.at(i); !item.HasInfo && !item.Unreadable {
+			pending[i] = *item
+		}
+	}
+	s.RUnlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	for i, item := range pending {
+		pending[i] = withEntryInfo(item)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	// The list may have been replaced meanwhile; only fill entries still in place
+	list, _, _ = s.listState(viewName)
+	for i, item := range pending {
+		if i < list.len() && list.at(i).Path == item.Path {
+			*list.at(i) = item
+		}
+	}
+}
+
+// entryList is the list shown in a view: folders, files, or both (directories
+// first) in the combined list. It indexes the backing slices without copying them.
+type entryList struct {
+	dirs, files []FileInfo
+}
+
+func (l entryList) len() int { return len(l.dirs) + len(l.files) }
+
+// at returns a pointer to the i-th entry; i must be in range.
+func (l entryList) at(i int) *FileInfo {
+	if i < len(l.dirs) {
+		return &l.dirs[i]
+	}
+	return &l.files[i-len(l.dirs)]
+}
+
+// window copies the entries in [from, to), clamped to the list.
+func (l entryList) window(from, to int) []FileInfo {
+	from, to = max(from, 0), min(to, l.len())
+	if from >= to {
+		return nil
+	}
+	items := make([]FileInfo, 0, to-from)
+	for i := from; i < to; i++ {
+		items = append(items, *l.at(i))
+	}
+	return items
+}
+
+// listState returns the list shown in viewName (honoring hidden mode) along with
+// pointers to its origin and cursor. The caller must hold the lock. Pointers are
+// nil (and the list empty) for unknown views.
+func (s *AppState) listState(viewName string) (list entryList, pOriginY *int, pCursorY *int) {
+	mode := s.hiddenMode
 	switch viewName {
 	case viewFolders:
-		if isHidden {
-			// Return copy
-			dirs := make([]FileInfo, len(s.hiddenDirs))
-			copy(dirs, s.hiddenDirs)
-			return dirs
+		switch mode {
+		case hiddenModeOnly:
+			return entryList{dirs: s.hiddenDirs}, &s.hiddenFoldersOriginY, &s.hiddenFoldersCursorY
+		case hiddenModeAll:
+			return entryList{dirs: s.allDirs}, &s.allFoldersOriginY, &s.allFoldersCursorY
 		}
-		dirs := make([]FileInfo, len(s.visibleDirs))
-		copy(dirs, s.visibleDirs)
-		return dirs
+		return entryList{dirs: s.visibleDirs}, &s.visibleFoldersOriginY, &s.visibleFoldersCursorY
 	case viewFiles:
-		if isHidden {
-			files := make([]FileInfo, len(s.hiddenFiles))
-			copy(files, s.hiddenFiles)
-			return files
+		switch mode {
+		case hiddenModeOnly:
+			return entryList{files: s.hiddenFiles}, &s.hiddenFilesOriginY, &s.hiddenFilesCursorY
+		case hiddenModeAll:
+			return entryList{files: s.allFiles}, &s.allFilesOriginY, &s.allFilesCursorY
 		}
-		files := make([]FileInfo, len(s.visibleFiles))
-		copy(files, s.visibleFiles)
-		return files
+		return entryList{files: s.visibleFiles}, &s.visibleFilesOriginY, &s.visibleFilesCursorY
+	case viewCombined:
+		switch mode {
+		case hiddenModeOnly:
+			return entryList{dirs: s.hiddenDirs, files: s.hiddenFiles}, &s.hiddenCombinedOriginY, &s.hiddenCombinedCursorY
+		case hiddenModeAll:
+			return entryList{dirs: s.allDirs, files: s.allFiles}, &s.allCombinedOriginY, &s.allCombinedCursorY
+		}
+		return entryList{dirs: s.visibleDirs, files: s.visibleFiles}, &s.visibleCombinedOriginY, &s.visibleCombinedCursorY
+	}
+	return entryList{}, nil, nil
+}
+
+// PanelRatio returns the width of the left stats column as a fraction of the terminal width.
+func (s *AppState) PanelRatio() float64 {
+	s.RLock()
+	defer s.RUnlock()
+	return s.panelRatio
+}
+
+// IsStatsHidden reports whether the stats column is collapsed into the status line.
+func (s *AppState) IsStatsHidden() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.statsHidden
+}
+
+// SetStatsHidden collapses the stats column into the status line, or brings it back.
+func (s *AppState) SetStatsHidden(hidden bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.statsHidden = hidden
+}
+
+// ToggleStatsHidden collapses or restores the stats column and returns
+// whether it is now hidden.
+func (s *AppState) ToggleStatsHidden() bool {
+	s.Lock()
+	defer s.Unlock()
+	s.statsHidden = !s.statsHidden
+	return s.statsHidden
+}
+
+// AdjustPanelRatio grows (positive delta) or shrinks the left stats column,
+// clamped to sensible bounds. Returns true if the ratio changed.
+func (s *AppState) AdjustPanelRatio(delta float64) bool {
+	s.Lock()
+	defer s.Unlock()
+	newRatio := s.panelRatio + delta
+	if newRatio < minPanelRatio {
+		newRatio = minPanelRatio
+	}
+	if newRatio > maxPanelRatio {
+		newRatio = maxPanelRatio
+	}
+	changed := newRatio != s.panelRatio
+	s.panelRatio = newRatio
+	return changed
+}
+
+// Preferences returns the UI settings that are saved between runs.
+func (s *AppState) Preferences() preferences {
+	s.RLock()
+	defer s.RUnlock()
+	return preferences{PanelRatio: s.panelRatio, StatsHidden: s.statsHidden, CombinedMode: s.combinedMode, GridMode: s.gridMode, SortDescending: s.sortDesc, LineNumbers: s.fileContentViewLineNumbers, DegradedWarning: s.degradedWarning}
+}
+
+// ApplyPreferences restores saved UI settings.
+func (s *AppState) ApplyPreferences(prefs preferences) {
+	s.Lock()
+	defer s.Unlock()
+	s.panelRatio = prefs.PanelRatio
+	s.statsHidden = prefs.StatsHidden
+	s.combinedMode = prefs.CombinedMode
+	s.gridMode = prefs.GridMode
+	s.sortDesc = prefs.SortDescending
+	s.fileContentViewLineNumbers = prefs.LineNumbers
+	s.degradedWarning = prefs.DegradedWarning
+}
+
+// SetDegradedWarning records the startup warning about unavailable features,
+// saved with the preferences.
+func (s *AppState) SetDegradedWarning(summary string) {
+	s.Lock()
+	defer s.Unlock()
+	s.degradedWarning = summary
+}
+
+// IsCombinedMode reports whether a single combined list replaces the Folders and Files panes.
+func (s *AppState) IsCombinedMode() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.combinedLocked()
+}
+
+// combinedLocked is IsCombinedMode for callers that hold the lock.
+func (s *AppState) combinedLocked() bool {
+	return s.combinedMode || s.commanderPane
+}
+
+// IsCommanderPane reports whether the state is one of the commander mode browsers.
+func (s *AppState) IsCommanderPane() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.commanderPane
+}
+
+// SetCommanderPane makes the state one of the commander mode browsers, shown
+// as a combined list without changing the saved combinedMode preference.
+func (s *AppState) SetCommanderPane(on bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.commanderPane = on
+}
+
+// ToggleCombinedMode switches between the combined list and the separate panes.
+// Each layout keeps its own cursors, so switching back restores them.
+func (s *AppState) ToggleCombinedMode() bool {
+	s.Lock()
+	defer s.Unlock()
+	s.combinedMode = !s.combinedMode
+	return s.combinedMode
+}
+
+// IsGridMode reports whether the Files pane lays entries out in columns.
+func (s *AppState) IsGridMode() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.gridMode
+}
+
+// ToggleGridMode switches the Files pane between the grid and a single column.
+func (s *AppState) ToggleGridMode() bool {
+	s.Lock()
+	defer s.Unlock()
+	s.gridMode = !s.gridMode
+	return s.gridMode
+}
+
+// SetGridColumns records how many columns the Files grid was drawn with.
+func (s *AppState) SetGridColumns(columns int) {
+	s.Lock()
+	defer s.Unlock()
+	s.gridColumns = columns
+}
+
+// GridColumns returns how many entries make up a row of the named list view:
+// the drawn column count for the Files pane in grid mode, otherwise 1.
+func (s *AppState) GridColumns(viewName string) int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.gridColumnsLocked(viewName)
+}
+
+// gridColumnsLocked is GridColumns for callers that hold the lock.
+func (s *AppState) gridColumnsLocked(viewName string) int {
+	if !s.gridMode || s.combinedLocked() || viewName != viewFiles || s.gridColumns < 1 {
+		return 1
 	}
-	return nil // Should not happen
+	return s.gridColumns
 }
 
 // --- Action Menu Getters ---
@@ -314,6 +1012,14 @@ func (s *AppState) GetActionMenuSelectedIdx() int {
 	return s.actionMenuSelectedIdx
 }
 
+// SetPreviousFocusView records the view to return focus to when an action
+// runs without the action menu (e.g. Enter viewing a file directly).
+func (s *AppState) SetPreviousFocusView(viewName string) {
+	s.Lock()
+	defer s.Unlock()
+	s.previousFocusView = viewName
+}
+
 func (s *AppState) GetPreviousFocusView() string {
 	s.RLock()
 	defer s.RUnlock()
@@ -357,104 +1063,2226 @@ func (s *AppState) GetFileContentViewTotalLines() int {
 	return s.fileContentViewTotalLines
 }
 
-// --- Help View Getters ---
-func (s *AppState) IsHelpVisible() bool {
+// FileContentRenderMode returns "rendered" or "raw" for formatted content,
+// and "" for plain text.
+func (s *AppState) FileContentRenderMode() string {
 	s.RLock()
 	defer s.RUnlock()
-	return s.helpVisible
+	switch {
+	case s.fileContentViewFormatter == nil:
+		return ""
+	case s.fileContentViewShowRaw:
+		return "raw"
+	default:
+		return "rendered"
+	}
 }
 
-// --- Confirm Delete Getters ---
-func (s *AppState) IsConfirmDeleteVisible() bool {
+// FileContentEndings returns the line endings of the viewed text and whether
+// whitespace markers are on.
+func (s *AppState) FileContentEndings() (lineEndings, bool) {
 	s.RLock()
 	defer s.RUnlock()
-	return s.confirmDeleteVisible
+	return s.fileContentViewEndings, s.fileContentViewShowWhitespace
 }
 
-func (s *AppState) GetItemToDelete() *FileInfo {
+// --- Prompt Getters ---
+func (s *AppState) IsPromptVisible() bool {
 	s.RLock()
 	defer s.RUnlock()
-	return s.itemToDelete
+	return s.isPromptVisible
 }
 
-// --- Message Bar Getters ---
-func (s *AppState) GetLastMessage() string {
+// GetPrompt returns the spec of the open prompt.
+func (s *AppState) GetPrompt() promptSpec {
 	s.RLock()
 	defer s.RUnlock()
-	return s.lastMessage
+	return s.prompt
 }
 
-// --- State Modification Methods (Write operations) ---
+func (s *AppState) GetPromptPrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.promptPrevFocus
+}
 
-// SetDirectoryContents updates the file/dir lists and resets cursors/origins.
-func (s *AppState) SetDirectoryContents(visibleDirs, visibleFiles, hiddenDirs, hiddenFiles []FileInfo) {
-	s.Lock()
-	defer s.Unlock()
-	s.visibleDirs = visibleDirs
-	s.visibleFiles = visibleFiles
-	s.hiddenDirs = hiddenDirs
-	s.hiddenFiles = hiddenFiles
+// --- Properties Popup Getters ---
+func (s *AppState) IsPropertiesVisible() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.isPropertiesVisible
+}
 
-	// Reset scrolls and cursors whenever content changes
-	s.visibleFoldersOriginY = 0
-	s.visibleFilesOriginY = 0
-	s.hiddenFoldersOriginY = 0
-	s.hiddenFilesOriginY = 0
-	s.visibleFoldersCursorY = 0
-	s.visibleFilesCursorY = 0
-	s.hiddenFoldersCursorY = 0
-	s.hiddenFilesCursorY = 0
+// Properties returns the popup target, its details and the (possibly pending) directory usage.
+func (s *AppState) Properties() (target FileInfo, details fileProperties, dirSize int64, dirItems int) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.propertiesTarget, s.propertiesDetails, s.propertiesDirSize, s.propertiesDirItems
 }
 
-// ToggleHidden flips the hidden file visibility and resets scrolls/cursors for the activated views.
-func (s *AppState) ToggleHidden() bool {
-	s.Lock()
-	defer s.Unlock()
-	s.showHidden = !s.showHidden
-	// Reset scroll and cursor for *both* sets of views for simplicity
-	s.visibleFoldersOriginY = 0
-	s.visibleFilesOriginY = 0
-	s.hiddenFoldersOriginY = 0
-	s.hiddenFilesOriginY = 0
-	s.visibleFoldersCursorY = 0
-	s.visibleFilesCursorY = 0
-	s.hiddenFoldersCursorY = 0
-	s.hiddenFilesCursorY = 0
+func (s *AppState) GetPropertiesPrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.propertiesPrevFocus
+}
 
-	return s.showHidden // Return new state
+// IsPropertiesGen reports whether gen still identifies the open properties popup.
+func (s *AppState) IsPropertiesGen(gen int) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.isPropertiesVisible && s.propertiesGen == gen
 }
 
-// SetStatsLoading marks the application as loading stats.
-func (s *AppState) SetStatsLoading() {
-	s.Lock()
-	defer s.Unlock()
-	s.isLoadingStats = true
-	s.gitStatus = "Calculating..." // Provide immediate feedback
-	s.totalSize = -1               // Reset size indicator
-	s.largestFile = FileInfo{}
-	s.statsError = nil
+// IsOverlayVisible reports whether any modal overlay is currently shown.
+func (s *AppState) IsOverlayVisible() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.overlayVisibleLocked()
 }
 
-// SetStatsResults updates the state after stats calculation finishes.
+// overlayVisibleLocked is IsOverlayVisible for callers holding the lock.
+func (s *AppState) overlayVisibleLocked() bool {
+	return s.isActionMenuVisible || s.isFileContentViewVisible || s.helpVisible ||
+		s.isConfirmVisible || s.isPromptVisible || s.isPropertiesVisible ||
+		s.isTopFilesVisible || s.isHistoryVisible || s.isMountsVisible || s.isPaletteVisible || s.isExtStatsVisible || s.isMessagesVisible ||
+		s.isStatsErrorsVisible || s.isTasksVisible || s.isCleanupVisible || s.isBrokenLinksVisible ||
+		s.isDirCompareVisible ||
+		s.markPending != 0 || s.isTooSmall
+}
+
+// IsTooSmall reports whether the terminal is too small for the normal layout.
+func (s *AppState) IsTooSmall() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.isTooSmall
+}
+
+// GetTooSmallPrevFocus returns the view that had focus before the terminal got too small.
+func (s *AppState) GetTooSmallPrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.tooSmallPrevFocus
+}
+
+// SetTooSmall records whether the terminal is too small, remembering the
+// focused view when entering that state so it can be restored afterwards.
+func (s *AppState) SetTooSmall(tooSmall bool, prevFocus string) {
+	s.Lock()
+	defer s.Unlock()
+	if tooSmall && !s.isTooSmall {
+		s.tooSmallPrevFocus = prevFocus
+	}
+	s.isTooSmall = tooSmall
+}
+
+// --- Extension Breakdown Overlay ---
+func (s *AppState) IsExtStatsVisible() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.isExtStatsVisible
+}
+
+func (s *AppState) ExtStats() []extStat {
+	s.RLock()
+	defer s.RUnlock()
+	stats := make([]extStat, len(s.extStats))
+	copy(stats, s.extStats)
+	return stats
+}
+
+func (s *AppState) GetExtStatsOriginY() int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.extStatsOriginY
+}
+
+func (s *AppState) GetExtStatsPrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.extStatsPrevFocus
+}
+
+func (s *AppState) OpenExtStats(prevFocus string) {
+	s.Lock()
+	defer s.Unlock()
+	s.isExtStatsVisible = true
+	s.extStatsOriginY = 0
+	s.extStatsPrevFocus = prevFocus
+}
+
+func (s *AppState) CloseExtStats() {
+	s.Lock()
+	defer s.Unlock()
+	s.isExtStatsVisible = false
+	s.extStatsOriginY = 0
+}
+
+// ScrollExtStats moves the extension breakdown's scroll position, clamped to its length.
+func (s *AppState) ScrollExtStats(delta int, viewHeight int) {
+	s.Lock()
+	defer s.Unlock()
+	s.extStatsOriginY = clampScroll(s.extStatsOriginY+delta, len(s.extStats), viewHeight)
+}
+
+// --- Largest Files Overlay Getters ---
+func (s *AppState) IsTopFilesVisible() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.isTopFilesVisible
+}
+
+func (s *AppState) TopFiles() []FileInfo {
+	s.RLock()
+	defer s.RUnlock()
+	files := make([]FileInfo, len(s.topFiles))
+	copy(files, s.topFiles)
+	return files
+}
+
+func (s *AppState) GetTopFilesSelectedIdx() int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.topFilesSelectedIdx
+}
+
+func (s *AppState) GetTopFilesPrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.topFilesPrevFocus
+}
+
+// --- Largest Files Overlay State Management ---
+
+func (s *AppState) OpenTopFiles(prevFocus string) {
+	s.Lock()
+	defer s.Unlock()
+	s.isTopFilesVisible = true
+	s.topFilesSelectedIdx = 0
+	s.topFilesPrevFocus = prevFocus
+}
+
+func (s *AppState) CloseTopFiles() {
+	s.Lock()
+	defer s.Unlock()
+	s.isTopFilesVisible = false
+	s.topFilesSelectedIdx = 0
+}
+
+// NavigateTopFiles moves the selection in the largest files overlay, wrapping around.
+func (s *AppState) NavigateTopFiles(delta int) {
+	s.Lock()
+	defer s.Unlock()
+	if !s.isTopFilesVisible || len(s.topFiles) == 0 {
+		return
+	}
+	s.topFilesSelectedIdx += delta
+	if s.topFilesSelectedIdx < 0 {
+		s.topFilesSelectedIdx = len(s.topFiles) - 1
+	}
+	if s.topFilesSelectedIdx >= len(s.topFiles) {
+		s.topFilesSelectedIdx = 0
+	}
+}
+
+// --- File History Overlay ---
+
+func (s *AppState) IsHistoryVisible() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.isHistoryVisible
+}
+
+// History returns the file the overlay shows, the commits loaded so far,
+// whether a page is loading or everything is loaded, and the last load error.
+func (s *AppState) History() (target FileInfo, entries []historyEntry, loading, complete bool, err error) {
+	s.RLock()
+	defer s.RUnlock()
+	entries = make([]historyEntry, len(s.historyEntries))
+	copy(entries, s.historyEntries)
+	return s.historyTarget, entries, s.historyLoading, s.historyComplete, s.historyErr
+}
+
+// HistoryPosition returns the selected commit and the first one on screen.
+func (s *AppState) HistoryPosition() (selectedIdx, originY int) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.historySelectedIdx, s.historyOriginY
+}
+
+func (s *AppState) GetHistoryPrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.historyPrevFocus
+}
+
+// OpenHistory shows the overlay for item with no commits loaded yet.
+func (s *AppState) OpenHistory(item FileInfo, prevFocus string) {
+	s.Lock()
+	defer s.Unlock()
+	s.isHistoryVisible = true
+	s.historyTarget = item
+	s.historyEntries = nil
+	s.historyLoading = false
+	s.historyComplete = false
+	s.historyErr = nil
+	s.historySelectedIdx = 0
+	s.historyOriginY = 0
+	s.historyGen++
+	s.historyPrevFocus = prevFocus
+}
+
+// BeginHistoryPage marks the next page as loading when the selection is
+// close enough to the end of the loaded commits to need it. It returns what
+// to pass to loadFileHistory (after is nil for the first page), and ok false
+// when no page should be read.
+func (s *AppState) BeginHistoryPage() (gen int, target FileInfo, after *historyEntry, ok bool) {
+	s.Lock()
+	defer s.Unlock()
+	if !s.isHistoryVisible || s.historyLoading || s.historyComplete ||
+		s.historySelectedIdx < len(s.historyEntries)-historyPrefetchRows {
+		return 0, FileInfo{}, nil, false
+	}
+	s.historyLoading = true
+	if n := len(s.historyEntries); n > 0 {
+		last := s.historyEntries[n-1]
+		after = &last
+	}
+	return s.historyGen, s.historyTarget, after, true
+}
+
+// AddHistoryPage appends a page read for the overlay opened as gen. A short
+// page, or a failed one, ends the loading.
+func (s *AppState) AddHistoryPage(gen int, entries []historyEntry, err error) {
+	s.Lock()
+	defer s.Unlock()
+	if !s.isHistoryVisible || s.historyGen != gen {
+		return // Closed, or reopened for another file
+	}
+	s.historyEntries = append(s.historyEntries, entries...)
+	s.historyLoading = false
+	s.historyComplete = err != nil || len(entries) < historyPageSize
+	s.historyErr = err
+}
+
+// TrackedNames returns the names git tracks in dir, if they were read during
+// the current listing load.
+func (s *AppState) TrackedNames(dir string) (map[string]bool, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	if s.trackedNames == nil || s.trackedDir != dir || s.trackedGen != s.dirLoadGen {
+		return nil, false
+	}
+	return s.trackedNames, true
+}
+
+// SetTrackedNames remembers the names git tracks in dir until the next listing load.
+func (s *AppState) SetTrackedNames(dir string, names map[string]bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.trackedDir, s.trackedNames, s.trackedGen = dir, names, s.dirLoadGen
+}
+
+func (s *AppState) CloseHistory() {
+	s.Lock()
+	defer s.Unlock()
+	s.isHistoryVisible = false
+	s.historyEntries = nil
+}
+
+// NavigateHistory moves the selection by delta, stopping at either end, and
+// scrolls so it stays within the viewHeight rows on screen.
+func (s *AppState) NavigateHistory(delta, viewHeight int) {
+	s.Lock()
+	defer s.Unlock()
+	if !s.isHistoryVisible || len(s.historyEntries) == 0 {
+		return
+	}
+	s.historySelectedIdx += delta
+	if s.historySelectedIdx >= len(s.historyEntries) {
+		s.historySelectedIdx = len(s.historyEntries) - 1
+	}
+	if s.historySelectedIdx < 0 {
+		s.historySelectedIdx = 0
+	}
+	if s.historySelectedIdx < s.historyOriginY {
+		s.historyOriginY = s.historySelectedIdx
+	} else if viewHeight > 0 && s.historySelectedIdx >= s.historyOriginY+viewHeight {
+		s.historyOriginY = s.historySelectedIdx - viewHeight + 1
+	}
+}
+
+// --- Drive / Mount Point Picker ---
+
+func (s *AppState) IsMountsVisible() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.isMountsVisible
+}
+
+// Mounts returns the picker's entries, whether they are still loading, and the load error.
+func (s *AppState) Mounts() (entries []mountEntry, loading bool, err error) {
+	s.RLock()
+	defer s.RUnlock()
+	entries = make([]mountEntry, len(s.mounts))
+	copy(entries, s.mounts)
+	return entries, s.mountsLoading, s.mountsErr
+}
+
+func (s *AppState) GetMountsSelectedIdx() int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.mountsSelectedIdx
+}
+
+func (s *AppState) GetMountsPrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.mountsPrevFocus
+}
+
+// OpenMounts shows the picker in its loading state.
+func (s *AppState) OpenMounts(prevFocus string) {
+	s.Lock()
+	defer s.Unlock()
+	s.isMountsVisible = true
+	s.mounts = nil
+	s.mountsLoading = true
+	s.mountsErr = nil
+	s.mountsSelectedIdx = 0
+	s.mountsPrevFocus = prevFocus
+}
+
+// SetMounts stores the loaded entries, preselecting the one containing the CWD.
+func (s *AppState) SetMounts(entries []mountEntry, err error) {
+	s.Lock()
+	defer s.Unlock()
+	s.mounts = entries
+	s.mountsLoading = false
+	s.mountsErr = err
+	best := -1
+	for i, m := range entries {
+		if isWithinDir(s.cwd, m.Path) && (best < 0 || len(m.Path) > len(entries[best].Path)) {
+			best = i
+		}
+	}
+	s.mountsSelectedIdx = max(best, 0)
+}
+
+func (s *AppState) CloseMounts() {
+	s.Lock()
+	defer s.Unlock()
+	s.isMountsVisible = false
+	s.mounts = nil
+}
+
+// NavigateMounts moves the selection in the picker, wrapping around.
+func (s *AppState) NavigateMounts(delta int) {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.mounts) == 0 {
+		return
+	}
+	s.mountsSelectedIdx = (s.mountsSelectedIdx + delta + len(s.mounts)) % len(s.mounts)
+}
+
+// --- Command Palette ---
+
+func (s *AppState) IsPaletteVisible() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.isPaletteVisible
+}
+
+func (s *AppState) GetPalettePrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.palettePrevFocus
+}
+
+// PaletteMatches returns the commands matching the query, best first, and the
+// index of the highlighted one.
+func (s *AppState) PaletteMatches() ([]paletteEntry, int) {
+	s.RLock()
+	defer s.RUnlock()
+	return filterPalette(s.paletteEntries, s.paletteQuery), s.paletteSelectedIdx
+}
+
+// OpenPalette shows the palette with entries and an empty query.
+func (s *AppState) OpenPalette(entries []paletteEntry, prevFocus string) {
+	s.Lock()
+	defer s.Unlock()
+	s.isPaletteVisible = true
+	s.paletteEntries = entries
+	s.paletteQuery = ""
+	s.paletteSelectedIdx = 0
+	s.palettePrevFocus = prevFocus
+}
+
+func (s *AppState) ClosePalette() {
+	s.Lock()
+	defer s.Unlock()
+	s.isPaletteVisible = false
+	s.paletteEntries = nil
+	s.paletteQuery = ""
+}
+
+// SetPaletteQuery refilters the palette, highlighting the best match.
+func (s *AppState) SetPaletteQuery(query string) {
+	s.Lock()
+	defer s.Unlock()
+	if query != s.paletteQuery {
+		s.paletteQuery = query
+		s.paletteSelectedIdx = 0
+	}
+}
+
+// NavigatePalette moves the highlight within the matches, wrapping around.
+func (s *AppState) NavigatePalette(delta int) {
+	s.Lock()
+	defer s.Unlock()
+	count := len(filterPalette(s.paletteEntries, s.paletteQuery))
+	if count == 0 {
+		return
+	}
+	s.paletteSelectedIdx = ((s.paletteSelectedIdx+delta)%count + count) % count
+}
+
+// FindInLists locates path in the directory listings. It returns the list view
+// it belongs to, its index there, and the hidden mode whose lists hold it: the
+// current mode when that lists it, otherwise the one that does.
+func (s *AppState) FindInLists(path string) (viewName string, index int, mode hiddenMode, found bool) {
+	s.RLock()
+	defer s.RUnlock()
+	type entrySlice struct {
+		view  string
+		mode  hiddenMode
+		items []FileInfo
+		dirs  []FileInfo // The directories listed before these entries in the combined list
+	}
+	lists := []entrySlice{
+		{viewFolders, hiddenModeVisible, s.visibleDirs, nil},
+		{viewFiles, hiddenModeVisible, s.visibleFiles, s.visibleDirs},
+		{viewFolders, hiddenModeOnly, s.hiddenDirs, nil},
+		{viewFiles, hiddenModeOnly, s.hiddenFiles, s.hiddenDirs},
+	}
+	if s.hiddenMode == hiddenModeAll {
+		// The merged lists hold everything
+		lists = []entrySlice{
+			{viewFolders, hiddenModeAll, s.allDirs, nil},
+			{viewFiles, hiddenModeAll, s.allFiles, s.allDirs},
+		}
+	}
+	for _, l := range lists {
+		for i, item := range l.items {
+			if item.Path == path {
+				if s.combinedLocked() {
+					return viewCombined, len(l.dirs) + i, l.mode, true
+				}
+				return l.view, i, l.mode, true
+			}
+		}
+	}
+	return "", 0, hiddenModeVisible, false
+}
+
+// --- Help View Getters ---
+func (s *AppState) IsHelpVisible() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.helpVisible
+}
+
+func (s *AppState) GetHelpOriginY() int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.helpOriginY
+}
+
+func (s *AppState) GetHelpPrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.helpPrevFocus
+}
+
+// --- Confirmation Dialog Getters ---
+func (s *AppState) IsConfirmVisible() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.isConfirmVisible
+}
+
+func (s *AppState) GetConfirm() confirmSpec {
+	s.RLock()
+	defer s.RUnlock()
+	return s.confirm
+}
+
+func (s *AppState) GetConfirmPrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.confirmPrevFocus
+}
+
+// --- Message Bar Getters ---
+func (s *AppState) GetLastMessage() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.lastMessage
+}
+
+// --- State Modification Methods (Write operations) ---
+
+// SetCwd switches to another directory, emptying the listing until it is reloaded.
+func (s *AppState) SetCwd(path string) {
+	s.Lock()
+	s.cwd = path
+	s.crumbIdx = -1
+	s.Unlock()
+	s.SetDirectoryContents(nil, nil, nil, nil)
+	s.SetIgnoredCounts(0, 0)
+}
+
+// SetDirectoryContents updates the file/dir lists and resets cursors/origins.
+func (s *AppState) SetDirectoryContents(visibleDirs, visibleFiles, hiddenDirs, hiddenFiles []FileInfo) {
+	s.Lock()
+	defer s.Unlock()
+	s.visibleDirs = visibleDirs
+	s.visibleFiles = visibleFiles
+	s.hiddenDirs = hiddenDirs
+	s.hiddenFiles = hiddenFiles
+	s.allDirs = mergeEntries(visibleDirs, hiddenDirs, s.naturalSort, s.sortDesc)
+	s.allFiles = mergeEntries(visibleFiles, hiddenFiles, s.naturalSort, s.sortDesc)
+
+	// Reset scrolls and cursors whenever content changes
+	s.resetListPositionsLocked()
+}
+
+// resetListPositionsLocked puts every list back at its top. The caller must hold the lock.
+func (s *AppState) resetListPositionsLocked() {
+	s.visibleFoldersOriginY = 0
+	s.visibleFilesOriginY = 0
+	s.hiddenFoldersOriginY = 0
+	s.hiddenFilesOriginY = 0
+	s.visibleFoldersCursorY = 0
+	s.visibleFilesCursorY = 0
+	s.hiddenFoldersCursorY = 0
+	s.hiddenFilesCursorY = 0
+	s.visibleCombinedOriginY = 0
+	s.hiddenCombinedOriginY = 0
+	s.visibleCombinedCursorY = 0
+	s.hiddenCombinedCursorY = 0
+	s.allFoldersOriginY = 0
+	s.allFilesOriginY = 0
+	s.allFoldersCursorY = 0
+	s.allFilesCursorY = 0
+	s.allCombinedOriginY = 0
+	s.allCombinedCursorY = 0
+}
+
+// BeginDirLoad marks a directory load as running and returns its generation;
+// any load still in flight becomes stale.
+func (s *AppState) BeginDirLoad() int {
+	s.Lock()
+	defer s.Unlock()
+	s.dirLoadGen++
+	s.isLoadingDir = true
+	s.dirLoadEntries = 0
+	return s.dirLoadGen
+}
+
+// IsDirLoadGen reports whether gen is still the current directory load.
+func (s *AppState) IsDirLoadGen(gen int) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.dirLoadGen == gen
+}
+
+// SetDirLoadProgress records how many entries the load gen has processed.
+func (s *AppState) SetDirLoadProgress(gen, entries int) {
+	s.Lock()
+	defer s.Unlock()
+	if s.dirLoadGen == gen {
+		s.dirLoadEntries = entries
+	}
+}
+
+// FinishDirLoad ends the load gen, returning false if a newer load superseded it.
+func (s *AppState) FinishDirLoad(gen int) bool {
+	s.Lock()
+	defer s.Unlock()
+	if s.dirLoadGen != gen {
+		return false
+	}
+	s.isLoadingDir = false
+	return true
+}
+
+// DirLoadProgress reports whether a directory load is running and how many entries it has processed.
+func (s *AppState) DirLoadProgress() (loading bool, entries int) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.isLoadingDir, s.dirLoadEntries
+}
+
+// CycleHiddenMode switches to the next hidden mode (visible, all, hidden only)
+// and returns it. Every list starts again at its top.
+func (s *AppState) CycleHiddenMode() hiddenMode {
+	s.Lock()
+	defer s.Unlock()
+	s.hiddenMode = (s.hiddenMode + 1) % hiddenModeCount
+	s.resetListPositionsLocked()
+	return s.hiddenMode
+}
+
+// SetHiddenMode switches to mode, starting every list again at its top.
+func (s *AppState) SetHiddenMode(mode hiddenMode) {
+	s.Lock()
+	defer s.Unlock()
+	s.hiddenMode = mode
+	s.resetListPositionsLocked()
+}
+
+// IsNaturalSort reports whether names are sorted with numeric-aware comparison.
+func (s *AppState) IsNaturalSort() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.naturalSort
+}
+
+// SetNaturalSort switches between natural and plain lexical name ordering.
+func (s *AppState) SetNaturalSort(natural bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.naturalSort = natural
+}
+
+// IsSortDescending reports whether names are listed in descending order.
+func (s *AppState) IsSortDescending() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.sortDesc
+}
+
+// ToggleSortDescending reverses the name order, re-sorting the lists in place,
+// and returns whether it is now descending. Cursors keep their indexes; the
+// caller moves them back onto the selected entry.
+func (s *AppState) ToggleSortDescending() bool {
+	s.Lock()
+	defer s.Unlock()
+	s.sortDesc = !s.sortDesc
+	for _, list := range [][]FileInfo{s.visibleDirs, s.visibleFiles, s.hiddenDirs, s.hiddenFiles, s.allDirs, s.allFiles} {
+		sortEntries(list, s.naturalSort, s.sortDesc)
+	}
+	return s.sortDesc
+}
+
+// IsGitIgnoreMode reports whether git-ignored entries should be hidden.
+func (s *AppState) IsGitIgnoreMode() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.gitIgnoreMode
+}
+
+// ToggleGitIgnoreMode flips git ignore mode and returns the new setting.
+func (s *AppState) ToggleGitIgnoreMode() bool {
+	s.Lock()
+	defer s.Unlock()
+	s.gitIgnoreMode = !s.gitIgnoreMode
+	return s.gitIgnoreMode
+}
+
+// FileGlob returns the filter restricting the listed files (inactive if none).
+func (s *AppState) FileGlob() globFilter {
+	s.RLock()
+	defer s.RUnlock()
+	return s.fileGlob
+}
+
+// SetFileGlob sets the filter restricting the listed files; it takes effect
+// with the next directory load.
+func (s *AppState) SetFileGlob(glob globFilter) {
+	s.Lock()
+	defer s.Unlock()
+	s.fileGlob = glob
+}
+
+// IsGitIgnoreActive reports whether the current listing was filtered by git ignore rules.
+func (s *AppState) IsGitIgnoreActive() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.gitIgnoreActive
+}
+
+// SetGitIgnoreActive records whether the current listing was filtered by git ignore rules.
+func (s *AppState) SetGitIgnoreActive(active bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.gitIgnoreActive = active
+}
+
+// newEntry is where an entry new since the previous load is listed.
+type newEntry struct {
+	isDir  bool
+	hidden bool
+}
+
+// TrackNewEntries records names, every entry just read from dir, and marks the
+// listed entries that weren't there when dir was last loaded as new. The first
+// load of a folder (including going back to one) has nothing new.
+func (s *AppState) TrackNewEntries(dir string, names []string) {
+	s.Lock()
+	defer s.Unlock()
+	s.newEntries = nil
+	if dir == s.knownDir {
+		for _, list := range []struct {
+			entries []FileInfo
+			hidden  bool
+		}{{s.visibleDirs, false}, {s.visibleFiles, false}, {s.hiddenDirs, true}, {s.hiddenFiles, true}} {
+			for _, item := range list.entries {
+				if s.knownNames[item.Name] {
+					continue
+				}
+				if s.newEntries == nil {
+					s.newEntries = make(map[string]newEntry)
+				}
+				s.newEntries[item.Name] = newEntry{isDir: item.IsDir, hidden: list.hidden}
+			}
+		}
+	}
+	known := make(map[string]bool, len(names))
+	for _, name := range names {
+		known[name] = true
+	}
+	s.knownDir, s.knownNames = dir, known
+}
+
+// IsNewEntry reports whether the entry name of the listing is new since the previous load.
+func (s *AppState) IsNewEntry(name string) bool {
+	s.RLock()
+	defer s.RUnlock()
+	_, ok := s.newEntries[name]
+	return ok
+}
+
+// NewEntryCount returns how many of the entries viewName lists in the current
+// hidden mode are new since the previous load.
+func (s *AppState) NewEntryCount(viewName string) int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.newEntryCountLocked(viewName)
+}
+
+// newEntryCountLocked is NewEntryCount for callers holding the lock.
+func (s *AppState) newEntryCountLocked(viewName string) int {
+	count := 0
+	for _, entry := range s.newEntries {
+		if (viewName == viewFolders && !entry.isDir) || (viewName == viewFiles && entry.isDir) {
+			continue
+		}
+		if (s.hiddenMode == hiddenModeVisible && entry.hidden) || (s.hiddenMode == hiddenModeOnly && !entry.hidden) {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// ClearNewEntries drops the new markers and returns how many there were.
+func (s *AppState) ClearNewEntries() int {
+	s.Lock()
+	defer s.Unlock()
+	count := len(s.newEntries)
+	s.newEntries = nil
+	return count
+}
+
+// IgnoredCounts returns how many folders and files .lazylsignore rules hid from the listing.
+func (s *AppState) IgnoredCounts() (dirs, files int) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.ignoredDirCount, s.ignoredFileCount
+}
+
+// SetIgnoredCounts records how many folders and files .lazylsignore rules hid from the listing.
+func (s *AppState) SetIgnoredCounts(dirs, files int) {
+	s.Lock()
+	defer s.Unlock()
+	s.ignoredDirCount = dirs
+	s.ignoredFileCount = files
+}
+
+// CachedIgnoreRules returns previously parsed rules if the ignore file is unchanged.
+func (s *AppState) CachedIgnoreRules(file string, modTime time.Time) (*ignoreRules, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	entry, ok := s.ignoreRulesCache[file]
+	if !ok || !entry.modTime.Equal(modTime) {
+		return nil, false
+	}
+	return entry.rules, true
+}
+
+// SetIgnoreRules caches the parsed rules of an ignore file, evicting the
+// oldest entry when full.
+func (s *AppState) SetIgnoreRules(file string, modTime time.Time, rules *ignoreRules) {
+	s.Lock()
+	defer s.Unlock()
+	if _, exists := s.ignoreRulesCache[file]; !exists && len(s.ignoreRulesCache) >= ignoreRulesCacheLimit {
+		var oldest string
+		var oldestAt time.Time
+		for k, entry := range s.ignoreRulesCache {
+			if oldestAt.IsZero() || entry.storedAt.Before(oldestAt) {
+				oldest, oldestAt = k, entry.storedAt
+			}
+		}
+		delete(s.ignoreRulesCache, oldest)
+	}
+	s.ignoreRulesCache[file] = ignoreRulesCacheEntry{modTime: modTime, storedAt: time.Now(), rules: rules}
+}
+
+// SetStatsLoading marks the application as loading stats and returns the new
+// stats generation; results from an older generation are stale.
+func (s *AppState) SetStatsLoading() int {
+	s.Lock()
+	defer s.Unlock()
+	s.statsGen++
+	s.isLoadingStats = true
+	s.gitStatus = "Calculating..." // Provide immediate feedback
+	s.totalSize = -1               // Reset size indicator
+	s.largestFile = FileInfo{}
+	s.largestDir = FileInfo{}
+	s.topFiles = nil
+	s.extStats = nil
+	s.fileCount = 0
+	s.dirCount = 0
+	s.diskUsage = -1
+	s.hasDiskSpace = false
+	s.statsError = nil
+	s.lastCommit = gitCommit{}
+	s.lastCommitErr = nil
+	s.repoRoot = ""
+	s.statsErrors = nil
+	s.statsErrorCount = 0
+	s.emptyDirs, s.emptyDirCount = nil, 0
+	s.emptyFiles, s.emptyFileCount = nil, 0
+	s.brokenLinks, s.brokenLinkCount = nil, 0
+	s.selSizePath = "" // The listing is being reloaded; measure the selection afresh
+	s.statsRevalidating = false
+	return s.statsGen
+}
+
+// IsRevalidatingStats reports whether the shown stats come from the cache and a fresh walk is running.
+func (s *AppState) IsRevalidatingStats() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.statsRevalidating
+}
+
+// ShowCachedStats displays a cached result while a revalidation walk runs.
+func (s *AppState) ShowCachedStats(stats statsSnapshot) {
+	s.Lock()
+	defer s.Unlock()
+	s.totalSize = stats.totalSize
+	s.largestFile = stats.largestFile
+	s.largestDir = stats.largestDir
+	s.topFiles = stats.topFiles
+	s.extStats = stats.extStats
+	s.fileCount = stats.fileCount
+	s.dirCount = stats.dirCount
+	s.diskUsage = stats.diskSize
+	s.emptyDirs, s.emptyDirCount = stats.emptyDirs, stats.emptyDirCount
+	s.emptyFiles, s.emptyFileCount = stats.emptyFiles, stats.emptyFileCount
+	s.brokenLinks, s.brokenLinkCount = stats.brokenLinks, stats.brokenLinkCount
+	s.isLoadingStats = false
+	s.statsRevalidating = true
+}
+
+// CachedStats returns the stats stored for key if the root directory's mtime
+// still matches, along with when they were stored.
+func (s *AppState) CachedStats(key statsCacheKey, modTime time.Time) (statsSnapshot, time.Time, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	entry, ok := s.statsCache[key]
+	if !ok || !entry.modTime.Equal(modTime) {
+		return statsSnapshot{}, time.Time{}, false
+	}
+	return entry.stats, entry.storedAt, true
+}
+
+// StoreStats caches a finished stats walk, evicting the oldest entry when full.
+func (s *AppState) StoreStats(key statsCacheKey, modTime time.Time, stats statsSnapshot) {
+	s.Lock()
+	defer s.Unlock()
+	if _, exists := s.statsCache[key]; !exists && len(s.statsCache) >= statsCacheLimit {
+		var oldest statsCacheKey
+		var oldestAt time.Time
+		for k, entry := range s.statsCache {
+			if oldestAt.IsZero() || entry.storedAt.Before(oldestAt) {
+				oldest, oldestAt = k, entry.storedAt
+			}
+		}
+		delete(s.statsCache, oldest)
+	}
+	s.statsCache[key] = statsCacheEntry{modTime: modTime, storedAt: time.Now(), stats: stats}
+}
+
+// InvalidateStatsCache drops the cached stats of path, e.g. on an explicit refresh.
+func (s *AppState) InvalidateStatsCache(path string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.statsCache, statsCacheKey{path: path})
+	delete(s.statsCache, statsCacheKey{path: path, gitIgnore: true})
+}
+
+// IsStatsGen reports whether gen is still the current stats generation.
+func (s *AppState) IsStatsGen(gen int) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.statsGen == gen
+}
+
+// SetEntryCounts stores the file and directory counts from the stats walk.
+func (s *AppState) SetEntryCounts(files, dirs int) {
+	s.Lock()
+	defer s.Unlock()
+	s.fileCount = files
+	s.dirCount = dirs
+}
+
+// SetDiskUsage stores the allocated size of the files found by the stats walk.
+func (s *AppState) SetDiskUsage(size int64) {
+	s.Lock()
+	defer s.Unlock()
+	s.diskUsage = size
+}
+
+// SetDiskSpace stores the filesystem's free and total space.
+func (s *AppState) SetDiskSpace(free, total uint64) {
+	s.Lock()
+	defer s.Unlock()
+	s.diskFree = free
+	s.diskTotal = total
+	s.hasDiskSpace = true
+}
+
+// SetExtStats stores the (possibly partial) extension breakdown from the stats walk.
+func (s *AppState) SetExtStats(stats []extStat) {
+	s.Lock()
+	defer s.Unlock()
+	s.extStats = stats
+}
+
+// SetTopFiles stores the largest files found by the stats walk (biggest first).
+func (s *AppState) SetTopFiles(files []FileInfo) {
+	s.Lock()
+	defer s.Unlock()
+	s.topFiles = files
+}
+
+// LargestDir returns the immediate subdirectory holding the most bytes, or a
+// zero FileInfo if there is none (or the stats are still loading).
+func (s *AppState) LargestDir() FileInfo {
+	s.RLock()
+	defer s.RUnlock()
+	return s.largestDir
+}
+
+// SetLargestDir stores the heaviest immediate subdirectory found by the stats walk.
+func (s *AppState) SetLargestDir(dir FileInfo) {
+	s.Lock()
+	defer s.Unlock()
+	s.largestDir = dir
+}
+
+// IsLargestDirSelected reports whether the Largest File pane's selection is on
+// the folder line rather than the file.
+func (s *AppState) IsLargestDirSelected() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.largestOnDir && s.largestDir.Name != ""
+}
+
+// BreadcrumbIndex returns the breadcrumb selected in the Root Folder pane,
+// -1 while it is the CWD's own.
+func (s *AppState) BreadcrumbIndex() int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.crumbIdx
+}
+
+// SetBreadcrumbIndex selects a breadcrumb in the Root Folder pane, -1 for the CWD's own.
+func (s *AppState) SetBreadcrumbIndex(idx int) {
+	s.Lock()
+	defer s.Unlock()
+	s.crumbIdx = idx
+}
+
+// SetLargestDirSelected moves the Largest File pane's selection to the folder
+// line (true) or the file.
+func (s *AppState) SetLargestDirSelected(onDir bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.largestOnDir = onDir
+}
+
+// SetStatsResults updates the state after stats calculation finishes.
 func (s *AppState) SetStatsResults(totalSize int64, largestFile FileInfo, gitStatus string, err error) {
 	s.Lock()
 	defer s.Unlock()
-	s.totalSize = totalSize
-	s.largestFile = largestFile
-	s.gitStatus = gitStatus
-	s.isLoadingStats = false
-	s.statsError = err
-	if err != nil && s.totalSize != -2 { // Ensure error state if err is present
-		s.totalSize = -2
+	s.totalSize = totalSize
+	s.largestFile = largestFile
+	s.gitStatus = gitStatus
+	s.isLoadingStats = false
+	s.statsRevalidating = false
+	s.statsError = err
+	if err != nil && s.totalSize != -2 { // Ensure error state if err is present
+		s.totalSize = -2
+	}
+}
+
+// InGitRepo reports whether the last stats run found the CWD inside a git repository.
+func (s *AppState) InGitRepo() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return strings.HasPrefix(s.gitStatus, "Active")
+}
+
+// SetStatsErrors stores the errors of the stats walk: the first few and how
+// many there were in total.
+func (s *AppState) SetStatsErrors(errs []statsWalkError, count int) {
+	s.Lock()
+	defer s.Unlock()
+	s.statsErrors = errs
+	s.statsErrorCount = count
+}
+
+// StatsErrors returns a copy of the kept stats walk errors and the total count.
+func (s *AppState) StatsErrors() ([]statsWalkError, int) {
+	s.RLock()
+	defer s.RUnlock()
+	errs := make([]statsWalkError, len(s.statsErrors))
+	copy(errs, s.statsErrors)
+	return errs, s.statsErrorCount
+}
+
+// SetCleanup stores the empty folders and zero-byte files found by the stats
+// walk: the first few of each and how many there were in total.
+func (s *AppState) SetCleanup(dirs []FileInfo, dirCount int, files []FileInfo, fileCount int) {
+	s.Lock()
+	defer s.Unlock()
+	s.emptyDirs, s.emptyDirCount = dirs, dirCount
+	s.emptyFiles, s.emptyFileCount = files, fileCount
+	if total := len(dirs) + len(files); s.cleanupSelectedIdx >= total {
+		s.cleanupSelectedIdx = max(total-1, 0)
+		s.cleanupOriginY = min(s.cleanupOriginY, s.cleanupSelectedIdx)
+	}
+}
+
+// CleanupEntries returns the kept empty folders followed by the kept
+// zero-byte files, and the total count of each.
+func (s *AppState) CleanupEntries() (entries []FileInfo, dirCount, fileCount int) {
+	s.RLock()
+	defer s.RUnlock()
+	entries = make([]FileInfo, 0, len(s.emptyDirs)+len(s.emptyFiles))
+	entries = append(entries, s.emptyDirs...)
+	entries = append(entries, s.emptyFiles...)
+	return entries, s.emptyDirCount, s.emptyFileCount
+}
+
+// SetBrokenLinks stores the dangling symlinks found by the stats walk: the
+// first few and how many there were in total.
+func (s *AppState) SetBrokenLinks(links []brokenLink, count int) {
+	s.Lock()
+	defer s.Unlock()
+	s.brokenLinks = links
+	s.brokenLinkCount = count
+	if s.brokenLinksSelectedIdx >= len(links) {
+		s.brokenLinksSelectedIdx = max(len(links)-1, 0)
+		s.brokenLinksOriginY = min(s.brokenLinksOriginY, s.brokenLinksSelectedIdx)
+	}
+}
+
+// BrokenLinks returns a copy of the kept broken links and the total count.
+func (s *AppState) BrokenLinks() ([]brokenLink, int) {
+	s.RLock()
+	defer s.RUnlock()
+	links := make([]brokenLink, len(s.brokenLinks))
+	copy(links, s.brokenLinks)
+	return links, s.brokenLinkCount
+}
+
+// SetLastCommit stores the last commit of the repository the stats ran in.
+func (s *AppState) SetLastCommit(commit gitCommit, err error) {
+	s.Lock()
+	defer s.Unlock()
+	s.lastCommit = commit
+	s.lastCommitErr = err
+}
+
+// LastCommit returns the last commit found by the stats run, and the error
+// if it couldn't be read.
+func (s *AppState) LastCommit() (gitCommit, error) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.lastCommit, s.lastCommitErr
+}
+
+// SetRepoRoot stores the root of the work tree found by the stats run.
+func (s *AppState) SetRepoRoot(root string) {
+	s.Lock()
+	defer s.Unlock()
+	s.repoRoot = root
+}
+
+// RepoRoot returns the root of the work tree the CWD is in, or "" outside a
+// repository (or while the stats run hasn't found out yet).
+func (s *AppState) RepoRoot() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.repoRoot
+}
+
+// --- Directory Size Job ---
+
+// BeginDirSizeJob cancels any running directory size job and returns the
+// context for a new one.
+func (s *AppState) BeginDirSizeJob() context.Context {
+	s.Lock()
+	defer s.Unlock()
+	if s.dirSizeCancel != nil {
+		s.dirSizeCancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.dirSizeCancel = cancel
+	return ctx
+}
+
+// CancelDirSizeJob stops the running directory size job, e.g. before navigating away.
+func (s *AppState) CancelDirSizeJob() {
+	s.Lock()
+	defer s.Unlock()
+	if s.dirSizeCancel != nil {
+		s.dirSizeCancel()
+		s.dirSizeCancel = nil
+	}
+}
+
+// StopBackgroundJobs stops the directory load, the stats walk, the directory
+// size job and the entry counts of a closed tab. Copies in the task list run
+// to completion.
+func (s *AppState) StopBackgroundJobs() {
+	s.CancelDirSizeJob()
+	s.CancelEntryCounts()
+	s.Lock()
+	defer s.Unlock()
+	if s.dirCompareCancel != nil {
+		s.dirCompareCancel()
+		s.dirCompareCancel = nil
+	}
+	s.statsGen++ // The running walk sees it is stale and bails out
+	// A listing still being read stops, and its callbacks never run
+	s.dirLoadGen++
+	s.isLoadingDir = false
+}
+
+// CachedDirSize returns a previously computed size if the directory is unchanged.
+func (s *AppState) CachedDirSize(path string, modTime time.Time) (int64, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	entry, ok := s.dirSizeCache[path]
+	if !ok || !entry.modTime.Equal(modTime) {
+		return 0, false
+	}
+	return entry.size, true
+}
+
+// SetDirSize records a computed directory size in the cache, evicting the
+// oldest entry when full, and in the listed entries.
+func (s *AppState) SetDirSize(path string, modTime time.Time, size int64) {
+	s.Lock()
+	defer s.Unlock()
+	if size >= 0 {
+		if _, exists := s.dirSizeCache[path]; !exists && len(s.dirSizeCache) >= dirSizeCacheLimit {
+			var oldest string
+			var oldestAt time.Time
+			for k, entry := range s.dirSizeCache {
+				if oldestAt.IsZero() || entry.storedAt.Before(oldestAt) {
+					oldest, oldestAt = k, entry.storedAt
+				}
+			}
+			delete(s.dirSizeCache, oldest)
+		}
+		s.dirSizeCache[path] = dirSizeCacheEntry{modTime: modTime, storedAt: time.Now(), size: size}
+	}
+	// A folder is in its visible or hidden list and in the merged one
+	for _, list := range [][]FileInfo{s.visibleDirs, s.hiddenDirs, s.allDirs} {
+		for i := range list {
+			if list[i].Path == path {
+				list[i].Size = size
+				break
+			}
+		}
+	}
+}
+
+// CachedRepoKind returns the repository kind found for the folder at path, if
+// the folder is unchanged since.
+func (s *AppState) CachedRepoKind(path string, modTime time.Time) (repoKind, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	entry, ok := s.repoKindCache[path]
+	if !ok || !entry.modTime.Equal(modTime) {
+		return repoNone, false
+	}
+	return entry.kind, true
+}
+
+// SetRepoKind caches the repository kind of the folder at path, found at
+// modTime, evicting the oldest entry when full.
+func (s *AppState) SetRepoKind(path string, modTime time.Time, kind repoKind) {
+	s.Lock()
+	defer s.Unlock()
+	if _, exists := s.repoKindCache[path]; !exists && len(s.repoKindCache) >= repoKindCacheLimit {
+		var oldest string
+		var oldestAt time.Time
+		for k, entry := range s.repoKindCache {
+			if oldestAt.IsZero() || entry.storedAt.Before(oldestAt) {
+				oldest, oldestAt = k, entry.storedAt
+			}
+		}
+		delete(s.repoKindCache, oldest)
+	}
+	s.repoKindCache[path] = repoKindCacheEntry{modTime: modTime, storedAt: time.Now(), kind: kind}
+}
+
+// SetDirUnreadable marks the listed folder at path unreadable, for when a
+// background job finds it can't be opened.
+func (s *AppState) SetDirUnreadable(path string) {
+	s.Lock()
+	defer s.Unlock()
+	for _, list := range [][]FileInfo{s.visibleDirs, s.hiddenDirs, s.allDirs} {
+		for i := range list {
+			if list[i].Path == path {
+				list[i].Unreadable = true
+				list[i].Size = -2
+				break
+			}
+		}
+	}
+}
+
+// --- Folder Entry Counts ---
+
+// EntryCount returns the cached number of entries of dir (-2 if it couldn't be
+// read), if it was counted at its current mtime.
+func (s *AppState) EntryCount(dir FileInfo) (int, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	entry, ok := s.entryCountCache[dir.Path]
+	if !ok || !entry.modTime.Equal(dir.ModTime) {
+		return 0, false
+	}
+	return entry.count, true
+}
+
+// RequestEntryCounts picks the folders of dirs that are neither counted nor
+// being counted. If there are any, it starts a new request for them, which
+// supersedes (and re-includes the folders of) the previous one.
+func (s *AppState) RequestEntryCounts(dirs []FileInfo) (gen int, todo []FileInfo) {
+	s.Lock()
+	defer s.Unlock()
+	for _, dir := range dirs {
+		if entry, ok := s.entryCountCache[dir.Path]; ok && entry.modTime.Equal(dir.ModTime) {
+			continue
+		}
+		if !s.entryCountPending[dir.Path] {
+			todo = append(todo, dir)
+		}
+	}
+	if len(todo) == 0 {
+		return s.entryCountGen, nil
+	}
+	s.entryCountGen++
+	s.entryCountPending = make(map[string]bool)
+	todo = todo[:0]
+	for _, dir := range dirs {
+		if entry, ok := s.entryCountCache[dir.Path]; !ok || !entry.modTime.Equal(dir.ModTime) {
+			s.entryCountPending[dir.Path] = true
+			todo = append(todo, dir)
+		}
+	}
+	return s.entryCountGen, todo
+}
+
+// IsEntryCountGen reports whether gen is still the latest count request.
+func (s *AppState) IsEntryCountGen(gen int) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return gen == s.entryCountGen
+}
+
+// SetEntryCount caches the entry count of dir, read at modTime, evicting the
+// oldest entry when full.
+func (s *AppState) SetEntryCount(path string, modTime time.Time, count int) {
+	s.Lock()
+	defer s.Unlock()
+	if _, exists := s.entryCountCache[path]; !exists && len(s.entryCountCache) >= entryCountCacheLimit {
+		var oldest string
+		var oldestAt time.Time
+		for k, entry := range s.entryCountCache {
+			if oldestAt.IsZero() || entry.storedAt.Before(oldestAt) {
+				oldest, oldestAt = k, entry.storedAt
+			}
+		}
+		delete(s.entryCountCache, oldest)
+	}
+	s.entryCountCache[path] = entryCountCacheEntry{modTime: modTime, storedAt: time.Now(), count: count}
+	delete(s.entryCountPending, path)
+}
+
+// CancelEntryCounts stops the running count request, e.g. before navigating away.
+func (s *AppState) CancelEntryCounts() {
+	s.Lock()
+	defer s.Unlock()
+	s.entryCountGen++
+	s.entryCountPending = nil
+}
+
+// SetMessage temporarily sets a message to be displayed (e.g., in status bar).
+func (s *AppState) SetMessage(msg string) {
+	s.Lock()
+	defer s.Unlock()
+	s.lastMessage = msg
+	// TODO: Implement a timer to clear the message after a delay
+	if msg != "" {
+		s.recordMessage(messageEntry{Time: time.Now(), Text: msg, IsError: isErrorMessage(msg)})
+	}
+}
+
+// recordMessage appends entry to the history ring buffer. The caller must hold the lock.
+func (s *AppState) recordMessage(entry messageEntry) {
+	if len(s.messageHistory) < messageHistoryLimit {
+		s.messageHistory = append(s.messageHistory, entry)
+		return
+	}
+	s.messageHistory[s.messageHistoryStart] = entry
+	s.messageHistoryStart = (s.messageHistoryStart + 1) % messageHistoryLimit
+}
+
+// isErrorMessage guesses whether a message bar text reports a failure.
+func isErrorMessage(msg string) bool {
+	lower := strings.ToLower(msg)
+	return strings.HasPrefix(lower, "error") || strings.Contains(lower, "failed") ||
+		strings.Contains(lower, "could not") || strings.Contains(lower, "cannot")
+}
+
+// MessageHistory returns the remembered messages, newest first.
+func (s *AppState) MessageHistory() []messageEntry {
+	s.RLock()
+	defer s.RUnlock()
+	n := len(s.messageHistory)
+	history := make([]messageEntry, n)
+	for i := 0; i < n; i++ {
+		history[i] = s.messageHistory[(s.messageHistoryStart+n-1-i)%n]
+	}
+	return history
+}
+
+// --- Compare Mark ---
+
+// CompareMark returns the file marked for compare, if any.
+func (s *AppState) CompareMark() (FileInfo, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.compareMark, s.hasCompareMark
+}
+
+// SetCompareMark marks item as the left side of the next compare.
+func (s *AppState) SetCompareMark(item FileInfo) {
+	s.Lock()
+	defer s.Unlock()
+	s.compareMark = item
+	s.hasCompareMark = true
+}
+
+// ClearCompareMark removes the compare mark.
+func (s *AppState) ClearCompareMark() {
+	s.Lock()
+	defer s.Unlock()
+	s.compareMark = FileInfo{}
+	s.hasCompareMark = false
+}
+
+// --- Marks ---
+
+// SetMark records path under letter, replacing what it held.
+func (s *AppState) SetMark(letter rune, path string) {
+	s.Lock()
+	defer s.Unlock()
+	if s.marks == nil {
+		s.marks = make(map[rune]string)
+	}
+	s.marks[letter] = path
+}
+
+// Mark returns the path recorded under letter.
+func (s *AppState) Mark(letter rune) (string, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	path, ok := s.marks[letter]
+	return path, ok
+}
+
+// MarkLetters returns the letters that hold a mark, in order.
+func (s *AppState) MarkLetters() []rune {
+	s.RLock()
+	defer s.RUnlock()
+	letters := make([]rune, 0, len(s.marks))
+	for letter := range s.marks {
+		letters = append(letters, letter)
+	}
+	sort.Slice(letters, func(i, j int) bool { return letters[i] < letters[j] })
+	return letters
+}
+
+// StartMarkKey waits for the letter following kind ('m' sets a mark, '`'
+// jumps to one), typed while prevFocus has focus.
+func (s *AppState) StartMarkKey(kind rune, prevFocus string) {
+	s.Lock()
+	defer s.Unlock()
+	s.markPending = kind
+	s.markPrevFocus = prevFocus
+}
+
+// MarkKeyPending returns the key waiting for its letter, or 0 for none.
+func (s *AppState) MarkKeyPending() rune {
+	s.RLock()
+	defer s.RUnlock()
+	return s.markPending
+}
+
+func (s *AppState) GetMarkPrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.markPrevFocus
+}
+
+func (s *AppState) EndMarkKey() {
+	s.Lock()
+	defer s.Unlock()
+	s.markPending = 0
+}
+
+// --- Preview Pane ---
+
+// IsPreviewMode reports whether the preview pane is shown.
+func (s *AppState) IsPreviewMode() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.previewMode
+}
+
+// TogglePreviewMode shows or hides the preview pane and returns the new state.
+func (s *AppState) TogglePreviewMode() bool {
+	s.Lock()
+	defer s.Unlock()
+	s.previewMode = !s.previewMode
+	s.previewPath = "" // Request afresh when shown again
+	s.previewGen++
+	return s.previewMode
+}
+
+// Preview returns the path and content of the current preview.
+func (s *AppState) Preview() (path, content string) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.previewPath, s.previewContent
+}
+
+// RequestPreview starts a preview of path, returning the request's generation.
+// changed is false if path is already being previewed.
+func (s *AppState) RequestPreview(path string) (gen int, changed bool) {
+	s.Lock()
+	defer s.Unlock()
+	if path == s.previewPath {
+		return s.previewGen, false
+	}
+	s.previewGen++
+	s.previewPath = path
+	s.previewContent = ""
+	return s.previewGen, true
+}
+
+// IsPreviewGen reports whether gen is still the latest preview request.
+func (s *AppState) IsPreviewGen(gen int) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return gen == s.previewGen
+}
+
+// SetPreview stores the content loaded for request gen, unless it was superseded.
+func (s *AppState) SetPreview(gen int, content string) {
+	s.Lock()
+	defer s.Unlock()
+	if gen == s.previewGen {
+		s.previewContent = content
+	}
+}
+
+// --- Selected Item Size ---
+
+// SelectionSize returns the path and size of the item last measured for the Size pane.
+func (s *AppState) SelectionSize() (path string, size int64) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.selSizePath, s.selSize
+}
+
+// RequestSelectionSize starts measuring path, returning the request's
+// generation. changed is false if path is already measured or being measured.
+func (s *AppState) RequestSelectionSize(path string) (gen int, changed bool) {
+	s.Lock()
+	defer s.Unlock()
+	if path == s.selSizePath {
+		return s.selSizeGen, false
+	}
+	s.selSizeGen++
+	s.selSizePath = path
+	s.selSize = -1
+	return s.selSizeGen, true
+}
+
+// IsSelectionSizeGen reports whether gen is still the latest size request.
+func (s *AppState) IsSelectionSizeGen(gen int) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return gen == s.selSizeGen
+}
+
+// SetSelectionSize stores the size measured for request gen, unless it was superseded.
+func (s *AppState) SetSelectionSize(gen int, size int64) {
+	s.Lock()
+	defer s.Unlock()
+	if gen == s.selSizeGen {
+		s.selSize = size
+	}
+}
+
+// --- Message History Overlay ---
+
+func (s *AppState) IsMessagesVisible() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.isMessagesVisible
+}
+
+func (s *AppState) GetMessagesOriginY() int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.messagesOriginY
+}
+
+func (s *AppState) GetMessagesPrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.messagesPrevFocus
+}
+
+func (s *AppState) OpenMessages(prevFocus string) {
+	s.Lock()
+	defer s.Unlock()
+	s.isMessagesVisible = true
+	s.messagesOriginY = 0
+	s.messagesPrevFocus = prevFocus
+}
+
+func (s *AppState) CloseMessages() {
+	s.Lock()
+	defer s.Unlock()
+	s.isMessagesVisible = false
+	s.messagesOriginY = 0
+}
+
+// ScrollMessages moves the message history's scroll position, clamped to its length.
+func (s *AppState) ScrollMessages(delta int, viewHeight int) {
+	s.Lock()
+	defer s.Unlock()
+	s.messagesOriginY = clampScroll(s.messagesOriginY+delta, len(s.messageHistory), viewHeight)
+}
+
+// --- Stats Errors Overlay ---
+
+func (s *AppState) IsStatsErrorsVisible() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.isStatsErrorsVisible
+}
+
+func (s *AppState) GetStatsErrorsOriginY() int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.statsErrorsOriginY
+}
+
+func (s *AppState) GetStatsErrorsPrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.statsErrorsPrevFocus
+}
+
+func (s *AppState) OpenStatsErrors(prevFocus string) {
+	s.Lock()
+	defer s.Unlock()
+	s.isStatsErrorsVisible = true
+	s.statsErrorsOriginY = 0
+	s.statsErrorsPrevFocus = prevFocus
+}
+
+func (s *AppState) CloseStatsErrors() {
+	s.Lock()
+	defer s.Unlock()
+	s.isStatsErrorsVisible = false
+	s.statsErrorsOriginY = 0
+}
+
+// ScrollStatsErrors moves the errors overlay's scroll position, clamped to its length.
+func (s *AppState) ScrollStatsErrors(delta int, viewHeight int) {
+	s.Lock()
+	defer s.Unlock()
+	s.statsErrorsOriginY = clampScroll(s.statsErrorsOriginY+delta, len(s.statsErrors), viewHeight)
+}
+
+// --- Background Tasks ---
+
+// AddTask registers a running task and returns its ID. cancel stops it.
+func (s *AppState) AddTask(name string, cancel context.CancelFunc) int {
+	s.Lock()
+	defer s.Unlock()
+	s.nextTaskID++
+	t := task{ID: s.nextTaskID, Name: name, Status: taskRunning, Started: time.Now(), cancel: cancel}
+	s.tasks = append([]task{t}, s.tasks...)
+	if s.isTasksVisible {
+		s.tasksSelectedIdx++ // Keep the same task selected
+	}
+	return t.ID
+}
+
+// SetTaskProgress records how many of total bytes a task has processed.
+func (s *AppState) SetTaskProgress(id int, done, total int64) {
+	s.Lock()
+	defer s.Unlock()
+	if t := s.taskLocked(id); t != nil {
+		t.Done, t.Total = done, total
+	}
+}
+
+// FinishTask records a task's outcome and returns the finished task. Only
+// the newest maxFinishedTasks finished tasks are kept.
+func (s *AppState) FinishTask(id int, result string, err error) task {
+	s.Lock()
+	defer s.Unlock()
+	t := s.taskLocked(id)
+	if t == nil {
+		return task{}
+	}
+	t.Finished = time.Now()
+	t.Result = result
+	switch {
+	case errors.Is(err, context.Canceled):
+		t.Status = taskCancelled
+	case err != nil:
+		t.Status = taskFailed
+		t.Result = trimError(err)
+	default:
+		t.Status = taskDone
+	}
+	finished := *t
+	kept, finishedCount := s.tasks[:0], 0
+	for _, t := range s.tasks {
+		if t.Status != taskRunning {
+			if finishedCount++; finishedCount > maxFinishedTasks {
+				continue
+			}
+		}
+		kept = append(kept, t)
+	}
+	s.tasks = kept
+	s.tasksSelectedIdx = min(s.tasksSelectedIdx, max(len(s.tasks)-1, 0))
+	return finished
+}
+
+// taskLocked returns the task with the given ID, or nil. The lock must be held.
+func (s *AppState) taskLocked(id int) *task {
+	for i := range s.tasks {
+		if s.tasks[i].ID == id {
+			return &s.tasks[i]
+		}
+	}
+	return nil
+}
+
+// Tasks returns a copy of the task list, newest first.
+func (s *AppState) Tasks() []task {
+	s.RLock()
+	defer s.RUnlock()
+	tasks := make([]task, len(s.tasks))
+	copy(tasks, s.tasks)
+	return tasks
+}
+
+// RunningTaskCount returns how many tasks have not finished yet.
+func (s *AppState) RunningTaskCount() int {
+	s.RLock()
+	defer s.RUnlock()
+	count := 0
+	for _, t := range s.tasks {
+		if t.Status == taskRunning {
+			count++
+		}
+	}
+	return count
+}
+
+// CancelSelectedTask asks the task selected in the overlay to stop. It
+// returns the task's name, and false if it is not running.
+func (s *AppState) CancelSelectedTask() (string, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	if s.tasksSelectedIdx >= len(s.tasks) || s.tasks[s.tasksSelectedIdx].Status != taskRunning {
+		return "", false
+	}
+	t := s.tasks[s.tasksSelectedIdx]
+	t.cancel()
+	return t.Name, true
+}
+
+func (s *AppState) IsTasksVisible() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.isTasksVisible
+}
+
+func (s *AppState) GetTasksSelectedIdx() int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.tasksSelectedIdx
+}
+
+func (s *AppState) GetTasksPrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.tasksPrevFocus
+}
+
+func (s *AppState) OpenTasks(prevFocus string) {
+	s.Lock()
+	defer s.Unlock()
+	s.isTasksVisible = true
+	s.tasksSelectedIdx = 0
+	s.tasksPrevFocus = prevFocus
+}
+
+func (s *AppState) CloseTasks() {
+	s.Lock()
+	defer s.Unlock()
+	s.isTasksVisible = false
+}
+
+// NavigateTasks moves the selection in the task list, wrapping around.
+func (s *AppState) NavigateTasks(delta int) {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.tasks) == 0 {
+		return
+	}
+	s.tasksSelectedIdx = (s.tasksSelectedIdx + delta + len(s.tasks)) % len(s.tasks)
+}
+
+// --- Cleanup Report Overlay ---
+
+func (s *AppState) IsCleanupVisible() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.isCleanupVisible
+}
+
+// CleanupPosition returns the selected entry of the cleanup report and the first one on screen.
+func (s *AppState) CleanupPosition() (selectedIdx, originY int) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.cleanupSelectedIdx, s.cleanupOriginY
+}
+
+func (s *AppState) GetCleanupPrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.cleanupPrevFocus
+}
+
+func (s *AppState) OpenCleanup(prevFocus string) {
+	s.Lock()
+	defer s.Unlock()
+	s.isCleanupVisible = true
+	s.cleanupSelectedIdx = 0
+	s.cleanupOriginY = 0
+	s.cleanupPrevFocus = prevFocus
+}
+
+func (s *AppState) CloseCleanup() {
+	s.Lock()
+	defer s.Unlock()
+	s.isCleanupVisible = false
+}
+
+// NavigateCleanup moves the selection in the cleanup report, scrolling to
+// keep it within the viewHeight lines on screen.
+func (s *AppState) NavigateCleanup(delta, viewHeight int) {
+	s.Lock()
+	defer s.Unlock()
+	total := len(s.emptyDirs) + len(s.emptyFiles)
+	if !s.isCleanupVisible || total == 0 {
+		return
+	}
+	s.cleanupSelectedIdx = min(max(s.cleanupSelectedIdx+delta, 0), total-1)
+	if s.cleanupSelectedIdx < s.cleanupOriginY {
+		s.cleanupOriginY = s.cleanupSelectedIdx
+	} else if viewHeight > 0 && s.cleanupSelectedIdx >= s.cleanupOriginY+viewHeight {
+		s.cleanupOriginY = s.cleanupSelectedIdx - viewHeight + 1
+	}
+}
+
+// --- Broken Links Overlay ---
+
+func (s *AppState) IsBrokenLinksVisible() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.isBrokenLinksVisible
+}
+
+// BrokenLinksPosition returns the selected broken link and the first one on screen.
+func (s *AppState) BrokenLinksPosition() (selectedIdx, originY int) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.brokenLinksSelectedIdx, s.brokenLinksOriginY
+}
+
+func (s *AppState) GetBrokenLinksPrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.brokenLinksPrevFocus
+}
+
+func (s *AppState) OpenBrokenLinks(prevFocus string) {
+	s.Lock()
+	defer s.Unlock()
+	s.isBrokenLinksVisible = true
+	s.brokenLinksSelectedIdx = 0
+	s.brokenLinksOriginY = 0
+	s.brokenLinksPrevFocus = prevFocus
+}
+
+func (s *AppState) CloseBrokenLinks() {
+	s.Lock()
+	defer s.Unlock()
+	s.isBrokenLinksVisible = false
+}
+
+// NavigateBrokenLinks moves the selection in the broken links overlay,
+// scrolling to keep it within the viewHeight lines on screen.
+func (s *AppState) NavigateBrokenLinks(delta, viewHeight int) {
+	s.Lock()
+	defer s.Unlock()
+	if !s.isBrokenLinksVisible || len(s.brokenLinks) == 0 {
+		return
+	}
+	s.brokenLinksSelectedIdx = min(max(s.brokenLinksSelectedIdx+delta, 0), len(s.brokenLinks)-1)
+	if s.brokenLinksSelectedIdx < s.brokenLinksOriginY {
+		s.brokenLinksOriginY = s.brokenLinksSelectedIdx
+	} else if viewHeight > 0 && s.brokenLinksSelectedIdx >= s.brokenLinksOriginY+viewHeight {
+		s.brokenLinksOriginY = s.brokenLinksSelectedIdx - viewHeight + 1
 	}
 }
 
-// SetMessage temporarily sets a message to be displayed (e.g., in status bar).
-func (s *AppState) SetMessage(msg string) {
+// clampScroll limits a scroll origin so that a view of viewHeight lines over
+// totalLines never scrolls past the top or leaves empty space at the bottom.
+func clampScroll(originY, totalLines, viewHeight int) int {
+	maxOriginY := totalLines - clampHeight(viewHeight)
+	if maxOriginY < 0 {
+		maxOriginY = 0
+	}
+	if originY > maxOriginY {
+		originY = maxOriginY
+	}
+	if originY < 0 {
+		originY = 0
+	}
+	return originY
+}
+
+// --- Render Snapshot ---
+// A frame draws the main panes (the lists, the stats column or status line)
+// from one renderSnapshot, read under a single RLock. Reading the state
+// getter by getter could mix two moments in one frame, e.g. a cursor from a
+// new listing with the rows of the old one. Only the rows on screen are
+// copied; overlays read their own state, each through a single getter.
+
+// listFrame is the shape of a list pane in a frame.
+type listFrame struct {
+	width, height int // Inside the frame
+	columns       int // Entries per row: 1, or more in the grid
+	nameWidth     int // Width of a grid cell's name
+}
+
+// listSnapshot is a list pane's part of a renderSnapshot.
+type listSnapshot struct {
+	frame        listFrame
+	rows         []FileInfo // The entries on screen, from originY
+	isNew        []bool     // Per row: new since the previous load of the folder
+	originY      int        // First entry on screen; keeps the cursor in view at this frame's size
+	cursorY      int
+	total        int
+	newCount     int
+	otherModeLen int // Entries of the pane's kind the hidden mode leaves out
+}
+
+// selected returns the entry under the cursor, if the list has one.
+func (l listSnapshot) selected() (FileInfo, bool) {
+	if i := l.cursorY - l.originY; i >= 0 && i < len(l.rows) {
+		return l.rows[i], true
+	}
+	return FileInfo{}, false
+}
+
+// renderSnapshot is everything the main panes draw from, as of one moment.
+type renderSnapshot struct {
+	cwd           string
+	crumbIdx      int
+	hiddenMode    hiddenMode
+	overlay       bool // A modal overlay is open, so no pane has focus
+	commanderPane bool
+	gridMode      bool
+
+	loadingDir     bool
+	dirLoadEntries int
+	ignoredDirs    int
+	ignoredFiles   int
+	gitIgnored     bool // Git-ignored entries were filtered out of the listing
+	fileGlob       globFilter
+
+	loadingStats  bool
+	revalidating  bool
+	totalSize     int64
+	largestFile   FileInfo
+	largestDir    FileInfo
+	onLargestDir  bool
+	fileCount     int
+	dirCount      int
+	diskUsage     int64 // -1 if unknown
+	diskFree      uint64
+	diskTotal     uint64
+	hasDiskSpace  bool
+	brokenLinks   int
+	statsErr      error
+	statsErrCount int
+	gitStatus     string
+	lastCommit    gitCommit
+	lastCommitErr error
+	repoRoot      string
+
+	lists map[string]listSnapshot // By list view name, for the frames asked for
+}
+
+// FrameWindow returns the entries [from, to) of viewName's list a frame of
+// that shape shows.
+func (s *AppState) FrameWindow(viewName string, frame listFrame) (from, to int) {
+	s.RLock()
+	defer s.RUnlock()
+	_, originY, _ := s.frameWindowLocked(viewName, frame)
+	return originY, originY + frame.height*frame.columns
+}
+
+// frameWindowLocked returns viewName's list, the first entry a frame of that
+// shape shows and the cursor. The caller must hold the lock.
+func (s *AppState) frameWindowLocked(viewName string, frame listFrame) (list entryList, originY, cursorY int) {
+	list, pOriginY, pCursorY := s.listState(viewName)
+	if pCursorY == nil {
+		return list, 0, 0
+	}
+	// The pane may have shrunk since the cursor last moved
+	return list, listOrigin(*pCursorY, *pOriginY, list.len(), frame.height, frame.columns, false), *pCursorY
+}
+
+// Snapshot copies what the main panes draw, including the rows on screen in
+// each of frames' list views. Each list's origin, as clamped for its frame,
+// is stored back, so the next move scrolls from what is on screen.
+func (s *AppState) Snapshot(frames map[string]listFrame) renderSnapshot {
 	s.Lock()
 	defer s.Unlock()
-	s.lastMessage = msg
-	// TODO: Implement a timer to clear the message after a delay
+	snap := renderSnapshot{
+		cwd:            s.cwd,
+		crumbIdx:       s.crumbIdx,
+		hiddenMode:     s.hiddenMode,
+		overlay:        s.overlayVisibleLocked(),
+		commanderPane:  s.commanderPane,
+		gridMode:       s.gridMode,
+		loadingDir:     s.isLoadingDir,
+		dirLoadEntries: s.dirLoadEntries,
+		ignoredDirs:    s.ignoredDirCount,
+		ignoredFiles:   s.ignoredFileCount,
+		gitIgnored:     s.gitIgnoreActive,
+		fileGlob:       s.fileGlob,
+		loadingStats:   s.isLoadingStats,
+		revalidating:   s.statsRevalidating,
+		totalSize:      s.totalSize,
+		largestFile:    s.largestFile,
+		largestDir:     s.largestDir,
+		onLargestDir:   s.largestOnDir && s.largestDir.Name != "",
+		fileCount:      s.fileCount,
+		dirCount:       s.dirCount,
+		diskUsage:      s.diskUsage,
+		diskFree:       s.diskFree,
+		diskTotal:      s.diskTotal,
+		hasDiskSpace:   s.hasDiskSpace,
+		brokenLinks:    s.brokenLinkCount,
+		statsErr:       s.statsError,
+		statsErrCount:  s.statsErrorCount,
+		gitStatus:      s.gitStatus,
+		lastCommit:     s.lastCommit,
+		lastCommitErr:  s.lastCommitErr,
+		repoRoot:       s.repoRoot,
+		lists:          make(map[string]listSnapshot, len(frames)),
+	}
+	for name, frame := range frames {
+		list, originY, cursorY := s.frameWindowLocked(name, frame)
+		if _, pOriginY, _ := s.listState(name); pOriginY != nil {
+			*pOriginY = originY
+		}
+		rows := list.window(originY, originY+frame.height*frame.columns)
+		isNew := make([]bool, len(rows))
+		for i, item := range rows {
+			_, isNew[i] = s.newEntries[item.Name]
+		}
+		snap.lists[name] = listSnapshot{
+			frame:        frame,
+			rows:         rows,
+			isNew:        isNew,
+			originY:      originY,
+			cursorY:      cursorY,
+			total:        list.len(),
+			newCount:     s.newEntryCountLocked(name),
+			otherModeLen: s.otherModeLenLocked(name),
+		}
+	}
+	return snap
+}
+
+// --- Directory Comparison Overlay ---
+
+func (s *AppState) IsDirCompareVisible() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.isDirCompareVisible
+}
+
+// DirCompare returns what the comparison has found so far.
+func (s *AppState) DirCompare() dirCompareReport {
+	s.RLock()
+	defer s.RUnlock()
+	return s.dirCompare // The walk only appends, past the copy's length
+}
+
+// DirComparePosition returns the selected finding of the comparison report and the first one on screen.
+func (s *AppState) DirComparePosition() (selectedIdx, originY int) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.dirCompareSelectedIdx, s.dirCompareOriginY
+}
+
+func (s *AppState) GetDirComparePrevFocus() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.dirComparePrevFocus
+}
+
+// StartDirCompare opens the report of a new comparison of a with b, stopping
+// any earlier one, and returns the generation the walk reports under.
+func (s *AppState) StartDirCompare(a, b string, byContent bool, cancel context.CancelFunc, prevFocus string) int {
+	s.Lock()
+	defer s.Unlock()
+	if s.dirCompareCancel != nil {
+		s.dirCompareCancel()
+	}
+	s.dirCompareGen++
+	s.dirCompareCancel = cancel
+	s.dirCompare = dirCompareReport{A: a, B: b, ByContent: byContent, Running: true}
+	s.isDirCompareVisible = true
+	s.dirCompareSelectedIdx = 0
+	s.dirCompareOriginY = 0
+	s.dirComparePrevFocus = prevFocus
+	return s.dirCompareGen
+}
+
+// AddDirCompareDiff records a finding of comparison gen, if it is still the current one.
+func (s *AppState) AddDirCompareDiff(gen int, diff dirDiff) {
+	s.Lock()
+	defer s.Unlock()
+	if gen != s.dirCompareGen {
+		return
+	}
+	s.dirCompare.Counts[diff.Kind]++
+	if len(s.dirCompare.Diffs) < maxCompareDiffs {
+		s.dirCompare.Diffs = append(s.dirCompare.Diffs, diff)
+	}
+}
+
+// CountDirCompareEntry counts an entry comparison gen looked at.
+func (s *AppState) CountDirCompareEntry(gen int) {
+	s.Lock()
+	defer s.Unlock()
+	if gen == s.dirCompareGen {
+		s.dirCompare.Checked++
+	}
+}
+
+// FinishDirCompare marks comparison gen done, err saying why it stopped early.
+func (s *AppState) FinishDirCompare(gen int, err error) {
+	s.Lock()
+	defer s.Unlock()
+	if gen != s.dirCompareGen {
+		return
+	}
+	s.dirCompare.Running = false
+	s.dirCompare.Err = err
+	s.dirCompareCancel = nil
+}
+
+// CloseDirCompare closes the comparison report, stopping the comparison if
+// it is still running.
+func (s *AppState) CloseDirCompare() {
+	s.Lock()
+	defer s.Unlock()
+	s.isDirCompareVisible = false
+	if s.dirCompareCancel != nil {
+		s.dirCompareCancel()
+		s.dirCompareCancel = nil
+	}
+}
+
+// NavigateDirCompare moves the selection in the comparison report, scrolling
+// to keep it within the viewHeight lines on screen.
+func (s *AppState) NavigateDirCompare(delta, viewHeight int) {
+	s.Lock()
+	defer s.Unlock()
+	total := len(s.dirCompare.Diffs)
+	if !s.isDirCompareVisible || total == 0 {
+		return
+	}
+	s.dirCompareSelectedIdx = min(max(s.dirCompareSelectedIdx+delta, 0), total-1)
+	if s.dirCompareSelectedIdx < s.dirCompareOriginY {
+		s.dirCompareOriginY = s.dirCompareSelectedIdx
+	} else if viewHeight > 0 && s.dirCompareSelectedIdx >= s.dirCompareOriginY+viewHeight {
+		s.dirCompareOriginY = s.dirCompareSelectedIdx - viewHeight + 1
+	}
 }
 
 // ClearMessage clears the temporary message.
@@ -466,44 +3294,52 @@ func (s *AppState) ClearMessage() {
 
 // --- List View Scrolling and Cursor Movement ---
 
+// listOrigin returns the origin that keeps cursor visible in a list of listLen
+// entries laid out cols to a row, viewHeight rows at a time. The origin is the
+// index of the first entry of a row. A cursor outside the view is scrolled to
+// the nearest edge, or to the middle with center.
+func listOrigin(cursor, origin, listLen, viewHeight, cols int, center bool) int {
+	if cols < 1 {
+		cols = 1
+	}
+	viewHeight = clampHeight(viewHeight)
+	cursorRow, originRow := cursor/cols, origin/cols
+	if cursorRow < originRow || cursorRow >= originRow+viewHeight {
+		switch {
+		case center:
+			originRow = cursorRow - viewHeight/2
+		case cursorRow < originRow: // Cursor moved above the visible area
+			originRow = cursorRow
+		default: // Cursor moved below the visible area
+			originRow = cursorRow - viewHeight + 1
+		}
+	}
+
+	// Clamp the origin (in case of page jumps or short lists)
+	totalRows := (listLen + cols - 1) / cols
+	if maxOriginRow := totalRows - viewHeight; originRow > maxOriginRow {
+		originRow = maxOriginRow
+	}
+	if originRow < 0 {
+		originRow = 0
+	}
+	return originRow * cols
+}
+
 // moveCursorAndOrigin updates the cursor and origin for the relevant list view.
+// In the grid layout, delta counts entries, so a row is GridColumns entries.
 // Returns true if the state changed.
 func (s *AppState) moveCursorAndOrigin(viewName string, delta int, viewHeight int) bool {
 	s.Lock()
 	defer s.Unlock()
 
-	var currentList []FileInfo
-	var pOriginY *int
-	var pCursorY *int
-
 	// Select the correct state variables based on viewName and showHidden
-	isHidden := s.showHidden
-	switch viewName {
-	case viewFolders:
-		if isHidden {
-			currentList = s.hiddenDirs
-			pOriginY = &s.hiddenFoldersOriginY
-			pCursorY = &s.hiddenFoldersCursorY
-		} else {
-			currentList = s.visibleDirs
-			pOriginY = &s.visibleFoldersOriginY
-			pCursorY = &s.visibleFoldersCursorY
-		}
-	case viewFiles:
-		if isHidden {
-			currentList = s.hiddenFiles
-			pOriginY = &s.hiddenFilesOriginY
-			pCursorY = &s.hiddenFilesCursorY
-		} else {
-			currentList = s.visibleFiles
-			pOriginY = &s.visibleFilesOriginY
-			pCursorY = &s.visibleFilesCursorY
-		}
-	default:
+	currentList, pOriginY, pCursorY := s.listState(viewName)
+	if pCursorY == nil {
 		return false // Invalid view name
 	}
 
-	listLen := len(currentList)
+	listLen := currentList.len()
 	if listLen <= 0 {
 		changed := *pOriginY != 0 || *pCursorY != 0
 		*pOriginY = 0
@@ -524,26 +3360,9 @@ func (s *AppState) moveCursorAndOrigin(viewName string, delta int, viewHeight in
 	}
 
 	// 2. Calculate new origin based on cursor position
-	newOriginY := oldOriginY
-	if newCursorY < newOriginY { // Cursor moved above the visible area
-		newOriginY = newCursorY
-	} else if newCursorY >= newOriginY+viewHeight { // Cursor moved below the visible area
-		newOriginY = newCursorY - viewHeight + 1
-	}
-
-	// 3. Validate and clamp origin (in case of page jumps or short lists)
-	maxOriginY := listLen - viewHeight
-	if maxOriginY < 0 {
-		maxOriginY = 0
-	}
-	if newOriginY > maxOriginY {
-		newOriginY = maxOriginY
-	}
-	if newOriginY < 0 {
-		newOriginY = 0
-	}
+	newOriginY := listOrigin(newCursorY, oldOriginY, listLen, viewHeight, s.gridColumnsLocked(viewName), false)
 
-	// 4. Update state if changed
+	// 3. Update state if changed
 	changed := oldCursorY != newCursorY || oldOriginY != newOriginY
 	if changed {
 		*pCursorY = newCursorY
@@ -558,37 +3377,12 @@ func (s *AppState) setCursorAndOrigin(viewName string, newCursorY int, viewHeigh
 	s.Lock()
 	defer s.Unlock()
 
-	var currentList []FileInfo
-	var pOriginY *int
-	var pCursorY *int
-
-	isHidden := s.showHidden
-	switch viewName {
-	case viewFolders:
-		if isHidden {
-			currentList = s.hiddenDirs
-			pOriginY = &s.hiddenFoldersOriginY
-			pCursorY = &s.hiddenFoldersCursorY
-		} else {
-			currentList = s.visibleDirs
-			pOriginY = &s.visibleFoldersOriginY
-			pCursorY = &s.visibleFoldersCursorY
-		}
-	case viewFiles:
-		if isHidden {
-			currentList = s.hiddenFiles
-			pOriginY = &s.hiddenFilesOriginY
-			pCursorY = &s.hiddenFilesCursorY
-		} else {
-			currentList = s.visibleFiles
-			pOriginY = &s.visibleFilesOriginY
-			pCursorY = &s.visibleFilesCursorY
-		}
-	default:
+	currentList, pOriginY, pCursorY := s.listState(viewName)
+	if pCursorY == nil {
 		return false
 	}
 
-	listLen := len(currentList)
+	listLen := currentList.len()
 	if listLen <= 0 {
 		changed := *pOriginY != 0 || *pCursorY != 0
 		*pOriginY = 0
@@ -607,26 +3401,10 @@ func (s *AppState) setCursorAndOrigin(viewName string, newCursorY int, viewHeigh
 		newCursorY = listLen - 1
 	}
 
-	// 2. Calculate new origin
-	newOriginY := *pOriginY
-	if newCursorY < newOriginY || newCursorY >= newOriginY+viewHeight {
-		// Cursor is outside the current view, center it if possible
-		newOriginY = newCursorY - viewHeight/2
-	}
-
-	// 3. Validate and clamp origin
-	maxOriginY := listLen - viewHeight
-	if maxOriginY < 0 {
-		maxOriginY = 0
-	}
-	if newOriginY > maxOriginY {
-		newOriginY = maxOriginY
-	}
-	if newOriginY < 0 {
-		newOriginY = 0
-	}
+	// 2. Calculate new origin, centering the cursor if it is outside the current view
+	newOriginY := listOrigin(newCursorY, oldOriginY, listLen, viewHeight, s.gridColumnsLocked(viewName), true)
 
-	// 4. Update state if changed
+	// 3. Update state if changed
 	changed := oldCursorY != newCursorY || oldOriginY != newOriginY
 	if changed {
 		*pCursorY = newCursorY
@@ -674,6 +3452,16 @@ func (s *AppState) NavigateActionMenu(delta int) {
 	}
 }
 
+// SelectActionMenuItem moves the action menu selection to idx.
+func (s *AppState) SelectActionMenuItem(idx int) {
+	s.Lock()
+	defer s.Unlock()
+	if !s.isActionMenuVisible || idx < 0 || idx >= len(s.actionMenuOptions) {
+		return
+	}
+	s.actionMenuSelectedIdx = idx
+}
+
 // --- File Content View State Management ---
 
 // SetFileContentView prepares the state for showing the file content.
@@ -682,17 +3470,144 @@ func (s *AppState) SetFileContentView(filename, content, prevFocus string) {
 	defer s.Unlock()
 	s.isFileContentViewVisible = true
 	s.fileContentViewFileName = filename
+	content, s.fileContentViewEndings = splitLineEndings(content)
 	s.fileContentViewContent = content
+	s.fileContentViewTotalLines = countContentLines(content)
+	s.fileContentViewOriginY = 0 // Reset scroll to top
+	s.fileContentViewPrevFocus = prevFocus
+	s.fileContentViewItem = FileInfo{}
+	s.fileContentViewSource = ""
+	s.fileContentViewFormatter = nil
+	s.fileContentViewShowRaw = false
+	s.fileContentViewRenderWidth = 0
+	s.fileContentViewImage = nil
+	s.fileContentViewImageInfo = ""
+}
+
+// SetFileContentItem records the file the open content view was read from.
+func (s *AppState) SetFileContentItem(item FileInfo) {
+	s.Lock()
+	defer s.Unlock()
+	s.fileContentViewItem = item
+}
+
+// FileContentItem returns the file the content view shows, and false for
+// content that was not read from a file (diffs, command output).
+func (s *AppState) FileContentItem() (FileInfo, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.fileContentViewItem, s.fileContentViewItem.Path != ""
+}
+
+// SetFileContentImage shows img in the content view instead of text; info
+// (dimensions, size) is shown in the title.
+func (s *AppState) SetFileContentImage(filename string, img image.Image, info, prevFocus string) {
+	s.SetFileContentView(filename, "", prevFocus)
+	s.Lock()
+	defer s.Unlock()
+	s.fileContentViewImage = img
+	s.fileContentViewImageInfo = info
+}
+
+// FileContentImageInfo returns the title info of an image preview, and false
+// if the content view shows text.
+func (s *AppState) FileContentImageInfo() (string, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.fileContentViewImageInfo, s.fileContentViewImage != nil
+}
+
+// countContentLines returns the number of lines the viewer shows for content.
+func countContentLines(content string) int {
 	// Calculate total lines (handle potential trailing newline)
-	s.fileContentViewTotalLines = strings.Count(content, "\n")
+	lines := strings.Count(content, "\n")
 	if !strings.HasSuffix(content, "\n") && len(content) > 0 {
-		s.fileContentViewTotalLines++
+		lines++
 	} else if len(content) == 0 {
-		s.fileContentViewTotalLines = 1 // Treat empty file as 1 line for display
+		lines = 1 // Treat empty file as 1 line for display
 	}
+	return lines
+}
 
-	s.fileContentViewOriginY = 0 // Reset scroll to top
-	s.fileContentViewPrevFocus = prevFocus
+// SetFileContentFormatter makes the open content view show its content
+// rendered by formatter; the content becomes the source for the raw toggle.
+func (s *AppState) SetFileContentFormatter(formatter contentFormatter) {
+	s.Lock()
+	defer s.Unlock()
+	s.fileContentViewSource = s.fileContentViewContent
+	s.fileContentViewFormatter = formatter
+	s.fileContentViewShowRaw = false
+	s.fileContentViewRenderWidth = 0
+}
+
+// ToggleFileContentRaw switches between the rendered content and its source.
+// It returns false if the content has no rendered form.
+func (s *AppState) ToggleFileContentRaw() bool {
+	s.Lock()
+	defer s.Unlock()
+	if s.fileContentViewFormatter == nil {
+		return false
+	}
+	s.fileContentViewShowRaw = !s.fileContentViewShowRaw
+	s.fileContentViewRenderWidth = 0
+	s.fileContentViewOriginY = 0 // Line positions differ between the two forms
+	return true
+}
+
+// FileContentLineNumbers returns how the viewer numbers lines.
+func (s *AppState) FileContentLineNumbers() lineNumberMode {
+	s.RLock()
+	defer s.RUnlock()
+	return s.fileContentViewLineNumbers
+}
+
+// CycleFileContentLineNumbers switches to the next line number mode and returns it.
+func (s *AppState) CycleFileContentLineNumbers() lineNumberMode {
+	s.Lock()
+	defer s.Unlock()
+	s.fileContentViewLineNumbers = s.fileContentViewLineNumbers.next()
+	return s.fileContentViewLineNumbers
+}
+
+// ToggleFileContentWhitespace switches the whitespace markers of the viewer
+// and returns whether they are now shown.
+func (s *AppState) ToggleFileContentWhitespace() bool {
+	s.Lock()
+	defer s.Unlock()
+	s.fileContentViewShowWhitespace = !s.fileContentViewShowWhitespace
+	return s.fileContentViewShowWhitespace
+}
+
+// RenderFileContent brings formatted content and image previews up to date
+// for the view size, so line counts and scrolling use the rendered lines.
+// Text is rendered width-gutter cells wide next to the line numbers; images
+// fill width x height cells.
+func (s *AppState) RenderFileContent(width, gutter, height int) {
+	s.Lock()
+	defer s.Unlock()
+	if s.fileContentViewImage != nil {
+		if s.fileContentViewRenderWidth != width || s.fileContentViewRenderHeight != height {
+			s.fileContentViewContent = renderImageBlocks(s.fileContentViewImage, width, height)
+			s.fileContentViewTotalLines = countContentLines(s.fileContentViewContent)
+			s.fileContentViewOriginY = 0
+			s.fileContentViewRenderWidth, s.fileContentViewRenderHeight = width, height
+		}
+		return
+	}
+	width -= gutter
+	if s.fileContentViewFormatter == nil || s.fileContentViewRenderWidth == width {
+		return
+	}
+	if s.fileContentViewShowRaw {
+		s.fileContentViewContent = s.fileContentViewSource
+	} else {
+		s.fileContentViewContent = s.fileContentViewFormatter(s.fileContentViewSource, width)
+	}
+	s.fileContentViewTotalLines = countContentLines(s.fileContentViewContent)
+	if s.fileContentViewOriginY >= s.fileContentViewTotalLines {
+		s.fileContentViewOriginY = 0
+	}
+	s.fileContentViewRenderWidth = width
 }
 
 // CloseFileContentView resets the state to hide the file content view.
@@ -704,6 +3619,10 @@ func (s *AppState) CloseFileContentView() {
 	s.fileContentViewContent = ""
 	s.fileContentViewTotalLines = 0
 	s.fileContentViewOriginY = 0
+	s.fileContentViewSource = ""
+	s.fileContentViewFormatter = nil
+	s.fileContentViewImage = nil
+	s.fileContentViewItem = FileInfo{}
 	// s.fileContentViewPrevFocus remains for layout to use
 }
 
@@ -715,46 +3634,138 @@ func (s *AppState) ScrollFileContentView(delta int, viewHeight int) {
 		return
 	}
 
-	newOriginY := s.fileContentViewOriginY + delta
-
-	// Calculate max possible origin
 	// Max origin is total lines - view height, but must be >= 0
-	maxOriginY := s.fileContentViewTotalLines - viewHeight
-	if maxOriginY < 0 {
-		maxOriginY = 0
+	s.fileContentViewOriginY = clampScroll(s.fileContentViewOriginY+delta, s.fileContentViewTotalLines, viewHeight)
+}
+
+// --- Prompt State Management ---
+
+// OpenPrompt shows the input prompt described by spec.
+func (s *AppState) OpenPrompt(spec promptSpec, prevFocus string) {
+	s.Lock()
+	defer s.Unlock()
+	s.isPromptVisible = true
+	s.prompt = spec
+	s.promptPrevFocus = prevFocus
+	if history := s.promptHistory[spec.Kind]; history != nil {
+		history.reset()
+	}
+}
+
+// ClosePrompt hides the input prompt.
+func (s *AppState) ClosePrompt() {
+	s.Lock()
+	defer s.Unlock()
+	s.isPromptVisible = false
+	s.prompt = promptSpec{}
+	// promptPrevFocus remains for the close handler to use
+}
+
+// RecordPromptInput adds input to the history of the open prompt's kind.
+func (s *AppState) RecordPromptInput(input string) {
+	s.Lock()
+	defer s.Unlock()
+	if s.promptHistory == nil {
+		s.promptHistory = make(map[string]*inputHistory)
 	}
+	history := s.promptHistory[s.prompt.Kind]
+	if history == nil {
+		history = &inputHistory{}
+		s.promptHistory[s.prompt.Kind] = history
+	}
+	history.add(input)
+}
 
-	// Clamp new origin
-	if newOriginY < 0 {
-		newOriginY = 0
+// StepPromptHistory recalls an older (delta < 0) or newer input of the open
+// prompt's kind. current is the text being edited; ok is false at either end.
+func (s *AppState) StepPromptHistory(delta int, current string) (string, bool) {
+	s.Lock()
+	defer s.Unlock()
+	history := s.promptHistory[s.prompt.Kind]
+	if history == nil {
+		return "", false
 	}
-	if newOriginY > maxOriginY {
-		newOriginY = maxOriginY
+	return history.step(delta, current)
+}
+
+// --- Properties Popup State Management ---
+
+// OpenProperties shows the properties popup and returns its generation, which
+// async directory-size results must present to be accepted.
+func (s *AppState) OpenProperties(item FileInfo, details fileProperties, prevFocus string) int {
+	s.Lock()
+	defer s.Unlock()
+	s.isPropertiesVisible = true
+	s.propertiesTarget = item
+	s.propertiesDetails = details
+	s.propertiesDirSize = -1
+	s.propertiesDirItems = 0
+	s.propertiesGen++
+	s.propertiesPrevFocus = prevFocus
+	return s.propertiesGen
+}
+
+// SetPropertiesDirUsage stores the recursive size for the popup opened as gen.
+func (s *AppState) SetPropertiesDirUsage(gen int, size int64, items int) {
+	s.Lock()
+	defer s.Unlock()
+	if !s.isPropertiesVisible || s.propertiesGen != gen {
+		return // Popup was closed or reopened for another item
 	}
+	s.propertiesDirSize = size
+	s.propertiesDirItems = items
+}
 
-	s.fileContentViewOriginY = newOriginY
+func (s *AppState) CloseProperties() {
+	s.Lock()
+	defer s.Unlock()
+	s.isPropertiesVisible = false
+	s.propertiesTarget = FileInfo{}
+	s.propertiesDetails = fileProperties{}
+	// propertiesPrevFocus remains for the close handler to use
 }
 
 // --- Help View State Management ---
 
-func (s *AppState) SetHelpVisible(visible bool) {
+func (s *AppState) OpenHelp(prevFocus string) {
+	s.Lock()
+	defer s.Unlock()
+	s.helpVisible = true
+	s.helpOriginY = 0
+	s.helpPrevFocus = prevFocus
+}
+
+func (s *AppState) CloseHelp() {
+	s.Lock()
+	defer s.Unlock()
+	s.helpVisible = false
+	s.helpOriginY = 0
+}
+
+// ScrollHelp moves the help overlay's scroll position, clamped to its lineCount lines.
+func (s *AppState) ScrollHelp(delta, lineCount, viewHeight int) {
 	s.Lock()
 	defer s.Unlock()
-	s.helpVisible = visible
+	s.helpOriginY = clampScroll(s.helpOriginY+delta, lineCount, viewHeight)
 }
 
-// --- Confirm Delete State Management ---
+// --- Confirmation Dialog State Management ---
 
-func (s *AppState) SetConfirmDeleteVisible(visible bool) {
+// OpenConfirm shows the confirmation dialog described by spec.
+func (s *AppState) OpenConfirm(spec confirmSpec, prevFocus string) {
 	s.Lock()
 	defer s.Unlock()
-	s.confirmDeleteVisible = visible
+	s.isConfirmVisible = true
+	s.confirm = spec
+	s.confirmPrevFocus = prevFocus
 }
 
-func (s *AppState) SetItemToDelete(item *FileInfo) {
+func (s *AppState) CloseConfirm() {
 	s.Lock()
 	defer s.Unlock()
-	s.itemToDelete = item
+	s.isConfirmVisible = false
+	s.confirm = confirmSpec{}
+	// confirmPrevFocus remains for the close handler to use
 }
 
 // --- Setters for UI state ---
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestClampHeight(t *testing.T) {
+	for _, tt := range []struct{ in, want int }{{10, 10}, {1, 1}, {0, 1}, {-7, 1}} {
+		if got := clampHeight(tt.in); got != tt.want {
+			t.Errorf("clampHeight(%d) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestClampScroll(t *testing.T) {
+	tests := []struct {
+		originY, totalLines, viewHeight int
+		want                            int
+	}{
+		{5, 100, 10, 5},
+		{95, 100, 10, 90},
+		{-1, 100, 10, 0},
+		{5, 8, 10, 0}, // Content fits
+		{50, 100, 0, 50},
+		{200, 100, 0, 99}, // A degenerate height is one row
+		{200, 100, -5, 99},
+		{3, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := clampScroll(tt.originY, tt.totalLines, tt.viewHeight); got != tt.want {
+			t.Errorf("clampScroll(%d, %d, %d) = %d, want %d", tt.originY, tt.totalLines, tt.viewHeight, got, tt.want)
+		}
+	}
+}
+
+func TestListOrigin(t *testing.T) {
+	tests := []struct {
+		name                                  string
+		cursor, origin, listLen, height, cols int
+		center                                bool
+		want                                  int
+	}{
+		{"visible cursor keeps the origin", 5, 0, 100, 10, 1, false, 0},
+		{"below scrolls to the bottom edge", 15, 0, 100, 10, 1, false, 6},
+		{"above scrolls to the top edge", 3, 10, 100, 10, 1, false, 3},
+		{"centered", 50, 0, 100, 10, 1, true, 45},
+		{"centered near the end is clamped", 98, 0, 100, 10, 1, true, 90},
+		{"centered near the top is clamped", 2, 40, 100, 10, 1, true, 0},
+		{"origin past a shortened list", 2, 50, 5, 10, 1, false, 0},
+		{"zero height is one row", 7, 0, 100, 0, 1, false, 7},
+		{"negative height is one row", 7, 0, 100, -3, 1, false, 7},
+		{"empty list", 0, 4, 0, 10, 1, false, 0},
+		{"grid rows", 25, 0, 100, 3, 4, false, 16}, // Cursor on row 6, rows 4-6 shown
+		{"grid centered", 41, 0, 100, 4, 4, true, 32},
+		{"zero columns are one", 15, 0, 100, 10, 0, false, 6},
+	}
+	for _, tt := range tests {
+		got := listOrigin(tt.cursor, tt.origin, tt.listLen, tt.height, tt.cols, tt.center)
+		if got != tt.want {
+			t.Errorf("%s: listOrigin(%d, %d, %d, %d, %d, %v) = %d, want %d",
+				tt.name, tt.cursor, tt.origin, tt.listLen, tt.height, tt.cols, tt.center, got, tt.want)
+		}
+	}
+}
+
+// numberedEntries returns n files named after gen and their index.
+func numberedEntries(gen, n int) []FileInfo {
+	entries := make([]FileInfo, n)
+	for i := range entries {
+		entries[i] = FileInfo{Name: fmt.Sprintf("g%d-%04d", gen, i)}
+	}
+	return entries
+}
+
+func TestSnapshotStoresClampedOrigin(t *testing.T) {
+	state := NewAppState(t.TempDir())
+	state.SetDirectoryContents(nil, numberedEntries(0, 100), nil, nil)
+	state.setCursorAndOrigin(viewFiles, 50, 100) // All of it fits: origin 0
+
+	snap := state.Snapshot(map[string]listFrame{viewFiles: {width: 40, height: 10, columns: 1}})
+	if got := snap.lists[viewFiles].originY; got != 41 {
+		t.Fatalf("snapshot origin %d, want 41", got)
+	}
+	state.moveCursorAndOrigin(viewFiles, -1, 10) // Still on screen: no scrolling
+	if _, pOriginY, _ := state.listState(viewFiles); *pOriginY != 41 {
+		t.Errorf("origin after moving up is %d, want the 41 on screen", *pOriginY)
+	}
+}
+
+// TestSnapshotConsistent takes snapshots while another goroutine replaces the
+// listing and moves the cursor; run it with -race. Every snapshot must show
+// rows of one listing, from its origin, with the cursor among them.
+func TestSnapshotConsistent(t *testing.T) {
+	state := NewAppState(t.TempDir())
+	frames := map[string]listFrame{
+		viewFolders: {width: 40, height: 7, columns: 1},
+		viewFiles:   {width: 40, height: 5, columns: 3},
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for gen := 0; gen < 300; gen++ {
+			n := gen % 40
+			state.SetDirectoryContents(numberedEntries(gen, n/2), numberedEntries(gen, n), nil, nil)
+			state.moveCursorAndOrigin(viewFiles, gen%23, 5)
+			state.setCursorAndOrigin(viewFolders, gen%11, 7)
+		}
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		snap := state.Snapshot(frames)
+		for name, list := range snap.lists {
+			capacity := list.frame.height * list.frame.columns
+			if want := min(capacity, max(list.total-list.originY, 0)); len(list.rows) != want {
+				t.Fatalf("%s: %d rows from %d of %d, want %d", name, len(list.rows), list.originY, list.total, want)
+			}
+			if list.total > 0 && (list.cursorY < list.originY || list.cursorY >= list.originY+capacity || list.cursorY >= list.total) {
+				t.Fatalf("%s: cursor %d outside rows %d+%d of %d", name, list.cursorY, list.originY, capacity, list.total)
+			}
+			var gen int
+			for i, row := range list.rows {
+				var rowGen, idx int
+				if _, err := fmt.Sscanf(row.Name, "g%d-%d", &rowGen, &idx); err != nil {
+					t.Fatal(err)
+				}
+				if i == 0 {
+					gen = rowGen
+				}
+				if rowGen != gen || idx != list.originY+i {
+					t.Fatalf("%s: row %d is %s, mixing listings or off its origin %d", name, i, row.Name, list.originY)
+				}
+			}
+		}
+	}
+}
+
+func TestListWindow(t *testing.T) {
+	state := NewAppState("/work")
+	dirs := numberedEntries(0, 3)
+	for i := range dirs {
+		dirs[i].IsDir = true
+	}
+	state.SetDirectoryContents(dirs, numberedEntries(1, 5), nil, nil)
+
+	tests := []struct {
+		view      string
+		from, to  int
+		wantNames []string
+	}{
+		{viewFiles, 1, 3, []string{"g1-0001", "g1-0002"}},
+		{viewFiles, 3, 99, []string{"g1-0003", "g1-0004"}},
+		{viewFiles, -2, 1, []string{"g1-0000"}},
+		{viewFiles, 7, 9, nil},
+		{viewCombined, 2, 4, []string{"g0-0002", "g1-0000"}}, // Across the folders/files boundary
+		{viewFolders, 0, 3, []string{"g0-0000", "g0-0001", "g0-0002"}},
+	}
+	for _, tt := range tests {
+		window, total := state.ListWindow(tt.view, tt.from, tt.to)
+		var names []string
+		for _, item := range window {
+			names = append(names, item.Name)
+		}
+		if fmt.Sprint(names) != fmt.Sprint(tt.wantNames) {
+			t.Errorf("ListWindow(%s, %d, %d) = %q, want %q", tt.view, tt.from, tt.to, names, tt.wantNames)
+		}
+		if want := state.ListLen(tt.view); total != want {
+			t.Errorf("ListWindow(%s) total %d, want %d", tt.view, total, want)
+		}
+	}
+}
+
+func TestSnapshotCopiesOnlyTheWindow(t *testing.T) {
+	const height = 10
+	state := NewAppState("/work")
+	state.SetDirectoryContents(nil, numberedEntries(0, 10000), nil, nil)
+	state.setCursorAndOrigin(viewFiles, 5000, height)
+
+	list := state.Snapshot(map[string]listFrame{viewFiles: {width: 40, height: height, columns: 1}}).lists[viewFiles]
+	if len(list.rows) != height || list.total != 10000 {
+		t.Fatalf("got %d rows of %d, want %d of 10000", len(list.rows), list.total, height)
+	}
+	if cursor := list.rows[list.cursorY-list.originY].Name; cursor != "g0-5000" {
+		t.Errorf("cursor row is %s, want g0-5000", cursor)
+	}
+}
+
+// BenchmarkKeypress moves the cursor and takes the snapshot a frame draws from,
+// as one keypress does, on lists of growing length: the cost should stay flat.
+func BenchmarkKeypress(b *testing.B) {
+	frames := map[string]listFrame{viewFiles: {width: 80, height: 40, columns: 1}}
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("%d entries", n), func(b *testing.B) {
+			state := NewAppState("/work")
+			state.SetDirectoryContents(nil, numberedEntries(0, n), nil, nil)
+			state.setCursorAndOrigin(viewFiles, n/2, 40)
+			delta := 1
+			for b.Loop() {
+				state.moveCursorAndOrigin(viewFiles, delta, 40)
+				delta = -delta
+				state.Snapshot(frames)
+			}
+		})
+	}
+}
+
+func TestStatsCache(t *testing.T) {
+	state := NewAppState("/work")
+	mtime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	key := statsCacheKey{path: "/work"}
+	state.StoreStats(key, mtime, statsSnapshot{totalSize: 42, fileCount: 3})
+
+	if got, _, ok := state.CachedStats(key, mtime); !ok || got.totalSize != 42 || got.fileCount != 3 {
+		t.Errorf("CachedStats = %+v, %v; want the stored stats", got, ok)
+	}
+	if _, _, ok := state.CachedStats(key, mtime.Add(time.Second)); ok {
+		t.Error("stats served after the folder's mtime changed")
+	}
+	if _, _, ok := state.CachedStats(statsCacheKey{path: "/work", gitIgnore: true}, mtime); ok {
+		t.Error("stats of a walk without git ignore served with it")
+	}
+
+	state.StoreStats(statsCacheKey{path: "/work", gitIgnore: true}, mtime, statsSnapshot{})
+	state.InvalidateStatsCache("/work")
+	for _, gitIgnore := range []bool{false, true} {
+		if _, _, ok := state.CachedStats(statsCacheKey{path: "/work", gitIgnore: gitIgnore}, mtime); ok {
+			t.Errorf("stats served after a refresh (git ignore %v)", gitIgnore)
+		}
+	}
+}
+
+func TestStatsCacheEvictsOldest(t *testing.T) {
+	state := NewAppState("/work")
+	var mtime time.Time
+	for i := range statsCacheLimit {
+		state.StoreStats(statsCacheKey{path: fmt.Sprintf("/d%d", i)}, mtime, statsSnapshot{})
+	}
+	// Back-date the first entry rather than relying on the clock to tell them apart
+	entry := state.statsCache[statsCacheKey{path: "/d0"}]
+	entry.storedAt = entry.storedAt.Add(-time.Hour)
+	state.statsCache[statsCacheKey{path: "/d0"}] = entry
+
+	state.StoreStats(statsCacheKey{path: fmt.Sprintf("/d%d", statsCacheLimit)}, mtime, statsSnapshot{})
+	if n := len(state.statsCache); n != statsCacheLimit {
+		t.Errorf("cache holds %d entries, want %d", n, statsCacheLimit)
+	}
+	if _, _, ok := state.CachedStats(statsCacheKey{path: "/d0"}, mtime); ok {
+		t.Error("the oldest entry was kept")
+	}
+	if _, _, ok := state.CachedStats(statsCacheKey{path: fmt.Sprintf("/d%d", statsCacheLimit)}, mtime); !ok {
+		t.Error("the newest entry was evicted")
+	}
+}
+
+func TestIgnoreRulesCacheEvictsOldest(t *testing.T) {
+	state := NewAppState("/work")
+	var mtime time.Time
+	for i := range ignoreRulesCacheLimit {
+		state.SetIgnoreRules(fmt.Sprintf("/d%d/.lazylsignore", i), mtime, &ignoreRules{})
+	}
+	// Back-date the first entry rather than relying on the clock to tell them apart
+	entry := state.ignoreRulesCache["/d0/.lazylsignore"]
+	entry.storedAt = entry.storedAt.Add(-time.Hour)
+	state.ignoreRulesCache["/d0/.lazylsignore"] = entry
+
+	state.SetIgnoreRules(fmt.Sprintf("/d%d/.lazylsignore", ignoreRulesCacheLimit), mtime, &ignoreRules{})
+	if n := len(state.ignoreRulesCache); n != ignoreRulesCacheLimit {
+		t.Errorf("cache holds %d entries, want %d", n, ignoreRulesCacheLimit)
+	}
+	if _, ok := state.CachedIgnoreRules("/d0/.lazylsignore", mtime); ok {
+		t.Error("the oldest entry was kept")
+	}
+	if _, ok := state.CachedIgnoreRules(fmt.Sprintf("/d%d/.lazylsignore", ignoreRulesCacheLimit), mtime); !ok {
+		t.Error("the newest entry was evicted")
+	}
+}
+
+func TestEntryCountCacheEvictsOldest(t *testing.T) {
+	state := NewAppState("/work")
+	var mtime time.Time
+	for i := range entryCountCacheLimit {
+		state.SetEntryCount(fmt.Sprintf("/d%d", i), mtime, i)
+	}
+	// Back-date the first entry rather than relying on the clock to tell them apart
+	entry := state.entryCountCache["/d0"]
+	entry.storedAt = entry.storedAt.Add(-time.Hour)
+	state.entryCountCache["/d0"] = entry
+
+	state.SetEntryCount(fmt.Sprintf("/d%d", entryCountCacheLimit), mtime, entryCountCacheLimit)
+	if n := len(state.entryCountCache); n != entryCountCacheLimit {
+		t.Errorf("cache holds %d entries, want %d", n, entryCountCacheLimit)
+	}
+	if _, ok := state.EntryCount(FileInfo{Path: "/d0", ModTime: mtime}); ok {
+		t.Error("the oldest entry was kept")
+	}
+	if count, ok := state.EntryCount(FileInfo{Path: fmt.Sprintf("/d%d", entryCountCacheLimit), ModTime: mtime}); !ok || count != entryCountCacheLimit {
+		t.Errorf("the newest entry: count %d, cached %v", count, ok)
+	}
+}
+
+func TestDirSizeCacheEvictsOldest(t *testing.T) {
+	state := NewAppState("/work")
+	var mtime time.Time
+	for i := range dirSizeCacheLimit {
+		state.SetDirSize(fmt.Sprintf("/d%d", i), mtime, int64(i))
+	}
+	// Back-date the first entry rather than relying on the clock to tell them apart
+	entry := state.dirSizeCache["/d0"]
+	entry.storedAt = entry.storedAt.Add(-time.Hour)
+	state.dirSizeCache["/d0"] = entry
+
+	state.SetDirSize(fmt.Sprintf("/d%d", dirSizeCacheLimit), mtime, dirSizeCacheLimit)
+	if n := len(state.dirSizeCache); n != dirSizeCacheLimit {
+		t.Errorf("cache holds %d entries, want %d", n, dirSizeCacheLimit)
+	}
+	if _, ok := state.CachedDirSize("/d0", mtime); ok {
+		t.Error("the oldest entry was kept")
+	}
+	if size, ok := state.CachedDirSize(fmt.Sprintf("/d%d", dirSizeCacheLimit), mtime); !ok || size != dirSizeCacheLimit {
+		t.Errorf("the newest entry: size %d, cached %v", size, ok)
+	}
+}
@@ -0,0 +1,174 @@
+// ---- File: statscmd.go ----
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+)
+
+// --- Non-interactive Stats (--stats) ---
+
+// statsFormats are the output formats of --stats.
+var statsFormats = map[string]bool{"text": true, "json": true}
+
+// statsReport is the --stats JSON output, a stable schema for scripts.
+type statsReport struct {
+	Path         string            `json:"path"`
+	TotalSize    *int64            `json:"total_size"` // Apparent size, hard links counted once; null if the walk had errors
+	DiskSize     *int64            `json:"disk_size"`  // Allocated size; null if unknown or the walk had errors
+	Files        int               `json:"files"`
+	Dirs         int               `json:"dirs"`
+	LargestFiles []statsReportFile `json:"largest_files"` // Biggest first
+	Extensions   []statsReportExt  `json:"extensions"`    // Most bytes first
+	GitStatus    string            `json:"git_status"`    // As in the Git Status pane, e.g. "Active: (main)"
+	LastCommit   *statsReportRev   `json:"last_commit"`   // null outside repositories and without commits
+	Error        *string           `json:"error"`         // The first error of the walk
+}
+
+type statsReportFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+type statsReportExt struct {
+	Ext   string `json:"ext"`
+	Count int    `json:"count"`
+	Bytes int64  `json:"bytes"`
+}
+
+type statsReportRev struct {
+	Hash    string `json:"hash"`
+	Time    string `json:"time"` // RFC 3339
+	Subject string `json:"subject"`
+}
+
+// newStatsReport converts a stats walk and git summary of dir to the report.
+func newStatsReport(dir string, stats statsSnapshot, gitStatus string, commit gitCommit) statsReport {
+	report := statsReport{
+		Path:         dir,
+		Files:        stats.fileCount,
+		Dirs:         stats.dirCount,
+		LargestFiles: []statsReportFile{},
+		Extensions:   []statsReportExt{},
+		GitStatus:    gitStatus,
+	}
+	if stats.err == nil {
+		total := stats.totalSize
+		report.TotalSize = &total
+		if stats.diskSize >= 0 {
+			diskSize := stats.diskSize
+			report.DiskSize = &diskSize
+		}
+	} else {
+		message := stats.err.Error()
+		report.Error = &message
+	}
+	for _, file := range stats.topFiles {
+		report.LargestFiles = append(report.LargestFiles, statsReportFile{Path: file.Path, Size: file.Size})
+	}
+	for _, ext := range stats.extStats {
+		report.Extensions = append(report.Extensions, statsReportExt(ext))
+	}
+	if commit.Hash != "" {
+		report.LastCommit = &statsReportRev{Hash: commit.Hash, Time: commit.Time.Format(time.RFC3339), Subject: commit.Subject}
+	}
+	return report
+}
+
+// writeStatsText prints report for people: a summary, then the largest files
+// and the extension breakdown.
+func writeStatsText(w io.Writer, report statsReport) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Path:\t%s\n", report.Path)
+	if report.TotalSize != nil {
+		fmt.Fprintf(tw, "Total size:\t%s (%d bytes)\n", formatSize(*report.TotalSize), *report.TotalSize)
+	} else {
+		fmt.Fprintf(tw, "Total size:\tunknown (%s)\n", *report.Error)
+	}
+	if report.DiskSize != nil {
+		fmt.Fprintf(tw, "On disk:\t%s (%d bytes)\n", formatSize(*report.DiskSize), *report.DiskSize)
+	}
+	fmt.Fprintf(tw, "Files:\t%d\n", report.Files)
+	fmt.Fprintf(tw, "Folders:\t%d\n", report.Dirs)
+	fmt.Fprintf(tw, "Git:\t%s\n", report.GitStatus)
+	if report.LastCommit != nil {
+		fmt.Fprintf(tw, "Last commit:\t%s %s %s\n", report.LastCommit.Hash, report.LastCommit.Time, report.LastCommit.Subject)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(report.LargestFiles) > 0 {
+		fmt.Fprintln(w, "\nLargest files:")
+		for _, file := range report.LargestFiles {
+			name := file.Path
+			if rel, err := filepath.Rel(report.Path, file.Path); err == nil {
+				name = rel
+			}
+			fmt.Fprintf(tw, "  %s\t%s\n", formatSize(file.Size), name)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(report.Extensions) > 0 {
+		fmt.Fprintln(w, "\nBy extension:")
+		for _, ext := range report.Extensions {
+			fmt.Fprintf(tw, "  %s\t%d files\t%s\n", ext.Ext, ext.Count, formatSize(ext.Bytes))
+		}
+		return tw.Flush()
+	}
+	return nil
+}
+
+// runStats implements --stats: it walks path (the CWD if empty) like the Size
+// pane, prints the result to stdout without starting the TUI and returns the
+// exit status, which is non-zero if part of the tree could not be read.
+func runStats(path, format string, stdout, stderr io.Writer) int {
+	if format == "" {
+		format = "text"
+	}
+	if !statsFormats[format] {
+		fmt.Fprintf(stderr, "lazyls: unknown --format %q (use text or json)\n", format)
+		return 2
+	}
+	if path == "" {
+		path = "."
+	}
+	dir, err := filepath.Abs(path)
+	if err == nil {
+		var info os.FileInfo
+		if info, err = os.Stat(dir); err == nil && !info.IsDir() {
+			err = fmt.Errorf("%s is not a directory", dir)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "lazyls: %v\n", err)
+		return 1
+	}
+
+	stats := walkStats(dir, statsWalkOptions{})
+	gitStatus, _, commit, _ := gitSummary(execGit{}, osFileReader{}, dir)
+	report := newStatsReport(dir, stats, gitStatus, commit)
+
+	if format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = writeStatsText(stdout, report)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "lazyls: %v\n", err)
+		return 1
+	}
+	if stats.err != nil {
+		fmt.Fprintf(stderr, "lazyls: %v\n", stats.err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunStats(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"main.go":     strings.Repeat("m", 2048),
+		"lib/util.go": strings.Repeat("u", 10),
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if code := runStats(root, "json", &stdout, &stderr); code != 0 {
+			t.Fatalf("exit status %d: %s", code, stderr.String())
+		}
+		var report statsReport
+		if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+			t.Fatalf("%v in %s", err, stdout.String())
+		}
+		if report.TotalSize == nil || *report.TotalSize != 2058 || report.Files != 2 || report.Dirs != 1 {
+			t.Errorf("got %+v, want 2058 bytes in 2 files and 1 folder", report)
+		}
+		if len(report.LargestFiles) != 2 || report.LargestFiles[0].Path != filepath.Join(root, "main.go") {
+			t.Errorf("largest files %+v, want main.go first", report.LargestFiles)
+		}
+		if report.Error != nil {
+			t.Errorf("unexpected error %q", *report.Error)
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if code := runStats(root, "", &stdout, &stderr); code != 0 {
+			t.Fatalf("exit status %d: %s", code, stderr.String())
+		}
+		for _, want := range []string{"Total size:  2.01 KiB (2058 bytes)", "Files:       2", "Largest files:", "main.go", filepath.Join("lib", "util.go")} {
+			if !strings.Contains(stdout.String(), want) {
+				t.Errorf("output lacks %q:\n%s", want, stdout.String())
+			}
+		}
+	})
+
+	failures := []struct {
+		name, path, format string
+		want               int
+	}{
+		{"unknown format", root, "xml", 2},
+		{"missing folder", filepath.Join(root, "gone"), "text", 1},
+		{"file", filepath.Join(root, "main.go"), "text", 1},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runStats(tt.path, tt.format, &stdout, &stderr); code != tt.want {
+				t.Errorf("exit status %d, want %d", code, tt.want)
+			}
+			if stdout.Len() > 0 || stderr.Len() == 0 {
+				t.Errorf("stdout %q, stderr %q: want only an error", stdout.String(), stderr.String())
+			}
+		})
+	}
+}
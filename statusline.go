@@ -0,0 +1,85 @@
+// ---- File: statusline.go ----
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Compact Status Line ---
+// With the stats column hidden ('z'), the lists take the full width and a
+// single line above them keeps the essentials: the CWD, the total size and
+// the git branch. The Largest File and Git Status panes' keys are out of
+// reach until the column is back.
+
+// handleToggleStats collapses the stats column into the status line, or
+// brings it back, and saves the choice with the preferences.
+func handleToggleStats(g *gocui.Gui, state *AppState) error {
+	if state.ToggleStatsHidden() {
+		state.SetMessage("Stats column hidden")
+	} else {
+		state.SetMessage("Stats column shown")
+	}
+	persistPreferences(state)
+	g.Update(func(gui *gocui.Gui) error { return nil })
+	return nil
+}
+
+// updateStatusLine renders the status line: the CWD, then the total size and
+// the git branch, the CWD shortened to leave them room.
+func updateStatusLine(g *gocui.Gui, snap renderSnapshot) {
+	v, err := g.View(viewStatusLine)
+	if err != nil {
+		return
+	}
+	v.Clear()
+	width, _ := v.Size()
+
+	var parts, plain []string // Colored and plain, for measuring
+	add := func(text, color string) {
+		parts = append(parts, color+toCells(text)+ansiReset)
+		plain = append(plain, text)
+	}
+	add(statusLineSize(snap), ansiCyan)
+	if branch := statusLineBranch(snap); branch != "" {
+		add(glyph("\ue702")+" "+branch, ansiGreen)
+	}
+
+	separator := " " + glyph("│") + " "
+	rest := displayWidth(separator + strings.Join(plain, separator))
+	home, _ := os.UserHomeDir()
+	cwd := shortenPath(snap.cwd, home, max(width-rest-1, 10))
+	line := " " + ansiGreen + toCells(cwd) + ansiReset
+	for _, part := range parts {
+		line += ansiDim + separator + ansiReset + part
+	}
+	fmt.Fprint(v, line)
+}
+
+// statusLineSize is the Size pane's total in a few words.
+func statusLineSize(snap renderSnapshot) string {
+	if snap.loadingStats {
+		return "Calculating..."
+	}
+	if snap.totalSize < 0 {
+		return "size unknown"
+	}
+	return fmt.Sprintf("%s in %s files", formatSize(snap.totalSize), formatCount(snap.fileCount))
+}
+
+// statusLineBranch is the branch (or detached commit) the Git Status pane
+// shows, plus any rebase or merge in progress; "" outside a repository.
+func statusLineBranch(snap renderSnapshot) string {
+	label, ok := strings.CutPrefix(snap.gitStatus, "Active: ")
+	if !ok || snap.loadingStats {
+		return ""
+	}
+	// "(main) (rebasing)" reads as "main (rebasing)"
+	if branch, suffix, found := strings.Cut(strings.TrimPrefix(label, "("), ")"); found && strings.HasPrefix(label, "(") {
+		return branch + suffix
+	}
+	return label
+}
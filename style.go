@@ -0,0 +1,100 @@
+// ---- File: style.go ----
+package main
+
+import (
+	"os"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Plain (Colorless) Output ---
+
+// plainMode strips colors from the UI, keeping bold, dim, underline and
+// reverse video. It is set by --plain or a non-empty $NO_COLOR (no-color.org).
+var plainMode bool
+
+// configureStyle turns on plain mode when requested and blanks the ANSI color
+// sequences, so everything written with them comes out uncolored. It must run
+// before the views are created.
+func configureStyle(plain bool) {
+	plainMode = plain || os.Getenv("NO_COLOR") != ""
+	if !plainMode {
+		return
+	}
+	for _, code := range []*string{&ansiRed, &ansiGreen, &ansiYellow, &ansiBlue, &ansiMagenta, &ansiCyan, &ansiWhite, &ansiBgGreen, &ansiFgBlack, &ansiGray} {
+		*code = ""
+	}
+	activeColors = nil // No file-type coloring either
+}
+
+// attrColorMask covers the color part of a gocui attribute; the style bits
+// (bold, underline, reverse) lie above it.
+const attrColorMask = 0x1FF
+
+// styleAttr returns the view attribute c, or only its style bits in plain mode.
+func styleAttr(c gocui.Attribute) gocui.Attribute {
+	if plainMode {
+		return c &^ attrColorMask
+	}
+	return c
+}
+
+// styleSel returns the selected-line attribute c. Plain mode can't tell the
+// selection apart by color, so it falls back to reverse video.
+func styleSel(c gocui.Attribute) gocui.Attribute {
+	if plainMode {
+		return styleAttr(c) | gocui.AttrReverse
+	}
+	return c
+}
+
+// --- ASCII-Only Output ---
+
+// asciiMode draws the UI with ASCII characters only, for terminals and
+// locales that garble box drawing and Nerd Font icons. It is set by --ascii
+// or "ascii": true in config.json.
+var asciiMode bool
+
+// asciiGlyphs are the stand-ins glyph uses in ASCII mode.
+var asciiGlyphs = map[string]string{
+	"…":      "...",
+	"—":      "-",
+	"─":      "-",
+	"│":      "|",
+	"┼":      "+",
+	"█":      "#",
+	"•":      "*",
+	"␍":      "^M",
+	"↑":      "Up",
+	"↓":      "Down",
+	"←":      "Left",
+	"→":      "Right",
+	"›":      ">",
+	"\ue702": "[G]", // Nerd Font git logo
+}
+
+// configureCharset turns on ASCII mode when requested. It must run before the
+// listing is loaded, as icons are resolved while listing.
+func configureCharset(cfg config, ascii bool) {
+	asciiMode = ascii || cfg.ASCII
+	if !asciiMode {
+		return
+	}
+	ellipsis = glyph(ellipsis)
+	activeIcons = asciiIcons
+	for key, name := range keyNames {
+		keyNames[key] = glyph(name)
+	}
+}
+
+// glyph returns the non-ASCII symbol s, or its ASCII stand-in in ASCII mode.
+// Everything lazyls draws itself goes through it; file names and file
+// contents are shown as they are.
+func glyph(s string) string {
+	if asciiMode {
+		if a, ok := asciiGlyphs[s]; ok {
+			return a
+		}
+	}
+	return s
+}
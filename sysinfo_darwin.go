@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// platformStat extracts the Unix-specific metadata (blocks, links, owner, times) from info.
+func platformStat(info os.FileInfo) (sysStat, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return sysStat{}, false
+	}
+	return sysStat{
+		Blocks: st.Blocks,
+		Nlink:  uint64(st.Nlink),
+		Dev:    uint64(st.Dev),
+		Ino:    uint64(st.Ino),
+		Uid:    st.Uid,
+		Gid:    st.Gid,
+		Atime:  time.Unix(st.Atimespec.Unix()),
+		Ctime:  time.Unix(st.Ctimespec.Unix()),
+	}, true
+}
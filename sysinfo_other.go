@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package main
+
+import "os"
+
+// platformStat is not supported on this platform; callers fall back to os.FileInfo.
+func platformStat(info os.FileInfo) (sysStat, bool) {
+	return sysStat{}, false
+}
@@ -0,0 +1,215 @@
+// ---- File: tabs.go ----
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Session Tabs ---
+// Each tab is a workspace of its own: an AppState with its listing, cursors,
+// stats and background jobs. The gui only ever runs on the active tab's
+// state; switching tabs reinstalls the layout and the keybindings bound to
+// the other one, so every key works on the active tab.
+
+// maxTabLabelWidth is the widest a folder name is shown in the tab bar.
+const maxTabLabelWidth = 24
+
+// sessionTab is one open tab and the view that had focus when it was left.
+type sessionTab struct {
+	state *AppState
+	focus string
+	other *AppState     // The inactive browser in commander mode, else nil
+	side  commanderSide // Where the active browser (state) is in commander mode
+}
+
+// tabSet holds the open tabs in tab bar order.
+type tabSet struct {
+	sync.Mutex
+	tabs         []sessionTab
+	active       int
+	pendingFocus string // Focus to restore once the switched-to tab's views exist
+}
+
+// tabs are the open tabs; main opens the first one.
+var tabs tabSet
+
+// Count returns the number of open tabs.
+func (t *tabSet) Count() int {
+	t.Lock()
+	defer t.Unlock()
+	return len(t.tabs)
+}
+
+// activeIndex returns the index of the active tab.
+func (t *tabSet) activeIndex() int {
+	t.Lock()
+	defer t.Unlock()
+	return t.active
+}
+
+// IsActive reports whether state is the one the gui runs on: the active
+// tab's (or its active commander browser's). With no tabs open yet, any state is.
+func (t *tabSet) IsActive(state *AppState) bool {
+	t.Lock()
+	defer t.Unlock()
+	return len(t.tabs) == 0 || t.tabs[t.active].state == state
+}
+
+// Labels returns the base names of the tabs' folders and the active tab's index.
+func (t *tabSet) Labels() ([]string, int) {
+	t.Lock()
+	defer t.Unlock()
+	labels := make([]string, len(t.tabs))
+	for i, tab := range t.tabs {
+		labels[i] = filepath.Base(tab.state.Cwd())
+	}
+	return labels, t.active
+}
+
+// add opens a tab on state after the active one and makes it active. focus is
+// the view the tab being left had focus in.
+func (t *tabSet) add(state *AppState, focus string) {
+	t.Lock()
+	defer t.Unlock()
+	if len(t.tabs) == 0 {
+		t.tabs = []sessionTab{{state: state}}
+		return
+	}
+	t.tabs[t.active].focus = focus
+	t.active++
+	t.tabs = append(t.tabs[:t.active], append([]sessionTab{{state: state}}, t.tabs[t.active:]...)...)
+	t.pendingFocus = ""
+}
+
+// cycle makes the tab delta places from the active one active, wrapping
+// around, and returns its state. focus is the view the tab being left had focus in.
+func (t *tabSet) cycle(delta int, focus string) *AppState {
+	t.Lock()
+	defer t.Unlock()
+	t.tabs[t.active].focus = focus
+	t.active = ((t.active+delta)%len(t.tabs) + len(t.tabs)) % len(t.tabs)
+	t.pendingFocus = t.tabs[t.active].focus
+	return t.tabs[t.active].state
+}
+
+// closeActive removes the active tab and returns the state of the tab that
+// takes its place, the one before it if it was the last. It returns nil if no
+// tab is left.
+func (t *tabSet) closeActive() *AppState {
+	t.Lock()
+	defer t.Unlock()
+	if other := t.tabs[t.active].other; other != nil {
+		other.StopBackgroundJobs()
+	}
+	t.tabs = append(t.tabs[:t.active], t.tabs[t.active+1:]...)
+	if len(t.tabs) == 0 {
+		return nil
+	}
+	t.active = min(t.active, len(t.tabs)-1)
+	t.pendingFocus = t.tabs[t.active].focus
+	return t.tabs[t.active].state
+}
+
+// takePendingFocus returns the view to focus after a tab switch, once.
+func (t *tabSet) takePendingFocus() string {
+	t.Lock()
+	defer t.Unlock()
+	focus := t.pendingFocus
+	t.pendingFocus = ""
+	return focus
+}
+
+// activateTab drives the gui with state: its layout and its keybindings.
+// gocui drops all views and bindings on a manager change, so the tab's views
+// are created afresh on the next layout pass.
+func activateTab(g *gocui.Gui, state *AppState) error {
+	g.SetManagerFunc(func(gui *gocui.Gui) error {
+		return layout(gui, state)
+	})
+	return setupKeybindings(g, state)
+}
+
+// newTabState returns the state of a new tab rooted at dir, with the display
+// preferences and limits of the tab it is opened from.
+func newTabState(dir string, from *AppState) *AppState {
+	state := NewAppState(dir)
+	state.SetSizeLimits(from.ViewLimit(), from.CopyLimit())
+	state.ApplyPreferences(from.Preferences())
+	return state
+}
+
+// handleNewTab opens a tab rooted at the selected folder, or at the CWD when
+// the selection isn't a folder.
+func handleNewTab(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	dir := state.Cwd()
+	if item, ok := state.SelectedItem(v.Name()); ok && item.IsDir {
+		dir = item.Path
+	}
+	tab := newTabState(dir, state)
+	tabs.add(tab, v.Name())
+	if err := activateTab(g, tab); err != nil {
+		return err
+	}
+	reloadDirectory(g, tab, nil)
+	tab.SetMessage(fmt.Sprintf("Opened tab %d of %d: %s", tabs.activeIndex()+1, tabs.Count(), dir))
+	return nil
+}
+
+// handleCycleTab switches to the tab delta places from the active one.
+func handleCycleTab(g *gocui.Gui, v *gocui.View, state *AppState, delta int) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	if tabs.Count() < 2 {
+		state.SetMessage("Only one tab is open (t opens another)")
+		g.Update(func(gui *gocui.Gui) error { return nil })
+		return nil
+	}
+	return activateTab(g, tabs.cycle(delta, v.Name()))
+}
+
+// handleCloseTab closes the active tab, stopping its background jobs. Closing
+// the last tab quits.
+func handleCloseTab(g *gocui.Gui, v *gocui.View, state *AppState) error {
+	if v == nil || state.IsOverlayVisible() {
+		return nil
+	}
+	next := tabs.closeActive()
+	if next == nil {
+		return gocui.ErrQuit
+	}
+	state.StopBackgroundJobs()
+	next.SetMessage(fmt.Sprintf("Closed tab %s, %d left", filepath.Base(state.Cwd()), tabs.Count()))
+	return activateTab(g, next)
+}
+
+// updateTabBar renders the tab bar: the tabs' folder names, the active one
+// highlighted.
+func updateTabBar(g *gocui.Gui) {
+	v, err := g.View(viewTabs)
+	if err != nil {
+		return
+	}
+	v.Clear()
+	labels, active := tabs.Labels()
+	var b strings.Builder
+	for i, label := range labels {
+		text := fmt.Sprintf(" %d %s ", i+1, truncateWidth(label, maxTabLabelWidth))
+		if i == active {
+			text = ansiReverse + ansiBold + text + ansiReset
+		}
+		if i > 0 {
+			b.WriteString(ansiDim + glyph("│") + ansiReset)
+		}
+		b.WriteString(text)
+	}
+	fmt.Fprint(v, b.String())
+}
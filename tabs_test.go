@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+)
+
+// openTestTabs makes states the open tabs, the first one active, until the
+// test ends.
+func openTestTabs(t *testing.T, states ...*AppState) {
+	t.Helper()
+	tabs.Lock()
+	for _, s := range states {
+		tabs.tabs = append(tabs.tabs, sessionTab{state: s})
+	}
+	tabs.active = 0
+	tabs.Unlock()
+	t.Cleanup(func() {
+		tabs.Lock()
+		tabs.tabs, tabs.active = nil, 0
+		tabs.Unlock()
+	})
+}
+
+func TestPersistPreferencesFromActiveTabOnly(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	active, closed := NewAppState(t.TempDir()), NewAppState(t.TempDir())
+	openTestTabs(t, active, closed)
+
+	if !tabs.IsActive(active) || tabs.IsActive(closed) {
+		t.Fatalf("IsActive: got active=%v, other=%v", tabs.IsActive(active), tabs.IsActive(closed))
+	}
+
+	active.ToggleGridMode()
+	persistPreferences(active)
+	persistPreferences(closed) // Holds the old grid setting
+	prefs, err := loadPreferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !prefs.GridMode {
+		t.Error("an inactive tab overwrote the active tab's preferences")
+	}
+}
+
+func TestStopBackgroundJobsCancelsDirLoad(t *testing.T) {
+	state := NewAppState(t.TempDir())
+	gen := state.BeginDirLoad()
+	state.StopBackgroundJobs()
+	if state.FinishDirLoad(gen) {
+		t.Error("a closed tab's directory load still finished")
+	}
+}
@@ -0,0 +1,151 @@
+// ---- File: tasks.go ----
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+// --- Background Tasks ---
+
+// maxFinishedTasks is how many finished tasks the task list keeps.
+const maxFinishedTasks = 20
+
+// taskRedrawInterval limits how often progress reports redraw the screen.
+const taskRedrawInterval = 200 * time.Millisecond
+
+// taskStatus is where a task is in its life.
+type taskStatus int
+
+const (
+	taskRunning taskStatus = iota
+	taskDone
+	taskFailed
+	taskCancelled
+)
+
+// task is a long-running operation, such as a copy, run in the background.
+type task struct {
+	ID       int
+	Name     string // e.g. "Duplicate 'src'"
+	Status   taskStatus
+	Done     int64 // Bytes processed so far
+	Total    int64 // Bytes to process, 0 while unknown
+	Started  time.Time
+	Finished time.Time
+	Result   string // What a finished task reports, or why it failed
+
+	cancel context.CancelFunc
+}
+
+// Percent returns how much of the task is done, or -1 while the total is unknown.
+func (t task) Percent() int {
+	if t.Total <= 0 {
+		return -1
+	}
+	return int(min(t.Done*100/t.Total, 100))
+}
+
+// Elapsed returns how long the task ran, or has been running.
+func (t task) Elapsed() time.Duration {
+	if t.Finished.IsZero() {
+		return time.Since(t.Started)
+	}
+	return t.Finished.Sub(t.Started)
+}
+
+// Throughput returns the bytes processed per second.
+func (t task) Throughput() int64 {
+	seconds := t.Elapsed().Seconds()
+	if seconds <= 0 {
+		return 0
+	}
+	return int64(float64(t.Done) / seconds)
+}
+
+// Message is what the message bar says when the task finishes.
+func (t task) Message() string {
+	switch t.Status {
+	case taskFailed:
+		return fmt.Sprintf("Error: %s - %s", t.Name, t.Result)
+	case taskCancelled:
+		return fmt.Sprintf("Cancelled: %s", t.Name)
+	}
+	return t.Result
+}
+
+// taskFunc does the work of a task. It reports progress in bytes (total 0
+// while unknown), stops with ctx's error once ctx is cancelled, and returns
+// the message to show when it is done.
+type taskFunc func(ctx context.Context, report func(done, total int64)) (string, error)
+
+// startTask runs fn in the background as a task the task list shows. When it
+// finishes, its result goes to the message bar, then then (if set) runs on
+// the UI goroutine with fn's error, e.g. to reload the listing.
+func startTask(g *gocui.Gui, state *AppState, name string, fn taskFunc, then func(gui *gocui.Gui, err error)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	id := state.AddTask(name, cancel)
+	go func() {
+		defer cancel()
+		var lastRedraw time.Time
+		report := func(done, total int64) {
+			state.SetTaskProgress(id, done, total)
+			if time.Since(lastRedraw) >= taskRedrawInterval {
+				lastRedraw = time.Now()
+				g.Update(func(gui *gocui.Gui) error { return nil })
+			}
+		}
+		result, err := fn(ctx, report)
+		if err != nil && ctx.Err() != nil {
+			err = ctx.Err() // The task stopped because it was cancelled
+		}
+		finished := state.FinishTask(id, result, err)
+		g.Update(func(gui *gocui.Gui) error {
+			state.SetMessage(finished.Message())
+			if then != nil {
+				then(gui, err)
+			}
+			return nil
+		})
+	}()
+	g.Update(func(gui *gocui.Gui) error {
+		return nil // Show the running task in the message bar
+	})
+}
+
+// progressWriter passes writes on to w, reporting every byte written and
+// failing once ctx is cancelled, so a copy into it can be stopped.
+type progressWriter struct {
+	ctx     context.Context
+	w       io.Writer
+	written func(n int64)
+}
+
+func (pw progressWriter) Write(p []byte) (int, error) {
+	if err := pw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := pw.w.Write(p)
+	pw.written(int64(n))
+	return n, err
+}
+
+// progressReader is progressWriter's counterpart for reads.
+type progressReader struct {
+	ctx  context.Context
+	r    io.Reader
+	read func(n int64)
+}
+
+func (pr progressReader) Read(p []byte) (int, error) {
+	if err := pr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := pr.r.Read(p)
+	pr.read(int64(n))
+	return n, err
+}
@@ -0,0 +1,86 @@
+// ---- File: textwidth.go ----
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// ellipsis marks text that was shortened to fit ("..." in ASCII mode).
+var ellipsis = "…"
+
+// displayWidth returns the number of terminal cells s occupies; CJK characters
+// and most emoji take two cells, combining marks none.
+func displayWidth(s string) int {
+	return runewidth.StringWidth(s)
+}
+
+// truncateWidth shortens s to at most width cells, ending it with "…" when
+// cut. Narrower than the ellipsis itself (ASCII mode's "..."), only as much
+// of the ellipsis as fits is left.
+func truncateWidth(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if displayWidth(s) <= width {
+		return s
+	}
+	if width < displayWidth(ellipsis) {
+		return runewidth.Truncate(ellipsis, width, "")
+	}
+	return runewidth.Truncate(s, width, ellipsis)
+}
+
+// toCells prepares text for a gocui view. gocui advances one cell per rune,
+// while termbox draws a wide rune across two cells and skips the cell after
+// it; a filler after each wide rune keeps the rest of the line aligned.
+// Ambiguous-width runes are drawn in one cell by termbox, so they get none.
+func toCells(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		b.WriteRune(r)
+		if runewidth.RuneWidth(r) == 2 && !runewidth.IsAmbiguousWidth(r) {
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+// middleEllipsis shortens name to at most width cells by replacing its middle
+// with "…", keeping the start and the end (at least the extension) visible,
+// e.g. "very-long-compon…final.test.tsx".
+func middleEllipsis(name string, width int) string {
+	if displayWidth(name) <= width {
+		return name
+	}
+	avail := width - displayWidth(ellipsis)
+	ext := filepath.Ext(name)
+	if avail < 2 || displayWidth(ext) >= avail {
+		return truncateWidth(name, width) // No room for both ends
+	}
+
+	tailWidth := avail / 2
+	if w := displayWidth(ext); w > tailWidth {
+		tailWidth = w
+	}
+	head := runewidth.Truncate(name, avail-tailWidth, "")
+	return head + ellipsis + lastCells(name, tailWidth)
+}
+
+// lastCells returns the longest suffix of s that fits in width cells.
+func lastCells(s string, width int) string {
+	runes := []rune(s)
+	start := len(runes)
+	used := 0
+	for start > 0 {
+		w := runewidth.RuneWidth(runes[start-1])
+		if used+w > width {
+			break
+		}
+		used += w
+		start--
+	}
+	return string(runes[start:])
+}
@@ -0,0 +1,113 @@
+package main
+
+import "testing"
+
+func TestTruncateWidth(t *testing.T) {
+	tests := []struct {
+		name     string
+		ellipsis string
+		s        string
+		width    int
+		want     string
+	}{
+		{"fits", "…", "abc", 3, "abc"},
+		{"cut", "…", "abcdef", 4, "abc…"},
+		{"zero width", "…", "abc", 0, ""},
+		{"negative width", "…", "abc", -2, ""},
+		{"wide runes", "…", "漢字漢字", 5, "漢字…"},
+		{"ascii cut", "...", "abcdef", 5, "ab..."},
+		{"ascii narrower than the ellipsis", "...", "abcdef", 2, ".."},
+		{"ascii one cell", "...", "abcdef", 1, "."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(saved string) { ellipsis = saved }(ellipsis)
+			ellipsis = tt.ellipsis
+			got := truncateWidth(tt.s, tt.width)
+			if got != tt.want {
+				t.Errorf("truncateWidth(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+			}
+			if w := displayWidth(got); w > max(tt.width, 0) {
+				t.Errorf("truncateWidth(%q, %d) is %d cells wide", tt.s, tt.width, w)
+			}
+		})
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"main.go", 7},
+		{"漢字.txt", 8},
+		{"😀.png", 6},
+		{"cafe\u0301", 4}, // Combining acute accent
+		{"한글 파일", 9},
+	}
+	for _, tt := range tests {
+		if got := displayWidth(tt.s); got != tt.want {
+			t.Errorf("displayWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestToCells(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"main.go", "main.go"},
+		{"漢字.txt", "漢 字 .txt"},
+		{"a😀b", "a😀 b"},
+		{"cafe\u0301", "cafe\u0301"},
+	}
+	for _, tt := range tests {
+		if got := toCells(tt.s); got != tt.want {
+			t.Errorf("toCells(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestMiddleEllipsis(t *testing.T) {
+	tests := []struct {
+		name  string
+		width int
+		want  string
+	}{
+		{"short.go", 20, "short.go"},
+		{"very-long-component-name.test.tsx", 20, "very-long-….test.tsx"},
+		{"a-very-long-file-name.go", 10, "a-ver…e.go"},
+		{"漢字漢字漢字漢字.txt", 12, "漢字漢….txt"},
+		{"name.extension-too-long", 8, "name.ex…"}, // No room for the extension
+		{"abcdef", 2, "a…"},
+	}
+	for _, tt := range tests {
+		got := middleEllipsis(tt.name, tt.width)
+		if got != tt.want {
+			t.Errorf("middleEllipsis(%q, %d) = %q, want %q", tt.name, tt.width, got, tt.want)
+		}
+		if w := displayWidth(got); w > tt.width {
+			t.Errorf("middleEllipsis(%q, %d) is %d cells wide", tt.name, tt.width, w)
+		}
+	}
+}
+
+func TestLastCells(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"abcdef", 3, "def"},
+		{"abc", 5, "abc"},
+		{"abc", 0, ""},
+		{"a漢字", 3, "字"}, // A wide rune that would straddle the edge is left out
+		{"a漢字", 5, "a漢字"},
+	}
+	for _, tt := range tests {
+		if got := lastCells(tt.s, tt.width); got != tt.want {
+			t.Errorf("lastCells(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
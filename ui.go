@@ -5,7 +5,11 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/jroimartin/gocui"
 )
@@ -17,9 +21,34 @@ const (
 	viewGit         = "git"         // For Git Status  // Renamed for clarity
 	viewFolders     = "folders"     // New view for folders
 	viewFiles       = "files"       // New view for files
+	viewCombined    = "combined"    // Single list replacing folders+files in combined mode
+	viewTooSmall    = "tooSmall"    // Full-screen notice when the terminal is too small
+	viewMessages    = "messages"    // Message history overlay
+	viewPreview     = "preview"     // Preview of the selected item below the lists
 	viewActionMenu  = "actionMenu"  // New view for the action menu
 	viewMessage     = "message"     // View for temporary messages
 	viewFileContent = "fileContent" // New view for file content
+	viewPrompt      = "prompt"      // Single-line input overlay
+	viewProperties  = "properties"  // File properties popup
+	viewTopFiles    = "topFiles"    // Largest files overlay
+	viewHistory     = "history"     // File history overlay
+	viewMounts      = "mounts"      // Drive / mount point picker
+	viewTasks       = "tasks"       // Background task list
+	viewCleanup     = "cleanup"     // Empty folders and zero-byte files found by the stats walk
+	viewBrokenLinks = "brokenLinks" // Dangling symlinks found by the stats walk
+	viewDirCompare  = "dirCompare"  // Differences between the CWD and another folder
+	viewExtStats    = "extStats"    // Extension breakdown overlay
+	viewStatsErrors = "statsErrors" // Paths the stats walk could not read
+	viewHelp        = "help"        // Keybinding help overlay
+	viewHints       = "hints"       // Key hints for the focused view, above the message bar
+	viewMarkKey     = "markKey"     // Covers the message bar while m or ` waits for its letter
+	viewPalette     = "palette"     // Command palette query line
+	viewPaletteList = "paletteList" // Commands matching the palette query
+	viewConfirm     = "confirm"     // Confirmation dialog
+	viewConfirmText = "confirmText" // Input line of a typed confirmation dialog
+	viewTabs        = "tabs"        // Tab bar above the panes while several tabs are open
+	viewCommander   = "commander"   // The inactive browser beside the combined list in commander mode
+	viewStatusLine  = "statusLine"  // One-line stats summary above the lists while the stats column is hidden
 )
 
 // ANSI Escape Codes for Styling
@@ -29,50 +58,212 @@ const (
 	ansiDim       = "\x1b[2m" // Added Dim
 	ansiUnderline = "\x1b[4m" // Added Underline
 	ansiReverse   = "\x1b[7m" // Added Reverse
-	ansiRed       = "\x1b[31m"
-	ansiGreen     = "\x1b[32m"
-	ansiYellow    = "\x1b[33m"
-	ansiBlue      = "\x1b[34m"
-	ansiMagenta   = "\x1b[35m"
-	ansiCyan      = "\x1b[36m"
-	ansiWhite     = "\x1b[37m"
-	ansiBgGreen   = "\x1b[42m" // Added Background Green
-	ansiFgBlack   = "\x1b[30m" // Added Black Foreground
+)
+
+// ANSI colors; variables so plain mode can blank them (see configureStyle)
+var (
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+	ansiWhite   = "\x1b[37m"
+	ansiBgGreen = "\x1b[42m" // Added Background Green
+	ansiFgBlack = "\x1b[30m" // Added Black Foreground
+	ansiGray    = "\x1b[38;5;244m"
+)
+
+// Smallest terminal the normal layout is drawn in; below this a notice is shown instead.
+const (
+	minTerminalWidth  = 40
+	minTerminalHeight = 10
+)
+
+// minHintBarHeight is the terminal height below which the key-hint bar is
+// dropped to leave its row to the panes.
+const minHintBarHeight = 13
+
+// layoutTooSmall replaces every view with a full-screen "terminal too small"
+// notice. The normal layout is rebuilt from state once the terminal grows back.
+func layoutTooSmall(g *gocui.Gui, state *AppState, geo layoutGeometry, maxX, maxY int) error {
+	prevFocus := ""
+	if cv := g.CurrentView(); cv != nil && cv.Name() != viewTooSmall {
+		prevFocus = cv.Name()
+	}
+	state.SetTooSmall(true, prevFocus)
+
+	for _, v := range g.Views() {
+		if v.Name() != viewTooSmall {
+			_ = g.DeleteView(v.Name())
+		}
+	}
+	g.Cursor = false
+
+	v, err := geo.setView(g, viewTooSmall)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return fmt.Errorf("creating too-small view: %w", err)
+		}
+		v.Frame = false
+		v.Wrap = false
+	}
+	v.Clear()
+
+	need := fmt.Sprintf("(need at least %dx%d)", minTerminalWidth, minTerminalHeight)
+	lines := []string{"Terminal too small " + need}
+	if len(lines[0]) > maxX {
+		lines = []string{"Terminal too small", need} // Split so it fits narrow terminals
+	}
+	lines = append(lines, fmt.Sprintf("Current: %dx%d", maxX, maxY))
+	top := (maxY - len(lines)) / 2
+	for i := 0; i < top; i++ {
+		fmt.Fprintln(v)
+	}
+	for _, line := range lines {
+		pad := (maxX - len(line)) / 2
+		if pad < 0 {
+			pad = 0
+		}
+		fmt.Fprintf(v, "%s%s%s%s\n", strings.Repeat(" ", pad), ansiYellow, line, ansiReset)
+	}
+
+	if _, err := g.SetCurrentView(viewTooSmall); err != nil {
+		log.Printf("Error setting focus to too-small view: %v", err)
+	}
+	return nil
+}
+
+// --- Overlays ---
+
+// overlayViews are the popups drawn over the panes, in the order they are
+// raised: one opened from another (a confirmation from the action menu) comes later.
+var overlayViews = []string{
+	viewActionMenu, viewPrompt, viewPalette, viewPaletteList, viewConfirm, viewConfirmText,
+	viewProperties, viewTopFiles, viewHistory, viewMounts, viewTasks, viewCleanup,
+	viewBrokenLinks, viewDirCompare, viewExtStats, viewMessages, viewStatsErrors, viewHelp,
+}
+
+// raiseOverlays puts the open overlays, each with its scrollbar, back on top.
+// gocui draws views in creation order, so a pane created while an overlay is
+// open (e.g. when a resize brings the panes back from the "too small" screen)
+// would otherwise be drawn over it.
+func raiseOverlays(g *gocui.Gui) {
+	for _, name := range overlayViews {
+		if _, err := g.View(name); err != nil {
+			continue
+		}
+		_, _ = g.SetViewOnTop(name)
+		if _, err := g.View(name + scrollbarSuffix); err == nil {
+			_, _ = g.SetViewOnTop(name + scrollbarSuffix)
+		}
+	}
+}
+
+// focusOverlay gives the named overlay the focus unless it has it already.
+// Overlays call it on every layout pass, so focus can't stay behind on a pane
+// recreated under them.
+func focusOverlay(g *gocui.Gui, name string) {
+	if cv := g.CurrentView(); cv != nil && cv.Name() == name {
+		return
+	}
+	if _, err := g.SetCurrentView(name); err != nil {
+		log.Printf("Error setting focus to %s view: %v", name, err)
+	}
+}
+
+// Minimum widths of the left stats column and the right lists, whatever the panel ratio.
+const (
+	minLeftPanelWidth  = 20
+	minRightPanelWidth = 20
 )
 
 // layout defines the TUI layout.
 func layout(g *gocui.Gui, state *AppState) error {
 	maxX, maxY := g.Size()
-	if maxX < 10 || maxY < 5 {
-		return fmt.Errorf("terminal too small")
+	_, commander := tabs.Commander()
+	geo := computeLayout(maxX, maxY, tabs.Count() > 1, commander, state)
+	if geo.tooSmall {
+		return layoutTooSmall(g, state, geo, maxX, maxY)
 	}
-
-	isActionMenuVisible := state.IsActionMenuVisible()
-	isFileContentViewVisible := state.IsFileContentViewVisible()
+	wasTooSmall := state.IsTooSmall()
+	if wasTooSmall {
+		_ = g.DeleteView(viewTooSmall)
+		state.SetTooSmall(false, "")
+	}
+	// The panes draw from one snapshot, so the frame shows a single moment
+	snap := state.Snapshot(listFrames(state, geo))
 
 	// --- Message View (Bottom Bar) ---
 	// Create this first so other views stop above it
-	bottomLineY := maxY - 1
-	if v, err := g.SetView(viewMessage, 0, bottomLineY, maxX-1, bottomLineY+1); err != nil { // Height is 1 line
+	if v, err := geo.setView(g, viewMessage); err != nil { // Height is 1 line
 		if err != gocui.ErrUnknownView {
 			return fmt.Errorf("creating message view: %w", err)
 		}
 		v.Frame = false
 		v.Wrap = false
 		v.Autoscroll = false
-		v.FgColor = gocui.ColorYellow
+		v.FgColor = styleAttr(gocui.ColorYellow)
 	}
 	updateMessageView(g, state)
 
-	// Adjust main area height to accommodate message bar
-	mainAreaMaxY := bottomLineY
+	// --- Tab Bar (top row, while several tabs are open) ---
+	if geo.shows(viewTabs) {
+		if v, err := geo.setView(g, viewTabs); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating tab bar view: %w", err)
+			}
+			v.Frame = false
+			v.Wrap = false
+		}
+		updateTabBar(g)
+	} else {
+		_ = g.DeleteView(viewTabs)
+	}
+
+	// --- Key Hint Bar (above the message bar, unless hidden in the config) ---
+	if geo.shows(viewHints) {
+		if v, err := geo.setView(g, viewHints); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating hint view: %w", err)
+			}
+			v.Frame = false
+			v.Wrap = false
+		}
+	} else {
+		_ = g.DeleteView(viewHints)
+	}
+
+	// --- Mark Letter (covers the message bar until the letter is typed) ---
+	if kind := state.MarkKeyPending(); kind != 0 {
+		v, err := geo.setView(g, viewMarkKey)
+		if err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating mark view: %w", err)
+			}
+			v.Frame = false
+			v.Wrap = false
+			v.FgColor = styleAttr(gocui.ColorCyan)
+			v.Editable = true // Unbound keys reach markKeyEditor
+			v.Editor = markKeyEditor(g, state)
+		}
+		v.Clear()
+		if kind == 'm' {
+			fmt.Fprint(v, " Mark: press a letter (a-z) to mark the selected item, Esc to cancel")
+		} else if letters := state.MarkLetters(); len(letters) == 0 {
+			fmt.Fprint(v, " Jump to mark: no marks set yet, Esc to cancel")
+		} else {
+			fmt.Fprintf(v, " Jump to mark: %s, Esc to cancel", strings.Join(strings.Split(string(letters), ""), " "))
+		}
+		focusOverlay(g, viewMarkKey)
+	} else {
+		_ = g.DeleteView(viewMarkKey)
+	}
 
 	// --- File Content View (Conditional Overlay) ---
-	if isFileContentViewVisible {
-		// Make it take up the whole main area
-		contentX0, contentY0 := 0, 0
-		contentX1, contentY1 := maxX-1, mainAreaMaxY
-		if v, err := g.SetView(viewFileContent, contentX0, contentY0, contentX1, contentY1); err != nil {
+	if geo.shows(viewFileContent) {
+		// It takes up the whole main area
+		if v, err := geo.setView(g, viewFileContent); err != nil {
 			if err != gocui.ErrUnknownView {
 				return fmt.Errorf("creating file content view: %w", err)
 			}
@@ -82,75 +273,524 @@ func layout(g *gocui.Gui, state *AppState) error {
 			v.Highlight = false
 			v.SelBgColor = gocui.ColorDefault // No selection highlight needed
 			v.SelFgColor = gocui.ColorDefault
-			v.FgColor = gocui.ColorWhite
+			v.FgColor = styleAttr(gocui.ColorWhite)
 		}
 		updateFileContentView(g, state) // Update its content
 
 		// Set focus to content view
-		if g.CurrentView() == nil || g.CurrentView().Name() != viewFileContent {
-			if _, err := g.SetCurrentView(viewFileContent); err != nil {
-				log.Printf("Error setting focus to file content view: %v", err)
-			}
-		}
+		focusOverlay(g, viewFileContent)
 		// When content view is visible, we don't need to draw the main layout below
-		return nil // Skip drawing the rest of the layout
+		removeScrollbars(g, viewFolders, viewFiles, viewCombined) // They would be drawn over it
+		updateHintView(g)                                         // The hint line shows the viewer's keys
+		return nil                                                // Skip drawing the rest of the layout
 	} else {
 		// Ensure content view is deleted if not visible
 		_ = g.DeleteView(viewFileContent)
+		removeScrollbars(g, viewFileContent)
 	}
 
-	// --- Main Layout Calculations (if content view is not visible) ---
-	leftPanelWidth := maxX / 3
-	if leftPanelWidth < 20 {
-		leftPanelWidth = 20
+	// --- Stats Column (or the status line standing in for it) ---
+	if geo.shows(viewStatus) {
+		if err := layoutStatsColumn(g, state, snap, geo); err != nil {
+			return err
+		}
+		_ = g.DeleteView(viewStatusLine)
+	} else {
+		if v, err := geo.setView(g, viewStatusLine); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating status line view: %w", err)
+			}
+			v.Frame = false
+			v.Wrap = false
+		}
+		updateStatusLine(g, snap)
+		for _, name := range []string{viewStatus, viewSize, viewLargest, viewGit} {
+			_ = g.DeleteView(name)
+		}
 	}
-	if leftPanelWidth >= maxX-20 { // Ensure right panel has some space
-		leftPanelWidth = maxX - 20
+
+	// --- Preview Pane (bottom half of the Files column) ---
+	if geo.shows(viewPreview) {
+		if err := layoutPreview(g, state, snap, geo); err != nil {
+			return err
+		}
+	} else {
+		_ = g.DeleteView(viewPreview)
+	}
+
+	// --- Combined View (single list replacing Folders and Files) ---
+	if state.IsCombinedMode() {
+		_ = g.DeleteView(viewFolders)
+		_ = g.DeleteView(viewFiles)
+		removeScrollbars(g, viewFolders, viewFiles)
+		if v, err := geo.setView(g, viewCombined); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating combined view: %w", err)
+			}
+			v.Highlight = true
+			v.SelBgColor = gocui.ColorDefault
+			v.SelFgColor = styleSel(gocui.ColorGreen)
+			v.Editable = false
+			v.Wrap = false
+			v.Frame = true
+			// Title set dynamically
+		}
+		updateListView(g, state, snap, viewCombined)
+		// --- Commander Mode (the inactive browser beside the combined list) ---
+		if geo.shows(viewCommander) {
+			if v, err := geo.setView(g, viewCommander); err != nil {
+				if err != gocui.ErrUnknownView {
+					return fmt.Errorf("creating commander view: %w", err)
+				}
+				v.Highlight = true
+				v.SelBgColor = gocui.ColorDefault
+				v.SelFgColor = styleSel(gocui.ColorGreen)
+				v.Editable = false
+				v.Wrap = false
+				v.Frame = true
+			}
+			updateCommanderView(g, geo)
+		} else {
+			_ = g.DeleteView(viewCommander)
+			removeScrollbars(g, viewCommander)
+		}
+	} else {
+		_ = g.DeleteView(viewCombined)
+		_ = g.DeleteView(viewCommander)
+		removeScrollbars(g, viewCombined, viewCommander)
+		if err := layoutSeparatePanes(g, state, snap, geo); err != nil {
+			return err
+		}
+	}
+
+	// --- Action Menu View (Conditional Overlay on top of main layout) ---
+	if geo.shows(viewActionMenu) {
+		if v, err := geo.setView(g, viewActionMenu); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating action menu view: %w", err)
+			}
+			v.Title = " Actions "
+			v.Frame = true
+			v.Highlight = false // We'll handle highlighting manually
+			v.FgColor = styleAttr(gocui.ColorWhite)
+			// Optional: Different background? v.BgColor = gocui.ColorBlue
+		}
+		updateActionMenuView(g, state) // Update content
+		// Set focus to action menu
+		focusOverlay(g, viewActionMenu)
+	} else {
+		// Ensure menu view is deleted if not visible
+		_ = g.DeleteView(viewActionMenu)
+	}
+
+	// --- Prompt View (Conditional single-line input overlay) ---
+	if geo.shows(viewPrompt) {
+		if v, err := geo.setView(g, viewPrompt); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating prompt view: %w", err)
+			}
+			v.Frame = true
+			v.Editable = true
+			v.Wrap = false
+			v.Highlight = false
+			v.FgColor = styleAttr(gocui.ColorWhite)
+			// Pre-fill the input once, when the view is first created
+			setPromptText(v, state.GetPrompt().Initial)
+		}
+		if v, err := g.View(viewPrompt); err == nil {
+			v.Title = state.GetPrompt().Title
+		}
+		g.Cursor = true // Show the text cursor while typing
+		focusOverlay(g, viewPrompt)
+	} else {
+		g.Cursor = false
+		_ = g.DeleteView(viewPrompt)
+	}
+
+	// --- Command Palette (Conditional Overlay: query line with the matches below) ---
+	if geo.shows(viewPalette) {
+		if v, err := geo.setView(g, viewPalette); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating command palette view: %w", err)
+			}
+			v.Title = " Command Palette "
+			v.Frame = true
+			v.Editable = true
+			v.Editor = paletteEditor(state)
+			v.Wrap = false
+			v.FgColor = styleAttr(gocui.ColorWhite)
+		}
+		if v, err := geo.setView(g, viewPaletteList); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating command palette list: %w", err)
+			}
+			v.Frame = true
+			v.Wrap = false
+			v.Highlight = false // Selection is drawn manually, like the action menu
+			v.FgColor = styleAttr(gocui.ColorWhite)
+		}
+		updatePaletteListView(g, state)
+		g.Cursor = true // Show the text cursor in the query line
+		focusOverlay(g, viewPalette)
+	} else {
+		_ = g.DeleteView(viewPalette)
+		_ = g.DeleteView(viewPaletteList)
+	}
+
+	// --- Confirmation Dialog (Conditional Overlay, with an input line when typed) ---
+	if geo.shows(viewConfirm) {
+		spec := state.GetConfirm()
+		if v, err := geo.setView(g, viewConfirm); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating confirmation view: %w", err)
+			}
+			v.Frame = true
+			v.Wrap = true
+			v.FgColor = styleAttr(gocui.ColorWhite)
+		}
+		if v, err := g.View(viewConfirm); err == nil {
+			v.Title = spec.Title
+			v.Clear()
+			for _, line := range confirmLines(spec) {
+				fmt.Fprintln(v, " "+line)
+			}
+		}
+		focus := viewConfirm
+		if geo.shows(viewConfirmText) {
+			focus = viewConfirmText
+			if v, err := geo.setView(g, viewConfirmText); err != nil {
+				if err != gocui.ErrUnknownView {
+					return fmt.Errorf("creating confirmation input view: %w", err)
+				}
+				v.Frame = true
+				v.Editable = true
+				v.Wrap = false
+				v.FgColor = styleAttr(gocui.ColorWhite)
+			}
+			g.Cursor = true
+		}
+		focusOverlay(g, focus)
+	} else {
+		_ = g.DeleteView(viewConfirm)
+		_ = g.DeleteView(viewConfirmText)
+	}
+
+	// --- Properties Popup (Conditional Overlay) ---
+	if geo.shows(viewProperties) {
+		if v, err := geo.setView(g, viewProperties); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating properties view: %w", err)
+			}
+			v.Title = " Properties "
+			v.Frame = true
+			v.Wrap = false
+			v.Highlight = false
+			v.FgColor = styleAttr(gocui.ColorWhite)
+		}
+		updatePropertiesView(g, state)
+		focusOverlay(g, viewProperties)
+	} else {
+		_ = g.DeleteView(viewProperties)
+	}
+
+	// --- Largest Files Overlay (Conditional Overlay) ---
+	if geo.shows(viewTopFiles) {
+		if v, err := geo.setView(g, viewTopFiles); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating largest files view: %w", err)
+			}
+			v.Title = fmt.Sprintf(" Largest Files (top %d) ", topFilesCount)
+			v.Frame = true
+			v.Wrap = false
+			v.Highlight = false // Selection is drawn manually, like the action menu
+			v.FgColor = styleAttr(gocui.ColorWhite)
+		}
+		updateTopFilesView(g, state)
+		focusOverlay(g, viewTopFiles)
+	} else {
+		_ = g.DeleteView(viewTopFiles)
+	}
+
+	// --- File History Overlay (Conditional Overlay) ---
+	if geo.shows(viewHistory) {
+		if v, err := geo.setView(g, viewHistory); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating history view: %w", err)
+			}
+			v.Frame = true
+			v.Wrap = false
+			v.Highlight = false // Selection is drawn manually, like the action menu
+			v.FgColor = styleAttr(gocui.ColorWhite)
+		}
+		updateHistoryView(g, state)
+		focusOverlay(g, viewHistory)
+	} else {
+		_ = g.DeleteView(viewHistory)
+		removeScrollbars(g, viewHistory)
+	}
+
+	// --- Drive / Mount Point Picker (Conditional Overlay) ---
+	if geo.shows(viewMounts) {
+		if v, err := geo.setView(g, viewMounts); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating mount points view: %w", err)
+			}
+			v.Title = " Drives / Mount Points "
+			v.Frame = true
+			v.Wrap = false
+			v.Highlight = false // Selection is drawn manually, like the action menu
+			v.FgColor = styleAttr(gocui.ColorWhite)
+		}
+		updateMountsView(g, state)
+		focusOverlay(g, viewMounts)
+	} else {
+		_ = g.DeleteView(viewMounts)
+	}
+
+	// --- Background Task List (Conditional Overlay) ---
+	if geo.shows(viewTasks) {
+		if v, err := geo.setView(g, viewTasks); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating tasks view: %w", err)
+			}
+			v.Frame = true
+			v.Wrap = false
+			v.Highlight = false // Selection is drawn manually, like the action menu
+			v.FgColor = styleAttr(gocui.ColorWhite)
+		}
+		updateTasksView(g, state)
+		focusOverlay(g, viewTasks)
+	} else {
+		_ = g.DeleteView(viewTasks)
+	}
+
+	// --- Cleanup Report Overlay (Conditional Overlay) ---
+	if geo.shows(viewCleanup) {
+		if v, err := geo.setView(g, viewCleanup); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating cleanup view: %w", err)
+			}
+			v.Frame = true
+			v.Wrap = false
+			v.Highlight = false // Selection is drawn manually, like the action menu
+			v.FgColor = styleAttr(gocui.ColorWhite)
+		}
+		updateCleanupView(g, state)
+		focusOverlay(g, viewCleanup)
+	} else {
+		_ = g.DeleteView(viewCleanup)
+		removeScrollbars(g, viewCleanup)
+	}
+
+	// --- Broken Links Overlay (Conditional Overlay) ---
+	if geo.shows(viewBrokenLinks) {
+		if v, err := geo.setView(g, viewBrokenLinks); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating broken links view: %w", err)
+			}
+			v.Frame = true
+			v.Wrap = false
+			v.Highlight = false // Selection is drawn manually, like the action menu
+			v.FgColor = styleAttr(gocui.ColorWhite)
+		}
+		updateBrokenLinksView(g, state)
+		focusOverlay(g, viewBrokenLinks)
+	} else {
+		_ = g.DeleteView(viewBrokenLinks)
+		removeScrollbars(g, viewBrokenLinks)
+	}
+
+	// --- Directory Comparison Overlay (Conditional Overlay) ---
+	if geo.shows(viewDirCompare) {
+		if v, err := geo.setView(g, viewDirCompare); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating directory comparison view: %w", err)
+			}
+			v.Frame = true
+			v.Wrap = false
+			v.Highlight = false // Selection is drawn manually, like the action menu
+			v.FgColor = styleAttr(gocui.ColorWhite)
+		}
+		updateDirCompareView(g, state)
+		focusOverlay(g, viewDirCompare)
+	} else {
+		_ = g.DeleteView(viewDirCompare)
+		removeScrollbars(g, viewDirCompare)
+	}
+
+	// --- Extension Breakdown Overlay (Conditional Overlay) ---
+	if geo.shows(viewExtStats) {
+		if v, err := geo.setView(g, viewExtStats); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating extension stats view: %w", err)
+			}
+			v.Frame = true
+			v.Wrap = false
+			v.Highlight = false
+			v.FgColor = styleAttr(gocui.ColorWhite)
+		}
+		updateExtStatsView(g, state)
+		focusOverlay(g, viewExtStats)
+	} else {
+		_ = g.DeleteView(viewExtStats)
+	}
+
+	// --- Message History Overlay (Conditional Overlay) ---
+	if geo.shows(viewMessages) {
+		if v, err := geo.setView(g, viewMessages); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating message history view: %w", err)
+			}
+			v.Frame = true
+			v.Wrap = false
+			v.Highlight = false
+			v.FgColor = styleAttr(gocui.ColorWhite)
+		}
+		updateMessagesView(g, state)
+		focusOverlay(g, viewMessages)
+	} else {
+		_ = g.DeleteView(viewMessages)
+	}
+
+	// --- Stats Errors Overlay (Conditional Overlay) ---
+	if geo.shows(viewStatsErrors) {
+		if v, err := geo.setView(g, viewStatsErrors); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating stats errors view: %w", err)
+			}
+			v.Frame = true
+			v.Wrap = false
+			v.Highlight = false
+			v.FgColor = styleAttr(gocui.ColorWhite)
+		}
+		updateStatsErrorsView(g, state)
+		focusOverlay(g, viewStatsErrors)
+	} else {
+		_ = g.DeleteView(viewStatsErrors)
+		removeScrollbars(g, viewStatsErrors)
+	}
+
+	// --- Keybinding Help Overlay (Conditional Overlay) ---
+	if geo.shows(viewHelp) {
+		if v, err := geo.setView(g, viewHelp); err != nil {
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("creating help view: %w", err)
+			}
+			v.Frame = true
+			v.Wrap = false
+			v.Highlight = false
+			v.FgColor = styleAttr(gocui.ColorWhite)
+			v.Title = " Keybindings (q to close) "
+		}
+		updateHelpView(g, state)
+		focusOverlay(g, viewHelp)
+	} else {
+		_ = g.DeleteView(viewHelp)
 	}
-	rightPanelX0 := leftPanelWidth + 1
-	rightPanelWidth := maxX - 1 - rightPanelX0
-	foldersWidth := rightPanelWidth / 2 // Integer division
-	filesX0 := rightPanelX0 + foldersWidth
 
+	raiseOverlays(g)
+
+	// Coming back from the "too small" screen: refocus what had focus before
+	if wasTooSmall {
+		if prevFocus := state.GetTooSmallPrevFocus(); prevFocus != "" {
+			if _, err := g.View(prevFocus); err == nil {
+				_, _ = g.SetCurrentView(prevFocus)
+			}
+		}
+	}
+
+	// Switched to another tab: refocus the view it was left in
+	if focus := tabs.takePendingFocus(); focus != "" && !state.IsOverlayVisible() {
+		if _, err := g.View(focus); err == nil {
+			_, _ = g.SetCurrentView(focus)
+		}
+	}
+
+	// --- Focus Management (when NO overlays are active) ---
+	if !state.IsOverlayVisible() {
+		// This block now primarily handles initial focus and ensures focus
+		// is on an interactive view if it somehow gets lost.
+		// Focus restoration from overlays is handled by the close handlers.
+		currentView := g.CurrentView()
+		interactiveViews := map[string]bool{viewFolders: true, viewFiles: true, viewLargest: true, viewStatus: true, viewGit: true}
+		defaultView := viewFolders
+		if state.IsCombinedMode() {
+			interactiveViews = map[string]bool{viewCombined: true, viewLargest: true, viewStatus: true, viewGit: true}
+			defaultView = viewCombined
+		}
+
+		// If no view has focus, or focus is on a non-interactive view, default to folders.
+		if currentView == nil || !interactiveViews[currentView.Name()] {
+			// Check if focus is already on folders or files before attempting to set it,
+			// unless currentView is nil. Avoid unnecessary focus setting.
+			needsFocusSet := (currentView == nil || !interactiveViews[currentView.Name()])
+
+			if needsFocusSet && (currentView == nil || currentView.Name() != defaultView) {
+				if _, err := g.SetCurrentView(defaultView); err != nil {
+					log.Printf("Error setting initial/fallback focus to folders: %v", err)
+				}
+			}
+		}
+		// No else needed: if focus is already on folders/files, leave it there.
+	}
+
+	// Hints follow focus, so they are drawn once it has settled
+	updateHintView(g)
+
+	return nil
+}
+
+// listFrames returns the shape of each list pane geo shows, for the frame's
+// snapshot. It readies the panes first: the grid's column count, and the
+// metadata of the rows about to be drawn (loaded lazily, a stat each).
+func listFrames(state *AppState, geo layoutGeometry) map[string]listFrame {
+	frames := make(map[string]listFrame)
+	for _, name := range []string{viewFolders, viewFiles, viewCombined} {
+		if r, ok := geo.views[name]; ok {
+			frames[name] = prepareListFrame(state, name, r)
+		}
+	}
+	return frames
+}
+
+// prepareListFrame readies the list pane viewName at r for drawing and
+// returns its shape.
+func prepareListFrame(state *AppState, viewName string, r rect) listFrame {
+	frame := listFrame{width: r.x1 - r.x0 - 1, height: clampHeight(r.y1 - r.y0 - 1), columns: 1}
+	if viewName == viewFiles && state.IsGridMode() {
+		frame.columns, frame.nameWidth = gridLayout(frame.width, state.LongestName(viewName, gridMaxNameWidth))
+		state.SetGridColumns(frame.columns)
+	}
+	from, to := state.FrameWindow(viewName, frame)
+	state.LoadEntryInfo(viewName, from, to)
+	return frame
+}
+
+// layoutStatsColumn creates the Root Folder, Size, Largest File and Git
+// Status panes of the left column.
+func layoutStatsColumn(g *gocui.Gui, state *AppState, snap renderSnapshot, geo layoutGeometry) error {
 	// --- Status View ---
-	statusY1 := 2 // Keep height 2 for label + value
-	if v, err := g.SetView(viewStatus, 0, 0, leftPanelWidth, statusY1); err != nil {
+	if v, err := geo.setView(g, viewStatus); err != nil {
 		if err != gocui.ErrUnknownView {
 			return fmt.Errorf("creating status view: %w", err)
 		}
 		v.Title = " Root Folder "
 		v.Frame = true
 	}
-	updateStatusView(g, state)
-
-	// --- Calculate Heights for New Stats Views ---
-	statsAreaY0 := statusY1 + 1
-	statsAreaHeight := mainAreaMaxY - statsAreaY0 // Available height
-	if statsAreaHeight < 6 {                      // Need at least 2 lines per box + frame
-		statsAreaHeight = 6 // Adjust minimum height
-	}
-	boxHeight := statsAreaHeight / 3 // Integer division
-	if boxHeight < 2 {               // Ensure minimum height for content
-		boxHeight = 2
-	}
+	updateStatusView(g, snap)
 
 	// --- Size View ---
-	sizeY0 := statsAreaY0
-	sizeY1 := sizeY0 + boxHeight
-	if v, err := g.SetView(viewSize, 0, sizeY0, leftPanelWidth, sizeY1); err != nil {
+	if v, err := geo.setView(g, viewSize); err != nil {
 		if err != gocui.ErrUnknownView {
 			return fmt.Errorf("creating size view: %w", err)
 		}
-		v.Title = " Size "
 		v.Wrap = false
 		v.Frame = true
 	}
-	updateSizeView(g, state)
+	updateSizeView(g, state, snap)
 
 	// --- Largest File View ---
-	largestY0 := sizeY1 + 1
-	largestY1 := largestY0 + boxHeight
-	if v, err := g.SetView(viewLargest, 0, largestY0, leftPanelWidth, largestY1); err != nil {
+	if v, err := geo.setView(g, viewLargest); err != nil {
 		if err != gocui.ErrUnknownView {
 			return fmt.Errorf("creating largest file view: %w", err)
 		}
@@ -158,12 +798,10 @@ func layout(g *gocui.Gui, state *AppState) error {
 		v.Wrap = false
 		v.Frame = true
 	}
-	updateLargestFileView(g, state)
+	updateLargestFileView(g, snap)
 
 	// --- Git Status View ---
-	gitY0 := largestY1 + 1
-	gitY1 := mainAreaMaxY // Use remaining space up to the message bar
-	if v, err := g.SetView(viewGit, 0, gitY0, leftPanelWidth, gitY1); err != nil {
+	if v, err := geo.setView(g, viewGit); err != nil {
 		if err != gocui.ErrUnknownView {
 			return fmt.Errorf("creating git status view: %w", err)
 		}
@@ -171,185 +809,677 @@ func layout(g *gocui.Gui, state *AppState) error {
 		v.Wrap = false
 		v.Frame = true
 	}
-	updateGitStatusView(g, state)
+	updateGitStatusView(g, snap)
+	return nil
+}
 
+// layoutSeparatePanes creates the side-by-side Folders and Files panes.
+func layoutSeparatePanes(g *gocui.Gui, state *AppState, snap renderSnapshot, geo layoutGeometry) error {
 	// --- Folders View ---
-	if v, err := g.SetView(viewFolders, rightPanelX0, 0, filesX0-1, mainAreaMaxY); err != nil {
+	if v, err := geo.setView(g, viewFolders); err != nil {
 		if err != gocui.ErrUnknownView {
 			return fmt.Errorf("creating folders view: %w", err)
 		}
-		v.Highlight = true                // Enable gocui highlighting
-		v.SelBgColor = gocui.ColorDefault // Background for selected line
-		v.SelFgColor = gocui.ColorGreen   // Foreground for selected line
+		v.Highlight = true                        // Enable gocui highlighting
+		v.SelBgColor = gocui.ColorDefault         // Background for selected line
+		v.SelFgColor = styleSel(gocui.ColorGreen) // Foreground for selected line
 		v.Editable = false
 		v.Wrap = false
 		v.Frame = true
 		// Title set dynamically
 	}
-	updateFoldersView(g, state)
+	updateFoldersView(g, state, snap)
 
 	// --- Files View ---
-	if v, err := g.SetView(viewFiles, filesX0, 0, maxX-1, mainAreaMaxY); err != nil {
+	if v, err := geo.setView(g, viewFiles); err != nil {
 		if err != gocui.ErrUnknownView {
 			return fmt.Errorf("creating files view: %w", err)
 		}
-		v.Highlight = true                // Enable gocui highlighting
-		v.SelBgColor = gocui.ColorDefault // Background for selected line
-		v.SelFgColor = gocui.ColorGreen   // Foreground for selected line
-		v.Editable = false
-		v.Wrap = false
-		v.Frame = true
-		// Title set dynamically
+		v.Highlight = true                        // Enable gocui highlighting
+		v.SelBgColor = gocui.ColorDefault         // Background for selected line
+		v.SelFgColor = styleSel(gocui.ColorGreen) // Foreground for selected line
+		v.Editable = false
+		v.Wrap = false
+		v.Frame = true
+		// Title set dynamically
+	}
+	updateFilesView(g, state, snap)
+	return nil
+}
+
+// layoutPreview places the preview pane and requests a preview of the item
+// selected in the focused list (or the first list when an overlay has focus).
+func layoutPreview(g *gocui.Gui, state *AppState, snap renderSnapshot, geo layoutGeometry) error {
+	v, err := geo.setView(g, viewPreview)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return fmt.Errorf("creating preview view: %w", err)
+		}
+		v.Frame = true
+		v.Wrap = false
+		v.Title = " Preview "
+	}
+
+	listView := primaryListView(state)
+	if cv := g.CurrentView(); cv != nil && (cv.Name() == viewFolders || cv.Name() == viewFiles || cv.Name() == viewCombined) {
+		listView = cv.Name()
+	}
+	v.Clear()
+	item, ok := snap.lists[listView].selected()
+	if !ok {
+		v.Title = " Preview "
+		return nil
+	}
+	schedulePreview(g, state, item)
+	width, _ := v.Size()
+	v.Title = " " + truncateWidth(item.Name, width-2) + " "
+	if _, content := state.Preview(); content != "" {
+		fmt.Fprint(v, content)
+	} else {
+		fmt.Fprintf(v, "%sLoading%s%s", ansiDim, glyph("…"), ansiReset)
+	}
+	return nil
+}
+
+// --- View Update Functions ---
+
+func updateMessageView(g *gocui.Gui, state *AppState) {
+	v, err := g.View(viewMessage)
+	if err != nil {
+		return // View might not exist yet
+	}
+	v.Clear()
+	message := state.GetLastMessage()
+	if message != "" {
+		fmt.Fprintf(v, " %s%s%s", ansiYellow, message, ansiReset)
+	}
+	// Running tasks are counted at the right end, e.g. "2 tasks running (T)"
+	if running := state.RunningTaskCount(); running > 0 {
+		key, _ := primaryKey(keyTable, "Lists", "show.tasks")
+		indicator := fmt.Sprintf("%s running (%s) ", pluralize(running, "task", "tasks"), key)
+		width, _ := v.Size()
+		if padding := width - 1 - displayWidth(message) - len(indicator); padding > 0 {
+			fmt.Fprintf(v, "%s%s%s%s", strings.Repeat(" ", padding), ansiCyan, indicator, ansiReset)
+		}
+	}
+}
+
+// updateHintView lists the most relevant keys of the focused view.
+func updateHintView(g *gocui.Gui) {
+	v, err := g.View(viewHints)
+	if err != nil {
+		return // Hidden in the config
+	}
+	v.Clear()
+	cv := g.CurrentView()
+	if cv == nil {
+		return
+	}
+	width, _ := v.Size()
+	fmt.Fprint(v, hintLine(keyTable, cv.Name(), width))
+}
+
+func updateStatusView(g *gocui.Gui, snap renderSnapshot) {
+	v, err := g.View(viewStatus)
+	if err != nil {
+		return // View might not exist yet
+	}
+	v.Clear()
+	width, _ := v.Size()
+	home, _ := os.UserHomeDir() // Without one, paths start at the root
+	crumbs := breadcrumbs(snap.cwd, home)
+	focused := g.CurrentView() == v && !snap.overlay
+	fmt.Fprintf(v, " %s", breadcrumbLine(crumbs, selectedCrumb(snap.crumbIdx, crumbs), width-2, focused))
+}
+
+func updateSizeView(g *gocui.Gui, state *AppState, snap renderSnapshot) {
+	v, err := g.View(viewSize)
+	if err != nil {
+		return // View might not exist yet
+	}
+	v.Clear()
+
+	// While the Files pane has focus, the selected item comes first
+	if cv := g.CurrentView(); cv != nil && (cv.Name() == viewFiles || cv.Name() == viewCombined) {
+		if item, ok := snap.lists[cv.Name()].selected(); ok {
+			v.Title = " Size: Selected "
+			updateSelectionSize(g, v, state, snap, item)
+			return
+		}
+	}
+	v.Title = " Size "
+
+	isLoading := snap.loadingStats
+	totalSize, statsErr := snap.totalSize, snap.statsErr
+
+	if isLoading {
+		fmt.Fprintf(v, "  %sCalculating...%s", ansiYellow, ansiReset)
+	} else if totalSize == -2 { // Error state
+		fmt.Fprintf(v, "  %sError%s (%s)", ansiRed, ansiReset, pluralize(snap.statsErrCount, "error", "errors"))
+		if statsErr != nil {
+			fmt.Fprintf(v, "\n   %s%s%s", ansiRed, trimError(statsErr), ansiReset)
+		}
+		fmt.Fprint(v, "\n   (E: list errors)")
+	} else if totalSize < 0 { // Should ideally not happen other than initial -1
+		fmt.Fprintf(v, "  N/A")
+	} else {
+		fmt.Fprintf(v, "  %s%s%s in %s files / %s dirs", ansiCyan, formatSize(totalSize), ansiReset, formatCount(snap.fileCount), formatCount(snap.dirCount))
+		// Sparse files take less than their size, small files a whole block
+		if snap.diskUsage >= 0 {
+			fmt.Fprintf(v, "\n  On disk: %s%s%s", ansiCyan, formatSize(snap.diskUsage), ansiReset)
+		}
+		if broken := snap.brokenLinks; broken > 0 {
+			fmt.Fprintf(v, "\n  %s%s%s %s(B: list)%s", ansiYellow, pluralize(broken, "broken link", "broken links"), ansiReset, ansiDim, ansiReset)
+		}
+		if snap.revalidating {
+			fmt.Fprintf(v, "\n  %s(cached, rescanning...)%s", ansiYellow, ansiReset)
+		}
+	}
+
+	// Disk space is independent of the walk, so show it whenever it's known
+	if snap.hasDiskSpace && !isLoading {
+		fmt.Fprintf(v, "\n  Disk: %s%s%s free of %s", ansiCyan, formatSize(int64(snap.diskFree)), ansiReset, formatSize(int64(snap.diskTotal)))
+	}
+}
+
+// updateSelectionSize fills the Size pane with item's own size (recursive
+// for folders, measured once the cursor rests), its mtime and, dimmed, the
+// total of the whole tree.
+func updateSelectionSize(g *gocui.Gui, v *gocui.View, state *AppState, snap renderSnapshot, item FileInfo) {
+	scheduleSelectionSize(g, state, item)
+	width, _ := v.Size()
+	fmt.Fprintf(v, "  %s %s%s%s", item.Icon, ansiBold, toCells(truncateWidth(item.Name, width-5)), ansiReset)
+	path, size := state.SelectionSize()
+	switch {
+	case path != item.Path || size == -1:
+		fmt.Fprintf(v, "\n  Size: %sCalculating...%s", ansiYellow, ansiReset)
+	case size == -2:
+		fmt.Fprintf(v, "\n  Size: %sunreadable%s", ansiRed, ansiReset)
+	default:
+		fmt.Fprintf(v, "\n  Size: %s%s%s", ansiCyan, formatSize(size), ansiReset)
+	}
+	if !item.ModTime.IsZero() {
+		fmt.Fprintf(v, "\n  Modified: %s", item.ModTime.Format("2006-01-02 15:04"))
+	}
+	if snap.totalSize >= 0 && !snap.loadingStats {
+		fmt.Fprintf(v, "\n  %sFolder total: %s%s", ansiDim, formatSize(snap.totalSize), ansiReset)
+	}
+}
+
+func updateLargestFileView(g *gocui.Gui, snap renderSnapshot) {
+	v, err := g.View(viewLargest)
+	if err != nil {
+		return
+	}
+	v.Clear()
+
+	isLoading := snap.loadingStats
+	totalSize, largestFile, statsErr := snap.totalSize, snap.largestFile, snap.statsErr
+
+	if isLoading {
+		fmt.Fprintf(v, "  %sSearching...%s", ansiYellow, ansiReset)
+	} else if totalSize == -2 { // Error state
+		fmt.Fprintf(v, "  %sError%s", ansiRed, ansiReset)
+		if statsErr != nil {
+			fmt.Fprintf(v, "\n   %s(See size view)%s", ansiRed, ansiReset)
+		}
+	} else if largestFile.Name == "" && totalSize == 0 {
+		fmt.Fprintf(v, "  (Empty Dir)")
+	} else if largestFile.Name == "" {
+		fmt.Fprintf(v, "  (No files)")
+	} else {
+		// While focused, ">" marks the line Enter acts on
+		cv := g.CurrentView()
+		focused := cv != nil && cv.Name() == viewLargest
+		onDir := snap.onLargestDir
+		fileMark, dirMark := " ", " "
+		if focused && onDir {
+			dirMark = ">"
+		} else if focused {
+			fileMark = ">"
+		}
+		// Show icon and bold green name on first line
+		fmt.Fprintf(v, " %s%s %s%s%s%s", fileMark, largestFile.Icon, ansiBold+ansiGreen, largestFile.Name, ansiReset, ansiReset)
+		// Show size on the next line, indented, in cyan
+		fmt.Fprintf(v, "\n   Size: %s%s%s", ansiCyan, formatSize(largestFile.Size), ansiReset)
+		// The heaviest immediate subfolder and its share, e.g. "node_modules — 1.2 GiB (61%)"
+		if dir := snap.largestDir; dir.Name != "" {
+			tail := fmt.Sprintf(" %s %s", glyph("—"), formatSize(dir.Size))
+			if totalSize > 0 {
+				tail += fmt.Sprintf(" (%d%%)", dir.Size*100/totalSize)
+			}
+			viewWidth, _ := v.Size()
+			nameWidth := max(viewWidth-3-displayWidth(dir.Icon)-displayWidth(tail), 4)
+			fmt.Fprintf(v, "\n %s%s %s%s%s%s%s%s", dirMark, dir.Icon, ansiBold, middleEllipsis(dir.Name, nameWidth), ansiReset, ansiCyan, tail, ansiReset)
+		}
+		if focused && onDir {
+			fmt.Fprintf(v, "\n   %s(Enter: select the folder)%s", ansiDim, ansiReset)
+		} else if focused {
+			fmt.Fprintf(v, "\n   %s(Enter: actions, L: top %d)%s", ansiDim, topFilesCount, ansiReset)
+		} else {
+			fmt.Fprintf(v, "\n   %s(L: top %d)%s", ansiDim, topFilesCount, ansiReset)
+		}
+	}
+}
+
+// updateExtStatsView renders the per-extension breakdown, e.g. ".go  412 files  18.30 MiB".
+func updateExtStatsView(g *gocui.Gui, state *AppState) {
+	v, err := g.View(viewExtStats)
+	if err != nil {
+		return
+	}
+	v.Clear()
+
+	stats := state.ExtStats()
+	title := fmt.Sprintf(" Extensions (%d) ", len(stats))
+	if state.IsLoadingStats() {
+		title = fmt.Sprintf(" Extensions (%d, scanning%s) ", len(stats), glyph("…"))
+	}
+	v.Title = title
+
+	if len(stats) == 0 {
+		if state.IsLoadingStats() {
+			fmt.Fprintf(v, " %sScanning...%s", ansiYellow, ansiReset)
+		} else {
+			fmt.Fprint(v, " (No files)")
+		}
+		return
+	}
+
+	_ = v.SetOrigin(0, state.GetExtStatsOriginY())
+	for _, st := range stats {
+		extColor := ansiGreen
+		if st.Ext == extBucketNone || st.Ext == extBucketHidden {
+			extColor = ansiDim
+		}
+		fmt.Fprintf(v, " %s%-16s%s %7d files  %s%11s%s\n",
+			extColor, st.Ext, ansiReset, st.Count, ansiCyan, formatSize(st.Bytes), ansiReset)
+	}
+}
+
+// updatePaletteListView renders the commands matching the palette query,
+// with the keys they are bound to right-aligned.
+func updatePaletteListView(g *gocui.Gui, state *AppState) {
+	v, err := g.View(viewPaletteList)
+	if err != nil {
+		return
+	}
+	v.Clear()
+
+	matches, selectedIdx := state.PaletteMatches()
+	if len(matches) == 0 {
+		fmt.Fprint(v, " (No matching commands)")
+		return
+	}
+	width, height := contentSize(v)
+	// Keep the selection visible when there are more matches than rows
+	_ = v.SetOrigin(0, max(selectedIdx-height+1, 0))
+	for i, entry := range matches {
+		label := truncateWidth(entry.Label, max(width-displayWidth(entry.Keys)-4, 1))
+		padding := strings.Repeat(" ", max(width-displayWidth(label)-displayWidth(entry.Keys)-2, 1))
+		if i == selectedIdx {
+			fmt.Fprintf(v, "%s %s%s%s %s\n", ansiReverse, toCells(label), padding, entry.Keys, ansiReset)
+		} else {
+			fmt.Fprintf(v, " %s%s%s%s%s \n", toCells(label), padding, ansiCyan, entry.Keys, ansiReset)
+		}
+	}
+}
+
+// updateHelpView renders the keybinding table, one section per context.
+func updateHelpView(g *gocui.Gui, state *AppState) {
+	v, err := g.View(viewHelp)
+	if err != nil {
+		return
+	}
+	v.Clear()
+	for _, line := range helpLines(keyTable) {
+		fmt.Fprintln(v, " "+line)
+	}
+	_ = v.SetOrigin(0, state.GetHelpOriginY())
+}
+
+// updateMessagesView renders the message history, newest first, with errors
+// in red and everything else in green.
+func updateMessagesView(g *gocui.Gui, state *AppState) {
+	v, err := g.View(viewMessages)
+	if err != nil {
+		return
+	}
+	v.Clear()
+
+	history := state.MessageHistory()
+	v.Title = fmt.Sprintf(" Messages (%d) ", len(history))
+	if len(history) == 0 {
+		fmt.Fprint(v, " (No messages yet)")
+		return
+	}
+
+	_ = v.SetOrigin(0, state.GetMessagesOriginY())
+	for _, entry := range history {
+		color := ansiGreen
+		if entry.IsError {
+			color = ansiRed
+		}
+		fmt.Fprintf(v, " %s%s%s  %s%s%s\n", ansiDim, entry.Time.Format("15:04:05"), ansiReset, color, toCells(entry.Text), ansiReset)
+	}
+}
+
+// updateStatsErrorsView lists the paths the stats walk could not read,
+// relative to the CWD, with the reason for each.
+func updateStatsErrorsView(g *gocui.Gui, state *AppState) {
+	v, err := g.View(viewStatsErrors)
+	if err != nil {
+		return
+	}
+	v.Clear()
+
+	errs, count := state.StatsErrors()
+	if len(errs) < count {
+		v.Title = fmt.Sprintf(" Scan Errors (first %d of %d) ", len(errs), count)
+	} else {
+		v.Title = fmt.Sprintf(" Scan Errors (%d) ", count)
+	}
+	if len(errs) == 0 {
+		fmt.Fprint(v, " (No errors)")
+		return
+	}
+
+	originY := state.GetStatsErrorsOriginY()
+	_ = v.SetOrigin(0, originY)
+	cwd := state.Cwd()
+	for _, e := range errs {
+		path := e.Path
+		if rel, err := filepath.Rel(cwd, e.Path); err == nil {
+			path = rel
+		}
+		fmt.Fprintf(v, " %s  %s%s%s\n", toCells(path), ansiRed, toCells(e.Reason), ansiReset)
+	}
+	drawScrollbar(g, viewStatsErrors, originY, len(errs), false)
+}
+
+// updateTopFilesView renders the largest files overlay with paths relative to the CWD.
+func updateTopFilesView(g *gocui.Gui, state *AppState) {
+	v, err := g.View(viewTopFiles)
+	if err != nil {
+		return
+	}
+	v.Clear()
+
+	files := state.TopFiles()
+	if len(files) == 0 {
+		if state.IsLoadingStats() {
+			fmt.Fprintf(v, " %sSearching...%s", ansiYellow, ansiReset)
+		} else {
+			fmt.Fprint(v, " (No files)")
+		}
+		return
 	}
-	updateFilesView(g, state)
 
-	// --- Action Menu View (Conditional Overlay on top of main layout) ---
-	if isActionMenuVisible {
-		menuOptions := state.GetActionMenuOptions()
-		menuWidth := 40                    // Adjust width as needed
-		menuHeight := len(menuOptions) + 1 // Options + Frame
-
-		// Basic centering
-		menuX0 := (maxX - menuWidth) / 2
-		menuY0 := (mainAreaMaxY + 1 - menuHeight) / 2 // Center in the main area
-		menuX1 := menuX0 + menuWidth
-		menuY1 := menuY0 + menuHeight
-
-		if v, err := g.SetView(viewActionMenu, menuX0, menuY0, menuX1, menuY1); err != nil {
-			if err != gocui.ErrUnknownView {
-				return fmt.Errorf("creating action menu view: %w", err)
-			}
-			v.Title = " Actions "
-			v.Frame = true
-			v.Highlight = false // We'll handle highlighting manually
-			v.FgColor = gocui.ColorWhite
-			// Optional: Different background? v.BgColor = gocui.ColorBlue
+	cwd := state.Cwd()
+	selectedIdx := state.GetTopFilesSelectedIdx()
+	for i, file := range files {
+		relPath, err := filepath.Rel(cwd, file.Path)
+		if err != nil {
+			relPath = file.Path
 		}
-		updateActionMenuView(g, state) // Update content
-		// Set focus to action menu
-		if g.CurrentView() == nil || g.CurrentView().Name() != viewActionMenu {
-			if _, err := g.SetCurrentView(viewActionMenu); err != nil {
-				log.Printf("Error setting focus to action menu: %v", err)
-			}
+		line := fmt.Sprintf(" %2d. %10s  %s %s ", i+1, formatSize(file.Size), file.Icon, toCells(relPath))
+		if i == selectedIdx {
+			fmt.Fprintf(v, "%s%s%s\n", ansiReverse, line, ansiReset)
+		} else {
+			fmt.Fprintln(v, line)
 		}
-	} else {
-		// Ensure menu view is deleted if not visible
-		_ = g.DeleteView(viewActionMenu)
 	}
+}
 
-	// --- Focus Management (when NO overlays are active) ---
-	if !isActionMenuVisible && !isFileContentViewVisible {
-		// This block now primarily handles initial focus and ensures focus
-		// is on an interactive view if it somehow gets lost.
-		// Focus restoration from overlays is handled by the close handlers.
-		currentView := g.CurrentView()
-		interactiveViews := map[string]bool{viewFolders: true, viewFiles: true}
+// updateHistoryView renders the commits of the history overlay that are on
+// screen, e.g. " a1b2c3d  2024-05-01  Jane Doe  Fix flaky test".
+func updateHistoryView(g *gocui.Gui, state *AppState) {
+	v, err := g.View(viewHistory)
+	if err != nil {
+		return
+	}
+	v.Clear()
 
-		// If no view has focus, or focus is on a non-interactive view, default to folders.
-		if currentView == nil || !interactiveViews[currentView.Name()] {
-			// Check if focus is already on folders or files before attempting to set it,
-			// unless currentView is nil. Avoid unnecessary focus setting.
-			needsFocusSet := (currentView == nil || !interactiveViews[currentView.Name()])
+	target, entries, loading, complete, loadErr := state.History()
+	count := fmt.Sprintf("%d", len(entries))
+	if !complete {
+		count += "+"
+	}
+	width, height := contentSize(v)
+	v.Title = " History: " + truncateWidth(target.Name, width-20) + fmt.Sprintf(" (%s commits) ", count)
 
-			if needsFocusSet && (currentView == nil || currentView.Name() != viewFolders) {
-				if _, err := g.SetCurrentView(viewFolders); err != nil {
-					log.Printf("Error setting initial/fallback focus to folders: %v", err)
-				}
-			}
+	selectedIdx, originY := state.HistoryPosition()
+	if selectedIdx >= originY+height { // The view shrank since the selection moved
+		originY = selectedIdx - height + 1
+	}
+	authorWidth := 0
+	for _, entry := range entries {
+		if w := displayWidth(entry.Author); w > authorWidth {
+			authorWidth = w
 		}
-		// No else needed: if focus is already on folders/files, leave it there.
 	}
-
-	return nil
+	if authorWidth > 18 {
+		authorWidth = 18
+	}
+	for i := originY; i < len(entries) && i < originY+height; i++ {
+		entry := entries[i]
+		author := truncateWidth(entry.Author, authorWidth)
+		author += strings.Repeat(" ", authorWidth-displayWidth(author))
+		line := toCells(truncateWidth(fmt.Sprintf(" %s  %s  %s  %s", entry.Hash, entry.Date, author, entry.Subject), width-1)) + " "
+		if i == selectedIdx {
+			fmt.Fprintf(v, "%s%s%s\n", ansiReverse, line, ansiReset)
+		} else {
+			fmt.Fprintln(v, line)
+		}
+	}
+	switch {
+	case loading:
+		fmt.Fprintf(v, " %sLoading...%s", ansiYellow, ansiReset)
+	case loadErr != nil:
+		fmt.Fprintf(v, " %sError: %s%s", ansiRed, trimError(loadErr), ansiReset)
+	case len(entries) == 0:
+		fmt.Fprint(v, " (No commits)")
+	}
+	drawScrollbar(g, viewHistory, originY, len(entries), false)
 }
 
-// --- View Update Functions ---
-
-func updateMessageView(g *gocui.Gui, state *AppState) {
-	v, err := g.View(viewMessage)
+// updateMountsView renders the mount point picker: path, filesystem type and
+// free space, e.g. " /mnt/data   ext4   120.50 GiB free of 931.51 GiB".
+func updateMountsView(g *gocui.Gui, state *AppState) {
+	v, err := g.View(viewMounts)
 	if err != nil {
-		return // View might not exist yet
+		return
 	}
 	v.Clear()
-	message := state.GetLastMessage()
-	if message != "" {
-		fmt.Fprintf(v, " %s%s%s", ansiYellow, message, ansiReset)
+
+	entries, loading, loadErr := state.Mounts()
+	switch {
+	case loading:
+		fmt.Fprintf(v, " %sLooking for mount points...%s", ansiYellow, ansiReset)
+		return
+	case loadErr != nil:
+		fmt.Fprintf(v, " %sError: %s%s", ansiRed, trimError(loadErr), ansiReset)
+		return
+	case len(entries) == 0:
+		fmt.Fprint(v, " (No mount points found)")
+		return
+	}
+
+	width, height := contentSize(v)
+	selectedIdx := state.GetMountsSelectedIdx()
+	// Keep the selection visible when there are more mounts than rows
+	_ = v.SetOrigin(0, max(selectedIdx-height+1, 0))
+	pathWidth := max(width-42, 10)
+	for i, m := range entries {
+		space := "unavailable"
+		if m.HasSpace {
+			space = fmt.Sprintf("%s free of %s", formatSize(int64(m.Free)), formatSize(int64(m.Total)))
+		}
+		path := middleEllipsis(m.Path, pathWidth)
+		padding := strings.Repeat(" ", max(pathWidth-displayWidth(path), 0))
+		line := fmt.Sprintf(" %s%s  %-8s  %s ", toCells(path), padding, m.FSType, space)
+		if i == selectedIdx {
+			fmt.Fprintf(v, "%s%s%s\n", ansiReverse, line, ansiReset)
+		} else {
+			fmt.Fprintln(v, line)
+		}
 	}
 }
 
-func updateStatusView(g *gocui.Gui, state *AppState) {
-	v, err := g.View(viewStatus)
+// updateTasksView renders the background tasks, newest first, e.g.
+// " Duplicate 'src'   42%  12.50 MiB/s  0:03" while running and
+// " Duplicate 'src'   done in 4s" once finished.
+func updateTasksView(g *gocui.Gui, state *AppState) {
+	v, err := g.View(viewTasks)
 	if err != nil {
-		return // View might not exist yet
+		return
 	}
 	v.Clear()
-	fmt.Fprintf(v, " %s%s%s", ansiGreen, state.BaseDir(), ansiReset)
+
+	tasks := state.Tasks()
+	running := state.RunningTaskCount()
+	v.Title = fmt.Sprintf(" Tasks (%d running) ", running)
+	if len(tasks) == 0 {
+		fmt.Fprint(v, " (No background tasks)")
+		return
+	}
+
+	width, height := contentSize(v)
+	selectedIdx := state.GetTasksSelectedIdx()
+	// Keep the selection visible when there are more tasks than rows
+	_ = v.SetOrigin(0, max(selectedIdx-height+1, 0))
+	statusWidth := min(36, max(width/2, 12))
+	nameWidth := max(width-statusWidth-3, 10)
+	for i, t := range tasks {
+		var status, color string
+		switch t.Status {
+		case taskRunning:
+			color = ansiYellow
+			if percent := t.Percent(); percent >= 0 {
+				status = fmt.Sprintf("%3d%%  %s/s", percent, formatSize(t.Throughput()))
+			} else {
+				status = fmt.Sprintf("%s  %s/s", formatSize(t.Done), formatSize(t.Throughput()))
+			}
+			status += "  " + formatElapsed(t.Elapsed())
+		case taskDone:
+			color, status = ansiGreen, "done in "+formatElapsed(t.Elapsed())
+		case taskFailed:
+			color, status = ansiRed, "failed: "+t.Result
+		case taskCancelled:
+			color, status = ansiGray, "cancelled"
+		}
+		name := middleEllipsis(t.Name, nameWidth)
+		padding := strings.Repeat(" ", max(nameWidth-displayWidth(name), 0))
+		status = truncateWidth(status, statusWidth)
+		if i == selectedIdx {
+			fmt.Fprintf(v, "%s %s%s  %s %s\n", ansiReverse, toCells(name), padding, toCells(status), ansiReset)
+		} else {
+			fmt.Fprintf(v, " %s%s  %s%s%s\n", toCells(name), padding, color, toCells(status), ansiReset)
+		}
+	}
 }
 
-func updateSizeView(g *gocui.Gui, state *AppState) {
-	v, err := g.View(viewSize)
+// updateCleanupView lists the empty folders, then the zero-byte files, the
+// stats walk found, relative to the CWD, with the counts in the title.
+func updateCleanupView(g *gocui.Gui, state *AppState) {
+	v, err := g.View(viewCleanup)
 	if err != nil {
-		return // View might not exist yet
+		return
 	}
 	v.Clear()
 
-	isLoading := state.IsLoadingStats()
-	totalSize, _, _, statsErr := state.Stats() // Only need totalSize and error
+	entries, dirCount, fileCount := state.CleanupEntries()
+	width, height := contentSize(v)
+	v.Title = fmt.Sprintf(" Empty: %s, %s ", pluralize(dirCount, "folder", "folders"), pluralize(fileCount, "zero-byte file", "zero-byte files"))
+	if len(entries) < dirCount+fileCount {
+		v.Title += fmt.Sprintf("(showing %d) ", len(entries))
+	}
+	if len(entries) == 0 {
+		fmt.Fprint(v, " (Nothing to clean up)")
+		return
+	}
 
-	if isLoading {
-		fmt.Fprintf(v, "  %sCalculating...%s", ansiYellow, ansiReset)
-	} else if totalSize == -2 { // Error state
-		fmt.Fprintf(v, "  %sError%s", ansiRed, ansiReset)
-		if statsErr != nil {
-			fmt.Fprintf(v, "\n   %s%s%s", ansiRed, trimError(statsErr), ansiReset)
+	selectedIdx, originY := state.CleanupPosition()
+	if selectedIdx >= originY+height { // The view shrank since the selection moved
+		originY = selectedIdx - height + 1
+	}
+	_ = v.SetOrigin(0, originY)
+	cwd := state.Cwd()
+	for i, entry := range entries {
+		path := entry.Path
+		if rel, err := filepath.Rel(cwd, entry.Path); err == nil {
+			path = rel
+		}
+		if entry.IsDir {
+			path += string(filepath.Separator)
+		}
+		path = middleEllipsis(path, max(width-4-displayWidth(entry.Icon), 10))
+		if i == selectedIdx {
+			fmt.Fprintf(v, "%s %s %s %s\n", ansiReverse, entry.Icon, toCells(path), ansiReset)
+		} else {
+			fmt.Fprintf(v, " %s%s %s%s\n", entryColor(entry), entry.Icon, toCells(path), ansiReset)
 		}
-	} else if totalSize < 0 { // Should ideally not happen other than initial -1
-		fmt.Fprintf(v, "  N/A")
-	} else {
-		fmt.Fprintf(v, "  %s%s%s", ansiCyan, formatSize(totalSize), ansiReset)
 	}
+	drawScrollbar(g, viewCleanup, originY, len(entries), false)
 }
 
-func updateLargestFileView(g *gocui.Gui, state *AppState) {
-	v, err := g.View(viewLargest)
+// updateBrokenLinksView lists the dangling symlinks relative to the CWD, each
+// with its target and why it doesn't resolve.
+func updateBrokenLinksView(g *gocui.Gui, state *AppState) {
+	v, err := g.View(viewBrokenLinks)
 	if err != nil {
 		return
 	}
 	v.Clear()
 
-	isLoading := state.IsLoadingStats()
-	totalSize, largestFile, _, statsErr := state.Stats()
+	links, count := state.BrokenLinks()
+	if len(links) < count {
+		v.Title = fmt.Sprintf(" Broken Links (first %d of %d) ", len(links), count)
+	} else {
+		v.Title = fmt.Sprintf(" Broken Links (%d) ", count)
+	}
+	if len(links) == 0 {
+		fmt.Fprint(v, " (No broken links)")
+		return
+	}
 
-	if isLoading {
-		fmt.Fprintf(v, "  %sSearching...%s", ansiYellow, ansiReset)
-	} else if totalSize == -2 { // Error state
-		fmt.Fprintf(v, "  %sError%s", ansiRed, ansiReset)
-		if statsErr != nil {
-			fmt.Fprintf(v, "\n   %s(See size view)%s", ansiRed, ansiReset)
+	width, height := contentSize(v)
+	selectedIdx, originY := state.BrokenLinksPosition()
+	if selectedIdx >= originY+height { // The view shrank since the selection moved
+		originY = selectedIdx - height + 1
+	}
+	_ = v.SetOrigin(0, originY)
+	cwd := state.Cwd()
+	pathWidth := max(width/2-2, 10)
+	for i, link := range links {
+		path := link.Path
+		if rel, err := filepath.Rel(cwd, link.Path); err == nil {
+			path = rel
+		}
+		path = middleEllipsis(path, pathWidth)
+		padding := strings.Repeat(" ", max(pathWidth-displayWidth(path), 0))
+		target := truncateWidth("-> "+link.Target, max(width-pathWidth-displayWidth(link.Reason)-5, 10))
+		if i == selectedIdx {
+			fmt.Fprintf(v, "%s %s%s %s  %s %s\n", ansiReverse, toCells(path), padding, toCells(target), toCells(link.Reason), ansiReset)
+		} else {
+			fmt.Fprintf(v, " %s%s%s%s %s  %s%s%s\n", ansiCyan, toCells(path), ansiReset, padding,
+				toCells(target), ansiRed, toCells(link.Reason), ansiReset)
 		}
-	} else if largestFile.Name == "" && totalSize == 0 {
-		fmt.Fprintf(v, "  (Empty Dir)")
-	} else if largestFile.Name == "" {
-		fmt.Fprintf(v, "  (No files)")
-	} else {
-		// Show icon and bold green name on first line
-		fmt.Fprintf(v, "  %s %s%s%s%s", largestFile.Icon, ansiBold+ansiGreen, largestFile.Name, ansiReset, ansiReset)
-		// Show size on the next line, indented, in cyan
-		fmt.Fprintf(v, "\n   Size: %s%s%s", ansiCyan, formatSize(largestFile.Size), ansiReset)
 	}
+	drawScrollbar(g, viewBrokenLinks, originY, len(links), false)
 }
 
-func updateGitStatusView(g *gocui.Gui, state *AppState) {
+func updateGitStatusView(g *gocui.Gui, snap renderSnapshot) {
 	v, err := g.View(viewGit)
 	if err != nil {
 		return
 	}
 	v.Clear()
 
-	isLoading := state.IsLoadingStats()
-	totalSize, _, gitStatus, statsErr := state.Stats()
+	isLoading := snap.loadingStats
+	totalSize, gitStatus, statsErr := snap.totalSize, snap.gitStatus, snap.statsErr
 
-	gitIcon := ""
+	gitIcon := glyph("\ue702")
 
 	if isLoading {
 		fmt.Fprintf(v, "  %s%s Checking...%s", ansiYellow, gitIcon, ansiReset)
@@ -359,14 +1489,14 @@ func updateGitStatusView(g *gocui.Gui, state *AppState) {
 		fmt.Fprintf(v, "  %s%s Status Unknown (Error)%s", ansiRed, gitIcon, ansiReset)
 	} else {
 		if strings.HasPrefix(gitStatus, "Active:") {
-			branchName := ""
+			branchName, suffix := "", ""
 			if parts := strings.SplitN(gitStatus, "(", 2); len(parts) == 2 {
 				if branchParts := strings.SplitN(parts[1], ")", 2); len(branchParts) == 2 {
-					branchName = branchParts[0]
+					branchName, suffix = branchParts[0], branchParts[1] // Suffix: " (rebasing)" etc.
 				}
 			}
 			if branchName != "" {
-				statusText := fmt.Sprintf("Active: (%s%s%s)", ansiBold, branchName, ansiReset+ansiGreen)
+				statusText := fmt.Sprintf("Active: (%s%s%s)%s", ansiBold, branchName, ansiReset+ansiGreen, suffix)
 				fmt.Fprintf(v, "  %s%s %s%s", ansiGreen, gitIcon, statusText, ansiReset)
 			} else {
 				fmt.Fprintf(v, "  %s%s %s%s", ansiGreen, gitIcon, gitStatus, ansiReset)
@@ -376,162 +1506,342 @@ func updateGitStatusView(g *gocui.Gui, state *AppState) {
 		} else {
 			fmt.Fprintf(v, "  %s %s%s", gitIcon, gitStatus, ansiReset)
 		}
+		if strings.HasPrefix(gitStatus, "Active") {
+			width, _ := v.Size()
+			fmt.Fprintf(v, "\n   %s", lastCommitLine(snap, width-3))
+			if root := snap.repoRoot; root != "" {
+				home, _ := os.UserHomeDir()
+				fmt.Fprintf(v, "\n   %sroot:%s %s", ansiDim, ansiReset, toCells(shortenPath(root, home, width-9)))
+			}
+		}
 		if statsErr != nil && totalSize != -2 {
 			fmt.Fprintf(v, "\n   %s(Scan had errors)%s", ansiYellow, ansiReset)
 		}
 	}
 }
 
+// lastCommitLine describes the repository's last commit in width cells,
+// shortening the subject to fit.
+func lastCommitLine(snap renderSnapshot, width int) string {
+	commit, err := snap.lastCommit, snap.lastCommitErr
+	switch {
+	case err != nil:
+		return ansiDim + truncateWidth("last: (unavailable)", width) + ansiReset
+	case commit.Hash == "":
+		return ansiDim + truncateWidth("last: (no commits yet)", width) + ansiReset
+	}
+	info := fmt.Sprintf("last: %s %s ", commit.Hash, formatAge(time.Since(commit.Time)))
+	if commit.Shallow {
+		info += "(shallow) " // Before the subject so truncation keeps it
+	}
+	if displayWidth(info) >= width {
+		return ansiYellow + truncateWidth(strings.TrimSpace(info), width) + ansiReset
+	}
+	return ansiYellow + info + ansiReset + toCells(truncateWidth(commit.Subject, width-displayWidth(info)))
+}
+
 // updateListView is a helper for Folders and Files views
-func updateListView(g *gocui.Gui, state *AppState, viewName string) {
-	v, err := g.View(viewName)
+func updateListView(g *gocui.Gui, state *AppState, snap renderSnapshot, viewName string) {
+	updateListViewAs(g, state, snap, viewName, viewName)
+}
+
+// updateListViewAs renders the viewName list of state's snapshot into the
+// view target, as the inactive commander mode browser is drawn.
+func updateListViewAs(g *gocui.Gui, state *AppState, snap renderSnapshot, viewName, target string) {
+	v, err := g.View(target)
 	if err != nil {
 		return // View not ready
 	}
+	list, ok := snap.lists[viewName]
+	if !ok {
+		return // Not framed in this snapshot
+	}
 	v.Clear()
 
-	var listToShow []FileInfo
-	var originY int
-	var cursorY int
-	var titleMode string
-	var listType string // "Folders" or "Files"
-
 	isFoldersView := viewName == viewFolders
-	if isFoldersView {
+	listType := "Files"
+	switch viewName {
+	case viewFolders:
 		listType = "Folders"
-		if state.IsShowingHidden() {
-			listToShow = state.HiddenDirs()
-			originY = state.HiddenFoldersOriginY()
-			cursorY = state.HiddenFoldersCursorY()
-			titleMode = "Hidden"
-		} else {
-			listToShow = state.VisibleDirs()
-			originY = state.VisibleFoldersOriginY()
-			cursorY = state.VisibleFoldersCursorY()
-			titleMode = "Visible"
-		}
-	} else { // Files View
-		listType = "Files"
-		if state.IsShowingHidden() {
-			listToShow = state.HiddenFiles()
-			originY = state.HiddenFilesOriginY()
-			cursorY = state.HiddenFilesCursorY()
-			titleMode = "Hidden"
-		} else {
-			listToShow = state.VisibleFiles()
-			originY = state.VisibleFilesOriginY()
-			cursorY = state.VisibleFilesCursorY()
-			titleMode = "Visible"
-		}
+	case viewCombined:
+		listType = "Entries"
+	}
+	titleMode := snap.hiddenMode.label()
+	viewWidth := list.frame.width
+	// Only the rows on screen were copied out of the state (and, for files,
+	// stat'ed: executable markers need permission bits, which are loaded lazily)
+	rows, listLen := list.rows, list.total
+	if showEntryCounts {
+		scheduleEntryCounts(g, state, rows)
 	}
 
 	// --- Title ---
 	// Construct the title text WITHOUT ANSI codes
-	viewTitle := fmt.Sprintf(" %s (%s) (%d) ", listType, titleMode, len(listToShow))
+	suppressed := snap.ignoredFiles
+	switch viewName {
+	case viewFolders:
+		suppressed = snap.ignoredDirs
+	case viewCombined:
+		suppressed = snap.ignoredDirs + snap.ignoredFiles
+	}
+	buildTitle := func(otherCount bool) string {
+		count := fmt.Sprint(listLen)
+		if otherCount {
+			count += listOtherModeCount(snap.hiddenMode, list.otherModeLen)
+		}
+		viewTitle := fmt.Sprintf(" %s (%s) (%s) ", listType, titleMode, count)
+		if list.newCount > 0 {
+			viewTitle = fmt.Sprintf(" %s (%s) (%s, %d new) ", listType, titleMode, count, list.newCount)
+		}
+		if snap.loadingDir {
+			viewTitle = fmt.Sprintf(" %s (%s) Loading... (%s entries) ", listType, titleMode, formatCount(snap.dirLoadEntries))
+		}
+		if suppressed > 0 {
+			viewTitle += fmt.Sprintf("[%d ignored] ", suppressed)
+		}
+		if snap.gitIgnored {
+			viewTitle += "[git-ignored hidden] "
+		}
+		if snap.fileGlob.Active() && viewName != viewFolders {
+			viewTitle += fmt.Sprintf("[%s] ", snap.fileGlob.Pattern())
+		}
+		// The commander mode browsers are told apart by their folders
+		if snap.commanderPane {
+			home, _ := os.UserHomeDir()
+			viewTitle = fmt.Sprintf(" %s:%s", shortenPath(snap.cwd, home, max(viewWidth/2, 10)), viewTitle)
+		}
+		return viewTitle
+	}
+	// The count of the other hidden mode's entries goes first when the pane
+	// is too narrow, then the title is cut to the frame
+	viewTitle := buildTitle(true)
+	if displayWidth(viewTitle) > viewWidth-2 {
+		viewTitle = truncateWidth(buildTitle(false), viewWidth-2)
+	}
 	// Set the title directly. Gocui will handle frame styling for focus.
 	v.Title = viewTitle
 
 	// --- Selection Colors Based on Focus ---
 	// Check if this view is the current focus AND no modal/overlay is active
-	isFocused := g.CurrentView() != nil && g.CurrentView().Name() == viewName && !state.IsActionMenuVisible() && !state.IsFileContentViewVisible() && !state.IsHelpVisible() && !state.IsConfirmDeleteVisible() // Check all overlays
+	isFocused := g.CurrentView() != nil && g.CurrentView().Name() == target && !snap.overlay
 
 	if isFocused {
 		// Make the SELECTED LINE bold green when focused
 		v.SelBgColor = gocui.ColorDefault
-		v.SelFgColor = gocui.ColorGreen | gocui.AttrBold // Use attribute for bold
+		v.SelFgColor = styleSel(gocui.ColorGreen | gocui.AttrBold) // Use attribute for bold
 		// Frame highlighting is handled by gocui automatically based on focus
 	} else {
 		// Regular green selection when not focused
 		v.SelBgColor = gocui.ColorDefault
-		v.SelFgColor = gocui.ColorGreen
+		v.SelFgColor = styleSel(gocui.ColorGreen)
+	}
+	if viewName == viewFiles && snap.gridMode {
+		updateFileGrid(g, snap, list, v, isFocused)
+		return
 	}
 
 	// --- Origin and Cursor ---
-	v.SetOrigin(0, originY)
-	_, viewHeight := v.Size()
-
-    // Adjust viewHeight if it's invalid (can happen during resize)
-    if viewHeight <= 0 {
-        viewHeight = 1 // Ensure at least 1 line height
-    }
-
-	relativeCursorY := cursorY - originY
-	// Ensure relative cursor is within view bounds
-	if relativeCursorY < 0 {
-		relativeCursorY = 0
-	} else if relativeCursorY >= viewHeight {
-		relativeCursorY = viewHeight - 1
-	}
-
-	// Set cursor position (relative to origin)
-	// Set cursor only if list is not empty to avoid potential panics/errors
-	if len(listToShow) > 0 {
-        // Ensure cursorY itself is valid before calculating relative position
-        if cursorY < 0 {
-            cursorY = 0
-        } else if cursorY >= len(listToShow) {
-            cursorY = len(listToShow) - 1
-        }
-        // Recalculate relativeCursorY based on clamped absolute cursorY and originY
-        relativeCursorY = cursorY - originY
-        if relativeCursorY < 0 {
-            relativeCursorY = 0
-        } else if relativeCursorY >= viewHeight {
-             relativeCursorY = viewHeight - 1
-        }
-
-		err = v.SetCursor(0, relativeCursorY)
-		if err != nil {
-			// Log error only if setting cursor actually fails when it shouldn't
-			log.Printf("Error setting cursor for view %s (len %d, absY %d, relY %d, origin %d, height %d): %v",
-                       viewName, len(listToShow), cursorY, relativeCursorY, originY, viewHeight, err)
+	// Only the visible window is written to the buffer below, so the view's
+	// own origin stays at the top of that buffer. The snapshot's origin keeps
+	// the cursor on screen, and the state keeps the cursor within the list.
+	v.SetOrigin(0, 0)
+	_ = v.SetCursor(0, list.cursorY-list.originY)
+	drawScrollbar(g, target, list.originY, listLen, snap.overlay)
+
+	// --- Content ---
+	// Folders (and every entry in the combined list) show their size in a
+	// right-aligned column when there's room. Names too long for the space left
+	// are shortened in the middle so the extension stays visible.
+	nameWidth := viewWidth - 4 - dirSizeColumnWidth // Leading space, icon, two separators
+	showSizes := (isFoldersView || viewName == viewCombined) && nameWidth >= 8
+	if !showSizes {
+		nameWidth = viewWidth - 3 // Leading space, icon, separator
+	}
+	// Folders also get a bar of their share of the tree, like ncdu, once the
+	// tree's total is known and if the names keep enough room
+	barColumn, showPercent := 0, false
+	if showSizes && sizeBarsEnabled() && snap.totalSize > 0 && !snap.loadingStats {
+		switch {
+		case nameWidth-sizeBarColumnWidth-sizePercentWidth >= minBarNameWidth:
+			barColumn, showPercent = sizeBarColumnWidth+sizePercentWidth, true
+		case nameWidth-sizeBarColumnWidth >= minBarNameWidth:
+			barColumn = sizeBarColumnWidth
 		}
-	} else {
-		// Explicitly set cursor to 0,0 if list is empty
-		_ = v.SetCursor(0, 0)
-        // Also ensure origin is 0 if list is empty
-        if originY != 0 {
-            _ = v.SetOrigin(0, 0)
-            if isFoldersView {
-                if state.IsShowingHidden() { state.SetHiddenFoldersOriginY(0) } else { state.SetVisibleFoldersOriginY(0) }
-            } else {
-                 if state.IsShowingHidden() { state.SetHiddenFilesOriginY(0) } else { state.SetVisibleFilesOriginY(0) }
-            }
-        }
+		nameWidth -= barColumn
+	}
+	// Nothing to select: explain why instead of leaving the pane blank
+	v.Highlight = listLen > 0
+	if listLen == 0 {
+		fmt.Fprintf(v, " %s%s%s", ansiGray, truncateWidth(emptyListText(snap, list, viewName), viewWidth-1), ansiReset)
+		return
 	}
 
+	for i, item := range rows {
+		// Render the line content using Fprintf
+		// Executables get an ls -F style "*" after the name
+		suffix := ""
+		if isExecutable(item) {
+			suffix = "*"
+		}
+		// Repositories inside the CWD (submodules, worktrees, nested clones) are named as such
+		if label := item.Repo.label(); label != "" {
+			suffix = " (" + label + ")"
+		}
+		if item.Unreadable {
+			suffix = " (unreadable)"
+		}
+		// Color the name by type (recently modified files in yellow); the selected
+		// line's highlight overrides it
+		// Icons wider than a cell (e.g. ASCII mode's "[D]") take it from the name
+		itemWidth := nameWidth - max(displayWidth(item.Icon)-1, 0)
+		count := entryCountLabel(state, item)
+		shortName := middleEllipsis(item.Name, itemWidth-len(suffix)-len(count))
+		name := toCells(shortName)
+		if color := nameColor(item); color != "" {
+			name = color + name + ansiReset
+		}
+		name += suffix
+		if count != "" {
+			name += ansiGray + count + ansiReset
+		}
+		// Entries new since the previous load take a "+" in the leading column
+		lead := " "
+		if list.isNew[i] {
+			lead = newEntryMarker()
+		}
+		if showSizes {
+			padding := strings.Repeat(" ", max(itemWidth-displayWidth(shortName)-len(suffix)-len(count), 0))
+			bar := ""
+			if barColumn > 0 {
+				bar = strings.Repeat(" ", barColumn)
+				if item.IsDir && item.Size >= 0 {
+					bar = sizeBarLabel(item.Size, snap.totalSize, showPercent)
+				}
+			}
+			fmt.Fprintf(v, "%s%s %s%s%s %s\n", lead, item.Icon, name, padding, bar, dirSizeLabel(item.Size))
+		} else {
+			fmt.Fprintf(v, "%s%s %s\n", lead, item.Icon, name)
+		}
+	}
+}
 
-	// --- Content ---
-	for i, item := range listToShow {
-		// Only process lines that might be visible
-		if i >= originY && i < originY+viewHeight {
-			// Render the line content using Fprintf
-			fmt.Fprintf(v, " %s %s\n", item.Icon, item.Name)
-		} else if i >= originY+viewHeight {
-			break // Optimization: stop processing lines below the visible area
-		}
-	}
-    // Add padding if content doesn't fill the view height
-    contentLines := len(listToShow) - originY
-    if contentLines < 0 { contentLines = 0 } // Handle empty list case
-    if contentLines < viewHeight {
-        padding := viewHeight - contentLines
-         // Avoid excessive padding if viewHeight is somehow huge and contentLines small
-        if padding > viewHeight { padding = viewHeight}
-        for i := 0; i < padding; i++ {
-            fmt.Fprintln(v) // Add empty lines
-        }
-    }
+// listOtherModeCount is what a list title adds to its count about the
+// entries the hidden mode leaves out, e.g. " / +4 hidden" in the visible
+// mode, so the title tells whether '.' would show more. "" if there are none
+// or everything is shown.
+func listOtherModeCount(mode hiddenMode, other int) string {
+	switch {
+	case other == 0:
+		return ""
+	case mode == hiddenModeVisible:
+		return fmt.Sprintf(" / +%d hidden", other)
+	case mode == hiddenModeOnly:
+		return fmt.Sprintf(" / +%d visible", other)
+	}
+	return ""
+}
+
+// newEntryMarker is the mark in front of an entry new since the previous load.
+func newEntryMarker() string {
+	return ansiGreen + ansiBold + "+" + ansiReset
+}
+
+// emptyListText is the placeholder of an empty list pane, e.g. "(no visible
+// files — press . to show 3 hidden)". It names the hidden-mode toggle when the
+// other mode has entries, so the user knows switching will help.
+func emptyListText(snap renderSnapshot, list listSnapshot, viewName string) string {
+	if snap.loadingDir {
+		return "Loading..."
+	}
+	kind := "files"
+	switch viewName {
+	case viewFolders:
+		kind = "folders"
+	case viewCombined:
+		kind = "entries"
+	}
+	// The mode is named unless everything is shown
+	mode, otherMode := "visible ", "hidden"
+	switch snap.hiddenMode {
+	case hiddenModeOnly:
+		mode, otherMode = "hidden ", "visible"
+	case hiddenModeAll:
+		mode = ""
+	}
+	if snap.fileGlob.Active() && viewName != viewFolders {
+		key, _ := primaryKey(keyTable, "Lists", "filter.glob")
+		return fmt.Sprintf("(no %s%s match %s %s press %s to change)", mode, kind, snap.fileGlob.Pattern(), glyph("—"), key)
+	}
+	other := list.otherModeLen
+	if other == 0 {
+		if snap.hiddenMode == hiddenModeOnly {
+			return fmt.Sprintf("(no hidden %s)", kind)
+		}
+		return fmt.Sprintf("(no %s)", kind)
+	}
+	key, _ := primaryKey(keyTable, "Lists", "toggle.hidden")
+	return fmt.Sprintf("(no %s%s %s press %s to show %s %s)", mode, kind, glyph("—"), key, formatCount(other), otherMode)
+}
+
+// dirSizeColumnWidth is the width reserved for directory sizes in the Folders pane.
+const dirSizeColumnWidth = 11
+
+// dirSizeLabel renders a directory size for the Folders pane, right-aligned.
+// Pending sizes show a dimmed "…", failed ones a dimmed "?".
+func dirSizeLabel(size int64) string {
+	switch {
+	case size == -1:
+		return fmt.Sprintf("%s%*s%s", ansiDim, dirSizeColumnWidth, glyph("…"), ansiReset)
+	case size < 0:
+		return fmt.Sprintf("%s%*s%s", ansiDim, dirSizeColumnWidth, "?", ansiReset)
+	default:
+		return fmt.Sprintf("%s%*s%s", ansiCyan, dirSizeColumnWidth, formatSize(size), ansiReset)
+	}
+}
+
+// Size bar geometry: a leading space and the bar, then optionally the share
+// as " 61%"; names keep at least minBarNameWidth cells or the bars are dropped.
+const (
+	sizeBarWidth       = 10
+	sizeBarColumnWidth = 1 + sizeBarWidth
+	sizePercentWidth   = 5
+	minBarNameWidth    = 10
+)
+
+// sizeBarEighths are the partial blocks that give bars an eighth-cell resolution.
+var sizeBarEighths = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// sizeBarsEnabled reports whether size bars can be drawn: they need block
+// characters and the contrast of dimmed text, so ASCII and plain mode drop them.
+func sizeBarsEnabled() bool {
+	return !asciiMode && !plainMode
+}
+
+// sizeBarLabel renders a folder's share of total as a gray bar, padded to
+// sizeBarColumnWidth cells, followed by the percentage if showPercent.
+func sizeBarLabel(size, total int64, showPercent bool) string {
+	share := min(float64(size)/float64(total), 1)
+	eighths := int(share * sizeBarWidth * 8)
+	if size > 0 && eighths == 0 {
+		eighths = 1 // Anything non-empty shows
+	}
+	bar := strings.Repeat("█", eighths/8) + sizeBarEighths[eighths%8]
+	bar += strings.Repeat(" ", sizeBarWidth-(eighths+7)/8)
+	label := " " + ansiGray + bar + ansiReset
+	if showPercent {
+		label += fmt.Sprintf("%s%*d%%%s", ansiGray, sizePercentWidth-1, int(share*100+0.5), ansiReset)
+	}
+	return label
 }
 
 // updateFoldersView uses the helper
-func updateFoldersView(g *gocui.Gui, state *AppState) {
-	updateListView(g, state, viewFolders)
+func updateFoldersView(g *gocui.Gui, state *AppState, snap renderSnapshot) {
+	updateListView(g, state, snap, viewFolders)
 }
 
 // updateFilesView uses the helper
-func updateFilesView(g *gocui.Gui, state *AppState) {
-	updateListView(g, state, viewFiles)
+func updateFilesView(g *gocui.Gui, state *AppState, snap renderSnapshot) {
+	updateListView(g, state, snap, viewFiles)
 }
 
 // updateActionMenuView renders the action menu.
@@ -544,14 +1854,114 @@ func updateActionMenuView(g *gocui.Gui, state *AppState) {
 
 	options := state.GetActionMenuOptions()
 	selectedIdx := state.GetActionMenuSelectedIdx()
+	target := state.GetActionMenuItemTarget()
+
+	// Non-selectable header describing the target; options follow the rule
+	width, _ := v.Size()
+	details := actionMenuDetails(target)
+	name := middleEllipsis(target.Name, width-displayWidth(target.Icon)-displayWidth(details)-3)
+	fmt.Fprintf(v, " %s %s%s%s%s%s\n", toCells(target.Icon), ansiBold, toCells(name), ansiReset, ansiDim, details+ansiReset)
+	fmt.Fprintln(v, strings.Repeat(glyph("─"), width))
 
 	for i, option := range options {
+		number := " "
+		if i < 9 {
+			number = fmt.Sprintf("%d", i+1)
+		}
+		base := "" // Attributes to restore after each highlight
 		if i == selectedIdx {
-			// Highlight selected option (Reverse video)
-			fmt.Fprintf(v, "%s %s %s\n", ansiReverse, option.Label, ansiReset)
+			base = ansiReverse // Highlight selected option (Reverse video)
+		}
+		label := option.Label
+		if idx := option.MnemonicIdx; idx >= 0 && idx < len(label) {
+			_, size := utf8.DecodeRuneInString(label[idx:])
+			label = label[:idx] + ansiUnderline + label[idx:idx+size] + ansiReset + base + label[idx+size:]
+		}
+		fmt.Fprintf(v, "%s %s%s%s %s %s\n", base, ansiDim, number, ansiReset+base, label, ansiReset)
+	}
+	// In a short terminal the menu is cut off; scroll the selected option into view
+	_, height := contentSize(v)
+	_ = v.SetOrigin(0, max(selectedIdx+2-height+1, 0))
+}
+
+// actionMenuDetails returns the size and modification time shown after a
+// file's name in the action menu header; folders get none.
+func actionMenuDetails(item FileInfo) string {
+	if item.IsDir {
+		return ""
+	}
+	return fmt.Sprintf("  %s  %s", formatSize(item.Size), item.ModTime.Format("2006-01-02 15:04"))
+}
+
+// actionMenuHeader returns the full, untruncated action menu header text.
+func actionMenuHeader(item FileInfo) string {
+	return fmt.Sprintf(" %s %s%s", item.Icon, item.Name, actionMenuDetails(item))
+}
+
+// propertiesLineCount is the fixed number of lines rendered by updatePropertiesView.
+const propertiesLineCount = 12
+
+// updatePropertiesView renders the properties popup.
+func updatePropertiesView(g *gocui.Gui, state *AppState) {
+	v, err := g.View(viewProperties)
+	if err != nil {
+		return
+	}
+	v.Clear()
+
+	target, props, dirSize, dirItems := state.Properties()
+	const timeLayout = "2006-01-02 15:04:05"
+	unknown := ansiDim + "n/a" + ansiReset
+
+	row := func(label, value string) {
+		fmt.Fprintf(v, " %s%-9s%s %s\n", ansiBold, label, ansiReset, value)
+	}
+
+	fmt.Fprintf(v, " %s %s%s%s\n", target.Icon, ansiBold+ansiGreen, target.Name, ansiReset)
+	if props.StatError != nil {
+		row("Path", props.Path)
+		fmt.Fprintf(v, " %s%s%s\n", ansiRed, trimError(props.StatError), ansiReset)
+		return
+	}
+
+	row("Path", props.Path)
+	kind := props.Kind
+	if props.LinkDest != "" {
+		kind = fmt.Sprintf("%s -> %s", kind, props.LinkDest)
+	}
+	row("Type", kind)
+	row("Size", fmt.Sprintf("%s%s%s (%d bytes)", ansiCyan, formatSize(props.Size), ansiReset, props.Size))
+
+	if props.HasSys {
+		row("On disk", fmt.Sprintf("%s (%d blocks)", formatSize(props.Sys.Blocks*512), props.Sys.Blocks))
+	} else {
+		row("On disk", unknown)
+	}
+	row("Modified", props.ModTime.Format(timeLayout))
+	if props.HasSys {
+		row("Changed", props.Sys.Ctime.Format(timeLayout))
+		row("Accessed", props.Sys.Atime.Format(timeLayout))
+	} else {
+		row("Changed", unknown)
+		row("Accessed", unknown)
+	}
+	row("Mode", fmt.Sprintf("%s (%s)", props.Mode.String(), formatOctalMode(props.Mode)))
+	if props.HasSys {
+		row("Owner", fmt.Sprintf("%s:%s", props.Owner, props.Group))
+		row("Links", fmt.Sprint(props.Sys.Nlink))
+	} else {
+		row("Owner", unknown)
+		row("Links", unknown)
+	}
+
+	if props.Kind == "Directory" {
+		if dirSize < 0 {
+			row("Contents", ansiYellow+"calculating"+glyph("…")+ansiReset)
 		} else {
-			fmt.Fprintf(v, " %s\n", option.Label)
+			row("Contents", fmt.Sprintf("%s%s%s in %d items", ansiCyan, formatSize(dirSize), ansiReset, dirItems))
 		}
+	} else if props.MIMEType != "" {
+		row("MIME type", props.MIMEType)
 	}
 }
 
@@ -563,43 +1973,38 @@ func updateFileContentView(g *gocui.Gui, state *AppState) {
 	}
 	v.Clear() // Clear the view's buffer before rewriting
 
+	// Formatted content is rendered for the current width before measuring it
+	numbering := state.FileContentLineNumbers()
+	if width, height := contentSize(v); width > numbering.gutter() {
+		state.RenderFileContent(width, numbering.gutter(), height)
+	}
+	imageInfo, isImage := state.FileContentImageInfo()
+
 	filename := state.GetFileContentViewFileName()
 	content := state.GetFileContentViewContent()
 	originY := state.GetFileContentViewOriginY()
 	totalLines := state.GetFileContentViewTotalLines()
-	_, viewHeight := v.Size()
+	_, viewHeight := contentSize(v)
 
 	// --- Title ---
-	scrollPercent := 0
-	// Prevent division by zero if totalLines equals viewHeight
-	if totalLines > viewHeight {
-		// Ensure the denominator is not zero or negative
-		denominator := totalLines - viewHeight
-		if denominator > 0 {
-			scrollPercent = (originY * 100) / denominator
-		} else {
-             // If totalLines <= viewHeight after all, it should be 100% visible
-             // Or if somehow originY is non-zero but shouldn't be, reset.
-            if originY == 0 {
-                 scrollPercent = 100 // Fully visible
-            } else {
-                 scrollPercent = 0 // Should technically not happen, maybe indicates error
-            }
-		}
-	} else if totalLines > 0 {
-		// Content fits entirely or is exactly the size of the view
-		scrollPercent = 100
-	} else {
-        // No content (totalLines is 0 or 1 for empty file display)
-        scrollPercent = 100 // Considered fully visible
-    }
-
-    // Clamp scrollPercent just in case
-    if scrollPercent > 100 { scrollPercent = 100 }
-    if scrollPercent < 0 { scrollPercent = 0 }
-
-
-	v.Title = fmt.Sprintf(" %s (%d lines, ~%d%%) ", filename, totalLines, scrollPercent) // Changed to approx %
+	percent := scrollPercent(originY, totalLines, viewHeight)
+
+	// Shorten the name by display width so the line count stays visible
+	endings, showWhitespace := state.FileContentEndings()
+	titleParts := []string{fmt.Sprintf("%d lines", totalLines), fmt.Sprintf("~%d%%", percent)} // Changed to approx %
+	mode := state.FileContentRenderMode()
+	if mode != "" {
+		titleParts = append(titleParts, mode)
+	}
+	if label := endings.Label(); label != "" {
+		titleParts = append(titleParts, label)
+	}
+	titleInfo := " (" + strings.Join(titleParts, ", ") + ") "
+	if isImage {
+		titleInfo = fmt.Sprintf(" (%s) ", imageInfo)
+	}
+	viewWidth, _ := v.Size()
+	v.Title = " " + truncateWidth(filename, viewWidth-2-len(titleInfo)) + titleInfo
 
 	// --- Origin ---
 	// Set the origin *before* writing content. This tells gocui which line
@@ -609,8 +2014,7 @@ func updateFileContentView(g *gocui.Gui, state *AppState) {
 		// Don't return here, still try to render content from the top if origin fails
 	}
 	// Cursor is not used/needed in this view
-	v.SetCursor(0,0) // Explicitly set cursor to 0,0 (relative to origin) as it's not used
-
+	v.SetCursor(0, 0) // Explicitly set cursor to 0,0 (relative to origin) as it's not used
 
 	// --- Content ---
 	// Write the *entire* content to the view's buffer. gocui will handle
@@ -624,21 +2028,46 @@ func updateFileContentView(g *gocui.Gui, state *AppState) {
 	if totalLines < numLinesFromSplit {
 		totalLines = numLinesFromSplit // Update totalLines if split yields more (e.g. trailing newline)
 	}
-	// Recalculate width needed for line numbers based on potentially updated totalLines
+	// Recalculate width needed for line numbers based on potentially updated totalLines;
+	// relative numbers only count up to the bottom of the view
 	lineNumberWidth := len(fmt.Sprintf("%d", totalLines))
+	if numbering == lineNumbersRelative {
+		lineNumberWidth = len(fmt.Sprintf("%d", viewHeight))
+	}
 	if lineNumberWidth < 1 { // Ensure at least width 1
 		lineNumberWidth = 1
 	}
 
+	drawScrollbar(g, viewFileContent, originY, totalLines, false)
+
+	// Image previews are drawn edge to edge, without line numbers
+	if isImage {
+		fmt.Fprint(v, content)
+		return
+	}
+
+	// Rendered lines no longer match the source lines the endings belong to
+	if mode == "rendered" {
+		endings = lineEndings{}
+	}
 
 	// Iterate through *all* lines from the split content
 	for i, line := range lines {
 		// Add line numbers with padding
 		lineNumber := i + 1
-		// Dim the line number color
-		fmt.Fprintf(v, "%s%*d%s ", ansiDim, lineNumberWidth, lineNumber, ansiReset)
+		if numbering == lineNumbersRelative {
+			lineNumber = i - originY // Lines above the view are not shown
+		}
+		// Dim the line number color; lines ending unlike the majority are flagged
+		switch {
+		case numbering == lineNumbersOff:
+		case endings.Deviates(i):
+			fmt.Fprintf(v, "%s%*d!%s", ansiYellow, lineNumberWidth, lineNumber, ansiReset)
+		default:
+			fmt.Fprintf(v, "%s%*d%s ", ansiDim, lineNumberWidth, lineNumber, ansiReset)
+		}
 		// Print the actual line content using Fprintln to add the newline back
-		fmt.Fprintln(v, line)
+		fmt.Fprintln(v, decorateLine(line, i, endings, showWhitespace))
 	}
 
 	// If the content was completely empty and Split returned empty slice,
@@ -647,10 +2076,9 @@ func updateFileContentView(g *gocui.Gui, state *AppState) {
 		fmt.Fprintf(v, "%s%*d%s ", ansiDim, lineNumberWidth, 1, ansiReset)
 		fmt.Fprintln(v, content) // Print the placeholder text
 	} else if len(lines) == 0 && content != "" {
-         // Should not happen if state calculates 1 line for empty, but safety check
-         fmt.Fprintf(v, "%s%*d%s ", ansiDim, lineNumberWidth, 1, ansiReset)
-         fmt.Fprintln(v, content)
-    }
-
+		// Should not happen if state calculates 1 line for empty, but safety check
+		fmt.Fprintf(v, "%s%*d%s ", ansiDim, lineNumberWidth, 1, ansiReset)
+		fmt.Fprintln(v, content)
+	}
 
 }
@@ -4,21 +4,38 @@ package main
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// decimalUnits switches formatSize from powers of 1024 (KiB, MiB, GiB) to
+// powers of 1000 (kB, MB, GB). It is toggled at runtime while background
+// goroutines format sizes, hence atomic.
+var decimalUnits atomic.Bool
+
+// configureSizeUnits applies the "size_units" setting; --si overrides it.
+func configureSizeUnits(cfg config, si bool) {
+	decimalUnits.Store(si || cfg.SizeUnits == "decimal")
+}
 
-	"github.com/atotto/clipboard" // Import clipboard library
+// sizeUnit is a unit suffix and the number of bytes in it.
+type sizeUnit struct {
+	suffix string
+	size   float64
+}
+
+// The units of each mode, largest first.
+var (
+	binarySizeUnits  = []sizeUnit{{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}}
+	decimalSizeUnits = []sizeUnit{{"TB", 1e12}, {"GB", 1e9}, {"MB", 1e6}, {"kB", 1e3}}
 )
 
-// formatSize converts bytes to a human-readable string (KB, MB, GB).
+// formatSize converts bytes to a human-readable string, e.g. "1.50 MiB", or
+// "1.57 MB" in decimal mode.
 func formatSize(sizeBytes int64) string {
-	const (
-		_          = iota // ignore first value by assigning to blank identifier
-		KB float64 = 1 << (10 * iota)
-		MB
-		GB
-		TB // Added Terabyte
-	)
-
 	switch {
 	case sizeBytes == -1: // Initial calculating state
 		return "Calculating..."
@@ -33,18 +50,120 @@ func formatSize(sizeBytes int64) string {
 	}
 
 	size := float64(sizeBytes)
+	units := binarySizeUnits
+	if decimalUnits.Load() {
+		units = decimalSizeUnits
+	}
+	for _, unit := range units {
+		if size >= unit.size {
+			return fmt.Sprintf("%.2f %s", size/unit.size, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", sizeBytes)
+}
+
+// parseByteSize reads a size as written in config.json: a number of bytes,
+// optionally followed by a unit, e.g. "512", "50MiB", "1.5 GB" or "200k".
+// Units are case-insensitive; KiB/MiB/GiB/TiB and the bare K/M/G/T are powers
+// of 1024, kB/MB/GB/TB powers of 1000. Sizes past what an int64 holds are
+// refused.
+func parseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	split := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	number, unit := s, ""
+	if split >= 0 {
+		number, unit = s[:split], strings.TrimSpace(s[split:])
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	multiplier := float64(0)
+	switch strings.ToLower(unit) {
+	case "", "b":
+		multiplier = 1
+	case "k", "kib":
+		multiplier = 1 << 10
+	case "m", "mib":
+		multiplier = 1 << 20
+	case "g", "gib":
+		multiplier = 1 << 30
+	case "t", "tib":
+		multiplier = 1 << 40
+	case "kb":
+		multiplier = 1e3
+	case "mb":
+		multiplier = 1e6
+	case "gb":
+		multiplier = 1e9
+	case "tb":
+		multiplier = 1e12
+	default:
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", s, unit)
+	}
+	size := value * multiplier
+	if size >= math.MaxInt64 { // float64(math.MaxInt64) rounds up to 2^63
+		return 0, fmt.Errorf("invalid size %q: too large", s)
+	}
+	return int64(size), nil
+}
+
+// pluralize renders a count with the noun matching it, e.g. "1 error", "3 errors".
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return formatCount(n) + " " + plural
+}
+
+// formatCount renders n with thousands separators, e.g. 12432 -> "12,432".
+func formatCount(n int) string {
+	digits := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	var b strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	return sign + b.String()
+}
 
+// formatAge renders how long ago something happened in its largest unit,
+// e.g. "2h ago" or "3mo ago".
+func formatAge(d time.Duration) string {
+	const day = 24 * time.Hour
 	switch {
-	case size >= TB:
-		return fmt.Sprintf("%.2f TiB", size/TB)
-	case size >= GB:
-		return fmt.Sprintf("%.2f GiB", size/GB)
-	case size >= MB:
-		return fmt.Sprintf("%.2f MiB", size/MB)
-	case size >= KB:
-		return fmt.Sprintf("%.2f KiB", size/KB)
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case d < day:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	case d < 30*day:
+		return fmt.Sprintf("%dd ago", int(d/day))
+	case d < 365*day:
+		return fmt.Sprintf("%dmo ago", int(d/(30*day)))
 	default:
-		return fmt.Sprintf("%d B", sizeBytes)
+		return fmt.Sprintf("%dy ago", int(d/(365*day)))
+	}
+}
+
+// formatElapsed renders a running time in its two largest units, e.g. "4s",
+// "2m05s" or "1h02m".
+func formatElapsed(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d/time.Minute), int(d%time.Minute/time.Second))
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d/time.Hour), int(d%time.Hour/time.Minute))
 	}
 }
 
@@ -79,13 +198,47 @@ func trimError(err error) string {
 	return errMsg
 }
 
-// copyToClipboard writes the given text to the system clipboard.
-func copyToClipboard(text string) error {
-	err := clipboard.WriteAll(text)
-	if err != nil {
-		// Log the detailed error, but return a simpler one potentially
-		// log.Printf("Clipboard write error: %v", err)
-		return fmt.Errorf("clipboard unavailable") // Or return original error
+// naturalLess compares names case-insensitively, treating runs of digits as
+// numbers so "file2" sorts before "file10". Equal numbers with different
+// zero padding order the shorter run first ("1" < "01").
+func naturalLess(a, b string) bool {
+	ra, rb := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if isDigit(ra[i]) && isDigit(rb[j]) {
+			startA, startB := i, j
+			for i < len(ra) && isDigit(ra[i]) {
+				i++
+			}
+			for j < len(rb) && isDigit(rb[j]) {
+				j++
+			}
+			numA := strings.TrimLeft(string(ra[startA:i]), "0")
+			numB := strings.TrimLeft(string(rb[startB:j]), "0")
+			if len(numA) != len(numB) {
+				return len(numA) < len(numB)
+			}
+			if numA != numB {
+				return numA < numB
+			}
+			if i-startA != j-startB {
+				return i-startA < j-startB
+			}
+			continue
+		}
+		if ra[i] != rb[j] {
+			return ra[i] < rb[j]
+		}
+		i++
+		j++
+	}
+	if len(ra)-i != len(rb)-j {
+		return len(ra)-i < len(rb)-j
 	}
-	return nil
+	return a < b // Only differ in case: keep the order deterministic
+}
+
+// isDigit reports whether r is an ASCII digit.
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
 }
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"512", 512, false},
+		{" 512 ", 512, false},
+		{"512B", 512, false},
+		{"200k", 200 << 10, false},
+		{"50MiB", 50 << 20, false},
+		{"50mib", 50 << 20, false},
+		{"1.5 GB", 1_500_000_000, false},
+		{"2G", 2 << 30, false},
+		{"1kB", 1000, false},
+		{"4TiB", 4 << 40, false},
+		{"8388607TiB", 8388607 << 40, false}, // Just fits
+		{"8388608TiB", 0, true},              // 2^63
+		{"9223372036854775807", 0, true},     // Rounds up to 2^63 as a float
+		{"100000000TB", 0, true},
+		{"", 0, true},
+		{"MiB", 0, true},
+		{"-5", 0, true},
+		{"1.2.3", 0, true},
+		{"5 parsecs", 0, true},
+		{"1e9", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseByteSize(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseByteSize(%q) = %d, %v; want %d (error: %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"file2.txt", "file10.txt", true},
+		{"file10.txt", "file2.txt", false},
+		{"file9", "file10", true},
+		{"v1.9.0", "v1.10.0", true},
+		{"a100b", "a99c", false},
+		{"12345678901234567890x", "9x", false}, // Longer than any integer type
+		{"file01", "file1", false},             // Equal values: the shorter run first
+		{"file1", "file01", true},
+		{"file007", "file8", true},
+		{"file0", "file00", true},
+		{"File2", "file10", true},
+		{"abc", "ABC", false}, // Same name in another case: byte order decides
+		{"ABC", "abc", true},
+		{"file", "file1", true},
+		{"file1", "file", false},
+		{"1", "a", true},
+		{"résumé2", "résumé10", true},
+		{"日本2", "日本10", true},
+		{"Ärger", "ärger2", true},
+		{"x", "x", false},
+	}
+	for _, tt := range tests {
+		if got := naturalLess(tt.a, tt.b); got != tt.want {
+			t.Errorf("naturalLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	names := []string{"img12.png", "img2.png", "IMG1.png", "img02.png", "img10.png", "img.png"}
+	sort.Slice(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+	want := []string{"img.png", "IMG1.png", "img2.png", "img02.png", "img10.png", "img12.png"}
+	if !slices.Equal(names, want) {
+		t.Errorf("sorted to %q, want %q", names, want)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	defer func(saved bool) { decimalUnits.Store(saved) }(decimalUnits.Load())
+	tests := []struct {
+		size            int64
+		binary, decimal string
+	}{
+		{-1, "Calculating...", "Calculating..."},
+		{-2, "Error", "Error"},
+		{-3, "Invalid Size", "Invalid Size"},
+		{0, "0 B", "0 B"},
+		{1, "1 B", "1 B"},
+		{999, "999 B", "999 B"},
+		{1000, "1000 B", "1.00 kB"},
+		{1023, "1023 B", "1.02 kB"},
+		{1024, "1.00 KiB", "1.02 kB"},
+		{1536, "1.50 KiB", "1.54 kB"},
+		{999_999, "976.56 KiB", "1000.00 kB"},
+		{1_000_000, "976.56 KiB", "1.00 MB"},
+		{1 << 20, "1.00 MiB", "1.05 MB"},
+		{1_000_000_000, "953.67 MiB", "1.00 GB"},
+		{1 << 30, "1.00 GiB", "1.07 GB"},
+		{1_000_000_000_000, "931.32 GiB", "1.00 TB"},
+		{1 << 40, "1.00 TiB", "1.10 TB"},
+		{1 << 50, "1024.00 TiB", "1125.90 TB"}, // No unit above TiB/TB
+	}
+	for _, tt := range tests {
+		decimalUnits.Store(false)
+		if got := formatSize(tt.size); got != tt.binary {
+			t.Errorf("formatSize(%d) = %q, want %q", tt.size, got, tt.binary)
+		}
+		decimalUnits.Store(true)
+		if got := formatSize(tt.size); got != tt.decimal {
+			t.Errorf("decimal formatSize(%d) = %q, want %q", tt.size, got, tt.decimal)
+		}
+	}
+}
+
+func TestConfigureSizeUnits(t *testing.T) {
+	defer func(saved bool) { decimalUnits.Store(saved) }(decimalUnits.Load())
+	tests := []struct {
+		units string
+		si    bool
+		want  bool
+	}{
+		{"", false, false},
+		{"binary", false, false},
+		{"decimal", false, true},
+		{"binary", true, true}, // --si wins
+	}
+	for _, tt := range tests {
+		configureSizeUnits(config{SizeUnits: tt.units}, tt.si)
+		if got := decimalUnits.Load(); got != tt.want {
+			t.Errorf("size_units %q, --si %v: decimal %v, want %v", tt.units, tt.si, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// ---- File: version.go ----
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// --- Version ---
+
+// Build information, set by release builds with
+//
+//	go build -ldflags "-X main.version=v1.2.3 -X main.commit=abc1234 -X main.buildDate=2024-01-02"
+//
+// Builds without them fall back to what the Go toolchain records (see versionString).
+var (
+	version   = ""
+	commit    = ""
+	buildDate = ""
+)
+
+// versionString describes this build for --version and the help overlay, e.g.
+// "lazyls v1.2.3 (commit abc1234, built 2024-01-02)". Values not injected via
+// -ldflags come from the module version and VCS stamp of `go install` and
+// `go build` builds.
+func versionString() string {
+	v, rev, date := version, commit, buildDate
+	modified := false
+	if info, ok := debug.ReadBuildInfo(); ok {
+		// Untagged builds get a pseudo-version that just repeats the commit
+		if v == "" && info.Main.Version != "(devel)" && !strings.HasPrefix(info.Main.Version, "v0.0.0-") {
+			v = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if rev == "" {
+					rev = setting.Value
+				}
+			case "vcs.time":
+				if date == "" {
+					date = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true" && commit == ""
+			}
+		}
+	}
+	if v == "" {
+		v = "dev"
+	}
+	if len(rev) > 7 {
+		rev = rev[:7]
+	}
+	if modified {
+		rev += "-dirty"
+	}
+	// vcs.time is RFC 3339; the date is enough
+	if i := strings.IndexByte(date, 'T'); i > 0 {
+		date = date[:i]
+	}
+
+	var details []string
+	if rev != "" {
+		details = append(details, "commit "+rev)
+	}
+	if date != "" {
+		details = append(details, "built "+date)
+	}
+	if len(details) == 0 {
+		return "lazyls " + v
+	}
+	return fmt.Sprintf("lazyls %s (%s)", v, strings.Join(details, ", "))
+}